@@ -8,6 +8,7 @@ import (
 	"log"
 	"net/http"
 	"net/url"
+	"os"
 
 	"github.com/aritradeveops/porichoy/internal/core/jwtutil"
 )
@@ -23,6 +24,23 @@ type TokenResponse struct {
 	} `json:"data"`
 }
 
+// keys trusts the active signing key of this app only, the kid is listed by
+// GET /api/v1/apps/demo.local/keys.
+var keys = jwtutil.NewStaticKeyStore(jwtutil.VerificationKey{
+	ID:         getenv("DEMO_JWT_KID", "demo.local"),
+	Issuer:     "http://localhost:8080",
+	Algorithms: []string{"HS256"},
+	Audience:   []string{"demo.local"},
+	Resolver:   getenv("DEMO_JWT_SECRET_RESOLVER", "env://DEMO_JWT_SECRET"),
+})
+
+func getenv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
 func main() {
 	subFS, err := fs.Sub(publicFS, "public")
 	if err != nil {
@@ -73,7 +91,7 @@ func main() {
 			w.Write([]byte("Unauthorized"))
 			return
 		}
-		userData, err := jwtutil.Verify(c.Value)
+		userData, err := jwtutil.Verify(r.Context(), c.Value, keys)
 		if err != nil {
 			w.WriteHeader(http.StatusUnauthorized)
 			w.Write([]byte("Unauthorized"))
@@ -1,34 +1,40 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/aritradeveops/porichoy/internal/config"
 	"github.com/aritradeveops/porichoy/internal/core/service"
 	"github.com/aritradeveops/porichoy/internal/persistence/db"
 	"github.com/aritradeveops/porichoy/internal/persistence/repository"
+	"github.com/aritradeveops/porichoy/internal/pkg/mailer"
+	"github.com/aritradeveops/porichoy/internal/pkg/sms"
 	"github.com/aritradeveops/porichoy/internal/ports/httpd"
+	"github.com/aritradeveops/porichoy/internal/ports/httpd/authn"
 	"github.com/aritradeveops/porichoy/internal/ports/httpd/handlers"
 	"github.com/aritradeveops/porichoy/internal/ports/httpd/ui"
 	"github.com/aritradeveops/porichoy/pkg/resolver"
 )
 
+// keyRotationInterval is how often scheduled signing key rotations are checked.
+const keyRotationInterval = time.Minute
+
 func Run() error {
 	config, err := config.LoadConfig()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %v", err)
 	}
-	rvr := resolver.NewResolverFactory()
-	r, err := rvr.Auto(config.Database.URIResolver)
-	connectionString, err := r.Resolve(config.Database.URIResolver)
+	connectionString, err := config.Database.ConnectionString()
 	if err != nil {
 		return err
 	}
-	db := db.NewPostgres(connectionString.(string))
+	db := db.NewPostgres(connectionString)
 	err = db.Connect()
 	if err != nil {
 		return fmt.Errorf("failed to connect to database: %v", err)
@@ -38,11 +44,29 @@ func Run() error {
 		return fmt.Errorf("failed to connect to database: %v", err)
 	}
 
+	mailer, err := mailer.New(config.Mail)
+	if err != nil {
+		return err
+	}
+
+	sms, err := sms.New(config.SMS)
+	if err != nil {
+		return err
+	}
+
 	repo := repository.New(dbtx)
-	srv := service.New(config, repo)
+	srv := service.New(config, repo, mailer, sms)
+	if err := srv.CheckMasterKey(); err != nil {
+		return fmt.Errorf("failed to resolve the master key: %v", err)
+	}
+	resolver.UseSecretStore(srv)
+
+	rotationCtx, stopRotation := context.WithCancel(context.Background())
+	defer stopRotation()
+	go srv.RunKeyRotation(rotationCtx, keyRotationInterval)
 	handlers := handlers.New(srv)
 	ui := ui.New(config.UI.Template, srv)
-	httpServer := httpd.NewServer(config, handlers, ui)
+	httpServer := httpd.NewServer(config, handlers, ui, authn.New(srv.SessionKeyStore()))
 
 	go func() {
 		err := httpServer.Start()
@@ -1,30 +1,170 @@
 package config
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/aritradeveops/porichoy/internal/core/validation"
+	"github.com/aritradeveops/porichoy/pkg/resolver"
 	_ "github.com/joho/godotenv/autoload"
 	"go.yaml.in/yaml/v3"
 )
 
+// TLS enables https on the http server. When ClientCAFile is set, client
+// certificates issued by those CAs are accepted for tls_client_auth apps.
+type TLS struct {
+	CertFile     string `yaml:"cert_file" validate:"required"`
+	KeyFile      string `yaml:"key_file" validate:"required"`
+	ClientCAFile string `yaml:"client_ca_file"`
+}
+
 type Http struct {
 	Host string `yaml:"host" validate:"required"`
 	Port int    `yaml:"port" validate:"required"`
+	TLS  *TLS   `yaml:"tls"`
 }
 
+// Database is connected to with URI, or with what URIResolver resolves to
+// for configs written before references could be used everywhere.
 type Database struct {
-	URIResolver string `yaml:"uri_resolver" validate:"required"`
+	URI         string `yaml:"uri" validate:"required_without=URIResolver"`
+	URIResolver string `yaml:"uri_resolver" validate:"required_without=URI,omitempty,resolver"`
+}
+
+func (d Database) ConnectionString() (string, error) {
+	if d.URI != "" {
+		return d.URI, nil
+	}
+	uri, err := resolver.Resolve(d.URIResolver)
+	if err != nil {
+		return "", fmt.Errorf("config: database.uri_resolver: %v", err)
+	}
+	s, ok := uri.(string)
+	if !ok {
+		return "", fmt.Errorf("config: database.uri_resolver: resolved to %T, not a string", uri)
+	}
+	return s, nil
 }
 
 type UI struct {
 	Template string `yaml:"template" validate:"oneof=vanilla"`
 }
 
+// Keys configures the signing keys porichoy generates itself.
+type Keys struct {
+	// MasterKeyResolver resolves the secret generated private keys are
+	// encrypted with at rest. The tokens of email verification, mfa, passkey
+	// ceremonies and email and sms logins are signed with keys derived from
+	// it, so it is required.
+	MasterKeyResolver string `yaml:"master_key_resolver" validate:"required,resolver"`
+	// AppKeySources are the prefixes, e.g. vault://secret/apps/, users other
+	// than root may resolve the keys of their apps from. They are limited to
+	// literal:// otherwise, every app owner can use anything under them.
+	AppKeySources []string `yaml:"app_key_sources" validate:"dive,resolver"`
+}
+
+// Argon2id parameters, Memory is in KiB.
+type Argon2id struct {
+	Memory      uint32 `yaml:"memory" validate:"omitempty,min=8192"`
+	Iterations  uint32 `yaml:"iterations" validate:"omitempty,min=1"`
+	Parallelism uint8  `yaml:"parallelism" validate:"omitempty,min=1"`
+}
+
+type Bcrypt struct {
+	Cost int `yaml:"cost" validate:"omitempty,min=10,max=31"`
+}
+
+// Hashing picks the algorithm new passwords are hashed with. Hashes of the
+// other algorithm, or with other parameters, are replaced on the next login.
+type Hashing struct {
+	Algorithm string   `yaml:"algorithm" validate:"omitempty,oneof=argon2id bcrypt"`
+	Argon2id  Argon2id `yaml:"argon2id"`
+	Bcrypt    Bcrypt   `yaml:"bcrypt"`
+}
+
+// Passwords is the password policy.
+type Passwords struct {
+	// History is how many of the previous passwords of a user can not be
+	// used again, 5 when unset and 0 turns the check off.
+	History *int    `yaml:"history" validate:"omitempty,min=0,max=24"`
+	Hashing Hashing `yaml:"hashing"`
+}
+
+// Login lists the methods users can sign in with, every method when empty.
+// Passkeys are enabled separately by the webauthn relying party.
+type Login struct {
+	Methods []string `yaml:"methods" validate:"dive,oneof=password email sms"`
+}
+
+type SMTP struct {
+	Host     string `yaml:"host" validate:"required"`
+	Port     int    `yaml:"port" validate:"required"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// Mail configures how porichoy sends mail. The smtp driver sends it, file
+// writes .eml files to Dir and log prints it, the latter two are meant for
+// local testing.
+type Mail struct {
+	Driver string `yaml:"driver" validate:"omitempty,oneof=smtp file log"`
+	From   string `yaml:"from" validate:"required_with=Driver"`
+	SMTP   *SMTP  `yaml:"smtp" validate:"required_if=Driver smtp"`
+	Dir    string `yaml:"dir" validate:"required_if=Driver file"`
+}
+
+// SMSWebhook receives every text message as json, Secret signs the body.
+type SMSWebhook struct {
+	URL    string `yaml:"url" validate:"required,url"`
+	Secret string `yaml:"secret"`
+}
+
+// SMS configures how porichoy sends text messages. The webhook driver hands
+// them to an http endpoint, file writes .txt files to Dir and log prints
+// them, the latter two are meant for local testing.
+type SMS struct {
+	Driver  string      `yaml:"driver" validate:"omitempty,oneof=webhook file log"`
+	Dir     string      `yaml:"dir" validate:"required_if=Driver file"`
+	Webhook *SMSWebhook `yaml:"webhook" validate:"required_if=Driver webhook"`
+	// AllowedCountryCodes are the calling codes, like +1 or +880, of the
+	// numbers users can add, every country when empty.
+	AllowedCountryCodes []string `yaml:"allowed_country_codes" validate:"dive,startswith=+,max=4"`
+}
+
+// WebAuthn is the relying party passkeys are registered with. RPID and
+// Origins default to the host and origin of the issuer, passkeys only work
+// on the RPID they were registered for so it must not change afterwards.
+type WebAuthn struct {
+	RPID          string   `yaml:"rp_id"`
+	RPDisplayName string   `yaml:"rp_display_name"`
+	Origins       []string `yaml:"origins" validate:"dive,url"`
+}
+
 type Config struct {
-	Http     Http     `yaml:"http" validate:"required"`
-	Database Database `yaml:"database" validate:"required"`
-	UI       UI       `yaml:"ui" validate:"required"`
+	// Issuer is the iss of every token porichoy signs, it has to be the
+	// public url clients reach the server at.
+	Issuer    string    `yaml:"issuer" validate:"omitempty,url"`
+	Http      Http      `yaml:"http" validate:"required"`
+	Database  Database  `yaml:"database" validate:"required"`
+	UI        UI        `yaml:"ui" validate:"required"`
+	Keys      Keys      `yaml:"keys"`
+	Mail      Mail      `yaml:"mail"`
+	SMS       SMS       `yaml:"sms"`
+	Passwords Passwords `yaml:"passwords"`
+	Login     Login     `yaml:"login"`
+	WebAuthn  WebAuthn  `yaml:"webauthn"`
+}
+
+// IssuerURL returns the configured issuer or the url of the http server.
+func (c *Config) IssuerURL() string {
+	if c.Issuer != "" {
+		return c.Issuer
+	}
+	scheme := "http"
+	if c.Http.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s:%d", scheme, c.Http.Host, c.Http.Port)
 }
 
 func LoadConfig() (*Config, error) {
@@ -34,14 +174,20 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
-	err = yaml.Unmarshal(yamlFile, config)
+	var document yaml.Node
+	err = yaml.Unmarshal(yamlFile, &document)
 	if err != nil {
 		return nil, err
 	}
-
-	// if err := resolveSecrets(config); err != nil {
-	// 	return nil, err
-	// }
+	if err := interpolate(&document, ""); err != nil {
+		return nil, err
+	}
+	if document.Kind != 0 {
+		err = document.Decode(config)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	if err := validation.Validate(config); err != nil {
 		return nil, err
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aritradeveops/porichoy/pkg/resolver"
+	"go.yaml.in/yaml/v3"
+)
+
+// references look like ${env://PORT} or ${vault://secret/porichoy#db}, a bare
+// ${NAME} is read from the environment and $${...} is kept as is
+var reference = regexp.MustCompile(`\$?\$\{([^}]*)\}`)
+
+// interpolate resolves the references in every scalar of the document before
+// it is decoded, so any field can be read from any registered resolver.
+func interpolate(node *yaml.Node, path string) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			if err := interpolate(child, path); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if path != "" {
+				key = path + "." + key
+			}
+			if err := interpolate(node.Content[i+1], key); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			if err := interpolate(child, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		value, err := expand(node.Value)
+		if err != nil {
+			return fmt.Errorf("config: %s: %v", path, err)
+		}
+		if value == node.Value {
+			return nil
+		}
+		node.Value = value
+		// let numbers and booleans decode into their fields, anything else
+		// stays a string even when it reads like null
+		if node.Style == 0 && isPlainScalar(value) {
+			node.Tag = ""
+		} else {
+			node.Tag = "!!str"
+		}
+	}
+	return nil
+}
+
+func expand(value string) (string, error) {
+	var err error
+	expanded := reference.ReplaceAllStringFunc(value, func(match string) string {
+		if err != nil {
+			return match
+		}
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+		key := strings.TrimSpace(match[2 : len(match)-1])
+		if key == "" {
+			err = fmt.Errorf("empty reference %s", match)
+			return match
+		}
+		if !strings.Contains(key, "://") {
+			key = "env://" + key
+		}
+		resolved, resolveErr := resolver.Resolve(key)
+		if resolveErr != nil {
+			err = fmt.Errorf("could not resolve %s: %v", match, resolveErr)
+			return match
+		}
+		return fmt.Sprint(resolved)
+	})
+	return expanded, err
+}
+
+func isPlainScalar(value string) bool {
+	if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return true
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return true
+	}
+	_, err := strconv.ParseBool(value)
+	return err == nil
+}
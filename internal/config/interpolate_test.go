@@ -0,0 +1,65 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.yaml.in/yaml/v3"
+)
+
+func decode(t *testing.T, document string) (*Config, error) {
+	t.Helper()
+	var node yaml.Node
+	assert.NoError(t, yaml.Unmarshal([]byte(document), &node))
+	if err := interpolate(&node, ""); err != nil {
+		return nil, err
+	}
+	config := &Config{}
+	assert.NoError(t, node.Decode(config))
+	return config, nil
+}
+
+func TestInterpolate(t *testing.T) {
+	t.Setenv("PORICHOY_TEST_HOST", "auth.example.com")
+	t.Setenv("PORICHOY_TEST_PORT", "8443")
+	t.Setenv("PORICHOY_TEST_EMPTY", "null")
+	secret := filepath.Join(t.TempDir(), "db.json")
+	assert.NoError(t, os.WriteFile(secret, []byte(`{"uri":"postgresql://file@db/porichoy"}`), 0o600))
+
+	config, err := decode(t, `
+issuer: https://${env://PORICHOY_TEST_HOST}
+http:
+  host: ${PORICHOY_TEST_HOST}
+  port: ${env://PORICHOY_TEST_PORT}
+database:
+  uri: ${file://`+secret+`#uri}
+ui:
+  template: "$${not_a_reference}"
+keys:
+  master_key_resolver: ${env://PORICHOY_TEST_EMPTY}
+`)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://auth.example.com", config.Issuer)
+	assert.Equal(t, "auth.example.com", config.Http.Host)
+	assert.Equal(t, 8443, config.Http.Port)
+	assert.Equal(t, "postgresql://file@db/porichoy", config.Database.URI)
+	assert.Equal(t, "${not_a_reference}", config.UI.Template)
+	assert.Equal(t, "null", config.Keys.MasterKeyResolver)
+}
+
+func TestInterpolate_UnresolvedReference(t *testing.T) {
+	_, err := decode(t, `
+http:
+  tls:
+    cert_file: ${env://PORICHOY_TEST_MISSING}
+`)
+	assert.ErrorContains(t, err, "config: http.tls.cert_file: could not resolve ${env://PORICHOY_TEST_MISSING}")
+
+	_, err = decode(t, `
+database:
+  uri: ${kms://alias/porichoy}
+`)
+	assert.ErrorContains(t, err, "config: database.uri:")
+}
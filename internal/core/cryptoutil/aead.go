@@ -0,0 +1,60 @@
+package cryptoutil
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+)
+
+// DeriveKey derives a 256 bit key for one purpose from a master secret, so
+// the same master secret never encrypts two kinds of data with the same key.
+func DeriveKey(secret string, purpose string) ([]byte, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("cryptoutil: empty master secret")
+	}
+	return hkdf.Key(sha256.New, []byte(secret), nil, purpose, 32)
+}
+
+// Seal encrypts plaintext with AES-256-GCM. The additional data is
+// authenticated but not stored, the same data must be passed to Open.
+func Seal(key []byte, plaintext []byte, additionalData []byte) (string, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, additionalData)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value encrypted by Seal.
+func Open(key []byte, sealed string, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("cryptoutil: sealed value is too short")
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,39 @@
+package cryptoutil
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+)
+
+// ParseCertificatePEM parses the first certificate of a PEM encoded bundle.
+func ParseCertificatePEM(data string) (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("cryptoutil: no certificate found in pem")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// LoadCertPool reads a PEM encoded CA bundle from disk.
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("cryptoutil: no certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+// CertificateThumbprint returns the base64url encoded SHA-256 hash of the DER
+// encoded certificate, as used by the x5t#S256 confirmation method.
+func CertificateThumbprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -2,7 +2,12 @@ package cryptoutil
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
 )
 
 func GenerateHash(length int) (string, error) {
@@ -13,3 +18,43 @@ func GenerateHash(length int) (string, error) {
 	}
 	return hex.EncodeToString(secretBytes), nil
 }
+
+// GenerateCode returns a random numeric code of the given number of digits,
+// short enough to be typed in from an email or a text message.
+func GenerateCode(digits int) (string, error) {
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(digits)), nil)
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", digits, n), nil
+}
+
+// HashSecret hashes a generated secret as sha256$<salt>$<hex(sha256(salt || secret))>.
+// A fast hash is enough since the secrets are random, passwords need a
+// PasswordHasher.
+func HashSecret(secret string) (string, error) {
+	salt, err := GenerateHash(16)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(salt + secret))
+	return "sha256$" + salt + "$" + hex.EncodeToString(sum[:]), nil
+}
+
+// HashToken hashes a random single-use token so it can be looked up by its
+// hash, unlike HashSecret it is unsalted and only fit for random tokens.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifySecret compares a secret to a hash made by HashSecret in constant time.
+func VerifySecret(hashed string, secret string) bool {
+	parts := strings.Split(hashed, "$")
+	if len(parts) != 3 || parts[0] != "sha256" || secret == "" {
+		return false
+	}
+	sum := sha256.Sum256([]byte(parts[1] + secret))
+	return subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(parts[2])) == 1
+}
@@ -0,0 +1,73 @@
+package cryptoutil
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashSecret(t *testing.T) {
+	t.Parallel()
+
+	secret, err := GenerateHash(64)
+	assert.NoError(t, err)
+	hashed, err := HashSecret(secret)
+	assert.NoError(t, err)
+	assert.NotContains(t, hashed, secret)
+
+	assert.True(t, VerifySecret(hashed, secret))
+	assert.False(t, VerifySecret(hashed, secret[1:]))
+	assert.False(t, VerifySecret(hashed, ""))
+	assert.False(t, VerifySecret(secret, secret))
+
+	// every hash has its own salt
+	again, err := HashSecret(secret)
+	assert.NoError(t, err)
+	assert.NotEqual(t, hashed, again)
+}
+
+func TestGenerateCode(t *testing.T) {
+	t.Parallel()
+
+	for range 100 {
+		code, err := GenerateCode(6)
+		assert.NoError(t, err)
+		assert.Len(t, code, 6)
+		assert.Regexp(t, "^[0-9]{6}$", code)
+	}
+}
+
+// the client_secrets migration hashes the existing secrets in sql
+func TestVerifySecret_MigratedHash(t *testing.T) {
+	t.Parallel()
+
+	salt := "0cc175b9c0f1b6a831c399e269772661"
+	sum := sha256.Sum256([]byte(salt + "legacy-secret"))
+	assert.True(t, VerifySecret("sha256$"+salt+"$"+hex.EncodeToString(sum[:]), "legacy-secret"))
+}
+
+func TestSignToken(t *testing.T) {
+	t.Parallel()
+
+	type claims struct {
+		Subject string `json:"sub"`
+	}
+	key := []byte("0123456789abcdef0123456789abcdef")
+	token, err := SignToken(key, claims{Subject: "user"}, time.Hour)
+	assert.NoError(t, err)
+
+	var got claims
+	assert.NoError(t, VerifyToken(key, token, &got))
+	assert.Equal(t, "user", got.Subject)
+
+	assert.ErrorIs(t, VerifyToken([]byte("another key, another purpose...."), token, &got), ErrInvalidToken)
+	assert.ErrorIs(t, VerifyToken(key, token[:len(token)-2], &got), ErrInvalidToken)
+	assert.ErrorIs(t, VerifyToken(key, "not a token", &got), ErrInvalidToken)
+
+	expired, err := SignToken(key, claims{Subject: "user"}, -time.Second)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, VerifyToken(key, expired, &got), ErrExpiredToken)
+}
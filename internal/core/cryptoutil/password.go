@@ -0,0 +1,160 @@
+package cryptoutil
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrUnknownPasswordScheme = errors.New("cryptoutil: unknown password hash scheme")
+
+// PasswordScheme hashes passwords with one algorithm into a PHC string
+// ($id$params$salt$hash), bcrypt's modular crypt format is close enough.
+type PasswordScheme interface {
+	Hash(password string) (string, error)
+	// Matches reports whether the hash was made by this scheme.
+	Matches(hashed string) bool
+	Verify(hashed string, password string) (bool, error)
+	// Outdated reports whether the hash was made with other parameters than
+	// the scheme is configured with.
+	Outdated(hashed string) bool
+}
+
+// PasswordHasher hashes new passwords with the current scheme and verifies
+// hashes of every scheme it knows, so hashes can be upgraded as users log in.
+type PasswordHasher struct {
+	current PasswordScheme
+	schemes []PasswordScheme
+}
+
+func NewPasswordHasher(current PasswordScheme, legacy ...PasswordScheme) *PasswordHasher {
+	return &PasswordHasher{
+		current: current,
+		schemes: append([]PasswordScheme{current}, legacy...),
+	}
+}
+
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	return h.current.Hash(password)
+}
+
+// Verify reports whether the password matches the hash and whether the hash
+// should be replaced with one of the current scheme.
+func (h *PasswordHasher) Verify(hashed string, password string) (ok bool, rehash bool, err error) {
+	for i, scheme := range h.schemes {
+		if !scheme.Matches(hashed) {
+			continue
+		}
+		matched, err := scheme.Verify(hashed, password)
+		if err != nil || !matched {
+			return false, false, err
+		}
+		return true, i != 0 || scheme.Outdated(hashed), nil
+	}
+	return false, false, ErrUnknownPasswordScheme
+}
+
+// Argon2id is the scheme recommended by RFC 9106, Memory is in KiB.
+type Argon2id struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  int
+	KeyLength   uint32
+}
+
+type argon2idHash struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+func (a Argon2id) Hash(password string) (string, error) {
+	salt := make([]byte, a.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, a.Iterations, a.Memory, a.Parallelism, a.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, a.Memory, a.Iterations, a.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (a Argon2id) Matches(hashed string) bool {
+	return strings.HasPrefix(hashed, "$argon2id$")
+}
+
+func (a Argon2id) Verify(hashed string, password string) (bool, error) {
+	h, err := parseArgon2id(hashed)
+	if err != nil {
+		return false, err
+	}
+	key := argon2.IDKey([]byte(password), h.salt, h.iterations, h.memory, h.parallelism, uint32(len(h.key)))
+	return subtle.ConstantTimeCompare(key, h.key) == 1, nil
+}
+
+func (a Argon2id) Outdated(hashed string) bool {
+	h, err := parseArgon2id(hashed)
+	if err != nil {
+		return true
+	}
+	return h.memory != a.Memory || h.iterations != a.Iterations || h.parallelism != a.Parallelism ||
+		len(h.salt) != a.SaltLength || uint32(len(h.key)) != a.KeyLength
+}
+
+func parseArgon2id(hashed string) (argon2idHash, error) {
+	var h argon2idHash
+	parts := strings.Split(hashed, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return h, fmt.Errorf("cryptoutil: malformed argon2id hash")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return h, fmt.Errorf("cryptoutil: unsupported argon2id version")
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &h.memory, &h.iterations, &h.parallelism); err != nil {
+		return h, fmt.Errorf("cryptoutil: malformed argon2id parameters")
+	}
+	var err error
+	if h.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return h, fmt.Errorf("cryptoutil: malformed argon2id salt")
+	}
+	if h.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(h.key) == 0 {
+		return h, fmt.Errorf("cryptoutil: malformed argon2id hash")
+	}
+	return h, nil
+}
+
+// Bcrypt is kept for the hashes made before argon2id was the default.
+type Bcrypt struct {
+	Cost int
+}
+
+func (b Bcrypt) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), b.Cost)
+	return string(hashed), err
+}
+
+func (b Bcrypt) Matches(hashed string) bool {
+	return strings.HasPrefix(hashed, "$2a$") || strings.HasPrefix(hashed, "$2b$") || strings.HasPrefix(hashed, "$2y$")
+}
+
+func (b Bcrypt) Verify(hashed string, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hashed), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (b Bcrypt) Outdated(hashed string) bool {
+	cost, err := bcrypt.Cost([]byte(hashed))
+	return err != nil || cost != b.Cost
+}
@@ -0,0 +1,52 @@
+package cryptoutil
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordHasher(t *testing.T) {
+	t.Parallel()
+
+	argon := Argon2id{Memory: 8 * 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	legacy := Bcrypt{Cost: bcrypt.MinCost}
+	hasher := NewPasswordHasher(argon, legacy)
+
+	hashed, err := hasher.Hash("correct horse battery staple")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hashed, "$argon2id$v=19$m=8192,t=1,p=1$"))
+
+	ok, rehash, err := hasher.Verify(hashed, "correct horse battery staple")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, rehash)
+	ok, _, err = hasher.Verify(hashed, "correct horse battery stapler")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	tests := []struct {
+		name   string
+		scheme PasswordScheme
+	}{
+		{name: "bcrypt hashes are upgraded", scheme: legacy},
+		{name: "other argon2id parameters are upgraded", scheme: Argon2id{Memory: 8 * 1024, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old, err := tt.scheme.Hash("hunter22")
+			assert.NoError(t, err)
+			ok, rehash, err := hasher.Verify(old, "hunter22")
+			assert.NoError(t, err)
+			assert.True(t, ok)
+			assert.True(t, rehash)
+		})
+	}
+
+	_, _, err = hasher.Verify("$md5$nope", "hunter22")
+	assert.ErrorIs(t, err, ErrUnknownPasswordScheme)
+	_, _, err = hasher.Verify("$argon2id$v=19$m=8192,t=1$c2FsdA$a2V5", "hunter22")
+	assert.Error(t, err)
+}
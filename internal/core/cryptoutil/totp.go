@@ -0,0 +1,91 @@
+package cryptoutil
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+const (
+	TotpDigits = 6
+	TotpPeriod = 30 * time.Second
+	// TotpSkew is how many steps either side of now a code is still accepted,
+	// to allow for clock drift on the authenticator.
+	TotpSkew = 1
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTotpSecret returns a random 160 bit secret, base32 encoded the way
+// authenticator apps expect it.
+func GenerateTotpSecret() (string, error) {
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// TotpStep is the RFC 6238 time step t falls in.
+func TotpStep(t time.Time) int64 {
+	return t.Unix() / int64(TotpPeriod/time.Second)
+}
+
+// TotpCode computes the code for one time step with HMAC-SHA1 and dynamic
+// truncation as described in RFC 4226.
+func TotpCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		return "", fmt.Errorf("cryptoutil: invalid totp secret: %w", err)
+	}
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", TotpDigits, value%1_000_000), nil
+}
+
+// VerifyTotp checks a code against the steps around now and returns the
+// matching step. Callers must reject steps at or before the last one used so
+// a code can not be replayed.
+func VerifyTotp(secret string, code string, now time.Time) (int64, bool) {
+	if len(code) != TotpDigits {
+		return 0, false
+	}
+	current := TotpStep(now)
+	for step := current - TotpSkew; step <= current+TotpSkew; step++ {
+		expected, err := TotpCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// TotpURI builds the otpauth:// uri authenticator apps read from a QR code.
+func TotpURI(issuer string, account string, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(TotpDigits))
+	query.Set("period", fmt.Sprint(int(TotpPeriod/time.Second)))
+	return (&url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: query.Encode(),
+	}).String()
+}
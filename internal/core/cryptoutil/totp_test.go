@@ -0,0 +1,53 @@
+package cryptoutil
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// RFC 6238 appendix B, SHA1, truncated to six digits
+func TestTotpCode(t *testing.T) {
+	t.Parallel()
+
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, want := range vectors {
+		code, err := TotpCode(secret, TotpStep(time.Unix(unix, 0)))
+		assert.NoError(t, err)
+		assert.Equal(t, want, code, "time %d", unix)
+	}
+}
+
+func TestVerifyTotp(t *testing.T) {
+	t.Parallel()
+
+	secret, err := GenerateTotpSecret()
+	assert.NoError(t, err)
+	now := time.Now()
+	current := TotpStep(now)
+
+	code, err := TotpCode(secret, current-1)
+	assert.NoError(t, err)
+	step, ok := VerifyTotp(secret, code, now)
+	assert.True(t, ok)
+	assert.Equal(t, current-1, step)
+
+	stale, err := TotpCode(secret, current-3)
+	assert.NoError(t, err)
+	_, ok = VerifyTotp(secret, stale, now)
+	assert.False(t, ok)
+
+	_, ok = VerifyTotp(secret, "12345", now)
+	assert.False(t, ok)
+
+	assert.Contains(t, TotpURI("porichoy", "jane@example.com", secret), "otpauth://totp/porichoy:jane@example.com?")
+}
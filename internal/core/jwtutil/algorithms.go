@@ -0,0 +1,144 @@
+package jwtutil
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Algorithms are the signing algorithms an app can choose from.
+var Algorithms = []string{
+	"HS256", "HS384", "HS512",
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+func IsSupportedAlgorithm(alg string) bool {
+	return slices.Contains(Algorithms, alg)
+}
+
+// IsSymmetricAlgorithm reports whether the algorithm signs with a shared
+// secret, keys of these algorithms are never published.
+func IsSymmetricAlgorithm(alg string) bool {
+	_, ok := jwt.GetSigningMethod(alg).(*jwt.SigningMethodHMAC)
+	return ok
+}
+
+func signingMethod(alg string) (jwt.SigningMethod, error) {
+	method := jwt.GetSigningMethod(alg)
+	if method == nil || !IsSupportedAlgorithm(alg) {
+		return nil, fmt.Errorf("jwtutil: %s is not implemented", alg)
+	}
+	return method, nil
+}
+
+// parsePrivateKey parses the secret of a signing key, a plain secret for the
+// HMAC algorithms and a PEM encoded private key for the others.
+func parsePrivateKey(method jwt.SigningMethod, secret string) (any, error) {
+	switch method.(type) {
+	case *jwt.SigningMethodHMAC:
+		return []byte(secret), nil
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		return jwt.ParseRSAPrivateKeyFromPEM([]byte(secret))
+	case *jwt.SigningMethodECDSA:
+		return jwt.ParseECPrivateKeyFromPEM([]byte(secret))
+	case *jwt.SigningMethodEd25519:
+		return jwt.ParseEdPrivateKeyFromPEM([]byte(secret))
+	default:
+		return nil, fmt.Errorf("jwtutil: %s is not implemented", method.Alg())
+	}
+}
+
+// parsePublicKey accepts either half of the key pair, apps usually only
+// configure the private key.
+func parsePublicKey(method jwt.SigningMethod, secret string) (any, error) {
+	switch method.(type) {
+	case *jwt.SigningMethodHMAC:
+		return []byte(secret), nil
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		if key, err := jwt.ParseRSAPublicKeyFromPEM([]byte(secret)); err == nil {
+			return key, nil
+		}
+	case *jwt.SigningMethodECDSA:
+		if key, err := jwt.ParseECPublicKeyFromPEM([]byte(secret)); err == nil {
+			return key, nil
+		}
+	case *jwt.SigningMethodEd25519:
+		if key, err := jwt.ParseEdPublicKeyFromPEM([]byte(secret)); err == nil {
+			return key, nil
+		}
+	default:
+		return nil, fmt.Errorf("jwtutil: %s is not implemented", method.Alg())
+	}
+	key, err := parsePrivateKey(method, secret)
+	if err != nil {
+		return nil, err
+	}
+	return publicKey(key)
+}
+
+func publicKey(key any) (crypto.PublicKey, error) {
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		return &key.PublicKey, nil
+	case *ecdsa.PrivateKey:
+		return &key.PublicKey, nil
+	case ed25519.PrivateKey:
+		return key.Public(), nil
+	case crypto.Signer:
+		return key.Public(), nil
+	default:
+		return nil, fmt.Errorf("jwtutil: unsupported private key %T", key)
+	}
+}
+
+const (
+	privateKind = "private"
+	publicKind  = "public"
+)
+
+// parsed keys are cached by the digest of their material, a rotated secret
+// misses the cache and the cache is dropped once it grows past maxParsedKeys
+const maxParsedKeys = 256
+
+var parsedKeys = struct {
+	sync.Mutex
+	keys map[[sha256.Size]byte]any
+}{keys: map[[sha256.Size]byte]any{}}
+
+// cachedKey parses the private or public key of a signing key once.
+func cachedKey(kind string, method jwt.SigningMethod, secret string) (any, error) {
+	digest := sha256.Sum256([]byte(kind + "\x00" + method.Alg() + "\x00" + secret))
+	parsedKeys.Lock()
+	key, ok := parsedKeys.keys[digest]
+	parsedKeys.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	var err error
+	if kind == privateKind {
+		key, err = parsePrivateKey(method, secret)
+	} else {
+		key, err = parsePublicKey(method, secret)
+	}
+	if err != nil {
+		return nil, err
+	}
+	parsedKeys.Lock()
+	if len(parsedKeys.keys) >= maxParsedKeys {
+		clear(parsedKeys.keys)
+	}
+	parsedKeys.keys[digest] = key
+	parsedKeys.Unlock()
+	return key, nil
+}
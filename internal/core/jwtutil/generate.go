@@ -0,0 +1,73 @@
+package jwtutil
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// GenerateKey generates the secret of a new signing key for the algorithm,
+// a random secret for HMAC and a PKCS #8 PEM encoded private key otherwise.
+func GenerateKey(alg string) (string, error) {
+	method, err := signingMethod(alg)
+	if err != nil {
+		return "", err
+	}
+	var key crypto.PrivateKey
+	switch method := method.(type) {
+	case *jwt.SigningMethodHMAC:
+		secret := make([]byte, method.Hash.Size()*2)
+		if _, err := rand.Read(secret); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(secret), nil
+	case *jwt.SigningMethodRSA:
+		key, err = rsa.GenerateKey(rand.Reader, rsaKeySize(method.Hash))
+	case *jwt.SigningMethodRSAPSS:
+		key, err = rsa.GenerateKey(rand.Reader, rsaKeySize(method.Hash))
+	case *jwt.SigningMethodECDSA:
+		var curve elliptic.Curve
+		switch method.CurveBits {
+		case 256:
+			curve = elliptic.P256()
+		case 384:
+			curve = elliptic.P384()
+		default:
+			curve = elliptic.P521()
+		}
+		key, err = ecdsa.GenerateKey(curve, rand.Reader)
+	case *jwt.SigningMethodEd25519:
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return "", fmt.Errorf("jwtutil: %s is not implemented", alg)
+	}
+	if err != nil {
+		return "", err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+// rsaKeySize matches the strength of the key to the hash of the algorithm.
+func rsaKeySize(hash crypto.Hash) int {
+	switch hash {
+	case crypto.SHA384:
+		return 3072
+	case crypto.SHA512:
+		return 4096
+	default:
+		return 2048
+	}
+}
@@ -0,0 +1,68 @@
+package jwtutil
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Profile are the standard claims about the end user, see OpenID Connect
+// Core 5.1. They are both part of the ID token and the userinfo response.
+type Profile struct {
+	Name          string `json:"name,omitempty"`
+	Picture       string `json:"picture,omitempty"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+}
+
+// UserInfo is the plain userinfo response.
+type UserInfo struct {
+	Subject string `json:"sub"`
+	Profile
+}
+
+// IDTokenClaims are the claims of an OpenID Connect ID token, the subject is
+// taken from the registered claims.
+type IDTokenClaims struct {
+	Profile
+	Nonce           string   `json:"nonce,omitempty"`
+	AuthTime        int64    `json:"auth_time,omitempty"`
+	Acr             string   `json:"acr,omitempty"`
+	Amr             []string `json:"amr,omitempty"`
+	AuthorizedParty string   `json:"azp,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// userInfoClaims signs the userinfo response, iss and aud are required for
+// signed responses.
+type userInfoClaims struct {
+	Profile
+	jwt.RegisteredClaims
+}
+
+// SignIDToken signs an ID token issued to the client.
+func SignIDToken(key SigningKey, claims IDTokenClaims, clientID string, iss string, lifetime time.Duration) (string, error) {
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		Issuer:    iss,
+		Subject:   claims.Subject,
+		Audience:  []string{clientID},
+		ExpiresAt: jwt.NewNumericDate(now.Add(lifetime)),
+		IssuedAt:  jwt.NewNumericDate(now),
+	}
+	claims.AuthorizedParty = clientID
+	return signClaims(key, "", claims)
+}
+
+// SignUserInfo signs a userinfo response for the client.
+func SignUserInfo(key SigningKey, info UserInfo, clientID string, iss string) (string, error) {
+	return signClaims(key, "", userInfoClaims{
+		Profile: info.Profile,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:   iss,
+			Subject:  info.Subject,
+			Audience: []string{clientID},
+			IssuedAt: jwt.NewNumericDate(time.Now()),
+		},
+	})
+}
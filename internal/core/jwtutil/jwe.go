@@ -0,0 +1,76 @@
+package jwtutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"fmt"
+	"slices"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+// key management and content encryption algorithms apps can register for
+// encrypted ID tokens and userinfo responses
+const (
+	EncryptionAlgRSAOAEP256 = "RSA-OAEP-256"
+	EncryptionAlgECDHES     = "ECDH-ES"
+	EncryptionEncA256GCM    = "A256GCM"
+)
+
+var (
+	EncryptionAlgorithms = []string{EncryptionAlgRSAOAEP256, EncryptionAlgECDHES}
+	EncryptionEncodings  = []string{EncryptionEncA256GCM}
+)
+
+// ParseEncryptionKey parses the public JWK a client registered and checks
+// that it can be used with the key management algorithm.
+func ParseEncryptionKey(raw string, alg string) (*jose.JSONWebKey, error) {
+	var jwk jose.JSONWebKey
+	if err := jwk.UnmarshalJSON([]byte(raw)); err != nil {
+		return nil, fmt.Errorf("jwtutil: invalid encryption jwk: %v", err)
+	}
+	if !jwk.IsPublic() || !jwk.Valid() {
+		return nil, fmt.Errorf("jwtutil: encryption jwk must be a valid public key")
+	}
+	if jwk.Use != "" && jwk.Use != "enc" {
+		return nil, fmt.Errorf("jwtutil: jwk is not meant for encryption")
+	}
+	switch alg {
+	case EncryptionAlgRSAOAEP256:
+		if _, ok := jwk.Key.(*rsa.PublicKey); !ok {
+			return nil, fmt.Errorf("jwtutil: %s requires an RSA key", alg)
+		}
+	case EncryptionAlgECDHES:
+		if _, ok := jwk.Key.(*ecdsa.PublicKey); !ok {
+			return nil, fmt.Errorf("jwtutil: %s requires an EC key", alg)
+		}
+	default:
+		return nil, fmt.Errorf("jwtutil: %s is not implemented", alg)
+	}
+	return &jwk, nil
+}
+
+// Encrypt wraps a signed JWT in a JWE for the client, the result is a nested
+// JWT as described in RFC 7519 section 5.2.
+func Encrypt(signed string, jwk string, alg string, enc string) (string, error) {
+	if !slices.Contains(EncryptionEncodings, enc) {
+		return "", fmt.Errorf("jwtutil: %s is not implemented", enc)
+	}
+	key, err := ParseEncryptionKey(jwk, alg)
+	if err != nil {
+		return "", err
+	}
+	encrypter, err := jose.NewEncrypter(jose.ContentEncryption(enc), jose.Recipient{
+		Algorithm: jose.KeyAlgorithm(alg),
+		Key:       key.Key,
+		KeyID:     key.KeyID,
+	}, (&jose.EncrypterOptions{}).WithContentType("JWT").WithType("JWT"))
+	if err != nil {
+		return "", err
+	}
+	object, err := encrypter.Encrypt([]byte(signed))
+	if err != nil {
+		return "", err
+	}
+	return object.CompactSerialize()
+}
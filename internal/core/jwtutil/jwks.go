@@ -0,0 +1,80 @@
+package jwtutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+)
+
+// JWK is the public half of a signing key as published in a JWK set
+// (RFC 7517, RFC 8037 for the OKP keys).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// EC and OKP
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicJWK returns the publishable form of an asymmetric key, it is
+// advertised for the first of its algorithms.
+func PublicJWK(key VerificationKey) (JWK, error) {
+	jwk := JWK{Kid: key.ID, Use: "sig"}
+	if len(key.Algorithms) == 0 {
+		return jwk, fmt.Errorf("jwtutil: key %s has no algorithm", key.ID)
+	}
+	alg := key.Algorithms[0]
+	jwk.Alg = alg
+	if IsSymmetricAlgorithm(alg) {
+		return jwk, fmt.Errorf("jwtutil: %s keys can not be published", alg)
+	}
+	method, err := signingMethod(alg)
+	if err != nil {
+		return jwk, err
+	}
+	secret, err := keyMaterial(key.Secret, key.Resolver)
+	if err != nil {
+		return jwk, err
+	}
+	public, err := parsePublicKey(method, secret)
+	if err != nil {
+		return jwk, err
+	}
+
+	switch public := public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encodeBase64URL(public.N.Bytes())
+		jwk.E = encodeBase64URL(big.NewInt(int64(public.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (public.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = public.Curve.Params().Name
+		jwk.X = encodeBase64URL(public.X.FillBytes(make([]byte, size)))
+		jwk.Y = encodeBase64URL(public.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = encodeBase64URL(public)
+	default:
+		return jwk, fmt.Errorf("jwtutil: unsupported public key %T", public)
+	}
+	return jwk, nil
+}
+
+func encodeBase64URL(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -1,7 +1,10 @@
 package jwtutil
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/aritradeveops/porichoy/pkg/resolver"
@@ -9,12 +12,35 @@ import (
 	"github.com/google/uuid"
 )
 
+// access token profiles an app can opt into
+const (
+	// user_id and dp claims with the email as the subject
+	ProfileLegacy = "legacy"
+	// JWT Profile for OAuth 2.0 Access Tokens (RFC 9068)
+	ProfileRFC9068 = "rfc9068"
+)
+
+// TypeAccessToken is the typ header of RFC 9068 access tokens.
+const TypeAccessToken = "at+jwt"
+
 type JwtPayload struct {
-	UserID   string `json:"user_id,omitempty"`
-	Name     string `json:"name,omitempty"`
-	Email    string `json:"email,omitempty"`
-	Dp       string `json:"dp,omitempty"`
-	Resolver string `json:"resolver,omitempty"`
+	UserID        string        `json:"user_id,omitempty"`
+	Name          string        `json:"name,omitempty"`
+	Email         string        `json:"email,omitempty"`
+	EmailVerified *bool         `json:"email_verified,omitempty"`
+	Dp            string        `json:"dp,omitempty"`
+	ClientID      string        `json:"client_id,omitempty"`
+	Scope         string        `json:"scope,omitempty"`
+	AuthTime      int64         `json:"auth_time,omitempty"`
+	Acr           string        `json:"acr,omitempty"`
+	Amr           []string      `json:"amr,omitempty"`
+	Cnf           *Confirmation `json:"cnf,omitempty"`
+}
+
+// Confirmation binds a token to a key held by the client (RFC 7800). Only the
+// certificate thumbprint of RFC 8705 is supported as of now.
+type Confirmation struct {
+	X5tS256 string `json:"x5t#S256,omitempty"`
 }
 
 type Claims struct {
@@ -22,26 +48,43 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
-func Sign(alg string, payload JwtPayload, secretResolver string, aud string, iss string, lifetime time.Duration) (string, error) {
-	method := jwt.GetSigningMethod(alg)
-	factory := resolver.NewResolverFactory()
-	payload.Resolver = secretResolver
-	r, err := factory.Auto(secretResolver)
-	if err != nil {
-		return "", fmt.Errorf("jwtutil: could not resolver secret: %v", err)
-	}
-	secret, err := r.Resolve(secretResolver)
-	if err != nil {
-		return "", fmt.Errorf("jwtutil: could not resolver secret: %v", err)
-	}
-	secretStr := secret.(string)
+// SigningKey is the key a token is signed with, its ID ends up in the kid
+// header so verifiers can find the matching VerificationKey. The key material
+// is either given as Secret or resolved from Resolver.
+type SigningKey struct {
+	ID       string
+	Alg      string
+	Resolver string
+	Secret   string
+}
 
+var (
+	ErrUnknownKey        = errors.New("jwtutil: unknown signing key")
+	ErrAlgorithmMismatch = errors.New("jwtutil: algorithm not allowed for key")
+	ErrInvalidAudience   = errors.New("jwtutil: audience not allowed for key")
+)
+
+func Sign(key SigningKey, payload JwtPayload, aud string, iss string, lifetime time.Duration) (string, error) {
+	return SignAccessToken(ProfileLegacy, key, payload, aud, iss, lifetime)
+}
+
+// SignAccessToken signs the payload with the claim layout of the profile.
+func SignAccessToken(profile string, key SigningKey, payload JwtPayload, aud string, iss string, lifetime time.Duration) (string, error) {
 	now := time.Now()
-	token := jwt.NewWithClaims(method, Claims{
+	subject := payload.Email
+	typ := ""
+	if profile == ProfileRFC9068 {
+		// the subject must be stable, the user id is conveyed by sub only
+		subject = payload.UserID
+		payload.UserID = ""
+		payload.Dp = ""
+		typ = TypeAccessToken
+	}
+	return signClaims(key, typ, Claims{
 		JwtPayload: payload,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    iss,
-			Subject:   payload.Email,
+			Subject:   subject,
 			Audience:  []string{aud},
 			ExpiresAt: jwt.NewNumericDate(now.Add(lifetime)),
 			NotBefore: jwt.NewNumericDate(now),
@@ -49,58 +92,84 @@ func Sign(alg string, payload JwtPayload, secretResolver string, aud string, iss
 			ID:        uuid.NewString(),
 		},
 	})
-	switch method.(type) {
-	case *jwt.SigningMethodHMAC:
-		signed, err := token.SignedString([]byte(secretStr))
-		return signed, err
-	case *jwt.SigningMethodRSA:
-		key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(secretStr))
-		if err != nil {
-			return "", err
-		}
-		signed, err := token.SignedString(key)
-		if err != nil {
-			return "", err
-		}
-		return signed, err
+}
 
-	default:
-		return "", fmt.Errorf("jwtutil: %s is not implemented", method.Alg())
+// signClaims signs the claims with the key, typ is left to the default when
+// empty.
+func signClaims(key SigningKey, typ string, claims jwt.Claims) (string, error) {
+	method, err := signingMethod(key.Alg)
+	if err != nil {
+		return "", err
+	}
+	secretStr, err := keyMaterial(key.Secret, key.Resolver)
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = key.ID
+	if typ != "" {
+		token.Header["typ"] = typ
+	}
+	signingKey, err := cachedKey(privateKind, method, secretStr)
+	if err != nil {
+		return "", err
 	}
+	return token.SignedString(signingKey)
 }
 
-func Verify(token string) (*JwtPayload, error) {
-	factory := resolver.NewResolverFactory()
-
+// Verify checks the token against the key its iss and kid point to in the
+// store. Nothing in the token decides which secret it is verified with.
+func Verify(ctx context.Context, token string, keys KeyStore) (*JwtPayload, error) {
 	claims := &Claims{}
+	var key VerificationKey
 
 	parsed, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
-		r, err := factory.Auto(claims.Resolver)
-		if err != nil {
-			return nil, fmt.Errorf("jwtutil: could not resolve secret: %v", err)
+		kid, _ := t.Header["kid"].(string)
+		if kid == "" || claims.Issuer == "" {
+			return nil, ErrUnknownKey
 		}
-		secret, err := r.Resolve(claims.Resolver)
+		var err error
+		key, err = keys.Lookup(ctx, claims.Issuer, kid)
 		if err != nil {
-			return nil, fmt.Errorf("jwtutil: could not resolve secret: %v", err)
+			return nil, fmt.Errorf("%w: %v", ErrUnknownKey, err)
 		}
-		switch t.Method.(type) {
-		case *jwt.SigningMethodHMAC:
-			return []byte(secret.(string)), nil
-		case *jwt.SigningMethodRSA:
-			key, err := jwt.ParseRSAPublicKeyFromPEM([]byte(secret.(string)))
-			if err != nil {
-				return nil, err
-			}
-			return key, nil
-		default:
-			return nil, fmt.Errorf("jwtutil: %s is not implemented", t.Method.Alg())
+		if !slices.Contains(key.Algorithms, t.Method.Alg()) {
+			return nil, ErrAlgorithmMismatch
 		}
-	})
+		secret, err := keyMaterial(key.Secret, key.Resolver)
+		if err != nil {
+			return nil, err
+		}
+		return cachedKey(publicKind, t.Method, secret)
+	}, jwt.WithExpirationRequired(), jwt.WithIssuedAt())
 	if err != nil {
 		return nil, err
 	}
 	if !parsed.Valid {
 		return nil, fmt.Errorf("jwtutil: invalid token")
 	}
+	if len(key.Audience) > 0 && !slices.ContainsFunc(claims.Audience, func(aud string) bool {
+		return slices.Contains(key.Audience, aud)
+	}) {
+		return nil, ErrInvalidAudience
+	}
+	if parsed.Header["typ"] == TypeAccessToken {
+		claims.UserID = claims.Subject
+	}
 	return &claims.JwtPayload, nil
 }
+
+func keyMaterial(secret string, secretResolver string) (string, error) {
+	if secret != "" {
+		return secret, nil
+	}
+	return resolveSecret(secretResolver)
+}
+
+func resolveSecret(secretResolver string) (string, error) {
+	secret, err := resolver.Resolve(secretResolver)
+	if err != nil {
+		return "", fmt.Errorf("jwtutil: could not resolve secret: %v", err)
+	}
+	return secret.(string), nil
+}
@@ -1,10 +1,20 @@
 package jwtutil
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"testing"
 	"time"
 
+	"github.com/go-jose/go-jose/v4"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 )
@@ -101,12 +111,11 @@ func TestJwtSign_AllAlgorithms(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 			token, err := Sign(
-				tt.alg,
+				SigningKey{ID: "localhost", Alg: tt.alg, Resolver: tt.resolver},
 				JwtPayload{
 					UserID: "user_" + tt.name,
 					Email:  "user_" + tt.name,
 				},
-				tt.resolver,
 				"localhost",
 				"localhost",
 				10*time.Second,
@@ -120,3 +129,274 @@ func TestJwtSign_AllAlgorithms(t *testing.T) {
 		})
 	}
 }
+
+func TestSignAccessToken_RFC9068(t *testing.T) {
+	t.Parallel()
+
+	token, err := SignAccessToken(
+		ProfileRFC9068,
+		SigningKey{ID: "example.com", Alg: "HS256", Resolver: fmt.Sprintf("literal://%s", testHmacKey)},
+		JwtPayload{
+			UserID:   "0b6d3f2e-8f5c-4a8e-9a43-4c1c2d0f8a11",
+			Email:    "user@example.com",
+			Dp:       "https://example.com/dp.png",
+			ClientID: "example.com",
+			Scope:    "read write",
+			Amr:      []string{"pwd"},
+		},
+		"https://api.example.com",
+		"localhost",
+		10*time.Second,
+	)
+	assert.NoError(t, err)
+
+	claims := jwt.MapClaims{}
+	parsed, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (any, error) {
+		return []byte(testHmacKey), nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, TypeAccessToken, parsed.Header["typ"])
+	assert.Equal(t, "0b6d3f2e-8f5c-4a8e-9a43-4c1c2d0f8a11", claims["sub"])
+	assert.Equal(t, "example.com", claims["client_id"])
+	assert.Equal(t, "read write", claims["scope"])
+	assert.NotEmpty(t, claims["jti"])
+	assert.NotContains(t, claims, "user_id")
+	assert.NotContains(t, claims, "dp")
+
+	keys := NewStaticKeyStore(VerificationKey{
+		ID:         "example.com",
+		Issuer:     "localhost",
+		Algorithms: []string{"HS256"},
+		Resolver:   fmt.Sprintf("literal://%s", testHmacKey),
+	})
+	payload, err := Verify(context.Background(), token, keys)
+	assert.NoError(t, err)
+	assert.Equal(t, "0b6d3f2e-8f5c-4a8e-9a43-4c1c2d0f8a11", payload.UserID)
+}
+
+func TestVerify_KeyStore(t *testing.T) {
+	t.Parallel()
+
+	hmacResolver := fmt.Sprintf("literal://%s", testHmacKey)
+	keys := NewStaticKeyStore(
+		VerificationKey{
+			ID:         "hmac",
+			Issuer:     "https://issuer.example.com",
+			Algorithms: []string{"HS256"},
+			Audience:   []string{"app.example.com"},
+			Resolver:   hmacResolver,
+		},
+		VerificationKey{
+			ID:         "rsa",
+			Issuer:     "https://issuer.example.com",
+			Algorithms: []string{"RS256"},
+			Audience:   []string{"app.example.com"},
+			Resolver:   "literal://" + testRSAPublicKey,
+		},
+	)
+	sign := func(key SigningKey, aud, iss string) string {
+		token, err := Sign(key, JwtPayload{Email: "user@example.com"}, aud, iss, 10*time.Second)
+		assert.NoError(t, err)
+		return token
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{
+			name:  "hmac",
+			token: sign(SigningKey{ID: "hmac", Alg: "HS256", Resolver: hmacResolver}, "app.example.com", "https://issuer.example.com"),
+		},
+		{
+			name:  "rsa",
+			token: sign(SigningKey{ID: "rsa", Alg: "RS256", Resolver: "literal://" + testRSAPrivateKey}, "app.example.com", "https://issuer.example.com"),
+		},
+		{
+			name:  "unknown kid",
+			token: sign(SigningKey{ID: "other", Alg: "HS256", Resolver: hmacResolver}, "app.example.com", "https://issuer.example.com"),
+			err:   ErrUnknownKey,
+		},
+		{
+			name:  "unknown issuer",
+			token: sign(SigningKey{ID: "hmac", Alg: "HS256", Resolver: hmacResolver}, "app.example.com", "https://evil.example.com"),
+			err:   ErrUnknownKey,
+		},
+		{
+			name:  "forged secret",
+			token: sign(SigningKey{ID: "hmac", Alg: "HS256", Resolver: "literal://forged"}, "app.example.com", "https://issuer.example.com"),
+			err:   jwt.ErrTokenSignatureInvalid,
+		},
+		{
+			// the public key of an RSA key must never be usable as an HMAC secret
+			name:  "algorithm confusion",
+			token: sign(SigningKey{ID: "rsa", Alg: "HS256", Resolver: "literal://" + testRSAPublicKey}, "app.example.com", "https://issuer.example.com"),
+			err:   ErrAlgorithmMismatch,
+		},
+		{
+			name:  "wrong audience",
+			token: sign(SigningKey{ID: "hmac", Alg: "HS256", Resolver: hmacResolver}, "other.example.com", "https://issuer.example.com"),
+			err:   ErrInvalidAudience,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			payload, err := Verify(context.Background(), tt.token, keys)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "user@example.com", payload.Email)
+		})
+	}
+}
+
+func TestSignAndVerify_AsymmetricAlgorithms(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	ecKey := func(curve elliptic.Curve) any {
+		key, err := ecdsa.GenerateKey(curve, rand.Reader)
+		assert.NoError(t, err)
+		return key
+	}
+
+	tests := []struct {
+		alg string
+		key any
+		kty string
+	}{
+		{alg: "PS256", key: rsaKey, kty: "RSA"},
+		{alg: "ES256", key: ecKey(elliptic.P256()), kty: "EC"},
+		{alg: "ES384", key: ecKey(elliptic.P384()), kty: "EC"},
+		{alg: "ES512", key: ecKey(elliptic.P521()), kty: "EC"},
+		{alg: "EdDSA", key: edKey, kty: "OKP"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.alg, func(t *testing.T) {
+			t.Parallel()
+			der, err := x509.MarshalPKCS8PrivateKey(tt.key)
+			assert.NoError(t, err)
+			resolver := "literal://" + string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+
+			token, err := Sign(SigningKey{ID: tt.alg, Alg: tt.alg, Resolver: resolver}, JwtPayload{Email: "user@example.com"}, "localhost", "localhost", 10*time.Second)
+			assert.NoError(t, err)
+
+			key := VerificationKey{ID: tt.alg, Issuer: "localhost", Algorithms: []string{tt.alg}, Resolver: resolver}
+			payload, err := Verify(context.Background(), token, NewStaticKeyStore(key))
+			assert.NoError(t, err)
+			assert.Equal(t, "user@example.com", payload.Email)
+
+			jwk, err := PublicJWK(key)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.kty, jwk.Kty)
+			assert.Equal(t, tt.alg, jwk.Alg)
+			assert.Equal(t, tt.alg, jwk.Kid)
+		})
+	}
+}
+
+func TestPublicJWK_Symmetric(t *testing.T) {
+	t.Parallel()
+
+	_, err := PublicJWK(VerificationKey{ID: "hmac", Algorithms: []string{"HS256"}, Resolver: "literal://" + testHmacKey})
+	assert.Error(t, err)
+}
+
+func TestGenerateKey_AllAlgorithms(t *testing.T) {
+	t.Parallel()
+
+	for _, alg := range Algorithms {
+		t.Run(alg, func(t *testing.T) {
+			t.Parallel()
+			secret, err := GenerateKey(alg)
+			assert.NoError(t, err)
+
+			token, err := Sign(SigningKey{ID: alg, Alg: alg, Secret: secret}, JwtPayload{Email: "user@example.com"}, "localhost", "localhost", 10*time.Second)
+			assert.NoError(t, err)
+
+			key := VerificationKey{ID: alg, Issuer: "localhost", Algorithms: []string{alg}, Secret: secret}
+			payload, err := Verify(context.Background(), token, NewStaticKeyStore(key))
+			assert.NoError(t, err)
+			assert.Equal(t, "user@example.com", payload.Email)
+		})
+	}
+}
+
+func TestSignIDToken_Encrypted(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		alg     string
+		public  any
+		private any
+	}{
+		{alg: EncryptionAlgRSAOAEP256, public: &rsaKey.PublicKey, private: rsaKey},
+		{alg: EncryptionAlgECDHES, public: &ecKey.PublicKey, private: ecKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.alg, func(t *testing.T) {
+			t.Parallel()
+			jwk, err := json.Marshal(jose.JSONWebKey{Key: tt.public, KeyID: "enc-1", Use: "enc"})
+			assert.NoError(t, err)
+
+			signingKey := SigningKey{ID: "sig-1", Alg: "HS256", Secret: testHmacKey}
+			idToken, err := SignIDToken(signingKey, IDTokenClaims{
+				Profile:          Profile{Email: "user@example.com"},
+				Nonce:            "n-0S6_WzA2Mj",
+				RegisteredClaims: jwt.RegisteredClaims{Subject: "0b6d3f2e-8f5c-4a8e-9a43-4c1c2d0f8a11"},
+			}, "client.example.com", "https://issuer.example.com", time.Minute)
+			assert.NoError(t, err)
+
+			encrypted, err := Encrypt(idToken, string(jwk), tt.alg, EncryptionEncA256GCM)
+			assert.NoError(t, err)
+
+			object, err := jose.ParseEncrypted(encrypted, []jose.KeyAlgorithm{jose.KeyAlgorithm(tt.alg)}, []jose.ContentEncryption{jose.A256GCM})
+			assert.NoError(t, err)
+			assert.Equal(t, "JWT", object.Header.ExtraHeaders[jose.HeaderContentType])
+			nested, err := object.Decrypt(tt.private)
+			assert.NoError(t, err)
+			assert.Equal(t, idToken, string(nested))
+
+			claims := jwt.MapClaims{}
+			_, err = jwt.ParseWithClaims(string(nested), claims, func(token *jwt.Token) (any, error) {
+				return []byte(testHmacKey), nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, "0b6d3f2e-8f5c-4a8e-9a43-4c1c2d0f8a11", claims["sub"])
+			assert.Equal(t, "client.example.com", claims["azp"])
+			assert.Equal(t, "n-0S6_WzA2Mj", claims["nonce"])
+			assert.Equal(t, "user@example.com", claims["email"])
+		})
+	}
+}
+
+func TestParseEncryptionKey_Mismatch(t *testing.T) {
+	t.Parallel()
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	jwk, err := json.Marshal(jose.JSONWebKey{Key: &ecKey.PublicKey})
+	assert.NoError(t, err)
+	_, err = ParseEncryptionKey(string(jwk), EncryptionAlgRSAOAEP256)
+	assert.Error(t, err)
+
+	private, err := json.Marshal(jose.JSONWebKey{Key: ecKey})
+	assert.NoError(t, err)
+	_, err = ParseEncryptionKey(string(private), EncryptionAlgECDHES)
+	assert.Error(t, err)
+}
@@ -0,0 +1,43 @@
+package jwtutil
+
+import (
+	"context"
+	"fmt"
+)
+
+// VerificationKey is a key trusted to verify tokens of one issuer, along with
+// what tokens signed by it are allowed to look like.
+type VerificationKey struct {
+	ID         string
+	Issuer     string
+	Algorithms []string
+	Audience   []string
+	Resolver   string
+	Secret     string
+}
+
+// KeyStore looks up the trusted key for an issuer and key id.
+type KeyStore interface {
+	Lookup(ctx context.Context, issuer string, kid string) (VerificationKey, error)
+}
+
+// StaticKeyStore is a KeyStore over a fixed set of keys.
+type StaticKeyStore struct {
+	keys map[string]VerificationKey
+}
+
+func NewStaticKeyStore(keys ...VerificationKey) *StaticKeyStore {
+	store := &StaticKeyStore{keys: map[string]VerificationKey{}}
+	for _, key := range keys {
+		store.keys[key.Issuer+"#"+key.ID] = key
+	}
+	return store
+}
+
+func (s *StaticKeyStore) Lookup(ctx context.Context, issuer string, kid string) (VerificationKey, error) {
+	key, ok := s.keys[issuer+"#"+kid]
+	if !ok {
+		return key, fmt.Errorf("jwtutil: no key %s for issuer %s", kid, issuer)
+	}
+	return key, nil
+}
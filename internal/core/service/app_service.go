@@ -2,15 +2,22 @@ package service
 
 import (
 	"context"
-	"fmt"
+	"errors"
 
 	"github.com/aritradeveops/porichoy/internal/core/cryptoutil"
+	"github.com/aritradeveops/porichoy/internal/core/jwtutil"
 	"github.com/aritradeveops/porichoy/internal/core/validation"
 	"github.com/aritradeveops/porichoy/internal/persistence/repository"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+var (
+	ErrAppNotFound  = errors.New("app_service: app not found")
+	ErrAppForbidden = errors.New("app_service: only the owner of the app can manage it")
+)
+
 type CreateAppPayload struct {
 	Name string `json:"name" validate:"required,min=3"`
 	// TODO: think about this field
@@ -21,52 +28,150 @@ type CreateAppPayload struct {
 	SuccessCallbackUrl   string   `json:"success_callback_url" validate:"required,url"`
 	ErrorCallbackUrl     string   `json:"error_callback_url" validate:"required,url"`
 	JwtAlgo              string   `json:"jwt_algo" validate:"required,jwt_algo"`
-	JwtSecretResolver    string   `json:"jwt_secret_resolver" validate:"required,resolver"`
+	JwtSecretResolver    string   `json:"jwt_secret_resolver" validate:"omitempty,resolver"`
 	JwtLifetime          string   `json:"jwt_lifetime" validate:"required,duration"`
 	RefreshTokenLifetime string   `json:"refresh_token_lifetime" validate:"required,duration"`
+	// how the app authenticates at the token endpoint, defaults to client_secret_post
+	TokenEndpointAuthMethod string `json:"token_endpoint_auth_method" validate:"omitempty,oneof=client_secret_post tls_client_auth self_signed_tls_client_auth"`
+	// expected subject DN of the client certificate for tls_client_auth
+	TlsClientAuthSubjectDn string `json:"tls_client_auth_subject_dn" validate:"required_if=TokenEndpointAuthMethod tls_client_auth"`
+	// PEM encoded certificate for self_signed_tls_client_auth
+	TlsClientCertificate string `json:"tls_client_certificate" validate:"required_if=TokenEndpointAuthMethod self_signed_tls_client_auth"`
+	// claim layout of the access tokens, rfc9068 or the default legacy one
+	AccessTokenProfile string `json:"access_token_profile" validate:"omitempty,oneof=legacy rfc9068"`
+	// public JWK the ID tokens and userinfo responses are encrypted with
+	EncryptionJwk string `json:"encryption_jwk" validate:"required_with=EncryptionAlg"`
+	EncryptionAlg string `json:"encryption_alg" validate:"required_with=EncryptionJwk,omitempty,oneof=RSA-OAEP-256 ECDH-ES"`
+	EncryptionEnc string `json:"encryption_enc" validate:"omitempty,oneof=A256GCM"`
+	// users have to verify their email before they can sign in to the app
+	RequireVerifiedEmail bool `json:"require_verified_email"`
+	// users have to sign in with a second factor to use the app
+	RequireMfa bool `json:"require_mfa"`
+}
+
+// CreatedApp carries the client secret of a new app, it can not be shown
+// again and is replaced with RotateClientSecret when lost.
+type CreatedApp struct {
+	repository.App
+	ClientSecret string `json:"client_secret"`
 }
 
-func (s *Service) CreateApp(ctx context.Context, initiator string, payload CreateAppPayload) (repository.App, error) {
+func (s *Service) CreateApp(ctx context.Context, initiator string, payload CreateAppPayload) (CreatedApp, error) {
 	var app repository.App
 	errs := validation.Validate(payload)
 	if errs != nil {
-		return app, errs
+		return CreatedApp{App: app}, errs
+	}
+
+	if payload.TokenEndpointAuthMethod == "" {
+		payload.TokenEndpointAuthMethod = AuthMethodClientSecretPost
+	}
+	if payload.AccessTokenProfile == "" {
+		payload.AccessTokenProfile = jwtutil.ProfileLegacy
+	}
+	if err := s.checkSecretReference(initiator, payload.JwtSecretResolver); err != nil {
+		return CreatedApp{}, err
+	}
+	if payload.JwtSecretResolver == "" {
+		// fail before anything is stored, the key can not be generated
+		if _, err := s.masterKey(signingKeysPurpose); err != nil {
+			return CreatedApp{App: app}, err
+		}
+	}
+	if payload.EncryptionJwk != "" {
+		if _, err := jwtutil.ParseEncryptionKey(payload.EncryptionJwk, payload.EncryptionAlg); err != nil {
+			return CreatedApp{App: app}, validation.ValidationErrors{{
+				Field: "encryption_jwk",
+				Code:  "jwk",
+			}}
+		}
+		if payload.EncryptionEnc == "" {
+			payload.EncryptionEnc = jwtutil.EncryptionEncA256GCM
+		}
+	}
+	if payload.TlsClientCertificate != "" {
+		if _, err := cryptoutil.ParseCertificatePEM(payload.TlsClientCertificate); err != nil {
+			return CreatedApp{App: app}, validation.ValidationErrors{{
+				Field: "tls_client_certificate",
+				Code:  "certificate",
+			}}
+		}
 	}
 
 	// TODO: think about this field
 	clientId := payload.Domain
-	app, err := s.repository.CreateApp(ctx, repository.CreateAppParams{
-		Name:       payload.Name,
-		Domain:     payload.Domain,
-		LandingUrl: payload.LandingUrl,
-		Logo:       pgtype.Text{String: payload.Logo, Valid: payload.Logo != ""},
-		CreatedBy:  uuid.MustParse(initiator),
-		ClientID:   clientId,
-	})
+	createdBy := uuid.MustParse(initiator)
+	// the app is only stored along with its keys and client secret
+	var clientSecret string
+	err := s.inTx(ctx, func(q repository.Querier) error {
+		var err error
+		app, err = q.CreateApp(ctx, repository.CreateAppParams{
+			Name:       payload.Name,
+			Domain:     payload.Domain,
+			LandingUrl: payload.LandingUrl,
+			Logo:       pgtype.Text{String: payload.Logo, Valid: payload.Logo != ""},
+			CreatedBy:  createdBy,
+			ClientID:   clientId,
+		})
+		if err != nil {
+			return err
+		}
 
+		err = q.CreateOauthInfo(ctx, repository.CreateOauthInfoParams{
+			RedirectUris:            payload.RedirectUris,
+			SuccessCallbackUrl:      payload.SuccessCallbackUrl,
+			ErrorCallbackUrl:        payload.ErrorCallbackUrl,
+			JwtAlgo:                 payload.JwtAlgo,
+			JwtSecretResolver:       pgtype.Text{String: payload.JwtSecretResolver, Valid: payload.JwtSecretResolver != ""},
+			JwtLifetime:             payload.JwtLifetime,
+			RefreshTokenLifetime:    payload.RefreshTokenLifetime,
+			AppID:                   app.ID,
+			CreatedBy:               createdBy,
+			TokenEndpointAuthMethod: payload.TokenEndpointAuthMethod,
+			TlsClientAuthSubjectDn:  pgtype.Text{String: payload.TlsClientAuthSubjectDn, Valid: payload.TlsClientAuthSubjectDn != ""},
+			TlsClientCertificate:    pgtype.Text{String: payload.TlsClientCertificate, Valid: payload.TlsClientCertificate != ""},
+			AccessTokenProfile:      payload.AccessTokenProfile,
+			EncryptionJwk:           pgtype.Text{String: payload.EncryptionJwk, Valid: payload.EncryptionJwk != ""},
+			EncryptionAlg:           pgtype.Text{String: payload.EncryptionAlg, Valid: payload.EncryptionAlg != ""},
+			EncryptionEnc:           pgtype.Text{String: payload.EncryptionEnc, Valid: payload.EncryptionEnc != ""},
+			RequireVerifiedEmail:    payload.RequireVerifiedEmail,
+			RequireMfa:              payload.RequireMfa,
+		})
+		if err != nil {
+			return err
+		}
+
+		// without a resolver the key pair is generated for the app
+		key, err := s.signingKeyParams(app.ID, payload.JwtAlgo, payload.JwtSecretResolver, KeyStateActive, createdBy)
+		if err != nil {
+			return err
+		}
+		_, err = q.CreateSigningKey(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		// only the hash is stored, the secret is shown this once
+		clientSecret, err = createClientSecret(ctx, q, app.ID, createdBy, nil)
+		return err
+	})
 	if err != nil {
-		return app, err
+		return CreatedApp{}, err
 	}
+	return CreatedApp{App: app, ClientSecret: clientSecret}, nil
+}
 
-	clientSecret, err := cryptoutil.GenerateHash(64)
-	if err != nil {
+// findManagedApp finds an app the initiator may manage, only the user who
+// created it and root can.
+func (s *Service) findManagedApp(ctx context.Context, initiator string, clientID string) (repository.FindAppByClientIDRow, error) {
+	app, err := s.repository.FindAppByClientID(ctx, clientID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return app, ErrAppNotFound
+	} else if err != nil {
 		return app, err
 	}
-
-	fmt.Println(clientSecret)
-
-	err = s.repository.CreateOauthInfo(ctx, repository.CreateOauthInfoParams{
-		ClientSecret:         clientSecret,
-		RedirectUris:         payload.RedirectUris,
-		SuccessCallbackUrl:   payload.SuccessCallbackUrl,
-		ErrorCallbackUrl:     payload.ErrorCallbackUrl,
-		JwtAlgo:              payload.JwtAlgo,
-		JwtSecretResolver:    pgtype.Text{String: payload.JwtSecretResolver, Valid: true},
-		JwtLifetime:          payload.JwtLifetime,
-		RefreshTokenLifetime: payload.RefreshTokenLifetime,
-		AppID:                app.ID,
-		CreatedBy:            uuid.MustParse(initiator),
-	})
-
-	return app, err
+	if initiator != uuid.Nil.String() && initiator != app.App.CreatedBy.String() {
+		return app, ErrAppForbidden
+	}
+	return app, nil
 }
@@ -2,10 +2,11 @@ package service
 
 import (
 	"context"
+	"crypto/x509"
 	"errors"
-	"fmt"
 	"net/url"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,8 +16,10 @@ import (
 	"github.com/aritradeveops/porichoy/internal/persistence/repository"
 	"github.com/aritradeveops/porichoy/internal/pkg/logger"
 	"github.com/aritradeveops/porichoy/pkg/timex"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const (
@@ -24,6 +27,65 @@ const (
 	ResponseTypeToken = "token"
 )
 
+// how the authorization response parameters are returned to the client, see
+// OAuth 2.0 Multiple Response Type Encoding Practices and Form Post Response Mode
+const (
+	ResponseModeQuery    = "query"
+	ResponseModeFragment = "fragment"
+	ResponseModeFormPost = "form_post"
+)
+
+// authentication methods references (RFC 8176) and the context class they
+// amount to, one for a single factor and two for multiple factors
+const (
+	AmrPassword       = "pwd"
+	AmrOTP            = "otp"
+	AmrHardwareKey    = "hwk"
+	AmrSms            = "sms"
+	AmrEmail          = "email" // a code or link sent by email, not in RFC 8176
+	AmrUser           = "user"  // the authenticator verified the user
+	AmrRecoveryCode   = "rcode" // a one-time recovery code, not in RFC 8176
+	AcrSingleFactor   = "1"
+	AcrMultipleFactor = "2"
+)
+
+// how a login proves who the user is, see LoginUserPayload
+const (
+	LoginMethodPassword = "password"
+	LoginMethodEmail    = "email"
+	LoginMethodSms      = "sms"
+)
+
+// values of the prompt parameter, see OpenID Connect Core 3.1.2.1
+const (
+	PromptNone    = "none"
+	PromptLogin   = "login"
+	PromptConsent = "consent"
+)
+
+// what the authorization endpoint has to do next for an oauth2 request
+const (
+	Oauth2StepLogin   = "login"
+	Oauth2StepConsent = "consent"
+	Oauth2StepApprove = "approve"
+	Oauth2StepError   = "error"
+)
+
+// what the end user decided on the consent screen, see Oauth2
+const (
+	ConsentApprove = "approve"
+	ConsentDeny    = "deny"
+)
+
+// authorization error codes returned to the client
+const (
+	Oauth2ErrorInvalidRequest  = "invalid_request"
+	Oauth2ErrorLoginRequired   = "login_required"
+	Oauth2ErrorConsentRequired = "consent_required"
+	Oauth2ErrorInvalidTarget   = "invalid_target"
+	Oauth2ErrorAccessDenied    = "access_denied"
+)
+
 type RegisterUserPayload struct {
 	Name     string `json:"name,omitempty" validate:"required,alphaspace,min=5"`
 	Email    string `json:"email,omitempty" validate:"required,email"`
@@ -37,17 +99,35 @@ type RegisterUserResponse struct {
 }
 
 type LoginUserPayload struct {
-	Email     string `json:"email,omitempty" validate:"required,email"`
-	Password  string `json:"password,omitempty" validate:"required"`
-	UserAgent string `json:"user_agent,omitempty" validate:"required"`
-	UserIP    string `json:"user_ip,omitempty" validate:"required"`
-	Host      string `json:"host,omitempty" validate:"required"`
+	// Method selects how the user signs in, a password when empty. An sms
+	// login is for the E.164 PhoneNumber instead of the Email
+	Method      string `json:"method,omitempty"`
+	Email       string `json:"email,omitempty" validate:"required_unless=Method sms,omitempty,email"`
+	Password    string `json:"password,omitempty" validate:"required_if=Method password"`
+	PhoneNumber string `json:"phone_number,omitempty" validate:"required_if=Method sms,omitempty,e164"`
+	UserAgent   string `json:"user_agent,omitempty" validate:"required"`
+	UserIP      string `json:"user_ip,omitempty" validate:"required"`
+	Host        string `json:"host,omitempty" validate:"required"`
+	// the app the user is signing in to, if any, for its mfa policy
+	ClientID string `json:"client_id,omitempty"`
 }
 type LoginUserResponse struct {
 	AccessToken        string    `json:"access_token,omitempty"`
 	RefreshToken       string    `json:"refresh_token,omitempty"`
 	AccessTokenExpiry  time.Time `json:"access_token_expiry,omitempty"`
 	RefreshTokenExpiry time.Time `json:"refresh_token_expiry,omitempty"`
+	// set instead of the tokens when a second factor is needed, the
+	// MfaToken has to be passed to VerifyMfa along with the code
+	MfaRequired bool   `json:"mfa_required,omitempty"`
+	MfaStep     string `json:"mfa_step,omitempty"`
+	MfaToken    string `json:"mfa_token,omitempty"`
+	// the second factors the user can verify with
+	MfaMethods []string `json:"mfa_methods,omitempty"`
+	// shown once after enrolling in mfa during the login
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+	// set instead of the tokens for an email or sms login, it has to be
+	// passed to CompleteEmailLogin or CompleteSmsLogin along with the code
+	LoginToken string `json:"login_token,omitempty"`
 }
 
 type Oauth2Payload struct {
@@ -59,41 +139,106 @@ type Oauth2Payload struct {
 	State               string `json:"state"`
 	LoginHint           string `json:"login_hint"`
 	Nonce               string `json:"nonce"`
+	Prompt              string `json:"prompt" validate:"omitempty,prompt"`
+	MaxAge              string `json:"max_age" validate:"omitempty,numeric"`
+	ResponseMode        string `json:"response_mode" validate:"omitempty,oneof=query fragment form_post"`
+	Scope               string `json:"scope"`
+	// the api the access token is requested for, see RFC 8707
+	Resource string `json:"resource" validate:"omitempty,uri"`
 }
+
+// Mode returns the requested response mode or the default of the response type.
+func (p Oauth2Payload) Mode() string {
+	if p.ResponseMode != "" {
+		return p.ResponseMode
+	}
+	if p.ResponseType == ResponseTypeToken {
+		return ResponseModeFragment
+	}
+	return ResponseModeQuery
+}
+
+// Oauth2AuthorizationResponse carries the parameters sent back to the client
+// once the authorization request is complete, successful or not.
+type Oauth2AuthorizationResponse struct {
+	RedirectURI  string     `json:"redirect_uri"`
+	ResponseMode string     `json:"response_mode"`
+	Params       url.Values `json:"params"`
+}
+
+// URL returns the redirect uri with the params encoded for the query and
+// fragment response modes.
+func (r Oauth2AuthorizationResponse) URL() string {
+	u, _ := url.Parse(r.RedirectURI)
+	if r.ResponseMode == ResponseModeFragment {
+		u.Fragment = ""
+		u.RawFragment = ""
+		return u.String() + "#" + r.Params.Encode()
+	}
+	q := u.Query()
+	for key, values := range r.Params {
+		for _, value := range values {
+			q.Add(key, value)
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func newAuthorizationResponse(redirectUri *url.URL, payload Oauth2Payload, params url.Values) *Oauth2AuthorizationResponse {
+	if payload.State != "" {
+		params.Set("state", payload.State)
+	}
+	return &Oauth2AuthorizationResponse{
+		RedirectURI:  redirectUri.String(),
+		ResponseMode: payload.Mode(),
+		Params:       params,
+	}
+}
+
+// Prompts returns the space delimited values of the prompt parameter.
+func (p Oauth2Payload) Prompts() []string {
+	return strings.Fields(p.Prompt)
+}
+
+type Oauth2PromptResponse struct {
+	Step    string `json:"step"`
+	AppName string `json:"app_name"`
+	// the error response when Step is Oauth2StepError
+	Authorization *Oauth2AuthorizationResponse `json:"authorization"`
+}
+
 type Oauth2TokenResponse struct {
 	AccessToken          string    `json:"access_token"`
+	IDToken              string    `json:"id_token,omitempty"`
+	Scope                string    `json:"scope,omitempty"`
 	AccessTokenLifetime  time.Time `json:"access_token_lifetime"`
 	RefreshToken         string    `json:"refresh_token"`
 	RefreshTokenLifetime time.Time `json:"refresh_token_lifetime"`
 }
 type Oauth2CodeResponse struct {
-	Code        string `json:"code"`
-	RedirectURI string `json:"redirect_uri"`
+	Code string `json:"code"`
 }
 
 type Oauth2Response struct {
-	App                 repository.App         `json:"app"`
-	OauthConfig         repository.OauthConfig `json:"oauth_config"`
-	Oauth2TokenResponse *Oauth2TokenResponse   `json:"oauth2_token_response"`
-	Oauth2CodeResponse  *Oauth2CodeResponse    `json:"oauth2_code_response"`
+	App                 repository.App               `json:"app"`
+	OauthConfig         repository.OauthConfig       `json:"oauth_config"`
+	Oauth2TokenResponse *Oauth2TokenResponse         `json:"oauth2_token_response"`
+	Oauth2CodeResponse  *Oauth2CodeResponse          `json:"oauth2_code_response"`
+	Authorization       *Oauth2AuthorizationResponse `json:"authorization"`
 }
 
 type Oauth2TokenPayload struct {
 	ClientID     string `json:"client_id" validate:"required"`
-	ClientSecret string `json:"client_secret" validate:"required"`
+	ClientSecret string `json:"client_secret"`
 	GrantType    string `json:"grant_type" validate:"required,oneof=authorization_code client_credentials"`
 	Code         string `json:"code" validate:"required"`
 	RedirectURI  string `json:"redirect_uri" validate:"required"`
 	UserAgent    string `json:"user_agent" validate:"required"`
 	UserIP       string `json:"user_ip" validate:"required"`
-}
-
-type Oauth2ConsentPayload struct {
-	ClientID string `json:"client_id" validate:"required"`
-}
-
-type Oauth2ConsentResponse struct {
-	AppName string `json:"app_name"`
+	Resource     string `json:"resource" validate:"omitempty,uri"`
+	// certificates presented during the tls handshake, leaf first
+	ClientCertificates []*x509.Certificate `json:"-"`
 }
 
 const (
@@ -105,8 +250,10 @@ var (
 	ErrInvalidLoginMethod      = errors.New("auth_service: invalid login method")
 	ErrUserExists              = errors.New("auth_service: user already exists")
 	ErrDeactivatedUser         = errors.New("auth_service: user account deactivated")
+	ErrEmailNotVerified        = errors.New("auth_service: email address not verified")
 	ErrInvalidOauthCall        = errors.New("auth_service: invalid oauth call")
 	ErrInvalidRedirectUri      = errors.New("auth_service: invalid redirect uri")
+	ErrInvalidGrant            = errors.New("auth_service: invalid authorization code")
 	ErrInternalError           = errors.New("auth_service: internal error")
 )
 
@@ -145,32 +292,75 @@ func (s *Service) RegisterUser(ctx context.Context, payload RegisterUserPayload,
 		return response, err
 	}
 	// hash the password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(payload.Password), bcrypt.DefaultCost)
+	hashedPassword, err := s.passwords.Hash(payload.Password)
 	if err != nil {
 		return response, err
 	}
 	// store the password
 	err = s.repository.CreatePasswordForUser(ctx, repository.CreatePasswordForUserParams{
-		HashedPassword: string(hashedPassword),
+		HashedPassword: hashedPassword,
 		CreatedBy:      user.ID,
 	})
 	if err != nil {
 		return response, err
 	}
 
+	// the root user's address is trusted, it comes from whoever set up the server
+	if isRootUser {
+		err = s.repository.MarkEmailVerified(ctx, repository.MarkEmailVerifiedParams{ID: user.ID, Email: user.Email})
+		if err != nil {
+			return response, err
+		}
+	} else if err := s.sendVerificationEmail(ctx, user); err != nil {
+		// the user can ask for another link, registration goes through
+		logger.Error().Err(err).Str("user_id", user.ID.String()).Msg("failed to send the verification email")
+	}
+
 	response.ID = user.ID.String()
 	response.Name = user.Name
 	response.Email = user.Email
 	return response, nil
 }
 
+// LoginUser signs a user in with the method they picked. Passwords are
+// checked right away, email and sms logins only send the code and finish
+// with CompleteEmailLogin or CompleteSmsLogin.
 func (s *Service) LoginUser(ctx context.Context, payload LoginUserPayload) (LoginUserResponse, error) {
-	// validate payload
 	var response LoginUserResponse
+	if payload.Method == "" {
+		payload.Method = LoginMethodPassword
+	}
+	// validate payload
 	errs := validation.Validate(payload)
 	if errs != nil {
 		return response, errs
 	}
+	if !s.loginMethodEnabled(payload.Method) {
+		return response, ErrInvalidLoginMethod
+	}
+	switch payload.Method {
+	case LoginMethodPassword:
+		return s.loginWithPassword(ctx, payload)
+	case LoginMethodEmail:
+		return s.startEmailLogin(ctx, payload)
+	case LoginMethodSms:
+		return s.startSmsLogin(ctx, payload)
+	}
+	return response, ErrInvalidLoginMethod
+}
+
+// loginMethodEnabled reports whether the server lets users sign in with
+// method, all of them are allowed unless configured otherwise.
+func (s *Service) loginMethodEnabled(method string) bool {
+	methods := s.config.Login.Methods
+	if len(methods) == 0 {
+		return method == LoginMethodPassword || method == LoginMethodEmail || method == LoginMethodSms
+	}
+	return slices.Contains(methods, method)
+}
+
+func (s *Service) loginWithPassword(ctx context.Context, payload LoginUserPayload) (LoginUserResponse, error) {
+	var response LoginUserResponse
 	rootApp, err := s.repository.FindRootApp(ctx)
 	if err != nil {
 		logger.Error().Err(err).Msg("zero")
@@ -189,29 +379,72 @@ func (s *Service) LoginUser(ctx context.Context, payload LoginUserPayload) (Logi
 
 	passwd, err := s.repository.FindUserPassword(ctx, user.ID)
 	if err != nil {
-		// if err is does not exist then throw invalid login method
+		// users who never set a password have to pick another method
 		logger.Error().Err(err).Msg("two")
 		return response, ErrInvalidLoginMethod
 	}
 
 	// compare passwords
-	err = bcrypt.CompareHashAndPassword([]byte(passwd.HashedPassword), []byte(payload.Password))
-	if err != nil {
+	ok, rehash, err := s.passwords.Verify(passwd.HashedPassword, payload.Password)
+	if err != nil || !ok {
 		logger.Error().Err(err).Msg("three")
 		return response, ErrInvalidLoginCredentials
 	}
+	// the password is only known now, hashes with an outdated algorithm or
+	// cost are upgraded
+	if rehash {
+		s.rehashPassword(ctx, passwd, payload.Password)
+	}
+
+	if err := requireVerifiedEmail(rootApp.OauthConfig, user); err != nil {
+		return response, err
+	}
+	return s.completeLogin(ctx, rootApp, user, payload.ClientID, []string{AmrPassword}, payload.UserIP, payload.UserAgent)
+}
+
+// completeLogin follows the first factor of any login method with the mfa
+// challenge, when one is needed, or with the session.
+func (s *Service) completeLogin(ctx context.Context, rootApp repository.FindRootAppRow, user repository.User, clientID string, amr []string, userIP string, userAgent string) (LoginUserResponse, error) {
+	configs := []repository.OauthConfig{rootApp.OauthConfig}
+	if clientID != "" {
+		if app, err := s.repository.FindAppByClientID(ctx, clientID); err == nil {
+			configs = append(configs, app.OauthConfig)
+		}
+	}
+	step, methods, err := s.mfaStep(ctx, user, amr, configs...)
+	if err != nil {
+		return LoginUserResponse{}, err
+	}
+	if step != "" {
+		return s.mfaChallenge(user, step, methods, amr)
+	}
+	return s.createLoginSession(ctx, rootApp, user, amr, userIP, userAgent)
+}
 
+// createLoginSession signs the tokens for a user who has fully authenticated
+// with porichoy itself and starts a session for the root app.
+func (s *Service) createLoginSession(ctx context.Context, rootApp repository.FindRootAppRow, user repository.User, amr []string, userIP string, userAgent string) (LoginUserResponse, error) {
+	var response LoginUserResponse
 	// sign tokens
 	dp := ""
 	if user.Dp.Valid {
 		dp = user.Dp.String
 	}
-	accessToken, err := jwtutil.Sign(rootApp.OauthConfig.JwtAlgo, jwtutil.JwtPayload{
-		UserID: user.ID.String(),
-		Name:   user.Name,
-		Email:  user.Email,
-		Dp:     dp,
-	}, rootApp.OauthConfig.JwtSecretResolver.String, rootApp.App.Domain, rootApp.App.Domain,
+	key, err := s.activeSigningKey(ctx, rootApp.App.ID)
+	if err != nil {
+		return response, err
+	}
+	accessToken, err := jwtutil.SignAccessToken(rootApp.OauthConfig.AccessTokenProfile, key, jwtutil.JwtPayload{
+		UserID:        user.ID.String(),
+		Name:          user.Name,
+		Email:         user.Email,
+		Dp:            dp,
+		ClientID:      rootApp.App.ClientID,
+		AuthTime:      time.Now().Unix(),
+		Acr:           acr(amr),
+		Amr:           amr,
+		EmailVerified: emailVerified(user),
+	}, rootApp.App.Domain, s.config.IssuerURL(),
 		timex.Duration(rootApp.OauthConfig.JwtLifetime).Duration())
 
 	if err != nil {
@@ -229,8 +462,8 @@ func (s *Service) LoginUser(ctx context.Context, payload LoginUserPayload) (Logi
 		UserID:       user.ID,
 		AppID:        rootApp.App.ID,
 		RefreshToken: refreshToken,
-		UserIp:       payload.UserIP,
-		UserAgent:    payload.UserAgent,
+		UserIp:       userIP,
+		UserAgent:    userAgent,
 		ExpiresAt:    time.Now().Add(timex.Duration(rootApp.OauthConfig.RefreshTokenLifetime).Duration()),
 		CreatedBy:    user.ID,
 	})
@@ -249,7 +482,13 @@ func (s *Service) LogoutUser(ctx context.Context, initiator string) error {
 	return s.repository.DeleteSession(ctx, uuid.MustParse(initiator))
 }
 
-func (s *Service) Oauth2(ctx context.Context, initiator string, payload Oauth2Payload) (Oauth2Response, error) {
+// Oauth2 issues the authorization response for the payload to the logged in
+// user. Unless the end user may be sent straight back to the client, as
+// decided by Oauth2Prompt, it answers with an error response. decision is
+// ConsentApprove or ConsentDeny when the end user submitted the consent
+// screen and empty otherwise.
+func (s *Service) Oauth2(ctx context.Context, user *jwtutil.JwtPayload, payload Oauth2Payload, decision string) (Oauth2Response, error) {
+	initiator := user.UserID
 	errs := validation.Validate(payload)
 	var response Oauth2Response
 	if errs != nil {
@@ -263,15 +502,45 @@ func (s *Service) Oauth2(ctx context.Context, initiator string, payload Oauth2Pa
 	response.App = app.App
 	response.OauthConfig = app.OauthConfig
 
-	redirectUri, err := url.Parse(payload.RedirectURI)
+	redirectUri, err := validateRedirectURI(app.OauthConfig, payload.RedirectURI)
 	if err != nil {
-		logger.Error().Err(err).Msg("two")
-		return response, ErrInvalidOauthCall
+		return response, err
 	}
-	fmt.Println(app.OauthConfig.RedirectUris, strings.Split(redirectUri.String(), "?")[0])
-	if !slices.Contains(app.OauthConfig.RedirectUris, strings.Split(redirectUri.String(), "?")[0]) {
-		logger.Error().Err(err).Msg("three")
-		return response, ErrInvalidRedirectUri
+	fail := func(code string) (Oauth2Response, error) {
+		response.Authorization = newAuthorizationResponse(redirectUri, payload, url.Values{
+			"error": {code},
+		})
+		return response, nil
+	}
+	if decision == ConsentDeny {
+		return fail(Oauth2ErrorAccessDenied)
+	}
+
+	step, code, err := s.authorizationStep(ctx, app, user, payload)
+	if err != nil {
+		return response, err
+	}
+	switch step {
+	case Oauth2StepError:
+		return fail(code)
+	case Oauth2StepLogin:
+		return fail(Oauth2ErrorLoginRequired)
+	case Oauth2StepConsent:
+		if decision != ConsentApprove {
+			return fail(Oauth2ErrorConsentRequired)
+		}
+	}
+
+	if decision == ConsentApprove {
+		err = s.repository.UpsertConsent(ctx, repository.UpsertConsentParams{
+			UserID:    uuid.MustParse(initiator),
+			AppID:     app.App.ID,
+			CreatedBy: uuid.MustParse(initiator),
+		})
+		if err != nil {
+			logger.Error().Err(err).Msg("could not record consent")
+			return response, ErrInternalError
+		}
 	}
 
 	if payload.ResponseType == ResponseTypeCode {
@@ -281,23 +550,27 @@ func (s *Service) Oauth2(ctx context.Context, initiator string, payload Oauth2Pa
 			return response, ErrInternalError
 		}
 		err = s.repository.CreateOauthCall(ctx, repository.CreateOauthCallParams{
-			AppID:     app.App.ID,
-			Code:      code,
-			UserID:    uuid.MustParse(initiator),
-			ExpiresAt: time.Now().Add(OauthCodeLifetime),
+			AppID:       app.App.ID,
+			Code:        code,
+			UserID:      uuid.MustParse(initiator),
+			Resource:    pgtype.Text{String: payload.Resource, Valid: payload.Resource != ""},
+			Scope:       pgtype.Text{String: payload.Scope, Valid: payload.Scope != ""},
+			AuthTime:    authTime(user),
+			Amr:         user.Amr,
+			Nonce:       pgtype.Text{String: payload.Nonce, Valid: payload.Nonce != ""},
+			ExpiresAt:   time.Now().Add(OauthCodeLifetime),
+			RedirectUri: payload.RedirectURI,
 		})
 		if err != nil {
 			logger.Error().Err(err).Msg("five")
 			return response, ErrInternalError
 		}
-		q := redirectUri.Query()
-		q.Add("code", code)
-		redirectUri.RawQuery = q.Encode()
-
 		response.Oauth2CodeResponse = &Oauth2CodeResponse{
-			Code:        code,
-			RedirectURI: redirectUri.String(),
+			Code: code,
 		}
+		response.Authorization = newAuthorizationResponse(redirectUri, payload, url.Values{
+			"code": {code},
+		})
 		return response, nil
 	} else if payload.ResponseType == ResponseTypeToken {
 		// TODO: and as it is inherently insecure so it's not important as of now
@@ -306,20 +579,131 @@ func (s *Service) Oauth2(ctx context.Context, initiator string, payload Oauth2Pa
 	return response, nil
 }
 
-func (s *Service) Oauth2ConsentResponse(ctx context.Context, payload Oauth2ConsentPayload) (Oauth2ConsentResponse, error) {
+// Oauth2Prompt decides whether the end user has to (re)authenticate or give
+// consent before an authorization response can be issued for the payload.
+// user is nil when the end user is not logged in.
+func (s *Service) Oauth2Prompt(ctx context.Context, user *jwtutil.JwtPayload, payload Oauth2Payload) (Oauth2PromptResponse, error) {
+	var resp Oauth2PromptResponse
 	errs := validation.Validate(payload)
-	var resp Oauth2ConsentResponse
 	if errs != nil {
 		return resp, errs
 	}
 	app, err := s.repository.FindAppByClientID(ctx, payload.ClientID)
 	if err != nil {
-		return resp, err
+		return resp, ErrInvalidOauthCall
 	}
 	resp.AppName = app.App.Name
+	redirectUri, err := validateRedirectURI(app.OauthConfig, payload.RedirectURI)
+	if err != nil {
+		return resp, err
+	}
+
+	step, code, err := s.authorizationStep(ctx, app, user, payload)
+	if err != nil {
+		return resp, err
+	}
+	resp.Step = step
+	if step == Oauth2StepError {
+		resp.Authorization = newAuthorizationResponse(redirectUri, payload, url.Values{
+			"error": {code},
+		})
+	}
 	return resp, nil
 }
 
+// authorizationStep checks the payload against the app and the session of
+// user, nil when the end user is not logged in, and returns what has to
+// happen next. The error code for the client comes with Oauth2StepError.
+func (s *Service) authorizationStep(ctx context.Context, app repository.FindAppByClientIDRow, user *jwtutil.JwtPayload, payload Oauth2Payload) (string, string, error) {
+	if payload.ResponseType == ResponseTypeToken && payload.Mode() == ResponseModeQuery {
+		// tokens must never end up in the query, RFC 6749 4.2.2
+		return Oauth2StepError, Oauth2ErrorInvalidRequest, nil
+	}
+
+	if payload.Resource != "" {
+		if _, err := s.findAppResource(ctx, app.OauthConfig, payload.Resource); err != nil {
+			return Oauth2StepError, Oauth2ErrorInvalidTarget, nil
+		}
+	}
+
+	prompts := payload.Prompts()
+	none := slices.Contains(prompts, PromptNone)
+	if none && len(prompts) > 1 {
+		return Oauth2StepError, Oauth2ErrorInvalidRequest, nil
+	}
+
+	needsLogin := user == nil || slices.Contains(prompts, PromptLogin)
+	if !needsLogin && payload.MaxAge != "" {
+		maxAge, _ := strconv.ParseInt(payload.MaxAge, 10, 64)
+		needsLogin = time.Since(time.Unix(user.AuthTime, 0)) > time.Duration(maxAge)*time.Second
+	}
+	// a session without a second factor has to be stepped up for apps
+	// requiring mfa
+	if !needsLogin && app.OauthConfig.RequireMfa {
+		needsLogin = acr(user.Amr) != AcrMultipleFactor
+	}
+	if needsLogin {
+		if none {
+			return Oauth2StepError, Oauth2ErrorLoginRequired, nil
+		}
+		return Oauth2StepLogin, "", nil
+	}
+
+	// a login does not help here, the user has to verify their email first
+	err := s.requireVerifiedEmailFor(ctx, app.OauthConfig, user.UserID)
+	if errors.Is(err, ErrEmailNotVerified) {
+		return Oauth2StepError, Oauth2ErrorAccessDenied, nil
+	} else if err != nil {
+		return "", "", err
+	}
+
+	needsConsent := slices.Contains(prompts, PromptConsent)
+	if !needsConsent {
+		_, err := s.repository.FindConsent(ctx, repository.FindConsentParams{
+			UserID: uuid.MustParse(user.UserID),
+			AppID:  app.App.ID,
+		})
+		needsConsent = err != nil
+	}
+	if needsConsent {
+		if none {
+			return Oauth2StepError, Oauth2ErrorConsentRequired, nil
+		}
+		return Oauth2StepConsent, "", nil
+	}
+
+	return Oauth2StepApprove, "", nil
+}
+
+// authTime is when the end user authenticated with porichoy, if known.
+func authTime(user *jwtutil.JwtPayload) *time.Time {
+	if user.AuthTime == 0 {
+		return nil
+	}
+	t := time.Unix(user.AuthTime, 0)
+	return &t
+}
+
+// acr counts the distinct methods, the same one used twice is still a
+// single factor.
+func acr(amr []string) string {
+	if len(slices.Compact(slices.Sorted(slices.Values(amr)))) > 1 {
+		return AcrMultipleFactor
+	}
+	return AcrSingleFactor
+}
+
+func validateRedirectURI(config repository.OauthConfig, raw string) (*url.URL, error) {
+	redirectUri, err := url.Parse(raw)
+	if err != nil {
+		return nil, ErrInvalidOauthCall
+	}
+	if !slices.Contains(config.RedirectUris, strings.Split(redirectUri.String(), "?")[0]) {
+		return nil, ErrInvalidRedirectUri
+	}
+	return redirectUri, nil
+}
+
 func (s *Service) Token(ctx context.Context, payload Oauth2TokenPayload) (Oauth2TokenResponse, error) {
 	var resp Oauth2TokenResponse
 
@@ -333,8 +717,16 @@ func (s *Service) Token(ctx context.Context, payload Oauth2TokenPayload) (Oauth2
 		return resp, err
 	}
 
-	if app.OauthConfig.ClientSecret != payload.ClientSecret {
-		return resp, ErrInvalidOauthCall
+	if err := s.authenticateClient(ctx, app.OauthConfig, payload.ClientSecret, payload.ClientCertificates); err != nil {
+		return resp, err
+	}
+
+	// tokens issued to a client holding a certificate are bound to it
+	var cnf *jwtutil.Confirmation
+	if len(payload.ClientCertificates) > 0 {
+		cnf = &jwtutil.Confirmation{
+			X5tS256: cryptoutil.CertificateThumbprint(payload.ClientCertificates[0]),
+		}
 	}
 
 	// if app.OauthConfig.GrantType != payload.GrantType {
@@ -342,29 +734,88 @@ func (s *Service) Token(ctx context.Context, payload Oauth2TokenPayload) (Oauth2
 	// }
 
 	if payload.GrantType == "authorization_code" {
-		oauthCall, err := s.repository.FindOauthCallByCode(ctx, payload.Code)
-		if err != nil {
+		// the code is gone once read, a replayed code finds nothing
+		oauthCall, err := s.repository.ConsumeOauthCall(ctx, payload.Code)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return resp, ErrInvalidGrant
+		} else if err != nil {
 			return resp, err
 		}
+		if oauthCall.AppID != app.App.ID || oauthCall.RedirectUri != payload.RedirectURI {
+			return resp, ErrInvalidGrant
+		}
 
 		user, err := s.repository.FindUserByID(ctx, oauthCall.UserID)
 		if err != nil {
 			return resp, err
 		}
-		logger.Info().Any("user", app).Msg("app")
-		accessToken, err := jwtutil.Sign(app.OauthConfig.JwtAlgo, jwtutil.JwtPayload{
-			UserID: user.ID.String(),
-			Name:   user.Name,
-			Email:  user.Email,
-			Dp:     user.Dp.String,
-		}, app.OauthConfig.JwtSecretResolver.String, app.App.Domain, s.config.Http.Host, timex.Duration(app.OauthConfig.JwtLifetime).Duration())
+
+		// the token request can only repeat the resource the authorization
+		// was bound to, it can not pick one the user never consented to
+		resourceID := oauthCall.Resource.String
+		if payload.Resource != "" && payload.Resource != resourceID {
+			return resp, ErrInvalidTarget
+		}
+		audience := app.App.Domain
+		lifetime := timex.Duration(app.OauthConfig.JwtLifetime).Duration()
+		resp.Scope = oauthCall.Scope.String
+		if resourceID != "" {
+			resource, err := s.findAppResource(ctx, app.OauthConfig, resourceID)
+			if err != nil {
+				return resp, err
+			}
+			audience = resource.Identifier
+			lifetime = timex.Duration(resource.TokenLifetime).Duration()
+			resp.Scope = strings.Join(grantedScopes(oauthCall.Scope.String, resource), " ")
+		}
+
+		var authTime int64
+		if oauthCall.AuthTime != nil {
+			authTime = oauthCall.AuthTime.Unix()
+		}
+		key, err := s.activeSigningKey(ctx, app.App.ID)
+		if err != nil {
+			return resp, err
+		}
+		accessToken, err := jwtutil.SignAccessToken(app.OauthConfig.AccessTokenProfile, key, jwtutil.JwtPayload{
+			UserID:        user.ID.String(),
+			Name:          user.Name,
+			Email:         user.Email,
+			Dp:            user.Dp.String,
+			ClientID:      app.App.ClientID,
+			Scope:         resp.Scope,
+			AuthTime:      authTime,
+			Acr:           acr(oauthCall.Amr),
+			Amr:           oauthCall.Amr,
+			Cnf:           cnf,
+			EmailVerified: emailVerified(user),
+		}, audience, s.config.IssuerURL(), lifetime)
 
 		if err != nil {
 			return resp, err
 		}
 
 		resp.AccessToken = accessToken
-		resp.AccessTokenLifetime = time.Now().Add(timex.Duration(app.OauthConfig.JwtLifetime).Duration())
+		resp.AccessTokenLifetime = time.Now().Add(lifetime)
+
+		scopes := strings.Fields(oauthCall.Scope.String)
+		if slices.Contains(scopes, ScopeOpenID) {
+			idToken, err := jwtutil.SignIDToken(key, jwtutil.IDTokenClaims{
+				Profile:          userProfile(user, scopes),
+				Nonce:            oauthCall.Nonce.String,
+				AuthTime:         authTime,
+				Acr:              acr(oauthCall.Amr),
+				Amr:              oauthCall.Amr,
+				RegisteredClaims: jwt.RegisteredClaims{Subject: user.ID.String()},
+			}, app.App.ClientID, s.config.IssuerURL(), timex.Duration(app.OauthConfig.JwtLifetime).Duration())
+			if err != nil {
+				return resp, err
+			}
+			resp.IDToken, err = encryptForClient(app.OauthConfig, idToken)
+			if err != nil {
+				return resp, err
+			}
+		}
 
 		refreshToken, err := cryptoutil.GenerateHash(64)
 		if err != nil {
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aritradeveops/porichoy/internal/core/jwtutil"
+	"github.com/aritradeveops/porichoy/internal/persistence/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// authorizeQuerier adds the consents of the end user and the codes issued
+// to them to the app of resourceQuerier.
+type authorizeQuerier struct {
+	*resourceQuerier
+	consented bool
+}
+
+func (q *authorizeQuerier) FindConsent(ctx context.Context, arg repository.FindConsentParams) (repository.Consent, error) {
+	if !q.consented || arg.UserID != q.user.ID || arg.AppID != q.app.App.ID {
+		return repository.Consent{}, pgx.ErrNoRows
+	}
+	return repository.Consent{UserID: arg.UserID, AppID: arg.AppID}, nil
+}
+
+func (q *authorizeQuerier) UpsertConsent(ctx context.Context, arg repository.UpsertConsentParams) error {
+	q.consented = true
+	return nil
+}
+
+func (q *authorizeQuerier) CreateOauthCall(ctx context.Context, arg repository.CreateOauthCallParams) error {
+	q.calls[arg.Code] = repository.OauthCall{
+		AppID:       arg.AppID,
+		Code:        arg.Code,
+		UserID:      arg.UserID,
+		Scope:       arg.Scope,
+		ExpiresAt:   arg.ExpiresAt,
+		RedirectUri: arg.RedirectUri,
+	}
+	return nil
+}
+
+func newAuthorizeService(t *testing.T) (*Service, *authorizeQuerier) {
+	s, resources, _ := newResourceService(t)
+	querier := &authorizeQuerier{resourceQuerier: resources}
+	s.repository = querier
+	return s, querier
+}
+
+func authorizePayload(prompt string, maxAge string) Oauth2Payload {
+	return Oauth2Payload{
+		ClientID:     "app.example.com",
+		ResponseType: ResponseTypeCode,
+		RedirectURI:  "https://app.example.com/callback",
+		State:        "xyz",
+		Prompt:       prompt,
+		MaxAge:       maxAge,
+	}
+}
+
+// loggedIn is a password session of the end user started ago.
+func loggedIn(querier *authorizeQuerier, ago time.Duration) *jwtutil.JwtPayload {
+	return &jwtutil.JwtPayload{
+		UserID:   querier.user.ID.String(),
+		AuthTime: time.Now().Add(-ago).Unix(),
+		Amr:      []string{AmrPassword},
+	}
+}
+
+func TestOauth2Prompt(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		consented bool
+		prompt    string
+		maxAge    string
+		ago       time.Duration
+		step      string
+		error     string
+	}{
+		{name: "consented", consented: true, step: Oauth2StepApprove},
+		{name: "no consent", step: Oauth2StepConsent},
+		{name: "no consent prompt=none", prompt: PromptNone, step: Oauth2StepError, error: Oauth2ErrorConsentRequired},
+		{name: "prompt=consent", consented: true, prompt: PromptConsent, step: Oauth2StepConsent},
+		{name: "prompt=login", consented: true, prompt: PromptLogin, step: Oauth2StepLogin},
+		{name: "prompt=none login", consented: true, prompt: "none login", step: Oauth2StepError, error: Oauth2ErrorInvalidRequest},
+		{name: "within max_age", consented: true, maxAge: "300", ago: time.Minute, step: Oauth2StepApprove},
+		{name: "past max_age", consented: true, maxAge: "60", ago: 2 * time.Minute, step: Oauth2StepLogin},
+		{name: "past max_age prompt=none", consented: true, prompt: PromptNone, maxAge: "60", ago: 2 * time.Minute, step: Oauth2StepError, error: Oauth2ErrorLoginRequired},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			s, querier := newAuthorizeService(t)
+			querier.consented = test.consented
+			resp, err := s.Oauth2Prompt(context.Background(), loggedIn(querier, test.ago), authorizePayload(test.prompt, test.maxAge))
+			assert.NoError(t, err)
+			assert.Equal(t, test.step, resp.Step)
+			if test.error != "" {
+				assert.Equal(t, test.error, resp.Authorization.Params.Get("error"))
+				assert.Equal(t, "xyz", resp.Authorization.Params.Get("state"))
+			}
+		})
+	}
+}
+
+// Oauth2 must not issue a code for a request Oauth2Prompt would not send
+// straight back to the client, whoever calls it
+func TestOauth2(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		consented bool
+		prompt    string
+		maxAge    string
+		ago       time.Duration
+		decision  string
+		error     string
+	}{
+		{name: "consented", consented: true},
+		{name: "no consent", error: Oauth2ErrorConsentRequired},
+		{name: "approved", decision: ConsentApprove},
+		{name: "denied", consented: true, decision: ConsentDeny, error: Oauth2ErrorAccessDenied},
+		{name: "prompt=consent", consented: true, prompt: PromptConsent, error: Oauth2ErrorConsentRequired},
+		{name: "prompt=consent approved", consented: true, prompt: PromptConsent, decision: ConsentApprove},
+		{name: "prompt=login", consented: true, prompt: PromptLogin, error: Oauth2ErrorLoginRequired},
+		{name: "prompt=login approved", prompt: PromptLogin, decision: ConsentApprove, error: Oauth2ErrorLoginRequired},
+		{name: "within max_age", consented: true, maxAge: "300", ago: time.Minute},
+		{name: "past max_age", consented: true, maxAge: "60", ago: 2 * time.Minute, error: Oauth2ErrorLoginRequired},
+		{name: "past max_age approved", maxAge: "60", ago: 2 * time.Minute, decision: ConsentApprove, error: Oauth2ErrorLoginRequired},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			s, querier := newAuthorizeService(t)
+			querier.consented = test.consented
+			resp, err := s.Oauth2(context.Background(), loggedIn(querier, test.ago), authorizePayload(test.prompt, test.maxAge), test.decision)
+			assert.NoError(t, err)
+			assert.Equal(t, "xyz", resp.Authorization.Params.Get("state"))
+			if test.error != "" {
+				assert.Equal(t, test.error, resp.Authorization.Params.Get("error"))
+				assert.Nil(t, resp.Oauth2CodeResponse)
+				assert.Empty(t, querier.calls)
+				assert.Equal(t, test.consented, querier.consented)
+				return
+			}
+			assert.Empty(t, resp.Authorization.Params.Get("error"))
+			assert.Contains(t, querier.calls, resp.Authorization.Params.Get("code"))
+			assert.True(t, querier.consented)
+		})
+	}
+}
+
+func TestTokenCodeReplay(t *testing.T) {
+	t.Parallel()
+	s, querier := newAuthorizeService(t)
+	querier.consented = true
+	ctx := context.Background()
+	secret, err := createClientSecret(ctx, querier, querier.app.App.ID, uuid.Nil, nil)
+	assert.NoError(t, err)
+
+	authorization, err := s.Oauth2(ctx, loggedIn(querier, 0), authorizePayload("", ""), "")
+	assert.NoError(t, err)
+	code := authorization.Authorization.Params.Get("code")
+
+	resp, err := s.Token(ctx, tokenPayload(code, secret, ""))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, resp.AccessToken)
+
+	_, err = s.Token(ctx, tokenPayload(code, secret, ""))
+	assert.ErrorIs(t, err, ErrInvalidGrant)
+}
+
+func TestTokenCodeBinding(t *testing.T) {
+	t.Parallel()
+	s, querier, secret := newResourceService(t)
+	ctx := context.Background()
+
+	// a code issued to another client
+	code := authorizedCode(querier, "")
+	call := querier.calls[code]
+	call.AppID = uuid.New()
+	querier.calls[code] = call
+	_, err := s.Token(ctx, tokenPayload(code, secret, ""))
+	assert.ErrorIs(t, err, ErrInvalidGrant)
+
+	// a registered redirect uri other than the one authorized
+	querier.app.OauthConfig.RedirectUris = append(querier.app.OauthConfig.RedirectUris, "https://app.example.com/other")
+	payload := tokenPayload(authorizedCode(querier, ""), secret, "")
+	payload.RedirectURI = "https://app.example.com/other"
+	_, err = s.Token(ctx, payload)
+	assert.ErrorIs(t, err, ErrInvalidGrant)
+
+	_, err = s.Token(ctx, tokenPayload("unknown", secret, ""))
+	assert.ErrorIs(t, err, ErrInvalidGrant)
+}
+
+func TestAcr(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		amr  []string
+		want string
+	}{
+		{[]string{AmrPassword}, AcrSingleFactor},
+		{[]string{AmrEmail}, AcrSingleFactor},
+		{[]string{AmrOTP, AmrOTP}, AcrSingleFactor},
+		{[]string{AmrSms, AmrSms}, AcrSingleFactor},
+		{[]string{AmrPassword, AmrOTP}, AcrMultipleFactor},
+		{[]string{AmrEmail, AmrOTP}, AcrMultipleFactor},
+		{[]string{AmrHardwareKey, AmrUser}, AcrMultipleFactor},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, acr(c.amr), c.amr)
+	}
+	// an email login followed by a totp code keeps both methods apart
+	claims := mfaChallengeClaims{Amr: []string{AmrEmail}}
+	assert.Equal(t, []string{AmrEmail, AmrOTP}, claims.amr(AmrOTP))
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"strings"
+
+	"github.com/aritradeveops/porichoy/internal/core/cryptoutil"
+	"github.com/aritradeveops/porichoy/internal/persistence/repository"
+	"github.com/aritradeveops/porichoy/internal/pkg/logger"
+)
+
+// token endpoint authentication methods, see RFC 8705 for the tls ones
+const (
+	AuthMethodClientSecretPost        = "client_secret_post"
+	AuthMethodTLSClientAuth           = "tls_client_auth"
+	AuthMethodSelfSignedTLSClientAuth = "self_signed_tls_client_auth"
+)
+
+var (
+	ErrInvalidClient = errors.New("auth_service: invalid client authentication")
+)
+
+// clientCAPool lazily loads the CAs trusted for tls_client_auth.
+func (s *Service) clientCAPool() (*x509.CertPool, error) {
+	s.clientCAs.once.Do(func() {
+		if s.config.Http.TLS == nil || s.config.Http.TLS.ClientCAFile == "" {
+			s.clientCAs.err = errors.New("auth_service: no client ca configured")
+			return
+		}
+		s.clientCAs.pool, s.clientCAs.err = cryptoutil.LoadCertPool(s.config.Http.TLS.ClientCAFile)
+	})
+	return s.clientCAs.pool, s.clientCAs.err
+}
+
+// authenticateClient authenticates the client at the token endpoint with the
+// method the app was registered with.
+func (s *Service) authenticateClient(ctx context.Context, config repository.OauthConfig, secret string, certs []*x509.Certificate) error {
+	switch config.TokenEndpointAuthMethod {
+	case AuthMethodTLSClientAuth:
+		if len(certs) == 0 {
+			return ErrInvalidClient
+		}
+		pool, err := s.clientCAPool()
+		if err != nil {
+			logger.Error().Err(err).Msg("tls_client_auth is not available")
+			return ErrInvalidClient
+		}
+		intermediates := x509.NewCertPool()
+		for _, cert := range certs[1:] {
+			intermediates.AddCert(cert)
+		}
+		_, err = certs[0].Verify(x509.VerifyOptions{
+			Roots:         pool,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		})
+		if err != nil {
+			return ErrInvalidClient
+		}
+		if !equalDistinguishedNames(certs[0].Subject.String(), config.TlsClientAuthSubjectDn.String) {
+			return ErrInvalidClient
+		}
+		return nil
+	case AuthMethodSelfSignedTLSClientAuth:
+		if len(certs) == 0 || !config.TlsClientCertificate.Valid {
+			return ErrInvalidClient
+		}
+		registered, err := cryptoutil.ParseCertificatePEM(config.TlsClientCertificate.String)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid registered client certificate")
+			return ErrInvalidClient
+		}
+		if !registered.Equal(certs[0]) {
+			return ErrInvalidClient
+		}
+		return nil
+	default:
+		return s.verifyClientSecret(ctx, config.AppID, secret)
+	}
+}
+
+// equalDistinguishedNames compares two RFC 4514 strings ignoring case and
+// the whitespace around separators.
+func equalDistinguishedNames(a, b string) bool {
+	normalize := func(dn string) string {
+		parts := strings.Split(dn, ",")
+		for i, part := range parts {
+			kv := strings.SplitN(part, "=", 2)
+			for j := range kv {
+				kv[j] = strings.TrimSpace(kv[j])
+			}
+			parts[i] = strings.Join(kv, "=")
+		}
+		return strings.ToLower(strings.Join(parts, ","))
+	}
+	return b != "" && normalize(a) == normalize(b)
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aritradeveops/porichoy/internal/config"
+	"github.com/aritradeveops/porichoy/internal/persistence/repository"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+// testCA issues client certificates for the tls_client_auth tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T, name string) testCA {
+	return testCA{}.intermediate(t, name)
+}
+
+// intermediate creates a CA signed by ca, a self-signed one for the zero
+// testCA.
+func (ca testCA) intermediate(t *testing.T, name string) testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	parent, signer := template, key
+	if ca.cert != nil {
+		parent, signer = ca.cert, ca.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signer)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return testCA{cert: cert, key: key}
+}
+
+// issue signs a certificate for subject, with the client auth usage unless
+// other usages are given.
+func (ca testCA) issue(t *testing.T, subject pkix.Name, usages ...x509.ExtKeyUsage) *x509.Certificate {
+	if len(usages) == 0 {
+		usages = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      subject,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  usages,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return cert
+}
+
+func encodeCertificate(cert *x509.Certificate) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+}
+
+// newClientAuthService trusts ca for tls_client_auth.
+func newClientAuthService(t *testing.T, ca testCA) *Service {
+	path := filepath.Join(t.TempDir(), "client-ca.pem")
+	assert.NoError(t, os.WriteFile(path, []byte(encodeCertificate(ca.cert)), 0o600))
+	return New(&config.Config{
+		Http: config.Http{TLS: &config.TLS{ClientCAFile: path}},
+	}, nil, nil, nil)
+}
+
+var clientSubject = pkix.Name{
+	CommonName:   "app.example.com",
+	Organization: []string{"Example"},
+	Country:      []string{"BD"},
+}
+
+func tlsClientAuthConfig(dn string) repository.OauthConfig {
+	return repository.OauthConfig{
+		TokenEndpointAuthMethod: AuthMethodTLSClientAuth,
+		TlsClientAuthSubjectDn:  pgtype.Text{String: dn, Valid: dn != ""},
+	}
+}
+
+func TestTLSClientAuth(t *testing.T) {
+	t.Parallel()
+	ca := newTestCA(t, "Client CA")
+	s := newClientAuthService(t, ca)
+	ctx := context.Background()
+	cert := ca.issue(t, clientSubject)
+	config := tlsClientAuthConfig("CN=app.example.com,O=Example,C=BD")
+
+	assert.NoError(t, s.authenticateClient(ctx, config, "", []*x509.Certificate{cert}))
+	// the subject is compared ignoring case and spaces around separators
+	assert.NoError(t, s.authenticateClient(ctx, tlsClientAuthConfig("cn=App.Example.com, o = Example, c=bd"), "", []*x509.Certificate{cert}))
+
+	// intermediates are taken from the rest of the presented chain
+	intermediate := ca.intermediate(t, "Issuing CA")
+	chained := intermediate.issue(t, clientSubject)
+	assert.NoError(t, s.authenticateClient(ctx, config, "", []*x509.Certificate{chained, intermediate.cert}))
+
+	tests := []struct {
+		name   string
+		config repository.OauthConfig
+		certs  []*x509.Certificate
+	}{
+		{"no certificate", config, nil},
+		{"other subject", config, []*x509.Certificate{ca.issue(t, pkix.Name{CommonName: "other.example.com", Organization: []string{"Example"}, Country: []string{"BD"}})}},
+		{"no registered subject", tlsClientAuthConfig(""), []*x509.Certificate{cert}},
+		{"untrusted ca", config, []*x509.Certificate{newTestCA(t, "Other CA").issue(t, clientSubject)}},
+		{"server certificate", config, []*x509.Certificate{ca.issue(t, clientSubject, x509.ExtKeyUsageServerAuth)}},
+		{"missing intermediate", config, []*x509.Certificate{chained}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := s.authenticateClient(ctx, test.config, "", test.certs)
+			assert.ErrorIs(t, err, ErrInvalidClient)
+		})
+	}
+}
+
+func TestTLSClientAuthWithoutClientCA(t *testing.T) {
+	t.Parallel()
+	ca := newTestCA(t, "Client CA")
+	s := New(&config.Config{}, nil, nil, nil)
+
+	err := s.authenticateClient(context.Background(), tlsClientAuthConfig("CN=app.example.com,O=Example,C=BD"), "", []*x509.Certificate{ca.issue(t, clientSubject)})
+	assert.ErrorIs(t, err, ErrInvalidClient)
+}
+
+func TestSelfSignedTLSClientAuth(t *testing.T) {
+	t.Parallel()
+	s := New(&config.Config{}, nil, nil, nil)
+	ctx := context.Background()
+	registered := newTestCA(t, "app.example.com").cert
+	config := repository.OauthConfig{
+		TokenEndpointAuthMethod: AuthMethodSelfSignedTLSClientAuth,
+		TlsClientCertificate:    pgtype.Text{String: encodeCertificate(registered), Valid: true},
+	}
+
+	assert.NoError(t, s.authenticateClient(ctx, config, "", []*x509.Certificate{registered}))
+	// the same subject is not enough, it has to be the registered certificate
+	other := newTestCA(t, "app.example.com").cert
+	assert.ErrorIs(t, s.authenticateClient(ctx, config, "", []*x509.Certificate{other}), ErrInvalidClient)
+	assert.ErrorIs(t, s.authenticateClient(ctx, config, "", nil), ErrInvalidClient)
+
+	config.TlsClientCertificate = pgtype.Text{}
+	assert.ErrorIs(t, s.authenticateClient(ctx, config, "", []*x509.Certificate{registered}), ErrInvalidClient)
+}
+
+func TestEqualDistinguishedNames(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		a, b  string
+		equal bool
+	}{
+		{"CN=app.example.com,O=Example", "CN=app.example.com,O=Example", true},
+		{"CN=app.example.com,O=Example", "cn=APP.example.com,o=example", true},
+		{"CN=app.example.com,O=Example", " CN = app.example.com , O = Example ", true},
+		{"CN=app.example.com,O=Example", "CN=app.example.com", false},
+		{"CN=app.example.com,O=Example", "O=Example,CN=app.example.com", false},
+		{"CN=app.example.com,O=Example", "CN=app.example.org,O=Example", false},
+		{"", "", false},
+		{"CN=app.example.com", "", false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.equal, equalDistinguishedNames(test.a, test.b), "%q and %q", test.a, test.b)
+	}
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/aritradeveops/porichoy/internal/core/cryptoutil"
+	"github.com/aritradeveops/porichoy/internal/core/validation"
+	"github.com/aritradeveops/porichoy/internal/persistence/repository"
+	"github.com/aritradeveops/porichoy/pkg/timex"
+	"github.com/google/uuid"
+)
+
+const defaultSecretGracePeriod = "24h"
+
+type RotateClientSecretPayload struct {
+	// how long the current secret keeps working, 24h by default
+	GracePeriod string `json:"grace_period" validate:"omitempty,duration"`
+	// when the new secret stops working, it does not expire when empty
+	ExpiresAt *time.Time `json:"expires_at" validate:"omitempty,gt"`
+}
+
+// RotatedClientSecret is the only time a new client secret is shown, it is
+// stored hashed.
+type RotatedClientSecret struct {
+	ClientSecret      string     `json:"client_secret"`
+	ExpiresAt         *time.Time `json:"expires_at"`
+	PreviousExpiresAt *time.Time `json:"previous_expires_at"`
+}
+
+// RotateClientSecret issues a new client secret, the current one keeps
+// working for the grace period so clients can be updated without downtime.
+// An app holds at most two valid secrets, older ones expire right away.
+func (s *Service) RotateClientSecret(ctx context.Context, initiator string, clientID string, payload RotateClientSecretPayload) (RotatedClientSecret, error) {
+	var rotated RotatedClientSecret
+	errs := validation.Validate(payload)
+	if errs != nil {
+		return rotated, errs
+	}
+	if payload.GracePeriod == "" {
+		payload.GracePeriod = defaultSecretGracePeriod
+	}
+	app, err := s.findManagedApp(ctx, initiator, clientID)
+	if err != nil {
+		return rotated, err
+	}
+
+	now := time.Now()
+	by := uuid.MustParse(initiator)
+	graceEnd := now.Add(timex.Duration(payload.GracePeriod).Duration())
+	// the old secrets only expire along with the new one being stored
+	err = s.inTx(ctx, func(q repository.Querier) error {
+		secrets, err := q.ListValidClientSecrets(ctx, app.App.ID)
+		if err != nil {
+			return err
+		}
+		for i, secret := range secrets {
+			expiresAt := now
+			if i == 0 {
+				expiresAt = graceEnd
+				if secret.ExpiresAt != nil && secret.ExpiresAt.Before(graceEnd) {
+					expiresAt = *secret.ExpiresAt
+				}
+				rotated.PreviousExpiresAt = &expiresAt
+			}
+			err := q.ExpireClientSecret(ctx, repository.ExpireClientSecretParams{
+				ID:        secret.ID,
+				ExpiresAt: &expiresAt,
+				UpdatedBy: &by,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		rotated.ClientSecret, err = createClientSecret(ctx, q, app.App.ID, by, payload.ExpiresAt)
+		return err
+	})
+	if err != nil {
+		return RotatedClientSecret{}, err
+	}
+	rotated.ExpiresAt = payload.ExpiresAt
+	return rotated, nil
+}
+
+// createClientSecret stores the hash of a new secret and returns the secret.
+func createClientSecret(ctx context.Context, q repository.Querier, appID uuid.UUID, createdBy uuid.UUID, expiresAt *time.Time) (string, error) {
+	secret, err := cryptoutil.GenerateHash(64)
+	if err != nil {
+		return "", err
+	}
+	hashed, err := cryptoutil.HashSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	_, err = q.CreateClientSecret(ctx, repository.CreateClientSecretParams{
+		HashedSecret: hashed,
+		ExpiresAt:    expiresAt,
+		AppID:        appID,
+		CreatedBy:    createdBy,
+	})
+	if err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// verifyClientSecret accepts any of the valid secrets of the app.
+func (s *Service) verifyClientSecret(ctx context.Context, appID uuid.UUID, secret string) error {
+	if secret == "" {
+		return ErrInvalidClient
+	}
+	secrets, err := s.repository.ListValidClientSecrets(ctx, appID)
+	if err != nil {
+		return err
+	}
+	valid := false
+	for _, stored := range secrets {
+		// every secret is checked so the timing does not tell which matched
+		if cryptoutil.VerifySecret(stored.HashedSecret, secret) {
+			valid = true
+		}
+	}
+	if !valid {
+		return ErrInvalidClient
+	}
+	return nil
+}
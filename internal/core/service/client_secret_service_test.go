@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aritradeveops/porichoy/internal/config"
+	"github.com/aritradeveops/porichoy/internal/core/cryptoutil"
+	"github.com/aritradeveops/porichoy/internal/persistence/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// clientSecretQuerier keeps one app and its secrets, InTx puts the secrets
+// back when the transaction fails like a rollback would.
+type clientSecretQuerier struct {
+	repository.Querier
+	app        repository.FindAppByClientIDRow
+	secrets    []repository.ClientSecret
+	failCreate bool
+}
+
+func (q *clientSecretQuerier) InTx(ctx context.Context, fn func(repository.Querier) error) error {
+	secrets := slices.Clone(q.secrets)
+	err := fn(q)
+	if err != nil {
+		q.secrets = secrets
+	}
+	return err
+}
+
+func (q *clientSecretQuerier) FindAppByClientID(ctx context.Context, clientID string) (repository.FindAppByClientIDRow, error) {
+	if clientID != q.app.App.ClientID {
+		return repository.FindAppByClientIDRow{}, pgx.ErrNoRows
+	}
+	return q.app, nil
+}
+
+func (q *clientSecretQuerier) ListValidClientSecrets(ctx context.Context, appID uuid.UUID) ([]repository.ClientSecret, error) {
+	var valid []repository.ClientSecret
+	// newest first
+	for i := len(q.secrets) - 1; i >= 0; i-- {
+		secret := q.secrets[i]
+		if secret.ExpiresAt == nil || secret.ExpiresAt.After(time.Now()) {
+			valid = append(valid, secret)
+		}
+	}
+	return valid, nil
+}
+
+func (q *clientSecretQuerier) ExpireClientSecret(ctx context.Context, arg repository.ExpireClientSecretParams) error {
+	for i, secret := range q.secrets {
+		if secret.ID == arg.ID {
+			q.secrets[i].ExpiresAt = arg.ExpiresAt
+		}
+	}
+	return nil
+}
+
+func (q *clientSecretQuerier) CreateClientSecret(ctx context.Context, arg repository.CreateClientSecretParams) (repository.ClientSecret, error) {
+	if q.failCreate {
+		return repository.ClientSecret{}, errors.New("connection reset")
+	}
+	row := repository.ClientSecret{
+		ID:           uuid.New(),
+		HashedSecret: arg.HashedSecret,
+		ExpiresAt:    arg.ExpiresAt,
+		AppID:        arg.AppID,
+		CreatedAt:    time.Now(),
+		CreatedBy:    arg.CreatedBy,
+	}
+	q.secrets = append(q.secrets, row)
+	return row, nil
+}
+
+func newClientSecretService(t *testing.T) (*Service, *clientSecretQuerier, string, string) {
+	owner := uuid.New()
+	querier := &clientSecretQuerier{app: repository.FindAppByClientIDRow{
+		App: repository.App{
+			ID:        uuid.New(),
+			ClientID:  "app.example.com",
+			CreatedBy: owner,
+		},
+	}}
+	secret, err := createClientSecret(context.Background(), querier, querier.app.App.ID, owner, nil)
+	assert.NoError(t, err)
+	s := New(&config.Config{Issuer: "https://id.example.com"}, querier, nil, nil)
+	return s, querier, owner.String(), secret
+}
+
+func TestRotateClientSecret(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s, querier, owner, first := newClientSecretService(t)
+	appID := querier.app.App.ID
+
+	rotated, err := s.RotateClientSecret(ctx, owner, "app.example.com", RotateClientSecretPayload{GracePeriod: "1h"})
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, rotated.ClientSecret)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), *rotated.PreviousExpiresAt, time.Minute)
+	// both work during the grace period
+	assert.NoError(t, s.verifyClientSecret(ctx, appID, first))
+	assert.NoError(t, s.verifyClientSecret(ctx, appID, rotated.ClientSecret))
+
+	// at most two secrets are valid, the oldest expires right away
+	again, err := s.RotateClientSecret(ctx, owner, "app.example.com", RotateClientSecretPayload{})
+	assert.NoError(t, err)
+	assert.ErrorIs(t, s.verifyClientSecret(ctx, appID, first), ErrInvalidClient)
+	assert.NoError(t, s.verifyClientSecret(ctx, appID, rotated.ClientSecret))
+	assert.NoError(t, s.verifyClientSecret(ctx, appID, again.ClientSecret))
+
+	// only hashes are stored
+	assert.True(t, cryptoutil.VerifySecret(querier.secrets[2].HashedSecret, again.ClientSecret))
+	assert.NotEqual(t, again.ClientSecret, querier.secrets[2].HashedSecret)
+}
+
+func TestRotateClientSecretRollback(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s, querier, owner, first := newClientSecretService(t)
+	querier.failCreate = true
+	_, err := s.RotateClientSecret(ctx, owner, "app.example.com", RotateClientSecretPayload{GracePeriod: "0s"})
+	assert.Error(t, err)
+	// the current secret was not expired without a new one to replace it
+	assert.Nil(t, querier.secrets[0].ExpiresAt)
+	assert.NoError(t, s.verifyClientSecret(ctx, querier.app.App.ID, first))
+}
+
+func TestRotateClientSecretOwnership(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s, querier, _, _ := newClientSecretService(t)
+	_, err := s.RotateClientSecret(ctx, uuid.NewString(), "app.example.com", RotateClientSecretPayload{})
+	assert.ErrorIs(t, err, ErrAppForbidden)
+	assert.Len(t, querier.secrets, 1)
+	_, err = s.RotateClientSecret(ctx, uuid.Nil.String(), "unknown.example.com", RotateClientSecretPayload{})
+	assert.ErrorIs(t, err, ErrAppNotFound)
+
+	// root manages every app
+	_, err = s.RotateClientSecret(ctx, uuid.Nil.String(), "app.example.com", RotateClientSecretPayload{})
+	assert.NoError(t, err)
+	assert.Len(t, querier.secrets, 2)
+}
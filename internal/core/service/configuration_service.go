@@ -28,6 +28,6 @@ func (s *Service) Configure(ctx context.Context, config ConfigurationPayload) er
 	if err != nil {
 		return err
 	}
-	logger.Info().Any("root app", app).Msg("root app created successfully!")
+	logger.Info().Any("root app", app.App).Msg("root app created successfully!")
 	return nil
 }
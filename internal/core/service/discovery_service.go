@@ -0,0 +1,56 @@
+package service
+
+import (
+	"github.com/aritradeveops/porichoy/internal/core/jwtutil"
+)
+
+// OpenIDConfiguration is the provider metadata served for discovery, see
+// OpenID Connect Discovery 1.0 section 3.
+type OpenIDConfiguration struct {
+	Issuer                                string   `json:"issuer"`
+	AuthorizationEndpoint                 string   `json:"authorization_endpoint"`
+	TokenEndpoint                         string   `json:"token_endpoint"`
+	UserinfoEndpoint                      string   `json:"userinfo_endpoint"`
+	JwksURI                               string   `json:"jwks_uri"`
+	ScopesSupported                       []string `json:"scopes_supported"`
+	ResponseTypesSupported                []string `json:"response_types_supported"`
+	ResponseModesSupported                []string `json:"response_modes_supported"`
+	GrantTypesSupported                   []string `json:"grant_types_supported"`
+	SubjectTypesSupported                 []string `json:"subject_types_supported"`
+	PromptValuesSupported                 []string `json:"prompt_values_supported"`
+	ClaimsSupported                       []string `json:"claims_supported"`
+	TokenEndpointAuthMethodsSupported     []string `json:"token_endpoint_auth_methods_supported"`
+	IDTokenSigningAlgValuesSupported      []string `json:"id_token_signing_alg_values_supported"`
+	IDTokenEncryptionAlgValuesSupported   []string `json:"id_token_encryption_alg_values_supported"`
+	IDTokenEncryptionEncValuesSupported   []string `json:"id_token_encryption_enc_values_supported"`
+	UserinfoSigningAlgValuesSupported     []string `json:"userinfo_signing_alg_values_supported"`
+	UserinfoEncryptionAlgValuesSupported  []string `json:"userinfo_encryption_alg_values_supported"`
+	UserinfoEncryptionEncValuesSupported  []string `json:"userinfo_encryption_enc_values_supported"`
+	TLSClientCertificateBoundAccessTokens bool     `json:"tls_client_certificate_bound_access_tokens"`
+}
+
+func (s *Service) OpenIDConfiguration() OpenIDConfiguration {
+	issuer := s.config.IssuerURL()
+	return OpenIDConfiguration{
+		Issuer:                                issuer,
+		AuthorizationEndpoint:                 issuer + "/oauth2",
+		TokenEndpoint:                         issuer + "/api/v1/auth/token",
+		UserinfoEndpoint:                      issuer + "/api/v1/auth/userinfo",
+		JwksURI:                               issuer + "/.well-known/jwks.json",
+		ScopesSupported:                       []string{ScopeOpenID, ScopeProfile, ScopeEmail},
+		ResponseTypesSupported:                []string{ResponseTypeCode},
+		ResponseModesSupported:                []string{ResponseModeQuery, ResponseModeFragment, ResponseModeFormPost},
+		GrantTypesSupported:                   []string{"authorization_code"},
+		SubjectTypesSupported:                 []string{"public"},
+		PromptValuesSupported:                 []string{PromptNone, PromptLogin, PromptConsent},
+		ClaimsSupported:                       []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "acr", "amr", "azp", "name", "picture", "email"},
+		TokenEndpointAuthMethodsSupported:     []string{AuthMethodClientSecretPost, AuthMethodTLSClientAuth, AuthMethodSelfSignedTLSClientAuth},
+		IDTokenSigningAlgValuesSupported:      jwtutil.Algorithms,
+		IDTokenEncryptionAlgValuesSupported:   jwtutil.EncryptionAlgorithms,
+		IDTokenEncryptionEncValuesSupported:   jwtutil.EncryptionEncodings,
+		UserinfoSigningAlgValuesSupported:     jwtutil.Algorithms,
+		UserinfoEncryptionAlgValuesSupported:  jwtutil.EncryptionAlgorithms,
+		UserinfoEncryptionEncValuesSupported:  jwtutil.EncryptionEncodings,
+		TLSClientCertificateBoundAccessTokens: s.config.Http.TLS != nil,
+	}
+}
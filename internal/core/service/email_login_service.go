@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aritradeveops/porichoy/internal/core/cryptoutil"
+	"github.com/aritradeveops/porichoy/internal/core/validation"
+	"github.com/aritradeveops/porichoy/internal/persistence/repository"
+	"github.com/aritradeveops/porichoy/internal/pkg/logger"
+	"github.com/aritradeveops/porichoy/internal/pkg/mailer"
+	"github.com/google/uuid"
+)
+
+const (
+	emailLoginPurpose    = "porichoy email login"
+	EmailLoginLifetime   = 10 * time.Minute
+	emailLoginCodeDigits = 6
+)
+
+var (
+	ErrInvalidEmailLogin = errors.New("email_login_service: invalid or expired login link or code")
+)
+
+// CompleteEmailLoginPayload takes either the token of the link or the
+// login token LoginUser answered with and the code from the email.
+type CompleteEmailLoginPayload struct {
+	Token      string `json:"token" validate:"required_without=LoginToken"`
+	LoginToken string `json:"login_token"`
+	Code       string `json:"code" validate:"required_with=LoginToken"`
+	ClientID   string `json:"client_id"`
+	UserAgent  string `json:"user_agent" validate:"required"`
+	UserIP     string `json:"user_ip" validate:"required"`
+}
+
+type emailLoginClaims struct {
+	ID string `json:"id"`
+}
+
+// startEmailLogin mails a single-use link and code to the user. Like
+// ForgotPassword it answers the same whether or not the address has an
+// account, the login token of an unknown address just never matches.
+func (s *Service) startEmailLogin(ctx context.Context, payload LoginUserPayload) (LoginUserResponse, error) {
+	var response LoginUserResponse
+	if !s.emailLoginLimits.ip.Allow(payload.UserIP) || !s.emailLoginLimits.email.Allow(strings.ToLower(payload.Email)) {
+		return response, ErrTooManyRequests
+	}
+	key, err := s.masterKey(emailLoginPurpose)
+	if err != nil {
+		return response, err
+	}
+	id := uuid.New()
+	response.LoginToken, err = cryptoutil.SignToken(key, emailLoginClaims{ID: id.String()}, EmailLoginLifetime)
+	if err != nil {
+		return response, err
+	}
+
+	user, err := s.repository.FindUserByEmail(ctx, payload.Email)
+	if err != nil || user.DeactivatedAt != nil {
+		return response, nil
+	}
+	// only the latest email works
+	err = s.repository.RevokeEmailLoginTokens(ctx, user.ID)
+	if err != nil {
+		return response, err
+	}
+	token, err := cryptoutil.GenerateHash(32)
+	if err != nil {
+		return response, err
+	}
+	code, err := cryptoutil.GenerateCode(emailLoginCodeDigits)
+	if err != nil {
+		return response, err
+	}
+	hashedCode, err := cryptoutil.HashSecret(code)
+	if err != nil {
+		return response, err
+	}
+	err = s.repository.CreateEmailLoginToken(ctx, repository.CreateEmailLoginTokenParams{
+		ID:          id,
+		HashedToken: cryptoutil.HashToken(token),
+		HashedCode:  hashedCode,
+		UserID:      user.ID,
+		RequestedIp: payload.UserIP,
+		ExpiresAt:   time.Now().Add(EmailLoginLifetime),
+		CreatedBy:   user.ID,
+	})
+	if err != nil {
+		return response, err
+	}
+
+	link := s.config.IssuerURL() + "/login/email?token=" + url.QueryEscape(token)
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: fmt.Sprintf("Your sign-in code is %s", code),
+		Text: fmt.Sprintf("Hi %s,\n\nsign in by opening the link below or by entering the code %s, either is valid for %s and can be used once.\n\n%s\n\nIf you did not try to sign in you can ignore this email.\n",
+			user.Name, code, EmailLoginLifetime, link),
+	})
+	if err != nil {
+		logger.Error().Err(err).Str("user_id", user.ID.String()).Msg("failed to send the login email")
+	}
+	return response, nil
+}
+
+// CompleteEmailLogin signs in the user an email login was started for. The
+// email proves the user owns the address, so it is marked verified.
+func (s *Service) CompleteEmailLogin(ctx context.Context, payload CompleteEmailLoginPayload) (LoginUserResponse, error) {
+	var response LoginUserResponse
+	errs := validation.Validate(payload)
+	if errs != nil {
+		return response, errs
+	}
+	if !s.loginMethodEnabled(LoginMethodEmail) {
+		return response, ErrInvalidLoginMethod
+	}
+	if !s.emailLoginLimits.ip.Allow(payload.UserIP) {
+		return response, ErrTooManyRequests
+	}
+	token, err := s.findEmailLoginToken(ctx, payload)
+	if err != nil {
+		return response, err
+	}
+	// consuming is what makes the link and code single-use
+	token, err = s.repository.ConsumeEmailLoginToken(ctx, token.ID)
+	if err != nil {
+		return response, ErrInvalidEmailLogin
+	}
+	user, err := s.repository.FindUserByID(ctx, token.UserID)
+	if err != nil {
+		return response, ErrInvalidEmailLogin
+	}
+	if user.DeactivatedAt != nil {
+		return response, ErrDeactivatedUser
+	}
+	if user.EmailVerifiedAt == nil {
+		err = s.repository.MarkEmailVerified(ctx, repository.MarkEmailVerifiedParams{ID: user.ID, Email: user.Email})
+		if err != nil {
+			return response, err
+		}
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+	rootApp, err := s.repository.FindRootApp(ctx)
+	if err != nil {
+		return response, err
+	}
+	return s.completeLogin(ctx, rootApp, user, payload.ClientID, []string{AmrEmail}, payload.UserIP, payload.UserAgent)
+}
+
+// findEmailLoginToken looks up the login by the token of the link, or by
+// the login token and code. Codes are short so each login only gets a few
+// guesses.
+func (s *Service) findEmailLoginToken(ctx context.Context, payload CompleteEmailLoginPayload) (repository.EmailLoginToken, error) {
+	if payload.Token != "" {
+		token, err := s.repository.FindEmailLoginTokenByHash(ctx, cryptoutil.HashToken(payload.Token))
+		if err != nil {
+			return token, ErrInvalidEmailLogin
+		}
+		return token, nil
+	}
+	key, err := s.masterKey(emailLoginPurpose)
+	if err != nil {
+		return repository.EmailLoginToken{}, err
+	}
+	var claims emailLoginClaims
+	if err := cryptoutil.VerifyToken(key, payload.LoginToken, &claims); err != nil {
+		return repository.EmailLoginToken{}, ErrInvalidEmailLogin
+	}
+	id, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return repository.EmailLoginToken{}, ErrInvalidEmailLogin
+	}
+	if !s.emailLoginLimits.code.Allow(claims.ID) {
+		return repository.EmailLoginToken{}, ErrTooManyRequests
+	}
+	token, err := s.repository.FindEmailLoginToken(ctx, id)
+	if err != nil || !cryptoutil.VerifySecret(token.HashedCode, payload.Code) {
+		return repository.EmailLoginToken{}, ErrInvalidEmailLogin
+	}
+	return token, nil
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aritradeveops/porichoy/internal/config"
+	"github.com/aritradeveops/porichoy/internal/persistence/repository"
+	"github.com/aritradeveops/porichoy/internal/pkg/mailer"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// emailLoginQuerier keeps the users and login tokens an email login
+// touches, anything else panics on the nil Querier.
+type emailLoginQuerier struct {
+	repository.Querier
+	users  []repository.User
+	tokens []repository.EmailLoginToken
+}
+
+func (q *emailLoginQuerier) FindUserByEmail(ctx context.Context, email string) (repository.User, error) {
+	for _, user := range q.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return repository.User{}, errors.New("no rows in result set")
+}
+
+func (q *emailLoginQuerier) RevokeEmailLoginTokens(ctx context.Context, userID uuid.UUID) error {
+	for i, token := range q.tokens {
+		if token.UserID == userID {
+			q.tokens[i].ExpiresAt = time.Now()
+		}
+	}
+	return nil
+}
+
+func (q *emailLoginQuerier) CreateEmailLoginToken(ctx context.Context, arg repository.CreateEmailLoginTokenParams) error {
+	q.tokens = append(q.tokens, repository.EmailLoginToken{
+		ID:          arg.ID,
+		HashedToken: arg.HashedToken,
+		HashedCode:  arg.HashedCode,
+		UserID:      arg.UserID,
+		RequestedIp: arg.RequestedIp,
+		ExpiresAt:   arg.ExpiresAt,
+		CreatedAt:   time.Now(),
+		CreatedBy:   arg.CreatedBy,
+	})
+	return nil
+}
+
+func (q *emailLoginQuerier) find(match func(repository.EmailLoginToken) bool) (repository.EmailLoginToken, error) {
+	for _, token := range q.tokens {
+		if match(token) && token.UsedAt == nil && token.ExpiresAt.After(time.Now()) {
+			return token, nil
+		}
+	}
+	return repository.EmailLoginToken{}, errors.New("no rows in result set")
+}
+
+func (q *emailLoginQuerier) FindEmailLoginToken(ctx context.Context, id uuid.UUID) (repository.EmailLoginToken, error) {
+	return q.find(func(token repository.EmailLoginToken) bool { return token.ID == id })
+}
+
+func (q *emailLoginQuerier) FindEmailLoginTokenByHash(ctx context.Context, hashedToken string) (repository.EmailLoginToken, error) {
+	return q.find(func(token repository.EmailLoginToken) bool { return token.HashedToken == hashedToken })
+}
+
+type recordingMailer struct {
+	messages []mailer.Message
+}
+
+func (m *recordingMailer) Send(ctx context.Context, message mailer.Message) error {
+	m.messages = append(m.messages, message)
+	return nil
+}
+
+func newEmailLoginService(methods ...string) (*Service, *recordingMailer) {
+	querier := &emailLoginQuerier{users: []repository.User{{
+		ID:    uuid.New(),
+		Email: "jane@example.com",
+		Name:  "Jane",
+	}}}
+	mail := &recordingMailer{}
+	s := New(&config.Config{
+		Issuer: "https://id.example.com",
+		Keys: config.Keys{
+			MasterKeyResolver: "literal://email-login-test-master-key",
+		},
+		Login: config.Login{Methods: methods},
+	}, querier, mail, nil)
+	return s, mail
+}
+
+func startEmailLogin(t *testing.T, s *Service, email string) string {
+	response, err := s.LoginUser(context.Background(), LoginUserPayload{
+		Method:    LoginMethodEmail,
+		Email:     email,
+		UserAgent: "test",
+		UserIP:    "192.0.2.1",
+		Host:      "id.example.com",
+	})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, response.LoginToken)
+	assert.Empty(t, response.AccessToken)
+	return response.LoginToken
+}
+
+func TestEmailLoginCode(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s, mail := newEmailLoginService()
+	loginToken := startEmailLogin(t, s, "jane@example.com")
+	assert.Len(t, mail.messages, 1)
+	code := regexp.MustCompile(`[0-9]{6}$`).FindString(mail.messages[0].Subject)
+	assert.Len(t, code, emailLoginCodeDigits)
+
+	_, err := s.findEmailLoginToken(ctx, CompleteEmailLoginPayload{LoginToken: loginToken, Code: "not-it"})
+	assert.ErrorIs(t, err, ErrInvalidEmailLogin)
+	token, err := s.findEmailLoginToken(ctx, CompleteEmailLoginPayload{LoginToken: loginToken, Code: code})
+	assert.NoError(t, err)
+	assert.Equal(t, "192.0.2.1", token.RequestedIp)
+
+	// a new email replaces the code of the previous one
+	startEmailLogin(t, s, "jane@example.com")
+	_, err = s.findEmailLoginToken(ctx, CompleteEmailLoginPayload{LoginToken: loginToken, Code: code})
+	assert.ErrorIs(t, err, ErrInvalidEmailLogin)
+}
+
+func TestEmailLoginLink(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s, mail := newEmailLoginService()
+	startEmailLogin(t, s, "jane@example.com")
+	link := regexp.MustCompile(`https://\S+`).FindString(mail.messages[0].Text)
+	assert.True(t, strings.HasPrefix(link, "https://id.example.com/login/email?token="))
+	parsed, err := url.Parse(link)
+	assert.NoError(t, err)
+
+	_, err = s.findEmailLoginToken(ctx, CompleteEmailLoginPayload{Token: parsed.Query().Get("token")})
+	assert.NoError(t, err)
+	_, err = s.findEmailLoginToken(ctx, CompleteEmailLoginPayload{Token: "forged"})
+	assert.ErrorIs(t, err, ErrInvalidEmailLogin)
+}
+
+func TestEmailLoginUnknownAddress(t *testing.T) {
+	t.Parallel()
+
+	s, mail := newEmailLoginService()
+	// answers like for an account so addresses can not be probed
+	loginToken := startEmailLogin(t, s, "nobody@example.com")
+	assert.Empty(t, mail.messages)
+	_, err := s.findEmailLoginToken(context.Background(), CompleteEmailLoginPayload{LoginToken: loginToken, Code: "000000"})
+	assert.ErrorIs(t, err, ErrInvalidEmailLogin)
+}
+
+func TestLoginMethodSelector(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s, mail := newEmailLoginService(LoginMethodPassword)
+	payload := LoginUserPayload{
+		Method:    LoginMethodEmail,
+		Email:     "jane@example.com",
+		UserAgent: "test",
+		UserIP:    "192.0.2.1",
+		Host:      "id.example.com",
+	}
+	_, err := s.LoginUser(ctx, payload)
+	assert.ErrorIs(t, err, ErrInvalidLoginMethod)
+	assert.Empty(t, mail.messages)
+
+	payload.Method = "carrier-pigeon"
+	_, err = s.LoginUser(ctx, payload)
+	assert.ErrorIs(t, err, ErrInvalidLoginMethod)
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aritradeveops/porichoy/internal/core/cryptoutil"
+	"github.com/aritradeveops/porichoy/internal/core/validation"
+	"github.com/aritradeveops/porichoy/internal/persistence/repository"
+	"github.com/aritradeveops/porichoy/internal/pkg/logger"
+	"github.com/aritradeveops/porichoy/internal/pkg/mailer"
+	"github.com/google/uuid"
+)
+
+const (
+	emailVerificationPurpose  = "porichoy email verification"
+	EmailVerificationLifetime = 24 * time.Hour
+)
+
+var ErrInvalidVerificationLink = errors.New("email_verification_service: invalid or expired verification link")
+
+type ResendVerificationEmailPayload struct {
+	Email  string `json:"email" validate:"required,email"`
+	UserIP string `json:"user_ip" validate:"required"`
+}
+
+// the email is part of the claims so a link stops working once the user's
+// address changes
+type emailVerificationClaims struct {
+	Subject string `json:"sub"`
+	Email   string `json:"email"`
+}
+
+// sendVerificationEmail mails the user a signed link to /verify-email.
+func (s *Service) sendVerificationEmail(ctx context.Context, user repository.User) error {
+	key, err := s.masterKey(emailVerificationPurpose)
+	if err != nil {
+		return err
+	}
+	token, err := cryptoutil.SignToken(key, emailVerificationClaims{
+		Subject: user.ID.String(),
+		Email:   user.Email,
+	}, EmailVerificationLifetime)
+	if err != nil {
+		return err
+	}
+	link := s.config.IssuerURL() + "/api/v1/auth/verify-email?token=" + url.QueryEscape(token)
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Text: fmt.Sprintf("Hi %s,\n\nconfirm your email address by opening the link below, it is valid for %s.\n\n%s\n\nIf you did not sign up you can ignore this email.\n",
+			user.Name, EmailVerificationLifetime, link),
+	})
+}
+
+// VerifyEmail marks the email of the user a verification link was sent to
+// as verified, links are only accepted for the address they were sent to.
+func (s *Service) VerifyEmail(ctx context.Context, token string) error {
+	key, err := s.masterKey(emailVerificationPurpose)
+	if err != nil {
+		return err
+	}
+	var claims emailVerificationClaims
+	if err := cryptoutil.VerifyToken(key, token, &claims); err != nil {
+		return ErrInvalidVerificationLink
+	}
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return ErrInvalidVerificationLink
+	}
+	user, err := s.repository.FindUserByID(ctx, userID)
+	if err != nil || user.Email != claims.Email {
+		return ErrInvalidVerificationLink
+	}
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+	return s.repository.MarkEmailVerified(ctx, repository.MarkEmailVerifiedParams{
+		ID:    user.ID,
+		Email: user.Email,
+	})
+}
+
+// ResendVerificationEmail sends a new link to an unverified address. It
+// succeeds whether or not the address belongs to a user so it can not be
+// used to find out who has an account, requests are limited per email and
+// per ip.
+func (s *Service) ResendVerificationEmail(ctx context.Context, payload ResendVerificationEmailPayload) error {
+	errs := validation.Validate(payload)
+	if errs != nil {
+		return errs
+	}
+	if !s.verificationLimits.ip.Allow(payload.UserIP) || !s.verificationLimits.email.Allow(strings.ToLower(payload.Email)) {
+		return ErrTooManyRequests
+	}
+	user, err := s.repository.FindUserByEmail(ctx, payload.Email)
+	if err != nil || user.EmailVerifiedAt != nil || user.DeactivatedAt != nil {
+		return nil
+	}
+	if err := s.sendVerificationEmail(ctx, user); err != nil {
+		logger.Error().Err(err).Str("user_id", user.ID.String()).Msg("failed to send the verification email")
+	}
+	return nil
+}
+
+// requireVerifiedEmail enforces the verified email policy of an app.
+func requireVerifiedEmail(config repository.OauthConfig, user repository.User) error {
+	if config.RequireVerifiedEmail && user.EmailVerifiedAt == nil {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
+
+// requireVerifiedEmailFor enforces the policy for a user only known by id,
+// the user is only looked up for apps that have the policy.
+func (s *Service) requireVerifiedEmailFor(ctx context.Context, config repository.OauthConfig, userID string) error {
+	if !config.RequireVerifiedEmail {
+		return nil
+	}
+	user, err := s.repository.FindUserByID(ctx, uuid.MustParse(userID))
+	if err != nil {
+		return err
+	}
+	return requireVerifiedEmail(config, user)
+}
+
+func emailVerified(user repository.User) *bool {
+	verified := user.EmailVerifiedAt != nil
+	return &verified
+}
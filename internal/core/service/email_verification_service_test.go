@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/aritradeveops/porichoy/internal/config"
+	"github.com/aritradeveops/porichoy/internal/persistence/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// verificationQuerier keeps the users an email verification touches,
+// anything else panics on the nil Querier.
+type verificationQuerier struct {
+	repository.Querier
+	users map[uuid.UUID]repository.User
+}
+
+func (q *verificationQuerier) FindUserByEmail(ctx context.Context, email string) (repository.User, error) {
+	for _, user := range q.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return repository.User{}, errors.New("no rows in result set")
+}
+
+func (q *verificationQuerier) FindUserByID(ctx context.Context, id uuid.UUID) (repository.User, error) {
+	user, ok := q.users[id]
+	if !ok {
+		return user, errors.New("no rows in result set")
+	}
+	return user, nil
+}
+
+func (q *verificationQuerier) MarkEmailVerified(ctx context.Context, arg repository.MarkEmailVerifiedParams) error {
+	user := q.users[arg.ID]
+	if user.Email == arg.Email {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		q.users[arg.ID] = user
+	}
+	return nil
+}
+
+func newVerificationService() (*Service, *verificationQuerier, *recordingMailer, repository.User) {
+	user := repository.User{
+		ID:    uuid.New(),
+		Email: "jane@example.com",
+		Name:  "Jane",
+	}
+	querier := &verificationQuerier{users: map[uuid.UUID]repository.User{user.ID: user}}
+	mail := &recordingMailer{}
+	s := New(&config.Config{
+		Issuer: "https://id.example.com",
+		Keys: config.Keys{
+			MasterKeyResolver: "literal://verification-test-master-key",
+		},
+	}, querier, mail, nil)
+	return s, querier, mail, user
+}
+
+var verificationLink = regexp.MustCompile(`verify-email\?token=(\S+)`)
+
+// verificationToken takes the token out of the last mail sent.
+func verificationToken(t *testing.T, mail *recordingMailer) string {
+	match := verificationLink.FindStringSubmatch(mail.messages[len(mail.messages)-1].Text)
+	assert.Len(t, match, 2)
+	token, err := url.QueryUnescape(match[1])
+	assert.NoError(t, err)
+	return token
+}
+
+func TestVerifyEmail(t *testing.T) {
+	t.Parallel()
+	s, querier, mail, user := newVerificationService()
+	ctx := context.Background()
+
+	err := s.ResendVerificationEmail(ctx, ResendVerificationEmailPayload{Email: user.Email, UserIP: "192.0.2.1"})
+	assert.NoError(t, err)
+	assert.Len(t, mail.messages, 1)
+	assert.Equal(t, user.Email, mail.messages[0].To)
+	token := verificationToken(t, mail)
+
+	assert.ErrorIs(t, s.VerifyEmail(ctx, token+"x"), ErrInvalidVerificationLink)
+	assert.Nil(t, querier.users[user.ID].EmailVerifiedAt)
+
+	assert.NoError(t, s.VerifyEmail(ctx, token))
+	verifiedAt := querier.users[user.ID].EmailVerifiedAt
+	assert.NotNil(t, verifiedAt)
+
+	// opening the link again changes nothing
+	assert.NoError(t, s.VerifyEmail(ctx, token))
+	assert.Equal(t, verifiedAt, querier.users[user.ID].EmailVerifiedAt)
+
+	// verified addresses get no new link
+	err = s.ResendVerificationEmail(ctx, ResendVerificationEmailPayload{Email: user.Email, UserIP: "192.0.2.1"})
+	assert.NoError(t, err)
+	assert.Len(t, mail.messages, 1)
+}
+
+func TestVerifyEmailAfterAddressChange(t *testing.T) {
+	t.Parallel()
+	s, querier, mail, user := newVerificationService()
+	ctx := context.Background()
+
+	err := s.ResendVerificationEmail(ctx, ResendVerificationEmailPayload{Email: user.Email, UserIP: "192.0.2.1"})
+	assert.NoError(t, err)
+	token := verificationToken(t, mail)
+
+	user.Email = "jane@example.org"
+	querier.users[user.ID] = user
+	assert.ErrorIs(t, s.VerifyEmail(ctx, token), ErrInvalidVerificationLink)
+	assert.Nil(t, querier.users[user.ID].EmailVerifiedAt)
+}
+
+func TestResendVerificationEmailUnknownAddress(t *testing.T) {
+	t.Parallel()
+	s, _, mail, _ := newVerificationService()
+
+	// the answer does not tell whether the address has an account
+	err := s.ResendVerificationEmail(context.Background(), ResendVerificationEmailPayload{Email: "john@example.com", UserIP: "192.0.2.1"})
+	assert.NoError(t, err)
+	assert.Empty(t, mail.messages)
+}
+
+func TestResendVerificationEmailRateLimit(t *testing.T) {
+	t.Parallel()
+	s, _, mail, user := newVerificationService()
+	ctx := context.Background()
+
+	for range 3 {
+		assert.NoError(t, s.ResendVerificationEmail(ctx, ResendVerificationEmailPayload{Email: user.Email, UserIP: "192.0.2.1"}))
+	}
+	assert.Len(t, mail.messages, 3)
+	// the limit is per address whatever its case or the ip asking
+	err := s.ResendVerificationEmail(ctx, ResendVerificationEmailPayload{Email: "Jane@Example.com", UserIP: "192.0.2.2"})
+	assert.ErrorIs(t, err, ErrTooManyRequests)
+	assert.Len(t, mail.messages, 3)
+
+	// and per ip whatever the address
+	for range 10 {
+		assert.NoError(t, s.ResendVerificationEmail(ctx, ResendVerificationEmailPayload{Email: uuid.NewString() + "@example.com", UserIP: "192.0.2.3"}))
+	}
+	err = s.ResendVerificationEmail(ctx, ResendVerificationEmailPayload{Email: "john@example.com", UserIP: "192.0.2.3"})
+	assert.ErrorIs(t, err, ErrTooManyRequests)
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aritradeveops/porichoy/internal/core/jwtutil"
+	"github.com/aritradeveops/porichoy/internal/persistence/repository"
+	"github.com/aritradeveops/porichoy/internal/pkg/logger"
+)
+
+var (
+	ErrUnknownIssuer = errors.New("key_service: unknown issuer")
+)
+
+// appKeyStore trusts the signing keys of the registered apps that are
+// active or still retiring.
+type appKeyStore struct {
+	service  *Service
+	rootOnly bool
+}
+
+// KeyStore returns the keys of all the apps, to verify tokens issued to any
+// of them. Only the keys nobody but porichoy can sign with are trusted, the
+// ones of the root app and the asymmetric keys porichoy generated. App
+// owners know their HMAC secrets and the keys they resolve themselves, a
+// token signed with those says nothing about the user.
+func (s *Service) KeyStore() jwtutil.KeyStore {
+	return &appKeyStore{service: s}
+}
+
+// SessionKeyStore returns the keys of the root app only, tokens issued to
+// other apps must not be accepted as porichoy sessions.
+func (s *Service) SessionKeyStore() jwtutil.KeyStore {
+	return &appKeyStore{service: s, rootOnly: true}
+}
+
+func (k *appKeyStore) Lookup(ctx context.Context, issuer string, kid string) (jwtutil.VerificationKey, error) {
+	var key jwtutil.VerificationKey
+	if issuer != k.service.config.IssuerURL() {
+		return key, ErrUnknownIssuer
+	}
+	row, err := k.service.repository.FindSigningKeyByKid(ctx, kid)
+	if err != nil {
+		return key, err
+	}
+	if !verifies(row.SigningKey, time.Now()) {
+		return key, jwtutil.ErrUnknownKey
+	}
+	audience := []string{row.App.Domain}
+	root, err := k.service.repository.FindRootApp(ctx)
+	if err != nil {
+		return key, err
+	}
+	if root.App.ID != row.App.ID && (k.rootOnly || !privateKey(row.SigningKey)) {
+		return key, jwtutil.ErrUnknownKey
+	}
+	if !k.rootOnly {
+		resources, err := k.service.repository.ListApiResources(ctx)
+		if err != nil {
+			return key, err
+		}
+		for _, resource := range resources {
+			audience = append(audience, resource.Identifier)
+		}
+	}
+	return k.service.verificationKey(row.SigningKey, issuer, audience)
+}
+
+// JWKS returns the public keys that are or will be used for signing, keys
+// of apps using HMAC secrets are left out.
+func (s *Service) JWKS(ctx context.Context) (jwtutil.JWKSet, error) {
+	set := jwtutil.JWKSet{Keys: []jwtutil.JWK{}}
+	keys, err := s.repository.ListPublishedSigningKeys(ctx)
+	if err != nil {
+		return set, err
+	}
+	now := time.Now()
+	for _, key := range keys {
+		if jwtutil.IsSymmetricAlgorithm(key.Algo) || (key.State != KeyStateNext && !verifies(key, now)) {
+			continue
+		}
+		var jwk jwtutil.JWK
+		verification, err := s.verificationKey(key, s.config.IssuerURL(), nil)
+		if err == nil {
+			jwk, err = jwtutil.PublicJWK(verification)
+		}
+		if err != nil {
+			// one misconfigured key must not take the key set down
+			logger.Error().Err(err).Str("kid", key.Kid).Msg("failed to publish signing key")
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set, nil
+}
+
+// verifies reports whether tokens signed with the key are still accepted.
+func verifies(key repository.SigningKey, now time.Time) bool {
+	switch key.State {
+	case KeyStateActive:
+		return true
+	case KeyStateRetiring:
+		return key.ExpiresAt == nil || now.Before(*key.ExpiresAt)
+	default:
+		return false
+	}
+}
+
+// privateKey reports whether only porichoy can sign with the key, it was
+// generated and its private half never leaves the server.
+func privateKey(key repository.SigningKey) bool {
+	return key.EncryptedPrivateKey.Valid && !jwtutil.IsSymmetricAlgorithm(key.Algo)
+}
+
+func (s *Service) verificationKey(key repository.SigningKey, issuer string, audience []string) (jwtutil.VerificationKey, error) {
+	secret, err := s.keyMaterial(key)
+	if err != nil {
+		return jwtutil.VerificationKey{}, err
+	}
+	return jwtutil.VerificationKey{
+		ID:         key.Kid,
+		Issuer:     issuer,
+		Algorithms: []string{key.Algo},
+		Audience:   audience,
+		Resolver:   key.SecretResolver.String,
+		Secret:     secret,
+	}, nil
+}
@@ -0,0 +1,550 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aritradeveops/porichoy/internal/core/cryptoutil"
+	"github.com/aritradeveops/porichoy/internal/core/validation"
+	"github.com/aritradeveops/porichoy/internal/persistence/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	mfaSecretPurpose     = "porichoy mfa secret"
+	mfaChallengePurpose  = "porichoy mfa challenge"
+	MfaChallengeLifetime = 5 * time.Minute
+	recoveryCodeCount    = 10
+)
+
+// what a login has to do after the password when a second factor is needed
+const (
+	MfaStepVerify = "verify"
+	MfaStepEnroll = "enroll"
+)
+
+const (
+	MfaMethodTotp    = "totp"
+	MfaMethodPasskey = "passkey"
+	MfaMethodSms     = "sms"
+)
+
+var (
+	ErrInvalidMfaToken     = errors.New("mfa_service: invalid or expired mfa token")
+	ErrInvalidMfaCode      = errors.New("mfa_service: invalid mfa code")
+	ErrTotpAlreadyEnrolled = errors.New("mfa_service: totp is already enrolled")
+	ErrTotpNotEnrolled     = errors.New("mfa_service: totp is not enrolled")
+	ErrMfaPolicyForbidden  = errors.New("mfa_service: only the root user can change mfa policies")
+	ErrUserNotFound        = errors.New("mfa_service: user not found")
+)
+
+type EnrollMfaPayload struct {
+	MfaToken string `json:"mfa_token" validate:"required"`
+}
+
+type VerifyMfaPayload struct {
+	MfaToken     string `json:"mfa_token" validate:"required"`
+	Code         string `json:"code" validate:"required_without=RecoveryCode"`
+	RecoveryCode string `json:"recovery_code"`
+	UserAgent    string `json:"user_agent" validate:"required"`
+	UserIP       string `json:"user_ip" validate:"required"`
+}
+
+type ConfirmTotpPayload struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// MfaCodePayload proves the user still holds their second factor before it
+// is changed.
+type MfaCodePayload struct {
+	Code         string `json:"code" validate:"required_without=RecoveryCode"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type SetMfaPolicyPayload struct {
+	Required bool `json:"required"`
+}
+
+type TotpEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+	// png data uri of the QR code for URI
+	QRCode string `json:"qr_code"`
+}
+
+type mfaChallengeClaims struct {
+	Subject string `json:"sub"`
+	Enroll  bool   `json:"enroll,omitempty"`
+	// how the user passed the first step
+	Amr []string `json:"amr,omitempty"`
+}
+
+// amr is the first factor followed by the second one.
+func (c mfaChallengeClaims) amr(second string) []string {
+	amr := slices.Clone(c.Amr)
+	if len(amr) == 0 {
+		amr = []string{AmrPassword}
+	}
+	return append(amr, second)
+}
+
+// mfaStep decides whether a login past its first factor still needs a
+// second factor. Users with one always verify it, others have to enroll
+// when they or one of the apps require mfa. The factor the login started
+// with does not count as the second one.
+func (s *Service) mfaStep(ctx context.Context, user repository.User, amr []string, configs ...repository.OauthConfig) (string, []string, error) {
+	methods, err := s.mfaMethods(ctx, user)
+	if err != nil {
+		return "", nil, err
+	}
+	if slices.Contains(amr, AmrSms) {
+		methods = slices.DeleteFunc(methods, func(method string) bool { return method == MfaMethodSms })
+	}
+	if len(methods) > 0 {
+		return MfaStepVerify, methods, nil
+	}
+	required := user.MfaRequired
+	for _, config := range configs {
+		required = required || config.RequireMfa
+	}
+	if required {
+		return MfaStepEnroll, []string{MfaMethodTotp}, nil
+	}
+	return "", nil, nil
+}
+
+// mfaMethods lists the second factors a user has set up.
+func (s *Service) mfaMethods(ctx context.Context, user repository.User) ([]string, error) {
+	var methods []string
+	factor, err := s.repository.FindTotpFactor(ctx, user.ID)
+	if err == nil && factor.ConfirmedAt != nil {
+		methods = append(methods, MfaMethodTotp)
+	} else if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	passkeys, err := s.repository.ListWebauthnCredentials(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(passkeys) > 0 {
+		methods = append(methods, MfaMethodPasskey)
+	}
+	if user.PhoneVerifiedAt != nil {
+		methods = append(methods, MfaMethodSms)
+	}
+	return methods, nil
+}
+
+// mfaChallenge answers a login with a short lived token naming the user,
+// it stands in for the first factor in the second step.
+func (s *Service) mfaChallenge(user repository.User, step string, methods []string, amr []string) (LoginUserResponse, error) {
+	var response LoginUserResponse
+	key, err := s.masterKey(mfaChallengePurpose)
+	if err != nil {
+		return response, err
+	}
+	token, err := cryptoutil.SignToken(key, mfaChallengeClaims{
+		Subject: user.ID.String(),
+		Enroll:  step == MfaStepEnroll,
+		Amr:     amr,
+	}, MfaChallengeLifetime)
+	if err != nil {
+		return response, err
+	}
+	response.MfaRequired = true
+	response.MfaStep = step
+	response.MfaToken = token
+	response.MfaMethods = methods
+	return response, nil
+}
+
+func (s *Service) verifyMfaChallenge(ctx context.Context, token string) (repository.User, mfaChallengeClaims, error) {
+	var claims mfaChallengeClaims
+	key, err := s.masterKey(mfaChallengePurpose)
+	if err != nil {
+		return repository.User{}, claims, err
+	}
+	if err := cryptoutil.VerifyToken(key, token, &claims); err != nil {
+		return repository.User{}, claims, ErrInvalidMfaToken
+	}
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return repository.User{}, claims, ErrInvalidMfaToken
+	}
+	user, err := s.repository.FindUserByID(ctx, userID)
+	if err != nil {
+		return user, claims, ErrInvalidMfaToken
+	}
+	if user.DeactivatedAt != nil {
+		return user, claims, ErrDeactivatedUser
+	}
+	return user, claims, nil
+}
+
+// EnrollMfa starts a totp enrolment for a login that has to enroll before
+// it can complete.
+func (s *Service) EnrollMfa(ctx context.Context, payload EnrollMfaPayload) (TotpEnrollment, error) {
+	errs := validation.Validate(payload)
+	if errs != nil {
+		return TotpEnrollment{}, errs
+	}
+	user, claims, err := s.verifyMfaChallenge(ctx, payload.MfaToken)
+	if err != nil {
+		return TotpEnrollment{}, err
+	}
+	if !claims.Enroll {
+		return TotpEnrollment{}, ErrInvalidMfaToken
+	}
+	return s.enrollTotp(ctx, user)
+}
+
+// VerifyMfa completes a login with a totp or recovery code, the amr of the
+// session tells them apart. Logins that had to enroll confirm the new factor
+// with the code and get their recovery codes along with the tokens.
+func (s *Service) VerifyMfa(ctx context.Context, payload VerifyMfaPayload) (LoginUserResponse, error) {
+	var response LoginUserResponse
+	errs := validation.Validate(payload)
+	if errs != nil {
+		return response, errs
+	}
+	user, claims, err := s.verifyMfaChallenge(ctx, payload.MfaToken)
+	if err != nil {
+		return response, err
+	}
+	rootApp, err := s.repository.FindRootApp(ctx)
+	if err != nil {
+		return response, err
+	}
+	var recoveryCodes []string
+	second := AmrOTP
+	if claims.Enroll {
+		recoveryCodes, err = s.confirmTotp(ctx, user, payload.Code)
+	} else {
+		err = s.verifySecondFactor(ctx, user, payload.Code, payload.RecoveryCode)
+		if payload.RecoveryCode != "" {
+			second = AmrRecoveryCode
+		}
+	}
+	if err != nil {
+		return response, err
+	}
+	response, err = s.createLoginSession(ctx, rootApp, user, claims.amr(second), payload.UserIP, payload.UserAgent)
+	if err != nil {
+		return response, err
+	}
+	response.RecoveryCodes = recoveryCodes
+	return response, nil
+}
+
+// EnrollTotp starts a totp enrolment for a logged in user, it is only used
+// once confirmed with ConfirmTotp.
+func (s *Service) EnrollTotp(ctx context.Context, initiator string) (TotpEnrollment, error) {
+	user, err := s.repository.FindUserByID(ctx, uuid.MustParse(initiator))
+	if err != nil {
+		return TotpEnrollment{}, err
+	}
+	return s.enrollTotp(ctx, user)
+}
+
+// ConfirmTotp finishes the enrolment of a logged in user and returns their
+// recovery codes.
+func (s *Service) ConfirmTotp(ctx context.Context, initiator string, payload ConfirmTotpPayload) ([]string, error) {
+	errs := validation.Validate(payload)
+	if errs != nil {
+		return nil, errs
+	}
+	user, err := s.repository.FindUserByID(ctx, uuid.MustParse(initiator))
+	if err != nil {
+		return nil, err
+	}
+	return s.confirmTotp(ctx, user, payload.Code)
+}
+
+// DisableTotp removes the totp factor and the recovery codes of a logged in
+// user. Users or apps requiring mfa make them enroll again on the next login.
+func (s *Service) DisableTotp(ctx context.Context, initiator string, payload MfaCodePayload) error {
+	errs := validation.Validate(payload)
+	if errs != nil {
+		return errs
+	}
+	user, err := s.repository.FindUserByID(ctx, uuid.MustParse(initiator))
+	if err != nil {
+		return err
+	}
+	if err := s.verifySecondFactor(ctx, user, payload.Code, payload.RecoveryCode); err != nil {
+		return err
+	}
+	return s.inTx(ctx, func(q repository.Querier) error {
+		err := q.DeleteTotpFactors(ctx, repository.DeleteTotpFactorsParams{
+			UserID:    user.ID,
+			DeletedBy: &user.ID,
+		})
+		if err != nil {
+			return err
+		}
+		return q.DeleteRecoveryCodes(ctx, repository.DeleteRecoveryCodesParams{
+			UserID:    user.ID,
+			DeletedBy: &user.ID,
+		})
+	})
+}
+
+// RegenerateRecoveryCodes replaces every recovery code of a logged in user.
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, initiator string, payload MfaCodePayload) ([]string, error) {
+	errs := validation.Validate(payload)
+	if errs != nil {
+		return nil, errs
+	}
+	user, err := s.repository.FindUserByID(ctx, uuid.MustParse(initiator))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifySecondFactor(ctx, user, payload.Code, payload.RecoveryCode); err != nil {
+		return nil, err
+	}
+	return s.newRecoveryCodes(ctx, user.ID)
+}
+
+// SetMfaPolicy requires or stops requiring mfa for a user, users without a
+// second factor have to enroll on their next login.
+func (s *Service) SetMfaPolicy(ctx context.Context, initiator string, userID string, payload SetMfaPolicyPayload) error {
+	if initiator != uuid.Nil.String() {
+		return ErrMfaPolicyForbidden
+	}
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	if _, err := s.repository.FindUserByID(ctx, id); err != nil {
+		return ErrUserNotFound
+	}
+	by := uuid.MustParse(initiator)
+	return s.repository.SetUserMfaRequired(ctx, repository.SetUserMfaRequiredParams{
+		ID:          id,
+		MfaRequired: payload.Required,
+		UpdatedBy:   &by,
+	})
+}
+
+func (s *Service) enrollTotp(ctx context.Context, user repository.User) (TotpEnrollment, error) {
+	var enrollment TotpEnrollment
+	factor, err := s.repository.FindTotpFactor(ctx, user.ID)
+	if err == nil && factor.ConfirmedAt != nil {
+		return enrollment, ErrTotpAlreadyEnrolled
+	} else if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return enrollment, err
+	}
+	// an unfinished enrolment is started over
+	err = s.repository.DeleteTotpFactors(ctx, repository.DeleteTotpFactorsParams{
+		UserID:    user.ID,
+		DeletedBy: &user.ID,
+	})
+	if err != nil {
+		return enrollment, err
+	}
+
+	secret, err := cryptoutil.GenerateTotpSecret()
+	if err != nil {
+		return enrollment, err
+	}
+	key, err := s.masterKey(mfaSecretPurpose)
+	if err != nil {
+		return enrollment, err
+	}
+	sealed, err := cryptoutil.Seal(key, []byte(secret), user.ID[:])
+	if err != nil {
+		return enrollment, err
+	}
+	_, err = s.repository.CreateTotpFactor(ctx, repository.CreateTotpFactorParams{
+		UserID:          user.ID,
+		EncryptedSecret: sealed,
+		CreatedBy:       user.ID,
+	})
+	if err != nil {
+		return enrollment, err
+	}
+
+	uri := cryptoutil.TotpURI(s.totpIssuer(), user.Email, secret)
+	png, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		return enrollment, err
+	}
+	enrollment.Secret = secret
+	enrollment.URI = uri
+	enrollment.QRCode = "data:image/png;base64," + base64.StdEncoding.EncodeToString(png)
+	return enrollment, nil
+}
+
+func (s *Service) confirmTotp(ctx context.Context, user repository.User, code string) ([]string, error) {
+	if !s.mfaLimits.Allow(user.ID.String()) {
+		return nil, ErrTooManyRequests
+	}
+	factor, err := s.repository.FindTotpFactor(ctx, user.ID)
+	if err != nil {
+		return nil, ErrTotpNotEnrolled
+	}
+	if factor.ConfirmedAt != nil {
+		return nil, ErrTotpAlreadyEnrolled
+	}
+	if err := s.checkTotp(ctx, factor, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	// a confirmed factor always comes with its recovery codes
+	err = s.inTx(ctx, func(q repository.Querier) error {
+		if err := q.ConfirmTotpFactor(ctx, factor.ID); err != nil {
+			return err
+		}
+		return storeRecoveryCodes(ctx, q, user.ID, hashes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// verifySecondFactor accepts a code of the confirmed totp factor or an
+// unused recovery code.
+func (s *Service) verifySecondFactor(ctx context.Context, user repository.User, code string, recoveryCode string) error {
+	if !s.mfaLimits.Allow(user.ID.String()) {
+		return ErrTooManyRequests
+	}
+	if recoveryCode != "" {
+		return s.useRecoveryCode(ctx, user.ID, recoveryCode)
+	}
+	factor, err := s.repository.FindTotpFactor(ctx, user.ID)
+	if err != nil || factor.ConfirmedAt == nil {
+		return ErrTotpNotEnrolled
+	}
+	return s.checkTotp(ctx, factor, code)
+}
+
+// checkTotp verifies a code and records its time step, a code is accepted
+// once even though it stays valid for a while.
+func (s *Service) checkTotp(ctx context.Context, factor repository.TotpFactor, code string) error {
+	key, err := s.masterKey(mfaSecretPurpose)
+	if err != nil {
+		return err
+	}
+	secret, err := cryptoutil.Open(key, factor.EncryptedSecret, factor.UserID[:])
+	if err != nil {
+		return err
+	}
+	step, ok := cryptoutil.VerifyTotp(string(secret), strings.TrimSpace(code), time.Now())
+	if !ok {
+		return ErrInvalidMfaCode
+	}
+	used, err := s.repository.UseTotpStep(ctx, repository.UseTotpStepParams{
+		ID:           factor.ID,
+		LastUsedStep: step,
+	})
+	if err != nil {
+		return err
+	}
+	if used == 0 {
+		return ErrInvalidMfaCode
+	}
+	return nil
+}
+
+func (s *Service) useRecoveryCode(ctx context.Context, userID uuid.UUID, code string) error {
+	code = strings.ToLower(strings.TrimSpace(code))
+	codes, err := s.repository.ListUnusedRecoveryCodes(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, recoveryCode := range codes {
+		if !cryptoutil.VerifySecret(recoveryCode.HashedCode, code) {
+			continue
+		}
+		used, err := s.repository.UseRecoveryCode(ctx, recoveryCode.ID)
+		if err != nil {
+			return err
+		}
+		if used == 1 {
+			return nil
+		}
+		break
+	}
+	return ErrInvalidMfaCode
+}
+
+// newRecoveryCodes replaces the recovery codes of the user, all of them or
+// none. Only hashes are stored, the codes are shown to the user this one
+// time.
+func (s *Service) newRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	err = s.inTx(ctx, func(q repository.Querier) error {
+		return storeRecoveryCodes(ctx, q, userID, hashes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// generateRecoveryCodes returns new recovery codes along with their hashes.
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for range recoveryCodeCount {
+		raw, err := cryptoutil.GenerateHash(5)
+		if err != nil {
+			return nil, nil, err
+		}
+		code := raw[:5] + "-" + raw[5:]
+		hashed, err := cryptoutil.HashSecret(code)
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, hashed)
+	}
+	return codes, hashes, nil
+}
+
+// storeRecoveryCodes deletes the recovery codes of the user and stores the
+// hashed ones in their place.
+func storeRecoveryCodes(ctx context.Context, q repository.Querier, userID uuid.UUID, hashes []string) error {
+	err := q.DeleteRecoveryCodes(ctx, repository.DeleteRecoveryCodesParams{
+		UserID:    userID,
+		DeletedBy: &userID,
+	})
+	if err != nil {
+		return err
+	}
+	for _, hashed := range hashes {
+		err := q.CreateRecoveryCode(ctx, repository.CreateRecoveryCodeParams{
+			UserID:     userID,
+			HashedCode: hashed,
+			CreatedBy:  userID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// totpIssuer labels the account in authenticator apps with the host of the
+// issuer.
+func (s *Service) totpIssuer() string {
+	u, err := url.Parse(s.config.IssuerURL())
+	if err != nil || u.Hostname() == "" {
+		return "porichoy"
+	}
+	return u.Hostname()
+}
@@ -0,0 +1,481 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aritradeveops/porichoy/internal/config"
+	"github.com/aritradeveops/porichoy/internal/core/cryptoutil"
+	"github.com/aritradeveops/porichoy/internal/persistence/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// mfaQuerier keeps one user with a password, their totp factors and
+// recovery codes, and one app. InTx puts the factors and codes back when the
+// transaction fails like a rollback would.
+type mfaQuerier struct {
+	repository.Querier
+	user     repository.User
+	password repository.Password
+	factors  []repository.TotpFactor
+	codes    []repository.RecoveryCode
+	rootApp  repository.FindRootAppRow
+	app      repository.FindAppByClientIDRow
+	key      repository.SigningKey
+	sessions []repository.CreateSessionParams
+	// CreateRecoveryCode fails once this many codes were created
+	failCodesAt int
+}
+
+func (q *mfaQuerier) InTx(ctx context.Context, fn func(repository.Querier) error) error {
+	factors := slices.Clone(q.factors)
+	codes := slices.Clone(q.codes)
+	err := fn(q)
+	if err != nil {
+		q.factors = factors
+		q.codes = codes
+	}
+	return err
+}
+
+func (q *mfaQuerier) FindRootApp(ctx context.Context) (repository.FindRootAppRow, error) {
+	return q.rootApp, nil
+}
+
+func (q *mfaQuerier) FindAppByClientID(ctx context.Context, clientID string) (repository.FindAppByClientIDRow, error) {
+	if clientID != q.app.App.ClientID {
+		return repository.FindAppByClientIDRow{}, pgx.ErrNoRows
+	}
+	return q.app, nil
+}
+
+func (q *mfaQuerier) FindActiveSigningKey(ctx context.Context, appID uuid.UUID) (repository.SigningKey, error) {
+	return q.key, nil
+}
+
+func (q *mfaQuerier) FindUserByID(ctx context.Context, id uuid.UUID) (repository.User, error) {
+	if id != q.user.ID {
+		return repository.User{}, pgx.ErrNoRows
+	}
+	return q.user, nil
+}
+
+func (q *mfaQuerier) FindUserByEmail(ctx context.Context, email string) (repository.User, error) {
+	if email != q.user.Email {
+		return repository.User{}, pgx.ErrNoRows
+	}
+	return q.user, nil
+}
+
+func (q *mfaQuerier) FindUserPassword(ctx context.Context, userID uuid.UUID) (repository.Password, error) {
+	return q.password, nil
+}
+
+func (q *mfaQuerier) SetUserMfaRequired(ctx context.Context, arg repository.SetUserMfaRequiredParams) error {
+	q.user.MfaRequired = arg.MfaRequired
+	return nil
+}
+
+func (q *mfaQuerier) ListWebauthnCredentials(ctx context.Context, userID uuid.UUID) ([]repository.WebauthnCredential, error) {
+	return nil, nil
+}
+
+func (q *mfaQuerier) CreateSession(ctx context.Context, arg repository.CreateSessionParams) error {
+	q.sessions = append(q.sessions, arg)
+	return nil
+}
+
+func (q *mfaQuerier) FindTotpFactor(ctx context.Context, userID uuid.UUID) (repository.TotpFactor, error) {
+	for i := len(q.factors) - 1; i >= 0; i-- {
+		if q.factors[i].UserID == userID && q.factors[i].DeletedAt == nil {
+			return q.factors[i], nil
+		}
+	}
+	return repository.TotpFactor{}, pgx.ErrNoRows
+}
+
+func (q *mfaQuerier) CreateTotpFactor(ctx context.Context, arg repository.CreateTotpFactorParams) (repository.TotpFactor, error) {
+	factor := repository.TotpFactor{
+		ID:              uuid.New(),
+		UserID:          arg.UserID,
+		EncryptedSecret: arg.EncryptedSecret,
+		CreatedAt:       time.Now(),
+		CreatedBy:       arg.CreatedBy,
+	}
+	q.factors = append(q.factors, factor)
+	return factor, nil
+}
+
+func (q *mfaQuerier) DeleteTotpFactors(ctx context.Context, arg repository.DeleteTotpFactorsParams) error {
+	now := time.Now()
+	for i := range q.factors {
+		if q.factors[i].UserID == arg.UserID && q.factors[i].DeletedAt == nil {
+			q.factors[i].DeletedAt = &now
+		}
+	}
+	return nil
+}
+
+func (q *mfaQuerier) ConfirmTotpFactor(ctx context.Context, id uuid.UUID) error {
+	now := time.Now()
+	for i := range q.factors {
+		if q.factors[i].ID == id {
+			q.factors[i].ConfirmedAt = &now
+		}
+	}
+	return nil
+}
+
+func (q *mfaQuerier) UseTotpStep(ctx context.Context, arg repository.UseTotpStepParams) (int64, error) {
+	for i := range q.factors {
+		if q.factors[i].ID == arg.ID && q.factors[i].LastUsedStep < arg.LastUsedStep {
+			q.factors[i].LastUsedStep = arg.LastUsedStep
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func (q *mfaQuerier) ListUnusedRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]repository.RecoveryCode, error) {
+	var codes []repository.RecoveryCode
+	for _, code := range q.codes {
+		if code.UserID == userID && code.UsedAt == nil && code.DeletedAt == nil {
+			codes = append(codes, code)
+		}
+	}
+	return codes, nil
+}
+
+func (q *mfaQuerier) UseRecoveryCode(ctx context.Context, id uuid.UUID) (int64, error) {
+	for i := range q.codes {
+		if q.codes[i].ID == id && q.codes[i].UsedAt == nil {
+			now := time.Now()
+			q.codes[i].UsedAt = &now
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func (q *mfaQuerier) DeleteRecoveryCodes(ctx context.Context, arg repository.DeleteRecoveryCodesParams) error {
+	now := time.Now()
+	for i := range q.codes {
+		if q.codes[i].UserID == arg.UserID && q.codes[i].DeletedAt == nil {
+			q.codes[i].DeletedAt = &now
+		}
+	}
+	return nil
+}
+
+func (q *mfaQuerier) CreateRecoveryCode(ctx context.Context, arg repository.CreateRecoveryCodeParams) error {
+	if q.failCodesAt > 0 && len(q.codes) >= q.failCodesAt {
+		return errors.New("connection reset")
+	}
+	q.codes = append(q.codes, repository.RecoveryCode{
+		ID:         uuid.New(),
+		UserID:     arg.UserID,
+		HashedCode: arg.HashedCode,
+		CreatedAt:  time.Now(),
+		CreatedBy:  arg.CreatedBy,
+	})
+	return nil
+}
+
+// unusedCodes counts the recovery codes the user can still use.
+func (q *mfaQuerier) unusedCodes() int {
+	codes, _ := q.ListUnusedRecoveryCodes(context.Background(), q.user.ID)
+	return len(codes)
+}
+
+func newMfaService(t *testing.T) (*Service, *mfaQuerier) {
+	appID := uuid.New()
+	querier := &mfaQuerier{
+		user: repository.User{
+			ID:    uuid.New(),
+			Email: "jane@example.com",
+			Name:  "Jane",
+		},
+		app: repository.FindAppByClientIDRow{
+			App:         repository.App{ID: appID, ClientID: "app.example.com"},
+			OauthConfig: repository.OauthConfig{AppID: appID},
+		},
+	}
+	s := New(&config.Config{
+		Issuer: "https://id.example.com",
+		Keys: config.Keys{
+			MasterKeyResolver: "literal://mfa-test-master-key",
+		},
+		Passwords: config.Passwords{
+			Hashing: config.Hashing{
+				Algorithm: "bcrypt",
+				Bcrypt:    config.Bcrypt{Cost: bcrypt.MinCost},
+			},
+		},
+	}, querier, nil, nil)
+	querier.rootApp, querier.key = testRootApp(t, s)
+	hashedPassword, err := s.passwords.Hash("Jane-Password-1")
+	assert.NoError(t, err)
+	querier.password = repository.Password{ID: uuid.New(), HashedPassword: hashedPassword, CreatedBy: querier.user.ID}
+	return s, querier
+}
+
+// enrollTotp sets up a confirmed totp factor for the user and returns its
+// secret and recovery codes.
+func enrollTotp(t *testing.T, s *Service, querier *mfaQuerier) (string, []string) {
+	ctx := context.Background()
+	enrollment, err := s.EnrollTotp(ctx, querier.user.ID.String())
+	assert.NoError(t, err)
+	codes, err := s.ConfirmTotp(ctx, querier.user.ID.String(), ConfirmTotpPayload{
+		Code: totpCode(t, enrollment.Secret, 0),
+	})
+	assert.NoError(t, err)
+	return enrollment.Secret, codes
+}
+
+// totpCode is the code of the time step offset steps away from now.
+func totpCode(t *testing.T, secret string, offset int64) string {
+	code, err := cryptoutil.TotpCode(secret, cryptoutil.TotpStep(time.Now())+offset)
+	assert.NoError(t, err)
+	return code
+}
+
+func loginWithPassword(t *testing.T, s *Service, clientID string) LoginUserResponse {
+	response, err := s.LoginUser(context.Background(), LoginUserPayload{
+		Email:     "jane@example.com",
+		Password:  "Jane-Password-1",
+		UserAgent: "test",
+		UserIP:    "192.0.2.1",
+		Host:      "id.example.com",
+		ClientID:  clientID,
+	})
+	assert.NoError(t, err)
+	return response
+}
+
+func verifyMfa(s *Service, mfaToken string, code string, recoveryCode string) (LoginUserResponse, error) {
+	return s.VerifyMfa(context.Background(), VerifyMfaPayload{
+		MfaToken:     mfaToken,
+		Code:         code,
+		RecoveryCode: recoveryCode,
+		UserAgent:    "test",
+		UserIP:       "192.0.2.1",
+	})
+}
+
+// accessTokenAmr reads the amr and acr claims of an access token.
+func accessTokenAmr(t *testing.T, token string) ([]string, string) {
+	parts := strings.Split(token, ".")
+	assert.Len(t, parts, 3)
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	assert.NoError(t, err)
+	var claims struct {
+		Amr []string `json:"amr"`
+		Acr string   `json:"acr"`
+	}
+	assert.NoError(t, json.Unmarshal(payload, &claims))
+	return claims.Amr, claims.Acr
+}
+
+func TestTotpEnrolment(t *testing.T) {
+	t.Parallel()
+	s, querier := newMfaService(t)
+	ctx := context.Background()
+	initiator := querier.user.ID.String()
+
+	enrollment, err := s.EnrollTotp(ctx, initiator)
+	assert.NoError(t, err)
+	assert.Contains(t, enrollment.URI, "otpauth://totp/")
+	assert.True(t, strings.HasPrefix(enrollment.QRCode, "data:image/png;base64,"))
+
+	// an unconfirmed factor does not count yet
+	response := loginWithPassword(t, s, "")
+	assert.False(t, response.MfaRequired)
+
+	_, err = s.ConfirmTotp(ctx, initiator, ConfirmTotpPayload{Code: "000000"})
+	assert.ErrorIs(t, err, ErrInvalidMfaCode)
+	assert.Zero(t, querier.unusedCodes())
+
+	codes, err := s.ConfirmTotp(ctx, initiator, ConfirmTotpPayload{Code: totpCode(t, enrollment.Secret, 0)})
+	assert.NoError(t, err)
+	assert.Len(t, codes, recoveryCodeCount)
+	assert.Equal(t, recoveryCodeCount, querier.unusedCodes())
+	for _, code := range querier.codes {
+		// only hashes are stored
+		assert.NotContains(t, codes, code.HashedCode)
+	}
+
+	_, err = s.EnrollTotp(ctx, initiator)
+	assert.ErrorIs(t, err, ErrTotpAlreadyEnrolled)
+}
+
+// a factor is not confirmed without the recovery codes to go with it
+func TestTotpEnrolmentRollback(t *testing.T) {
+	t.Parallel()
+	s, querier := newMfaService(t)
+	ctx := context.Background()
+	initiator := querier.user.ID.String()
+
+	enrollment, err := s.EnrollTotp(ctx, initiator)
+	assert.NoError(t, err)
+	querier.failCodesAt = 3
+	_, err = s.ConfirmTotp(ctx, initiator, ConfirmTotpPayload{Code: totpCode(t, enrollment.Secret, 0)})
+	assert.Error(t, err)
+	factor, err := querier.FindTotpFactor(ctx, querier.user.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, factor.ConfirmedAt)
+	assert.Empty(t, querier.codes)
+}
+
+func TestRegenerateRecoveryCodes(t *testing.T) {
+	t.Parallel()
+	s, querier := newMfaService(t)
+	ctx := context.Background()
+	initiator := querier.user.ID.String()
+	secret, old := enrollTotp(t, s, querier)
+
+	codes, err := s.RegenerateRecoveryCodes(ctx, initiator, MfaCodePayload{Code: totpCode(t, secret, 1)})
+	assert.NoError(t, err)
+	assert.Len(t, codes, recoveryCodeCount)
+	assert.Equal(t, recoveryCodeCount, querier.unusedCodes())
+	_, err = s.RegenerateRecoveryCodes(ctx, initiator, MfaCodePayload{RecoveryCode: old[0]})
+	assert.ErrorIs(t, err, ErrInvalidMfaCode)
+
+	// the new codes only replace the old ones all at once
+	querier.failCodesAt = len(querier.codes) + 3
+	_, err = s.RegenerateRecoveryCodes(ctx, initiator, MfaCodePayload{RecoveryCode: codes[0]})
+	assert.Error(t, err)
+	assert.Equal(t, recoveryCodeCount-1, querier.unusedCodes())
+	querier.failCodesAt = 0
+	_, err = s.RegenerateRecoveryCodes(ctx, initiator, MfaCodePayload{RecoveryCode: codes[1]})
+	assert.NoError(t, err)
+}
+
+func TestVerifyMfa(t *testing.T) {
+	t.Parallel()
+	s, querier := newMfaService(t)
+	secret, _ := enrollTotp(t, s, querier)
+
+	response := loginWithPassword(t, s, "")
+	assert.True(t, response.MfaRequired)
+	assert.Equal(t, MfaStepVerify, response.MfaStep)
+	assert.Equal(t, []string{MfaMethodTotp}, response.MfaMethods)
+	assert.Empty(t, response.AccessToken)
+	assert.Empty(t, querier.sessions)
+
+	_, err := verifyMfa(s, response.MfaToken, "000000", "")
+	assert.ErrorIs(t, err, ErrInvalidMfaCode)
+	_, err = verifyMfa(s, "not-a-token", totpCode(t, secret, 1), "")
+	assert.ErrorIs(t, err, ErrInvalidMfaToken)
+
+	code := totpCode(t, secret, 1)
+	response, err = verifyMfa(s, response.MfaToken, code, "")
+	assert.NoError(t, err)
+	assert.Len(t, querier.sessions, 1)
+	amr, acr := accessTokenAmr(t, response.AccessToken)
+	assert.Equal(t, []string{AmrPassword, AmrOTP}, amr)
+	assert.Equal(t, AcrMultipleFactor, acr)
+
+	// a code is accepted once
+	response = loginWithPassword(t, s, "")
+	_, err = verifyMfa(s, response.MfaToken, code, "")
+	assert.ErrorIs(t, err, ErrInvalidMfaCode)
+}
+
+func TestVerifyMfaRecoveryCode(t *testing.T) {
+	t.Parallel()
+	s, querier := newMfaService(t)
+	_, codes := enrollTotp(t, s, querier)
+
+	response := loginWithPassword(t, s, "")
+	// codes are matched regardless of case and surrounding space
+	verified, err := verifyMfa(s, response.MfaToken, "", " "+strings.ToUpper(codes[0])+" ")
+	assert.NoError(t, err)
+	amr, acr := accessTokenAmr(t, verified.AccessToken)
+	assert.Equal(t, []string{AmrPassword, AmrRecoveryCode}, amr)
+	assert.Equal(t, AcrMultipleFactor, acr)
+	assert.Equal(t, recoveryCodeCount-1, querier.unusedCodes())
+
+	// a recovery code works once
+	response = loginWithPassword(t, s, "")
+	_, err = verifyMfa(s, response.MfaToken, "", codes[0])
+	assert.ErrorIs(t, err, ErrInvalidMfaCode)
+	_, err = verifyMfa(s, response.MfaToken, "", "wrong-code")
+	assert.ErrorIs(t, err, ErrInvalidMfaCode)
+	_, err = verifyMfa(s, response.MfaToken, "", codes[1])
+	assert.NoError(t, err)
+	assert.Len(t, querier.sessions, 2)
+}
+
+func TestVerifyMfaRateLimit(t *testing.T) {
+	t.Parallel()
+	s, querier := newMfaService(t)
+	secret, _ := enrollTotp(t, s, querier)
+
+	response := loginWithPassword(t, s, "")
+	for range 4 {
+		_, err := verifyMfa(s, response.MfaToken, "000000", "")
+		assert.ErrorIs(t, err, ErrInvalidMfaCode)
+	}
+	// the confirmation during the enrolment counted too
+	_, err := verifyMfa(s, response.MfaToken, totpCode(t, secret, 1), "")
+	assert.ErrorIs(t, err, ErrTooManyRequests)
+	assert.Empty(t, querier.sessions)
+}
+
+func TestRequireMfa(t *testing.T) {
+	t.Parallel()
+	s, querier := newMfaService(t)
+	ctx := context.Background()
+
+	// nothing is required by default
+	response := loginWithPassword(t, s, "")
+	assert.False(t, response.MfaRequired)
+	assert.NotEmpty(t, response.AccessToken)
+
+	// an app requiring mfa has the user enroll when logging in to it
+	querier.app.OauthConfig.RequireMfa = true
+	response = loginWithPassword(t, s, querier.app.App.ClientID)
+	assert.True(t, response.MfaRequired)
+	assert.Equal(t, MfaStepEnroll, response.MfaStep)
+	response = loginWithPassword(t, s, "")
+	assert.False(t, response.MfaRequired)
+	querier.app.OauthConfig.RequireMfa = false
+
+	// only root sets the policy of a user
+	err := s.SetMfaPolicy(ctx, querier.user.ID.String(), querier.user.ID.String(), SetMfaPolicyPayload{Required: false})
+	assert.ErrorIs(t, err, ErrMfaPolicyForbidden)
+	err = s.SetMfaPolicy(ctx, uuid.Nil.String(), uuid.NewString(), SetMfaPolicyPayload{Required: true})
+	assert.ErrorIs(t, err, ErrUserNotFound)
+	err = s.SetMfaPolicy(ctx, uuid.Nil.String(), querier.user.ID.String(), SetMfaPolicyPayload{Required: true})
+	assert.NoError(t, err)
+
+	response = loginWithPassword(t, s, "")
+	assert.True(t, response.MfaRequired)
+	assert.Equal(t, MfaStepEnroll, response.MfaStep)
+	assert.Empty(t, response.AccessToken)
+
+	// the enrolment is finished within the login
+	enrollment, err := s.EnrollMfa(ctx, EnrollMfaPayload{MfaToken: response.MfaToken})
+	assert.NoError(t, err)
+	verified, err := verifyMfa(s, response.MfaToken, totpCode(t, enrollment.Secret, 0), "")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, verified.AccessToken)
+	assert.Len(t, verified.RecoveryCodes, recoveryCodeCount)
+
+	// and the next login verifies the factor
+	response = loginWithPassword(t, s, "")
+	assert.Equal(t, MfaStepVerify, response.MfaStep)
+
+	// a challenge to verify can not be used to enroll another factor
+	_, err = s.EnrollMfa(ctx, EnrollMfaPayload{MfaToken: response.MfaToken})
+	assert.ErrorIs(t, err, ErrInvalidMfaToken)
+}
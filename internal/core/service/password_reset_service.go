@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aritradeveops/porichoy/internal/core/cryptoutil"
+	"github.com/aritradeveops/porichoy/internal/core/validation"
+	"github.com/aritradeveops/porichoy/internal/persistence/repository"
+	"github.com/aritradeveops/porichoy/internal/pkg/logger"
+	"github.com/aritradeveops/porichoy/internal/pkg/mailer"
+)
+
+const PasswordResetTokenLifetime = 30 * time.Minute
+
+var (
+	ErrInvalidResetToken = errors.New("password_reset_service: invalid or expired reset token")
+	ErrTooManyRequests   = errors.New("password_reset_service: too many requests")
+)
+
+type ForgotPasswordPayload struct {
+	Email  string `json:"email" validate:"required,email"`
+	UserIP string `json:"user_ip" validate:"required"`
+}
+
+type ResetPasswordPayload struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
+	UserIP   string `json:"user_ip" validate:"required"`
+}
+
+// ForgotPassword mails a single-use reset link to the user. It succeeds
+// whether or not the address belongs to a user so it can not be used to find
+// out who has an account, requests are limited per email and per ip.
+func (s *Service) ForgotPassword(ctx context.Context, payload ForgotPasswordPayload) error {
+	errs := validation.Validate(payload)
+	if errs != nil {
+		return errs
+	}
+	if !s.resetLimits.ip.Allow(payload.UserIP) || !s.resetLimits.email.Allow(strings.ToLower(payload.Email)) {
+		return ErrTooManyRequests
+	}
+	user, err := s.repository.FindUserByEmail(ctx, payload.Email)
+	if err != nil || user.DeactivatedAt != nil {
+		return nil
+	}
+
+	// only the latest link works
+	err = s.repository.RevokePasswordResetTokens(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	token, err := cryptoutil.GenerateHash(32)
+	if err != nil {
+		return err
+	}
+	err = s.repository.CreatePasswordResetToken(ctx, repository.CreatePasswordResetTokenParams{
+		HashedToken: cryptoutil.HashToken(token),
+		UserID:      user.ID,
+		RequestedIp: payload.UserIP,
+		ExpiresAt:   time.Now().Add(PasswordResetTokenLifetime),
+		CreatedBy:   user.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	link := s.config.IssuerURL() + "/reset-password?token=" + url.QueryEscape(token)
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Text: fmt.Sprintf("Hi %s,\n\nchoose a new password by opening the link below, it is valid for %s and can be used once.\n\n%s\n\nIf you did not ask for this you can ignore this email, your password stays the same.\n",
+			user.Name, PasswordResetTokenLifetime, link),
+	})
+	if err != nil {
+		logger.Error().Err(err).Str("user_id", user.ID.String()).Msg("failed to send the password reset email")
+	}
+	return nil
+}
+
+// ResetPassword replaces the password of the user the token was issued to
+// and signs them out everywhere. Access tokens issued before stay valid
+// until they expire, only the sessions they are refreshed with end.
+func (s *Service) ResetPassword(ctx context.Context, payload ResetPasswordPayload) error {
+	errs := validation.Validate(payload)
+	if errs != nil {
+		return errs
+	}
+	if !s.resetLimits.ip.Allow(payload.UserIP) {
+		return ErrTooManyRequests
+	}
+	// checked before the token is used up so another password can be tried
+	errs = validation.ValidatePassword(payload.Password)
+	if errs != nil {
+		return errs
+	}
+
+	hashedToken := cryptoutil.HashToken(payload.Token)
+	token, err := s.repository.FindPasswordResetToken(ctx, hashedToken)
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+	user, err := s.repository.FindUserByID(ctx, token.UserID)
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+	if user.DeactivatedAt != nil {
+		return ErrDeactivatedUser
+	}
+	err = s.checkPasswordHistory(ctx, user.ID, payload.Password)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := s.passwords.Hash(payload.Password)
+	if err != nil {
+		return err
+	}
+	// the password only changes along with the token being used up and the
+	// sessions ending
+	return s.inTx(ctx, func(q repository.Querier) error {
+		// consuming is what makes the token single-use, two concurrent
+		// resets can not both get past it
+		_, err := q.ConsumePasswordResetToken(ctx, hashedToken)
+		if err != nil {
+			return ErrInvalidResetToken
+		}
+		err = storePassword(ctx, q, user.ID, hashedPassword)
+		if err != nil {
+			return err
+		}
+		err = q.RevokePasswordResetTokens(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		// whoever knew the old password loses their sessions too
+		return q.DeleteSession(ctx, user.ID)
+	})
+}
@@ -0,0 +1,238 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aritradeveops/porichoy/internal/persistence/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// resetQuerier adds the user, the reset tokens and the sessions to the
+// passwords kept by passwordQuerier, InTx rolls all of them back.
+type resetQuerier struct {
+	*passwordQuerier
+	user          repository.User
+	tokens        []repository.PasswordResetToken
+	endedSessions int
+	failSessions  bool
+}
+
+func (q *resetQuerier) InTx(ctx context.Context, fn func(repository.Querier) error) error {
+	passwords := slices.Clone(q.passwords)
+	tokens := slices.Clone(q.tokens)
+	err := fn(q)
+	if err != nil {
+		q.passwords = passwords
+		q.tokens = tokens
+	}
+	return err
+}
+
+func (q *resetQuerier) FindUserByEmail(ctx context.Context, email string) (repository.User, error) {
+	if email != q.user.Email {
+		return repository.User{}, errors.New("no rows in result set")
+	}
+	return q.user, nil
+}
+
+func (q *resetQuerier) FindUserByID(ctx context.Context, id uuid.UUID) (repository.User, error) {
+	if id != q.user.ID {
+		return repository.User{}, errors.New("no rows in result set")
+	}
+	return q.user, nil
+}
+
+func (q *resetQuerier) CreatePasswordResetToken(ctx context.Context, arg repository.CreatePasswordResetTokenParams) error {
+	q.tokens = append(q.tokens, repository.PasswordResetToken{
+		ID:          uuid.New(),
+		HashedToken: arg.HashedToken,
+		UserID:      arg.UserID,
+		RequestedIp: arg.RequestedIp,
+		ExpiresAt:   arg.ExpiresAt,
+		CreatedAt:   time.Now(),
+		CreatedBy:   arg.CreatedBy,
+	})
+	return nil
+}
+
+func (q *resetQuerier) find(hashedToken string) (int, bool) {
+	for i, token := range q.tokens {
+		if token.HashedToken == hashedToken && token.UsedAt == nil && token.ExpiresAt.After(time.Now()) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func (q *resetQuerier) FindPasswordResetToken(ctx context.Context, hashedToken string) (repository.PasswordResetToken, error) {
+	i, ok := q.find(hashedToken)
+	if !ok {
+		return repository.PasswordResetToken{}, errors.New("no rows in result set")
+	}
+	return q.tokens[i], nil
+}
+
+func (q *resetQuerier) ConsumePasswordResetToken(ctx context.Context, hashedToken string) (repository.PasswordResetToken, error) {
+	i, ok := q.find(hashedToken)
+	if !ok {
+		return repository.PasswordResetToken{}, errors.New("no rows in result set")
+	}
+	now := time.Now()
+	q.tokens[i].UsedAt = &now
+	return q.tokens[i], nil
+}
+
+func (q *resetQuerier) RevokePasswordResetTokens(ctx context.Context, userID uuid.UUID) error {
+	for i, token := range q.tokens {
+		if token.UserID == userID && token.UsedAt == nil && token.ExpiresAt.After(time.Now()) {
+			q.tokens[i].ExpiresAt = time.Now()
+		}
+	}
+	return nil
+}
+
+func (q *resetQuerier) DeleteSession(ctx context.Context, userID uuid.UUID) error {
+	if q.failSessions {
+		return errors.New("connection reset")
+	}
+	q.endedSessions++
+	return nil
+}
+
+func newResetService(t *testing.T) (*Service, *resetQuerier, *recordingMailer) {
+	s, passwords := newPasswordService(t, nil, "Old-Password-1")
+	querier := &resetQuerier{
+		passwordQuerier: passwords,
+		user: repository.User{
+			ID:    passwords.userID,
+			Email: "jane@example.com",
+			Name:  "Jane",
+		},
+	}
+	mail := &recordingMailer{}
+	s.repository = querier
+	s.mailer = mail
+	return s, querier, mail
+}
+
+var resetLink = regexp.MustCompile(`reset-password\?token=(\S+)`)
+
+// requestReset asks for a reset link and takes the token out of the mail.
+func requestReset(t *testing.T, s *Service, mail *recordingMailer) string {
+	err := s.ForgotPassword(context.Background(), ForgotPasswordPayload{Email: "jane@example.com", UserIP: "192.0.2.1"})
+	assert.NoError(t, err)
+	match := resetLink.FindStringSubmatch(mail.messages[len(mail.messages)-1].Text)
+	assert.Len(t, match, 2)
+	token, err := url.QueryUnescape(match[1])
+	assert.NoError(t, err)
+	return token
+}
+
+func resetPassword(s *Service, token string, password string) error {
+	return s.ResetPassword(context.Background(), ResetPasswordPayload{
+		Token:    token,
+		Password: password,
+		UserIP:   "192.0.2.1",
+	})
+}
+
+func TestResetPassword(t *testing.T) {
+	t.Parallel()
+	s, querier, mail := newResetService(t)
+
+	token := requestReset(t, s, mail)
+	assert.NoError(t, resetPassword(s, token, "New-Password-2"))
+	assert.Len(t, querier.passwords, 2)
+	ok, _, _ := s.passwords.Verify(querier.passwords[1].HashedPassword, "New-Password-2")
+	assert.True(t, ok)
+	// the user is signed out everywhere
+	assert.Equal(t, 1, querier.endedSessions)
+
+	// the link works once
+	assert.ErrorIs(t, resetPassword(s, token, "Other-Password-3"), ErrInvalidResetToken)
+	assert.Len(t, querier.passwords, 2)
+}
+
+func TestResetPasswordLatestLinkOnly(t *testing.T) {
+	t.Parallel()
+	s, querier, mail := newResetService(t)
+
+	first := requestReset(t, s, mail)
+	second := requestReset(t, s, mail)
+	assert.ErrorIs(t, resetPassword(s, first, "New-Password-2"), ErrInvalidResetToken)
+	assert.NoError(t, resetPassword(s, second, "New-Password-2"))
+	assert.Len(t, querier.passwords, 2)
+}
+
+func TestResetPasswordExpiredToken(t *testing.T) {
+	t.Parallel()
+	s, querier, mail := newResetService(t)
+
+	token := requestReset(t, s, mail)
+	querier.tokens[0].ExpiresAt = time.Now().Add(-time.Second)
+	assert.ErrorIs(t, resetPassword(s, token, "New-Password-2"), ErrInvalidResetToken)
+	assert.Len(t, querier.passwords, 1)
+	assert.Zero(t, querier.endedSessions)
+}
+
+func TestResetPasswordKeepsTokenOnRejectedPassword(t *testing.T) {
+	t.Parallel()
+	s, querier, mail := newResetService(t)
+
+	token := requestReset(t, s, mail)
+	assert.Error(t, resetPassword(s, token, "weak"))
+	assert.ErrorIs(t, resetPassword(s, token, "Old-Password-1"), ErrPasswordReused)
+	assert.NoError(t, resetPassword(s, token, "New-Password-2"))
+	assert.Len(t, querier.passwords, 2)
+}
+
+// a reset that could not sign the user out leaves the password and the
+// link as they were
+func TestResetPasswordRollback(t *testing.T) {
+	t.Parallel()
+	s, querier, mail := newResetService(t)
+
+	token := requestReset(t, s, mail)
+	querier.failSessions = true
+	assert.Error(t, resetPassword(s, token, "New-Password-2"))
+	assert.Len(t, querier.passwords, 1)
+	assert.Nil(t, querier.tokens[0].UsedAt)
+
+	querier.failSessions = false
+	assert.NoError(t, resetPassword(s, token, "New-Password-2"))
+	assert.Len(t, querier.passwords, 2)
+	assert.Equal(t, 1, querier.endedSessions)
+}
+
+func TestForgotPasswordUnknownEmail(t *testing.T) {
+	t.Parallel()
+	s, querier, mail := newResetService(t)
+
+	// the same answer whether or not the address has an account
+	err := s.ForgotPassword(context.Background(), ForgotPasswordPayload{Email: "john@example.com", UserIP: "192.0.2.1"})
+	assert.NoError(t, err)
+	err = s.ForgotPassword(context.Background(), ForgotPasswordPayload{Email: "jane@example.com", UserIP: "192.0.2.1"})
+	assert.NoError(t, err)
+	assert.Len(t, mail.messages, 1)
+	assert.Equal(t, "jane@example.com", mail.messages[0].To)
+	assert.Len(t, querier.tokens, 1)
+}
+
+func TestForgotPasswordRateLimit(t *testing.T) {
+	t.Parallel()
+	s, _, mail := newResetService(t)
+
+	for range 3 {
+		requestReset(t, s, mail)
+	}
+	err := s.ForgotPassword(context.Background(), ForgotPasswordPayload{Email: "Jane@Example.com", UserIP: "192.0.2.2"})
+	assert.ErrorIs(t, err, ErrTooManyRequests)
+	assert.Len(t, mail.messages, 3)
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aritradeveops/porichoy/internal/config"
+	"github.com/aritradeveops/porichoy/internal/core/cryptoutil"
+	"github.com/aritradeveops/porichoy/internal/core/validation"
+	"github.com/aritradeveops/porichoy/internal/persistence/repository"
+	"github.com/aritradeveops/porichoy/internal/pkg/logger"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const defaultPasswordHistory = 5
+
+// argon2id parameters when porichoy.yml has none, the second recommended
+// option of RFC 9106
+var defaultArgon2id = cryptoutil.Argon2id{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 4,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+var (
+	ErrIncorrectPassword = errors.New("password_service: current password is incorrect")
+	ErrPasswordReused    = errors.New("password_service: password was used recently")
+)
+
+type ChangePasswordPayload struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+	// signs the user out of every session but the one making the change
+	EndOtherSessions bool `json:"end_other_sessions"`
+	// refresh token of the session making the change
+	RefreshToken string `json:"-"`
+}
+
+// ChangePassword replaces the password of a logged in user, who has to know
+// the current one. Guesses at it are limited per user. Access tokens issued
+// before the change stay valid until they expire, only the sessions they
+// are refreshed with end.
+func (s *Service) ChangePassword(ctx context.Context, initiator string, payload ChangePasswordPayload) error {
+	errs := validation.Validate(payload)
+	if errs != nil {
+		return errs
+	}
+	errs = validation.ValidatePassword(payload.NewPassword)
+	if errs != nil {
+		return errs
+	}
+	userID := uuid.MustParse(initiator)
+	passwd, err := s.repository.FindUserPassword(ctx, userID)
+	if err != nil {
+		return ErrInvalidLoginMethod
+	}
+	if !s.passwordLimits.Allow(initiator) {
+		return ErrTooManyRequests
+	}
+	ok, _, err := s.passwords.Verify(passwd.HashedPassword, payload.CurrentPassword)
+	if err != nil || !ok {
+		return ErrIncorrectPassword
+	}
+
+	if err := s.checkPasswordHistory(ctx, userID, payload.NewPassword); err != nil {
+		return err
+	}
+	hashedPassword, err := s.passwords.Hash(payload.NewPassword)
+	if err != nil {
+		return err
+	}
+	return s.inTx(ctx, func(q repository.Querier) error {
+		if err := storePassword(ctx, q, userID, hashedPassword); err != nil {
+			return err
+		}
+		// reset links sent before the change must not undo it
+		err := q.RevokePasswordResetTokens(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if payload.EndOtherSessions {
+			return q.DeleteOtherSessions(ctx, repository.DeleteOtherSessionsParams{
+				UserID:       userID,
+				RefreshToken: payload.RefreshToken,
+			})
+		}
+		return nil
+	})
+}
+
+// checkPasswordHistory rejects the current and recent passwords of the user,
+// unless the history is configured to 0.
+func (s *Service) checkPasswordHistory(ctx context.Context, userID uuid.UUID, password string) error {
+	history := defaultPasswordHistory
+	if s.config.Passwords.History != nil {
+		history = *s.config.Passwords.History
+	}
+	if history == 0 {
+		return nil
+	}
+	previous, err := s.repository.ListPasswordHistory(ctx, repository.ListPasswordHistoryParams{
+		CreatedBy: userID,
+		Limit:     int32(history),
+	})
+	if err != nil {
+		return err
+	}
+	for _, passwd := range previous {
+		if ok, _, _ := s.passwords.Verify(passwd.HashedPassword, password); ok {
+			return ErrPasswordReused
+		}
+	}
+	return nil
+}
+
+// storePassword replaces the password of the user with the hashed one, the
+// old one is soft deleted and kept for the history check.
+func storePassword(ctx context.Context, q repository.Querier, userID uuid.UUID, hashedPassword string) error {
+	return q.ReplaceUserPassword(ctx, repository.ReplaceUserPasswordParams{
+		HashedPassword: hashedPassword,
+		CreatedBy:      userID,
+	})
+}
+
+// newPasswordHasher hashes with the configured algorithm, argon2id unless
+// told otherwise, and keeps verifying the other one.
+func newPasswordHasher(config config.Hashing) *cryptoutil.PasswordHasher {
+	argon := defaultArgon2id
+	if config.Argon2id.Memory != 0 {
+		argon.Memory = config.Argon2id.Memory
+	}
+	if config.Argon2id.Iterations != 0 {
+		argon.Iterations = config.Argon2id.Iterations
+	}
+	if config.Argon2id.Parallelism != 0 {
+		argon.Parallelism = config.Argon2id.Parallelism
+	}
+	bcryptScheme := cryptoutil.Bcrypt{Cost: bcrypt.DefaultCost}
+	if config.Bcrypt.Cost != 0 {
+		bcryptScheme.Cost = config.Bcrypt.Cost
+	}
+	if config.Algorithm == "bcrypt" {
+		return cryptoutil.NewPasswordHasher(bcryptScheme, argon)
+	}
+	return cryptoutil.NewPasswordHasher(argon, bcryptScheme)
+}
+
+// rehashPassword replaces a hash in place, failing to do so does not fail
+// the login, it is tried again on the next one.
+func (s *Service) rehashPassword(ctx context.Context, passwd repository.Password, password string) {
+	hashedPassword, err := s.passwords.Hash(password)
+	if err == nil {
+		err = s.repository.UpdatePasswordHash(ctx, repository.UpdatePasswordHashParams{
+			ID:             passwd.ID,
+			HashedPassword: hashedPassword,
+		})
+	}
+	if err != nil {
+		logger.Error().Err(err).Str("user_id", passwd.CreatedBy.String()).Msg("failed to rehash the password")
+	}
+}
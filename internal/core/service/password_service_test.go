@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aritradeveops/porichoy/internal/config"
+	"github.com/aritradeveops/porichoy/internal/persistence/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// passwordQuerier keeps the passwords of one user, the current one last,
+// and what a password change revokes. Anything else panics on the nil
+// Querier. InTx puts the passwords back when the transaction fails like a
+// rollback would.
+type passwordQuerier struct {
+	repository.Querier
+	userID        uuid.UUID
+	passwords     []repository.Password
+	revokedResets int
+	endedSessions []repository.DeleteOtherSessionsParams
+	failSessions  bool
+}
+
+func (q *passwordQuerier) InTx(ctx context.Context, fn func(repository.Querier) error) error {
+	passwords := slices.Clone(q.passwords)
+	revokedResets := q.revokedResets
+	err := fn(q)
+	if err != nil {
+		q.passwords = passwords
+		q.revokedResets = revokedResets
+	}
+	return err
+}
+
+func (q *passwordQuerier) FindUserPassword(ctx context.Context, userID uuid.UUID) (repository.Password, error) {
+	if userID != q.userID || len(q.passwords) == 0 {
+		return repository.Password{}, errors.New("no rows in result set")
+	}
+	return q.passwords[len(q.passwords)-1], nil
+}
+
+func (q *passwordQuerier) ListPasswordHistory(ctx context.Context, arg repository.ListPasswordHistoryParams) ([]repository.Password, error) {
+	var history []repository.Password
+	for i := len(q.passwords) - 1; i >= 0 && len(history) < int(arg.Limit); i-- {
+		history = append(history, q.passwords[i])
+	}
+	return history, nil
+}
+
+func (q *passwordQuerier) ReplaceUserPassword(ctx context.Context, arg repository.ReplaceUserPasswordParams) error {
+	now := time.Now()
+	for i := range q.passwords {
+		if q.passwords[i].DeletedAt == nil {
+			q.passwords[i].DeletedAt = &now
+		}
+	}
+	q.passwords = append(q.passwords, repository.Password{
+		ID:             uuid.New(),
+		HashedPassword: arg.HashedPassword,
+		CreatedAt:      now,
+		CreatedBy:      arg.CreatedBy,
+	})
+	return nil
+}
+
+func (q *passwordQuerier) RevokePasswordResetTokens(ctx context.Context, userID uuid.UUID) error {
+	q.revokedResets++
+	return nil
+}
+
+func (q *passwordQuerier) DeleteOtherSessions(ctx context.Context, arg repository.DeleteOtherSessionsParams) error {
+	if q.failSessions {
+		return errors.New("connection reset")
+	}
+	q.endedSessions = append(q.endedSessions, arg)
+	return nil
+}
+
+// newPasswordService hashes with the cheapest bcrypt cost, the tests are
+// about the policy and not the hashing.
+func newPasswordService(t *testing.T, history *int, passwords ...string) (*Service, *passwordQuerier) {
+	querier := &passwordQuerier{userID: uuid.New()}
+	s := New(&config.Config{
+		Issuer: "https://id.example.com",
+		Passwords: config.Passwords{
+			History: history,
+			Hashing: config.Hashing{
+				Algorithm: "bcrypt",
+				Bcrypt:    config.Bcrypt{Cost: bcrypt.MinCost},
+			},
+		},
+	}, querier, nil, nil)
+	for _, password := range passwords {
+		hashedPassword, err := s.passwords.Hash(password)
+		assert.NoError(t, err)
+		assert.NoError(t, storePassword(context.Background(), querier, querier.userID, hashedPassword))
+	}
+	return s, querier
+}
+
+func changePassword(s *Service, querier *passwordQuerier, current string, next string) error {
+	return s.ChangePassword(context.Background(), querier.userID.String(), ChangePasswordPayload{
+		CurrentPassword: current,
+		NewPassword:     next,
+	})
+}
+
+func TestChangePassword(t *testing.T) {
+	t.Parallel()
+	s, querier := newPasswordService(t, nil, "Current-Pass-1")
+
+	err := changePassword(s, querier, "Wrong-Pass-1", "Next-Pass-2")
+	assert.ErrorIs(t, err, ErrIncorrectPassword)
+	assert.Len(t, querier.passwords, 1)
+
+	// the new password has to meet the policy too
+	err = changePassword(s, querier, "Current-Pass-1", "short")
+	assert.Error(t, err)
+	assert.Len(t, querier.passwords, 1)
+
+	err = s.ChangePassword(context.Background(), querier.userID.String(), ChangePasswordPayload{
+		CurrentPassword:  "Current-Pass-1",
+		NewPassword:      "Next-Pass-2",
+		EndOtherSessions: true,
+		RefreshToken:     "refresh-token",
+	})
+	assert.NoError(t, err)
+	assert.Len(t, querier.passwords, 2)
+	assert.Equal(t, 1, querier.revokedResets)
+	assert.Equal(t, []repository.DeleteOtherSessionsParams{{
+		UserID:       querier.userID,
+		RefreshToken: "refresh-token",
+	}}, querier.endedSessions)
+
+	// the old password stopped working
+	err = changePassword(s, querier, "Current-Pass-1", "Third-Pass-3")
+	assert.ErrorIs(t, err, ErrIncorrectPassword)
+	assert.NoError(t, changePassword(s, querier, "Next-Pass-2", "Third-Pass-3"))
+}
+
+func TestChangePasswordHistory(t *testing.T) {
+	t.Parallel()
+	passwords := []string{"First-Pass-1", "Second-Pass-2", "Third-Pass-3"}
+
+	// 5 previous passwords are kept by default, the current one included
+	s, querier := newPasswordService(t, nil, passwords...)
+	assert.ErrorIs(t, changePassword(s, querier, "Third-Pass-3", "Third-Pass-3"), ErrPasswordReused)
+	assert.ErrorIs(t, changePassword(s, querier, "Third-Pass-3", "First-Pass-1"), ErrPasswordReused)
+	assert.Len(t, querier.passwords, 3)
+
+	two := 2
+	s, querier = newPasswordService(t, &two, passwords...)
+	assert.ErrorIs(t, changePassword(s, querier, "Third-Pass-3", "Second-Pass-2"), ErrPasswordReused)
+	assert.NoError(t, changePassword(s, querier, "Third-Pass-3", "First-Pass-1"))
+
+	// 0 turns the check off
+	off := 0
+	s, querier = newPasswordService(t, &off, passwords...)
+	assert.NoError(t, changePassword(s, querier, "Third-Pass-3", "Third-Pass-3"))
+	assert.NoError(t, changePassword(s, querier, "Third-Pass-3", "First-Pass-1"))
+}
+
+// the password does not change unless the other sessions end along with it
+func TestChangePasswordRollback(t *testing.T) {
+	t.Parallel()
+	s, querier := newPasswordService(t, nil, "Current-Pass-1")
+	querier.failSessions = true
+
+	err := s.ChangePassword(context.Background(), querier.userID.String(), ChangePasswordPayload{
+		CurrentPassword:  "Current-Pass-1",
+		NewPassword:      "Next-Pass-2",
+		EndOtherSessions: true,
+	})
+	assert.Error(t, err)
+	assert.Len(t, querier.passwords, 1)
+	assert.Nil(t, querier.passwords[0].DeletedAt)
+	assert.Zero(t, querier.revokedResets)
+}
+
+func TestChangePasswordRateLimit(t *testing.T) {
+	t.Parallel()
+	s, querier := newPasswordService(t, nil, "Current-Pass-1")
+
+	for range 5 {
+		assert.ErrorIs(t, changePassword(s, querier, "Wrong-Pass-1", "Next-Pass-2"), ErrIncorrectPassword)
+	}
+	// not even the right password is checked anymore
+	assert.ErrorIs(t, changePassword(s, querier, "Current-Pass-1", "Next-Pass-2"), ErrTooManyRequests)
+	assert.Len(t, querier.passwords, 1)
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/aritradeveops/porichoy/internal/core/validation"
+	"github.com/aritradeveops/porichoy/internal/persistence/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type CreateApiResourcePayload struct {
+	Name string `json:"name" validate:"required,min=3"`
+	// absolute uri of the api, used as the audience of its access tokens
+	Identifier    string   `json:"identifier" validate:"required,uri"`
+	Scopes        []string `json:"scopes" validate:"dive,required,excludesall= "`
+	TokenLifetime string   `json:"token_lifetime" validate:"required,duration"`
+	// the user who decides which apps may use the api, root when empty
+	Owner string `json:"owner" validate:"omitempty,uuid"`
+}
+
+type ApiResourceGrantPayload struct {
+	Identifier string `json:"identifier" validate:"required,uri"`
+	ClientID   string `json:"client_id" validate:"required"`
+}
+
+var (
+	ErrInvalidTarget        = errors.New("resource_service: invalid target")
+	ErrApiResourceNotFound  = errors.New("resource_service: api resource not found")
+	ErrApiResourceForbidden = errors.New("resource_service: only root registers api resources, they are managed by root and their owners")
+)
+
+// CreateApiResource registers an api, only root can. The audience of its
+// tokens is its identifier, anyone registering it could have tokens issued
+// for someone else's api.
+func (s *Service) CreateApiResource(ctx context.Context, initiator string, payload CreateApiResourcePayload) (repository.ApiResource, error) {
+	var resource repository.ApiResource
+	if initiator != uuid.Nil.String() {
+		return resource, ErrApiResourceForbidden
+	}
+	errs := validation.Validate(payload)
+	if errs != nil {
+		return resource, errs
+	}
+	if payload.Scopes == nil {
+		payload.Scopes = []string{}
+	}
+	var owner *uuid.UUID
+	if payload.Owner != "" {
+		id := uuid.MustParse(payload.Owner)
+		owner = &id
+	}
+	return s.repository.CreateApiResource(ctx, repository.CreateApiResourceParams{
+		Name:          payload.Name,
+		Identifier:    payload.Identifier,
+		Scopes:        payload.Scopes,
+		TokenLifetime: payload.TokenLifetime,
+		CreatedBy:     uuid.MustParse(initiator),
+		OwnerID:       owner,
+	})
+}
+
+// GrantApiResource allows an app to request tokens for the api, it is up to
+// the owner of the api and not to the one of the app.
+func (s *Service) GrantApiResource(ctx context.Context, initiator string, payload ApiResourceGrantPayload) error {
+	app, by, err := s.findGrant(ctx, initiator, payload)
+	if err != nil {
+		return err
+	}
+	return s.repository.AddAppApiResource(ctx, repository.AddAppApiResourceParams{
+		Identifier: payload.Identifier,
+		UpdatedBy:  &by,
+		AppID:      app.App.ID,
+	})
+}
+
+// RevokeApiResource stops an app from requesting tokens for the api, the
+// tokens already issued stay valid until they expire.
+func (s *Service) RevokeApiResource(ctx context.Context, initiator string, payload ApiResourceGrantPayload) error {
+	app, by, err := s.findGrant(ctx, initiator, payload)
+	if err != nil {
+		return err
+	}
+	return s.repository.RemoveAppApiResource(ctx, repository.RemoveAppApiResourceParams{
+		Identifier: payload.Identifier,
+		UpdatedBy:  &by,
+		AppID:      app.App.ID,
+	})
+}
+
+// findGrant finds the app of a grant, once the initiator is known to manage
+// the resource.
+func (s *Service) findGrant(ctx context.Context, initiator string, payload ApiResourceGrantPayload) (repository.FindAppByClientIDRow, uuid.UUID, error) {
+	var app repository.FindAppByClientIDRow
+	by := uuid.MustParse(initiator)
+	errs := validation.Validate(payload)
+	if errs != nil {
+		return app, by, errs
+	}
+	resource, err := s.repository.FindApiResourceByIdentifier(ctx, payload.Identifier)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return app, by, ErrApiResourceNotFound
+	} else if err != nil {
+		return app, by, err
+	}
+	if by != uuid.Nil && (resource.OwnerID == nil || *resource.OwnerID != by) {
+		return app, by, ErrApiResourceForbidden
+	}
+	app, err = s.repository.FindAppByClientID(ctx, payload.ClientID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return app, by, ErrAppNotFound
+	}
+	return app, by, err
+}
+
+func (s *Service) ListApiResources(ctx context.Context) ([]repository.ApiResource, error) {
+	return s.repository.ListApiResources(ctx)
+}
+
+// findApiResource looks up the resource indicated by a client (RFC 8707).
+func (s *Service) findApiResource(ctx context.Context, identifier string) (repository.ApiResource, error) {
+	resource, err := s.repository.FindApiResourceByIdentifier(ctx, identifier)
+	if err != nil {
+		return resource, ErrInvalidTarget
+	}
+	return resource, nil
+}
+
+// findAppResource looks up a resource the app is allowed to request tokens
+// for, every other resource is an invalid target for it.
+func (s *Service) findAppResource(ctx context.Context, config repository.OauthConfig, identifier string) (repository.ApiResource, error) {
+	if !slices.Contains(config.ApiResources, identifier) {
+		return repository.ApiResource{}, ErrInvalidTarget
+	}
+	return s.findApiResource(ctx, identifier)
+}
+
+// grantedScopes narrows the requested scopes down to the ones the resource
+// defines, none are granted when none were requested.
+func grantedScopes(requested string, resource repository.ApiResource) []string {
+	granted := []string{}
+	for scope := range strings.FieldsSeq(requested) {
+		if slices.Contains(resource.Scopes, scope) && !slices.Contains(granted, scope) {
+			granted = append(granted, scope)
+		}
+	}
+	return granted
+}
@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aritradeveops/porichoy/internal/config"
+	"github.com/aritradeveops/porichoy/internal/persistence/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+// resourceQuerier keeps one app allowed to use the billing api, the ledger
+// api is registered too but not allowed for the app. Both apis are owned by
+// resourceOwner.
+type resourceQuerier struct {
+	repository.Querier
+	app       repository.FindAppByClientIDRow
+	resources []repository.ApiResource
+	secrets   []repository.ClientSecret
+	calls     map[string]repository.OauthCall
+	user      repository.User
+	key       repository.SigningKey
+}
+
+func (q *resourceQuerier) FindAppByClientID(ctx context.Context, clientID string) (repository.FindAppByClientIDRow, error) {
+	if clientID != q.app.App.ClientID {
+		return repository.FindAppByClientIDRow{}, pgx.ErrNoRows
+	}
+	return q.app, nil
+}
+
+func (q *resourceQuerier) FindApiResourceByIdentifier(ctx context.Context, identifier string) (repository.ApiResource, error) {
+	for _, resource := range q.resources {
+		if resource.Identifier == identifier {
+			return resource, nil
+		}
+	}
+	return repository.ApiResource{}, pgx.ErrNoRows
+}
+
+func (q *resourceQuerier) CreateApiResource(ctx context.Context, arg repository.CreateApiResourceParams) (repository.ApiResource, error) {
+	row := repository.ApiResource{
+		ID:            uuid.New(),
+		Name:          arg.Name,
+		Identifier:    arg.Identifier,
+		Scopes:        arg.Scopes,
+		TokenLifetime: arg.TokenLifetime,
+		CreatedAt:     time.Now(),
+		CreatedBy:     arg.CreatedBy,
+		OwnerID:       arg.OwnerID,
+	}
+	q.resources = append(q.resources, row)
+	return row, nil
+}
+
+func (q *resourceQuerier) AddAppApiResource(ctx context.Context, arg repository.AddAppApiResourceParams) error {
+	if arg.AppID == q.app.App.ID && !slices.Contains(q.app.OauthConfig.ApiResources, arg.Identifier) {
+		q.app.OauthConfig.ApiResources = append(q.app.OauthConfig.ApiResources, arg.Identifier)
+	}
+	return nil
+}
+
+func (q *resourceQuerier) RemoveAppApiResource(ctx context.Context, arg repository.RemoveAppApiResourceParams) error {
+	if arg.AppID == q.app.App.ID {
+		q.app.OauthConfig.ApiResources = slices.DeleteFunc(q.app.OauthConfig.ApiResources, func(identifier string) bool {
+			return identifier == arg.Identifier
+		})
+	}
+	return nil
+}
+
+func (q *resourceQuerier) ListValidClientSecrets(ctx context.Context, appID uuid.UUID) ([]repository.ClientSecret, error) {
+	return q.secrets, nil
+}
+
+func (q *resourceQuerier) CreateClientSecret(ctx context.Context, arg repository.CreateClientSecretParams) (repository.ClientSecret, error) {
+	row := repository.ClientSecret{
+		ID:           uuid.New(),
+		HashedSecret: arg.HashedSecret,
+		AppID:        arg.AppID,
+		CreatedAt:    time.Now(),
+		CreatedBy:    arg.CreatedBy,
+	}
+	q.secrets = append(q.secrets, row)
+	return row, nil
+}
+
+func (q *resourceQuerier) ConsumeOauthCall(ctx context.Context, code string) (repository.OauthCall, error) {
+	call, ok := q.calls[code]
+	if !ok {
+		return repository.OauthCall{}, pgx.ErrNoRows
+	}
+	delete(q.calls, code)
+	return call, nil
+}
+
+func (q *resourceQuerier) FindUserByID(ctx context.Context, id uuid.UUID) (repository.User, error) {
+	if id != q.user.ID {
+		return repository.User{}, pgx.ErrNoRows
+	}
+	return q.user, nil
+}
+
+func (q *resourceQuerier) FindActiveSigningKey(ctx context.Context, appID uuid.UUID) (repository.SigningKey, error) {
+	return q.key, nil
+}
+
+func (q *resourceQuerier) CreateSession(ctx context.Context, arg repository.CreateSessionParams) error {
+	return nil
+}
+
+const (
+	billingApi = "https://billing.example.com"
+	ledgerApi  = "https://ledger.example.com"
+)
+
+var resourceOwner = uuid.New()
+
+func newResourceService(t *testing.T) (*Service, *resourceQuerier, string) {
+	appID := uuid.New()
+	querier := &resourceQuerier{
+		app: repository.FindAppByClientIDRow{
+			App: repository.App{
+				ID:        appID,
+				Domain:    "app.example.com",
+				ClientID:  "app.example.com",
+				CreatedBy: uuid.New(),
+			},
+			OauthConfig: repository.OauthConfig{
+				RedirectUris:         []string{"https://app.example.com/callback"},
+				JwtAlgo:              "HS256",
+				JwtLifetime:          "15m",
+				RefreshTokenLifetime: "24h",
+				ApiResources:         []string{billingApi},
+				AppID:                appID,
+			},
+		},
+		resources: []repository.ApiResource{
+			{ID: uuid.New(), Identifier: billingApi, Scopes: []string{"invoices:read", "invoices:write"}, TokenLifetime: "5m", OwnerID: &resourceOwner},
+			{ID: uuid.New(), Identifier: ledgerApi, Scopes: []string{"entries:read"}, TokenLifetime: "5m", OwnerID: &resourceOwner},
+		},
+		calls: map[string]repository.OauthCall{},
+		user: repository.User{
+			ID:    uuid.New(),
+			Email: "jane@example.com",
+			Name:  "Jane",
+		},
+	}
+	secret, err := createClientSecret(context.Background(), querier, appID, uuid.Nil, nil)
+	assert.NoError(t, err)
+	s := New(&config.Config{
+		Issuer: "https://id.example.com",
+		Keys: config.Keys{
+			MasterKeyResolver: "literal://resource-test-master-key",
+		},
+	}, querier, nil, nil)
+	querier.key = testSigningKey(t, s, appID, KeyStateActive)
+	return s, querier, secret
+}
+
+// authorizedCode stores a code the way Authorize does, bound to resource
+// when it is not empty.
+func authorizedCode(querier *resourceQuerier, resource string) string {
+	return authorizedScopes(querier, resource, "invoices:read")
+}
+
+func authorizedScopes(querier *resourceQuerier, resource string, scope string) string {
+	code := uuid.NewString()
+	querier.calls[code] = repository.OauthCall{
+		ID:          uuid.New(),
+		AppID:       querier.app.App.ID,
+		Code:        code,
+		UserID:      querier.user.ID,
+		Resource:    pgtype.Text{String: resource, Valid: resource != ""},
+		Scope:       pgtype.Text{String: scope, Valid: scope != ""},
+		ExpiresAt:   time.Now().Add(OauthCodeLifetime),
+		RedirectUri: "https://app.example.com/callback",
+	}
+	return code
+}
+
+func tokenPayload(code string, secret string, resource string) Oauth2TokenPayload {
+	return Oauth2TokenPayload{
+		ClientID:     "app.example.com",
+		ClientSecret: secret,
+		GrantType:    "authorization_code",
+		Code:         code,
+		RedirectURI:  "https://app.example.com/callback",
+		UserAgent:    "test",
+		UserIP:       "127.0.0.1",
+		Resource:     resource,
+	}
+}
+
+func TestTokenResourceBinding(t *testing.T) {
+	t.Parallel()
+	s, querier, secret := newResourceService(t)
+	ctx := context.Background()
+
+	// repeating the bound resource grants the requested scopes
+	resp, err := s.Token(ctx, tokenPayload(authorizedCode(querier, billingApi), secret, billingApi))
+	assert.NoError(t, err)
+	assert.Equal(t, "invoices:read", resp.Scope)
+
+	// the resource is optional at the token endpoint
+	resp, err = s.Token(ctx, tokenPayload(authorizedCode(querier, billingApi), secret, ""))
+	assert.NoError(t, err)
+	assert.Equal(t, "invoices:read", resp.Scope)
+
+	// a different resource than the bound one
+	_, err = s.Token(ctx, tokenPayload(authorizedCode(querier, billingApi), secret, ledgerApi))
+	assert.ErrorIs(t, err, ErrInvalidTarget)
+
+	// the authorization was not bound to any resource
+	_, err = s.Token(ctx, tokenPayload(authorizedCode(querier, ""), secret, billingApi))
+	assert.ErrorIs(t, err, ErrInvalidTarget)
+
+	resp, err = s.Token(ctx, tokenPayload(authorizedCode(querier, ""), secret, ""))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, resp.AccessToken)
+}
+
+func TestTokenResourceScopes(t *testing.T) {
+	t.Parallel()
+	s, querier, secret := newResourceService(t)
+	ctx := context.Background()
+
+	resp, err := s.Token(ctx, tokenPayload(authorizedScopes(querier, billingApi, "invoices:write openid refunds:write"), secret, ""))
+	assert.NoError(t, err)
+	assert.Equal(t, "invoices:write", resp.Scope)
+
+	// no scope of the resource is granted unless asked for
+	resp, err = s.Token(ctx, tokenPayload(authorizedScopes(querier, billingApi, ""), secret, ""))
+	assert.NoError(t, err)
+	assert.Empty(t, resp.Scope)
+}
+
+func TestTokenResourceAllowList(t *testing.T) {
+	t.Parallel()
+	s, querier, secret := newResourceService(t)
+
+	// the resource was allowed for the app when the code was issued
+	code := authorizedCode(querier, billingApi)
+	querier.app.OauthConfig.ApiResources = nil
+	_, err := s.Token(context.Background(), tokenPayload(code, secret, ""))
+	assert.ErrorIs(t, err, ErrInvalidTarget)
+}
+
+func TestPromptResourceAllowList(t *testing.T) {
+	t.Parallel()
+	s, _, _ := newResourceService(t)
+	payload := Oauth2Payload{
+		ClientID:     "app.example.com",
+		ResponseType: ResponseTypeCode,
+		RedirectURI:  "https://app.example.com/callback",
+	}
+
+	for _, resource := range []string{ledgerApi, "https://unknown.example.com"} {
+		payload.Resource = resource
+		resp, err := s.Oauth2Prompt(context.Background(), nil, payload)
+		assert.NoError(t, err)
+		assert.Equal(t, Oauth2StepError, resp.Step)
+		assert.Equal(t, Oauth2ErrorInvalidTarget, resp.Authorization.Params.Get("error"))
+	}
+
+	payload.Resource = billingApi
+	resp, err := s.Oauth2Prompt(context.Background(), nil, payload)
+	assert.NoError(t, err)
+	assert.Equal(t, Oauth2StepLogin, resp.Step)
+}
+
+func TestCreateApiResourceRootOnly(t *testing.T) {
+	t.Parallel()
+	s, querier, _ := newResourceService(t)
+	payload := CreateApiResourcePayload{
+		Name:          "Payments",
+		Identifier:    "https://payments.example.com",
+		TokenLifetime: "5m",
+		Owner:         resourceOwner.String(),
+	}
+
+	// not even the owner to be, anyone could claim the audience of another api
+	_, err := s.CreateApiResource(context.Background(), resourceOwner.String(), payload)
+	assert.ErrorIs(t, err, ErrApiResourceForbidden)
+	assert.Len(t, querier.resources, 2)
+
+	resource, err := s.CreateApiResource(context.Background(), uuid.Nil.String(), payload)
+	assert.NoError(t, err)
+	assert.Equal(t, &resourceOwner, resource.OwnerID)
+}
+
+func TestGrantApiResource(t *testing.T) {
+	t.Parallel()
+	s, querier, _ := newResourceService(t)
+	ctx := context.Background()
+	grant := ApiResourceGrantPayload{Identifier: ledgerApi, ClientID: "app.example.com"}
+
+	// the owner of the app can not allow it an api of someone else
+	err := s.GrantApiResource(ctx, querier.app.App.CreatedBy.String(), grant)
+	assert.ErrorIs(t, err, ErrApiResourceForbidden)
+	err = s.GrantApiResource(ctx, uuid.NewString(), grant)
+	assert.ErrorIs(t, err, ErrApiResourceForbidden)
+	assert.Equal(t, []string{billingApi}, querier.app.OauthConfig.ApiResources)
+
+	assert.NoError(t, s.GrantApiResource(ctx, resourceOwner.String(), grant))
+	assert.Equal(t, []string{billingApi, ledgerApi}, querier.app.OauthConfig.ApiResources)
+	assert.NoError(t, s.RevokeApiResource(ctx, uuid.Nil.String(), ApiResourceGrantPayload{Identifier: billingApi, ClientID: "app.example.com"}))
+	assert.Equal(t, []string{ledgerApi}, querier.app.OauthConfig.ApiResources)
+
+	err = s.GrantApiResource(ctx, uuid.Nil.String(), ApiResourceGrantPayload{Identifier: "https://unknown.example.com", ClientID: "app.example.com"})
+	assert.ErrorIs(t, err, ErrApiResourceNotFound)
+	err = s.GrantApiResource(ctx, resourceOwner.String(), ApiResourceGrantPayload{Identifier: ledgerApi, ClientID: "unknown.example.com"})
+	assert.ErrorIs(t, err, ErrAppNotFound)
+}
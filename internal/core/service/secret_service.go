@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aritradeveops/porichoy/internal/core/cryptoutil"
+	"github.com/aritradeveops/porichoy/internal/core/validation"
+	"github.com/aritradeveops/porichoy/internal/persistence/repository"
+	"github.com/aritradeveops/porichoy/pkg/resolver"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const secretsPurpose = "porichoy secrets"
+
+var (
+	ErrSecretNotFound     = errors.New("secret_service: secret not found")
+	ErrSecretForbidden    = errors.New("secret_service: only root can manage and reference stored secrets")
+	ErrKeySourceForbidden = errors.New("secret_service: app keys can only be resolved from literal:// and the configured app key sources")
+)
+
+type CreateSecretPayload struct {
+	// referenced as db://<name> by resolvers
+	Name  string `json:"name" validate:"required,max=255,excludesall= #"`
+	Value string `json:"value" validate:"required"`
+}
+
+type RotateSecretPayload struct {
+	Value string `json:"value" validate:"required"`
+}
+
+// StoredSecret is what is shown of a secret, its value never leaves porichoy
+// other than through a resolver.
+type StoredSecret struct {
+	Name      string     `json:"name"`
+	Version   int32      `json:"version"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
+}
+
+func (s *Service) CreateSecret(ctx context.Context, initiator string, payload CreateSecretPayload) (StoredSecret, error) {
+	if initiator != uuid.Nil.String() {
+		return StoredSecret{}, ErrSecretForbidden
+	}
+	errs := validation.Validate(payload)
+	if errs != nil {
+		return StoredSecret{}, errs
+	}
+	value, dataKey, err := s.sealSecret(payload.Name, payload.Value)
+	if err != nil {
+		return StoredSecret{}, err
+	}
+	secret, err := s.repository.CreateSecret(ctx, repository.CreateSecretParams{
+		Name:             payload.Name,
+		EncryptedValue:   value,
+		EncryptedDataKey: dataKey,
+		CreatedBy:        uuid.MustParse(initiator),
+	})
+	if err != nil {
+		return StoredSecret{}, err
+	}
+	return storedSecret(secret), nil
+}
+
+func (s *Service) ListSecrets(ctx context.Context, initiator string) ([]StoredSecret, error) {
+	if initiator != uuid.Nil.String() {
+		return nil, ErrSecretForbidden
+	}
+	secrets, err := s.repository.ListSecrets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stored := make([]StoredSecret, 0, len(secrets))
+	for _, secret := range secrets {
+		stored = append(stored, storedSecret(secret))
+	}
+	return stored, nil
+}
+
+// RotateSecret replaces the value of a secret, it is sealed with a fresh data
+// key. This instance resolves the new value right away, other instances once
+// their db:// cache has expired.
+func (s *Service) RotateSecret(ctx context.Context, initiator string, name string, payload RotateSecretPayload) (StoredSecret, error) {
+	if initiator != uuid.Nil.String() {
+		return StoredSecret{}, ErrSecretForbidden
+	}
+	errs := validation.Validate(payload)
+	if errs != nil {
+		return StoredSecret{}, errs
+	}
+	value, dataKey, err := s.sealSecret(name, payload.Value)
+	if err != nil {
+		return StoredSecret{}, err
+	}
+	by := uuid.MustParse(initiator)
+	secret, err := s.repository.RotateSecret(ctx, repository.RotateSecretParams{
+		Name:             name,
+		EncryptedValue:   value,
+		EncryptedDataKey: dataKey,
+		UpdatedBy:        &by,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return StoredSecret{}, ErrSecretNotFound
+		}
+		return StoredSecret{}, err
+	}
+	resolver.Forget("db://" + name)
+	return storedSecret(secret), nil
+}
+
+// DeleteSecret removes a secret for good, resolvers referencing it fail right
+// away on this instance and on others once their db:// cache has expired.
+func (s *Service) DeleteSecret(ctx context.Context, initiator string, name string) error {
+	if initiator != uuid.Nil.String() {
+		return ErrSecretForbidden
+	}
+	deleted, err := s.repository.DeleteSecretByName(ctx, name)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrSecretNotFound
+	}
+	resolver.Forget("db://" + name)
+	return nil
+}
+
+// Secret decrypts a stored secret, it makes the service the store of the
+// db:// resolver.
+func (s *Service) Secret(ctx context.Context, name string) (string, error) {
+	secret, err := s.repository.FindSecretByName(ctx, name)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrSecretNotFound
+		}
+		return "", err
+	}
+	masterKey, err := s.masterKey(secretsPurpose)
+	if err != nil {
+		return "", err
+	}
+	dataKey, err := cryptoutil.Open(masterKey, secret.EncryptedDataKey, []byte(secret.Name))
+	if err != nil {
+		return "", fmt.Errorf("secret_service: could not decrypt the data key of %s: %v", secret.Name, err)
+	}
+	value, err := cryptoutil.Open(dataKey, secret.EncryptedValue, []byte(secret.Name))
+	if err != nil {
+		return "", fmt.Errorf("secret_service: could not decrypt %s: %v", secret.Name, err)
+	}
+	return string(value), nil
+}
+
+// checkSecretReference keeps users other than root from pointing the keys of
+// their apps anywhere but literal:// and the configured app key sources. The
+// app would sign with a secret of the server otherwise, env://, file://,
+// vault://, s3:// and db:// alike, and the resolver errors would tell which
+// of them exist.
+func (s *Service) checkSecretReference(initiator string, source string) error {
+	if source == "" || initiator == uuid.Nil.String() || strings.HasPrefix(source, "literal://") {
+		return nil
+	}
+	for _, prefix := range s.config.Keys.AppKeySources {
+		path, _, _ := strings.Cut(strings.TrimPrefix(source, prefix), "#")
+		if strings.HasPrefix(source, prefix) && !slices.Contains(strings.Split(path, "/"), "..") {
+			return nil
+		}
+	}
+	return ErrKeySourceForbidden
+}
+
+// sealSecret encrypts the value with a random data key which is in turn
+// encrypted with the master key, both are bound to the name of the secret.
+func (s *Service) sealSecret(name string, value string) (string, string, error) {
+	masterKey, err := s.masterKey(secretsPurpose)
+	if err != nil {
+		return "", "", err
+	}
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", "", err
+	}
+	sealedValue, err := cryptoutil.Seal(dataKey, []byte(value), []byte(name))
+	if err != nil {
+		return "", "", err
+	}
+	sealedKey, err := cryptoutil.Seal(masterKey, dataKey, []byte(name))
+	if err != nil {
+		return "", "", err
+	}
+	return sealedValue, sealedKey, nil
+}
+
+func storedSecret(secret repository.Secret) StoredSecret {
+	return StoredSecret{
+		Name:      secret.Name,
+		Version:   secret.Version,
+		CreatedAt: secret.CreatedAt,
+		UpdatedAt: secret.UpdatedAt,
+	}
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aritradeveops/porichoy/internal/config"
+	"github.com/aritradeveops/porichoy/internal/persistence/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// secretQuerier keeps the secrets table in a map by name.
+type secretQuerier struct {
+	repository.Querier
+	secrets map[string]repository.Secret
+}
+
+func (q *secretQuerier) CreateSecret(ctx context.Context, arg repository.CreateSecretParams) (repository.Secret, error) {
+	secret := repository.Secret{
+		ID:               uuid.New(),
+		Name:             arg.Name,
+		EncryptedValue:   arg.EncryptedValue,
+		EncryptedDataKey: arg.EncryptedDataKey,
+		Version:          1,
+		CreatedAt:        time.Now(),
+		CreatedBy:        arg.CreatedBy,
+	}
+	q.secrets[arg.Name] = secret
+	return secret, nil
+}
+
+func (q *secretQuerier) FindSecretByName(ctx context.Context, name string) (repository.Secret, error) {
+	secret, ok := q.secrets[name]
+	if !ok {
+		return secret, pgx.ErrNoRows
+	}
+	return secret, nil
+}
+
+func (q *secretQuerier) RotateSecret(ctx context.Context, arg repository.RotateSecretParams) (repository.Secret, error) {
+	secret, ok := q.secrets[arg.Name]
+	if !ok {
+		return secret, pgx.ErrNoRows
+	}
+	now := time.Now()
+	secret.EncryptedValue = arg.EncryptedValue
+	secret.EncryptedDataKey = arg.EncryptedDataKey
+	secret.Version++
+	secret.UpdatedAt = &now
+	secret.UpdatedBy = arg.UpdatedBy
+	q.secrets[arg.Name] = secret
+	return secret, nil
+}
+
+func (q *secretQuerier) DeleteSecretByName(ctx context.Context, name string) (int64, error) {
+	if _, ok := q.secrets[name]; !ok {
+		return 0, nil
+	}
+	delete(q.secrets, name)
+	return 1, nil
+}
+
+func newSecretService() (*Service, *secretQuerier) {
+	querier := &secretQuerier{secrets: map[string]repository.Secret{}}
+	s := New(&config.Config{
+		Keys: config.Keys{
+			MasterKeyResolver: "literal://secret-test-master-key",
+		},
+	}, querier, nil, nil)
+	return s, querier
+}
+
+func TestStoredSecret(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	root := uuid.Nil.String()
+	s, querier := newSecretService()
+	stored, err := s.CreateSecret(ctx, root, CreateSecretPayload{Name: "github-app-key", Value: "first"})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, stored.Version)
+	assert.NotContains(t, querier.secrets["github-app-key"].EncryptedValue, "first")
+	value, err := s.Secret(ctx, "github-app-key")
+	assert.NoError(t, err)
+	assert.Equal(t, "first", value)
+
+	stored, err = s.RotateSecret(ctx, root, "github-app-key", RotateSecretPayload{Value: "second"})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, stored.Version)
+	value, err = s.Secret(ctx, "github-app-key")
+	assert.NoError(t, err)
+	assert.Equal(t, "second", value)
+
+	assert.NoError(t, s.DeleteSecret(ctx, root, "github-app-key"))
+	_, err = s.Secret(ctx, "github-app-key")
+	assert.ErrorIs(t, err, ErrSecretNotFound)
+	assert.ErrorIs(t, s.DeleteSecret(ctx, root, "github-app-key"), ErrSecretNotFound)
+	_, err = s.RotateSecret(ctx, root, "github-app-key", RotateSecretPayload{Value: "third"})
+	assert.ErrorIs(t, err, ErrSecretNotFound)
+}
+
+func TestStoredSecretBoundToName(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s, querier := newSecretService()
+	_, err := s.CreateSecret(ctx, uuid.Nil.String(), CreateSecretPayload{Name: "a", Value: "value of a"})
+	assert.NoError(t, err)
+
+	// a sealed value copied to another name does not open
+	swapped := querier.secrets["a"]
+	swapped.Name = "b"
+	querier.secrets["b"] = swapped
+	_, err = s.Secret(ctx, "b")
+	assert.Error(t, err)
+}
+
+func TestStoredSecretRootOnly(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	user := uuid.NewString()
+	s, querier := newSecretService()
+	_, err := s.CreateSecret(ctx, user, CreateSecretPayload{Name: "a", Value: "value"})
+	assert.ErrorIs(t, err, ErrSecretForbidden)
+	assert.Empty(t, querier.secrets)
+
+	_, err = s.CreateSecret(ctx, uuid.Nil.String(), CreateSecretPayload{Name: "a", Value: "value"})
+	assert.NoError(t, err)
+	_, err = s.ListSecrets(ctx, user)
+	assert.ErrorIs(t, err, ErrSecretForbidden)
+	_, err = s.RotateSecret(ctx, user, "a", RotateSecretPayload{Value: "mine"})
+	assert.ErrorIs(t, err, ErrSecretForbidden)
+	assert.ErrorIs(t, s.DeleteSecret(ctx, user, "a"), ErrSecretForbidden)
+
+	// nor can their apps sign with one
+	_, err = s.CreateSigningKey(ctx, user, "app.example.com", CreateSigningKeyPayload{Algo: "HS256", SecretResolver: "db://a"})
+	assert.ErrorIs(t, err, ErrKeySourceForbidden)
+}
+
+func TestSecretReferenceSources(t *testing.T) {
+	t.Parallel()
+
+	s, _ := newSecretService()
+	s.config.Keys.AppKeySources = []string{"vault://secret/apps/", "file:///run/apps/"}
+	user := uuid.NewString()
+	tests := []struct {
+		source  string
+		allowed bool
+	}{
+		{"", true},
+		{"literal://my-app-secret", true},
+		{"env://DATABASE_CONNECTION", false},
+		{"file:///run/secrets/jwt.pem", false},
+		{"file:///run/apps/mine.pem", true},
+		{"file:///run/apps/../secrets/jwt.pem", false},
+		{"vault://secret/porichoy/db#uri", false},
+		{"vault://secret/apps/mine#key", true},
+		{"vault://secret/apps-other/key", false},
+		{"s3://porichoy-secrets/jwt.pem", false},
+		{"db://github-app-key", false},
+	}
+	for _, test := range tests {
+		err := s.checkSecretReference(user, test.source)
+		if test.allowed {
+			assert.NoError(t, err, test.source)
+		} else {
+			assert.ErrorIs(t, err, ErrKeySourceForbidden, test.source)
+		}
+		// root can point keys anywhere
+		assert.NoError(t, s.checkSecretReference(uuid.Nil.String(), test.source), test.source)
+	}
+}
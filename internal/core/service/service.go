@@ -1,18 +1,101 @@
 package service
 
 import (
+	"context"
+	"crypto/x509"
+	"sync"
+	"time"
+
 	"github.com/aritradeveops/porichoy/internal/config"
+	"github.com/aritradeveops/porichoy/internal/core/cryptoutil"
 	"github.com/aritradeveops/porichoy/internal/persistence/repository"
+	"github.com/aritradeveops/porichoy/internal/pkg/mailer"
+	"github.com/aritradeveops/porichoy/internal/pkg/ratelimit"
+	"github.com/aritradeveops/porichoy/internal/pkg/sms"
+	"github.com/go-webauthn/webauthn/webauthn"
 )
 
 type Service struct {
 	config     *config.Config
 	repository repository.Querier
+	mailer     mailer.Mailer
+	sms        sms.SMSProvider
+	passwords  *cryptoutil.PasswordHasher
+	clientCAs  struct {
+		once sync.Once
+		pool *x509.CertPool
+		err  error
+	}
+	webauthn struct {
+		once sync.Once
+		rp   *webauthn.WebAuthn
+		err  error
+	}
+	master struct {
+		once   sync.Once
+		secret string
+		err    error
+	}
+	resetLimits struct {
+		email *ratelimit.Limiter
+		ip    *ratelimit.Limiter
+	}
+	verificationLimits struct {
+		email *ratelimit.Limiter
+		ip    *ratelimit.Limiter
+	}
+	// attempts at a second factor per user
+	mfaLimits *ratelimit.Limiter
+	// guesses at the current password when changing it, per user
+	passwordLimits   *ratelimit.Limiter
+	emailLoginLimits struct {
+		email *ratelimit.Limiter
+		ip    *ratelimit.Limiter
+		// guesses at the code of one login
+		code *ratelimit.Limiter
+	}
+	smsLimits struct {
+		number *ratelimit.Limiter
+		ip     *ratelimit.Limiter
+		// guesses at the latest code of one purpose
+		code *ratelimit.Limiter
+	}
 }
 
-func New(config *config.Config, repository repository.Querier) *Service {
-	return &Service{
+func New(config *config.Config, repository repository.Querier, mailer mailer.Mailer, sms sms.SMSProvider) *Service {
+	s := &Service{
 		config:     config,
 		repository: repository,
+		mailer:     mailer,
+		sms:        sms,
+		passwords:  newPasswordHasher(config.Passwords.Hashing),
+	}
+	s.resetLimits.email = ratelimit.New(3, time.Hour)
+	s.resetLimits.ip = ratelimit.New(10, time.Hour)
+	s.verificationLimits.email = ratelimit.New(3, time.Hour)
+	s.verificationLimits.ip = ratelimit.New(10, time.Hour)
+	s.mfaLimits = ratelimit.New(5, 5*time.Minute)
+	s.passwordLimits = ratelimit.New(5, 15*time.Minute)
+	s.emailLoginLimits.email = ratelimit.New(5, 15*time.Minute)
+	s.emailLoginLimits.ip = ratelimit.New(30, time.Hour)
+	s.emailLoginLimits.code = ratelimit.New(5, EmailLoginLifetime)
+	s.smsLimits.number = ratelimit.New(3, 15*time.Minute)
+	s.smsLimits.ip = ratelimit.New(20, time.Hour)
+	s.smsLimits.code = ratelimit.New(5, SmsCodeLifetime)
+	return s
+}
+
+// transactor is a repository that can run queries in a transaction, like
+// repository.Queries.
+type transactor interface {
+	InTx(ctx context.Context, fn func(repository.Querier) error) error
+}
+
+// inTx runs fn in a transaction when the repository supports them, writes
+// that have to happen together go through the querier it is given.
+func (s *Service) inTx(ctx context.Context, fn func(q repository.Querier) error) error {
+	if t, ok := s.repository.(transactor); ok {
+		return t.InTx(ctx, fn)
 	}
+	return fn(s.repository)
 }
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aritradeveops/porichoy/internal/persistence/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// testRootApp is a root app signing with a key generated under the master
+// key of s, for the tests that go as far as starting a session.
+func testRootApp(t *testing.T, s *Service) (repository.FindRootAppRow, repository.SigningKey) {
+	app := repository.FindRootAppRow{
+		App: repository.App{
+			ID:       uuid.New(),
+			Name:     "Porichoy",
+			Domain:   "id.example.com",
+			ClientID: "porichoy",
+		},
+		OauthConfig: repository.OauthConfig{
+			JwtAlgo:              "HS256",
+			JwtLifetime:          "15m",
+			RefreshTokenLifetime: "24h",
+		},
+	}
+	return app, testSigningKey(t, s, app.App.ID, KeyStateActive)
+}
+
+// testSigningKey generates a key of the app like CreateApp does.
+func testSigningKey(t *testing.T, s *Service, appID uuid.UUID, state string) repository.SigningKey {
+	params, err := s.signingKeyParams(appID, "HS256", "", state, uuid.Nil)
+	assert.NoError(t, err)
+	return signingKeyRow(params)
+}
+
+// signingKeyRow is the row CreateSigningKey stores for params.
+func signingKeyRow(params repository.CreateSigningKeyParams) repository.SigningKey {
+	return repository.SigningKey{
+		ID:                  uuid.New(),
+		Kid:                 params.Kid,
+		Algo:                params.Algo,
+		SecretResolver:      params.SecretResolver,
+		EncryptedPrivateKey: params.EncryptedPrivateKey,
+		State:               params.State,
+		ActivatesAt:         params.ActivatesAt,
+		ActivatedAt:         params.ActivatedAt,
+		AppID:               params.AppID,
+		CreatedAt:           time.Now(),
+		CreatedBy:           params.CreatedBy,
+	}
+}
@@ -0,0 +1,407 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aritradeveops/porichoy/internal/core/cryptoutil"
+	"github.com/aritradeveops/porichoy/internal/core/jwtutil"
+	"github.com/aritradeveops/porichoy/internal/core/validation"
+	"github.com/aritradeveops/porichoy/internal/persistence/repository"
+	"github.com/aritradeveops/porichoy/internal/pkg/logger"
+	"github.com/aritradeveops/porichoy/pkg/resolver"
+	"github.com/aritradeveops/porichoy/pkg/timex"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// a key is staged as next, signs as active, keeps verifying as retiring until
+// the tokens signed with it have expired and is then revoked
+const (
+	KeyStateNext     = "next"
+	KeyStateActive   = "active"
+	KeyStateRetiring = "retiring"
+	KeyStateRevoked  = "revoked"
+)
+
+const signingKeysPurpose = "porichoy signing keys"
+
+var (
+	ErrNoNextSigningKey   = errors.New("signing_key_service: no next key to rotate to")
+	ErrActiveSigningKey   = errors.New("signing_key_service: the active key can not be revoked")
+	ErrSigningKeyNotFound = errors.New("signing_key_service: signing key not found")
+	ErrNoMasterKey        = errors.New("signing_key_service: no master key configured")
+)
+
+// SigningKey is what is shown of a signing key, never its material or
+// where it is resolved from.
+type SigningKey struct {
+	Kid   string `json:"kid"`
+	Algo  string `json:"algo"`
+	State string `json:"state"`
+	// generated by porichoy and stored encrypted, otherwise resolved
+	Generated   bool       `json:"generated"`
+	ActivatesAt *time.Time `json:"activates_at"`
+	ActivatedAt *time.Time `json:"activated_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func newSigningKey(key repository.SigningKey) SigningKey {
+	return SigningKey{
+		Kid:         key.Kid,
+		Algo:        key.Algo,
+		State:       key.State,
+		Generated:   key.EncryptedPrivateKey.Valid,
+		ActivatesAt: key.ActivatesAt,
+		ActivatedAt: key.ActivatedAt,
+		ExpiresAt:   key.ExpiresAt,
+		CreatedAt:   key.CreatedAt,
+	}
+}
+
+type CreateSigningKeyPayload struct {
+	Algo string `json:"algo" validate:"required,jwt_algo"`
+	// the key is generated and stored encrypted when no resolver is given
+	SecretResolver string `json:"secret_resolver" validate:"omitempty,resolver"`
+	// the key is activated at this time, or by the next manual rotation when empty
+	ActivatesAt *time.Time `json:"activates_at"`
+}
+
+// CreateSigningKey stages the next key of an app, it is published right away
+// so verifiers can pick it up before it signs anything.
+func (s *Service) CreateSigningKey(ctx context.Context, initiator string, clientID string, payload CreateSigningKeyPayload) (SigningKey, error) {
+	var key SigningKey
+	errs := validation.Validate(payload)
+	if errs != nil {
+		return key, errs
+	}
+	if err := s.checkSecretReference(initiator, payload.SecretResolver); err != nil {
+		return key, err
+	}
+	app, err := s.findManagedApp(ctx, initiator, clientID)
+	if err != nil {
+		return key, err
+	}
+	params, err := s.signingKeyParams(app.App.ID, payload.Algo, payload.SecretResolver, KeyStateNext, uuid.MustParse(initiator))
+	if err != nil {
+		return key, err
+	}
+	params.ActivatesAt = payload.ActivatesAt
+	created, err := s.repository.CreateSigningKey(ctx, params)
+	if err != nil {
+		return key, err
+	}
+	return newSigningKey(created), nil
+}
+
+func (s *Service) ListSigningKeys(ctx context.Context, initiator string, clientID string) ([]SigningKey, error) {
+	app, err := s.findManagedApp(ctx, initiator, clientID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.repository.ListSigningKeysByAppID(ctx, app.App.ID)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]SigningKey, len(rows))
+	for i, row := range rows {
+		keys[i] = newSigningKey(row)
+	}
+	return keys, nil
+}
+
+// RotateSigningKey activates the oldest next key of the app and retires the
+// active one.
+func (s *Service) RotateSigningKey(ctx context.Context, initiator string, clientID string) (SigningKey, error) {
+	app, err := s.findManagedApp(ctx, initiator, clientID)
+	if err != nil {
+		return SigningKey{}, err
+	}
+	by := uuid.MustParse(initiator)
+	key, err := s.rotateSigningKey(ctx, app.App, app.OauthConfig, uuid.Nil, &by)
+	if err != nil {
+		return SigningKey{}, err
+	}
+	return newSigningKey(key), nil
+}
+
+// RevokeSigningKey stops a key from verifying immediately, tokens signed with
+// it are rejected from now on.
+func (s *Service) RevokeSigningKey(ctx context.Context, initiator string, clientID string, kid string) error {
+	if _, err := s.findManagedApp(ctx, initiator, clientID); err != nil {
+		return err
+	}
+	key, err := s.repository.FindSigningKeyByKid(ctx, kid)
+	if err != nil || key.App.ClientID != clientID {
+		return ErrSigningKeyNotFound
+	}
+	if key.SigningKey.State == KeyStateActive {
+		return ErrActiveSigningKey
+	}
+	now := time.Now()
+	by := uuid.MustParse(initiator)
+	return s.repository.UpdateSigningKeyState(ctx, repository.UpdateSigningKeyStateParams{
+		ID:          key.SigningKey.ID,
+		State:       KeyStateRevoked,
+		ActivatedAt: key.SigningKey.ActivatedAt,
+		ExpiresAt:   &now,
+		UpdatedBy:   &by,
+	})
+}
+
+// RotateDueSigningKeys activates the next keys whose activation time has come
+// and revokes the retiring keys that no longer have valid tokens.
+func (s *Service) RotateDueSigningKeys(ctx context.Context) error {
+	due, err := s.repository.ListDueSigningKeys(ctx)
+	if err != nil {
+		return err
+	}
+	if len(due) > 0 {
+		apps, err := s.repository.ListApps(ctx)
+		if err != nil {
+			return err
+		}
+		for _, key := range due {
+			for _, app := range apps {
+				if app.App.ID != key.AppID {
+					continue
+				}
+				if _, err := s.rotateSigningKey(ctx, app.App, app.OauthConfig, key.ID, nil); err != nil {
+					logger.Error().Err(err).Str("kid", key.Kid).Msg("failed to rotate signing key")
+				}
+			}
+		}
+	}
+	return s.repository.RevokeExpiredSigningKeys(ctx)
+}
+
+// RunKeyRotation rotates the due signing keys every interval until the
+// context is done.
+func (s *Service) RunKeyRotation(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.RotateDueSigningKeys(ctx); err != nil {
+			logger.Error().Err(err).Msg("signing key rotation failed")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// rotateSigningKey activates the given next key, or the oldest one when id is
+// nil, and retires the keys active before it. Apps signing with a generated
+// key get a new one generated when there is no next key. The keys change
+// state in one transaction so a failed rotation leaves them as they were.
+func (s *Service) rotateSigningKey(ctx context.Context, app repository.App, config repository.OauthConfig, id uuid.UUID, by *uuid.UUID) (repository.SigningKey, error) {
+	lifetime, err := s.maxTokenLifetime(ctx, config)
+	if err != nil {
+		return repository.SigningKey{}, err
+	}
+	var next repository.SigningKey
+	err = s.inTx(ctx, func(q repository.Querier) error {
+		keys, err := q.ListSigningKeysByAppID(ctx, app.ID)
+		if err != nil {
+			return err
+		}
+		found := false
+		for _, key := range keys {
+			if key.State == KeyStateNext && (id == uuid.Nil || key.ID == id) {
+				next, found = key, true
+				break
+			}
+		}
+		if !found && id == uuid.Nil {
+			for _, key := range keys {
+				if key.State != KeyStateActive || !key.EncryptedPrivateKey.Valid {
+					continue
+				}
+				createdBy := app.CreatedBy
+				if by != nil {
+					createdBy = *by
+				}
+				params, err := s.signingKeyParams(app.ID, key.Algo, "", KeyStateNext, createdBy)
+				if err != nil {
+					return err
+				}
+				next, err = q.CreateSigningKey(ctx, params)
+				if err != nil {
+					return err
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			return ErrNoNextSigningKey
+		}
+
+		// activate first so that there is no moment without an active key
+		now := time.Now()
+		err = q.UpdateSigningKeyState(ctx, repository.UpdateSigningKeyStateParams{
+			ID:          next.ID,
+			State:       KeyStateActive,
+			ActivatedAt: &now,
+			UpdatedBy:   by,
+		})
+		if err != nil {
+			return err
+		}
+		next.State = KeyStateActive
+		next.ActivatedAt = &now
+
+		expiresAt := now.Add(lifetime)
+		for _, key := range keys {
+			if key.State != KeyStateActive || key.ID == next.ID {
+				continue
+			}
+			err := q.UpdateSigningKeyState(ctx, repository.UpdateSigningKeyStateParams{
+				ID:          key.ID,
+				State:       KeyStateRetiring,
+				ActivatedAt: key.ActivatedAt,
+				ExpiresAt:   &expiresAt,
+				UpdatedBy:   by,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return repository.SigningKey{}, err
+	}
+	return next, nil
+}
+
+// maxTokenLifetime is the longest lifetime of a token signed by the keys of
+// an app, the app tokens and those issued for an api resource.
+func (s *Service) maxTokenLifetime(ctx context.Context, config repository.OauthConfig) (time.Duration, error) {
+	lifetime := timex.Duration(config.JwtLifetime).Duration()
+	resources, err := s.repository.ListApiResources(ctx)
+	if err != nil {
+		return lifetime, err
+	}
+	for _, resource := range resources {
+		lifetime = max(lifetime, timex.Duration(resource.TokenLifetime).Duration())
+	}
+	return lifetime, nil
+}
+
+// activeSigningKey is the key new tokens of the app are signed with.
+func (s *Service) activeSigningKey(ctx context.Context, appID uuid.UUID) (jwtutil.SigningKey, error) {
+	key, err := s.repository.FindActiveSigningKey(ctx, appID)
+	if err != nil {
+		return jwtutil.SigningKey{}, err
+	}
+	secret, err := s.keyMaterial(key)
+	if err != nil {
+		return jwtutil.SigningKey{}, err
+	}
+	return jwtutil.SigningKey{
+		ID:       key.Kid,
+		Alg:      key.Algo,
+		Resolver: key.SecretResolver.String,
+		Secret:   secret,
+	}, nil
+}
+
+// signingKeyParams prepares a new key, it is generated and encrypted with the
+// master key when no resolver is given.
+func (s *Service) signingKeyParams(appID uuid.UUID, alg string, secretResolver string, state string, createdBy uuid.UUID) (repository.CreateSigningKeyParams, error) {
+	params := repository.CreateSigningKeyParams{
+		Kid:       uuid.NewString(),
+		Algo:      alg,
+		State:     state,
+		AppID:     appID,
+		CreatedBy: createdBy,
+	}
+	if state == KeyStateActive {
+		now := time.Now()
+		params.ActivatedAt = &now
+	}
+	if secretResolver != "" {
+		params.SecretResolver = pgtype.Text{String: secretResolver, Valid: true}
+		return params, nil
+	}
+
+	masterKey, err := s.masterKey(signingKeysPurpose)
+	if err != nil {
+		return params, err
+	}
+	secret, err := jwtutil.GenerateKey(alg)
+	if err != nil {
+		return params, err
+	}
+	// the kid is authenticated with the key so sealed keys can not be swapped
+	sealed, err := cryptoutil.Seal(masterKey, []byte(secret), []byte(params.Kid))
+	if err != nil {
+		return params, err
+	}
+	params.EncryptedPrivateKey = pgtype.Text{String: sealed, Valid: true}
+	return params, nil
+}
+
+// keyMaterial decrypts a generated key, keys with a resolver are resolved when
+// used and have none.
+func (s *Service) keyMaterial(key repository.SigningKey) (string, error) {
+	if !key.EncryptedPrivateKey.Valid {
+		return "", nil
+	}
+	masterKey, err := s.masterKey(signingKeysPurpose)
+	if err != nil {
+		return "", err
+	}
+	secret, err := cryptoutil.Open(masterKey, key.EncryptedPrivateKey.String, []byte(key.Kid))
+	if err != nil {
+		return "", fmt.Errorf("signing_key_service: could not decrypt key %s: %v", key.Kid, err)
+	}
+	return string(secret), nil
+}
+
+// CheckMasterKey resolves the master key. Besides generated keys and stored
+// secrets, email verification, mfa challenges, passkey ceremonies and email
+// and sms logins all depend on it, the server does not start without it.
+func (s *Service) CheckMasterKey() error {
+	_, err := s.masterKey(signingKeysPurpose)
+	return err
+}
+
+// masterKey lazily resolves the master key, the resolved secret is only used
+// to derive a key encryption key for the given purpose.
+func (s *Service) masterKey(purpose string) ([]byte, error) {
+	s.master.once.Do(func() {
+		source := s.config.Keys.MasterKeyResolver
+		if source == "" {
+			s.master.err = ErrNoMasterKey
+			return
+		}
+		// db:// secrets are themselves sealed with the master key
+		if strings.HasPrefix(source, "db://") {
+			s.master.err = fmt.Errorf("signing_key_service: the master key can not be resolved from the database")
+			return
+		}
+		value, err := resolver.Resolve(source)
+		if err != nil {
+			s.master.err = err
+			return
+		}
+		secret, ok := value.(string)
+		if !ok || secret == "" {
+			s.master.err = ErrNoMasterKey
+			return
+		}
+		s.master.secret = secret
+	})
+	if s.master.err != nil {
+		return nil, s.master.err
+	}
+	return cryptoutil.DeriveKey(s.master.secret, purpose)
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aritradeveops/porichoy/internal/config"
+	"github.com/aritradeveops/porichoy/internal/persistence/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// signingKeyQuerier keeps one app and its keys, InTx puts the keys back
+// when the transaction fails like a rollback would.
+type signingKeyQuerier struct {
+	repository.Querier
+	app  repository.FindAppByClientIDRow
+	keys []repository.SigningKey
+	// UpdateSigningKeyState fails for keys moving to this state
+	failState string
+}
+
+func (q *signingKeyQuerier) InTx(ctx context.Context, fn func(repository.Querier) error) error {
+	keys := slices.Clone(q.keys)
+	err := fn(q)
+	if err != nil {
+		q.keys = keys
+	}
+	return err
+}
+
+func (q *signingKeyQuerier) FindAppByClientID(ctx context.Context, clientID string) (repository.FindAppByClientIDRow, error) {
+	if clientID != q.app.App.ClientID {
+		return repository.FindAppByClientIDRow{}, pgx.ErrNoRows
+	}
+	return q.app, nil
+}
+
+func (q *signingKeyQuerier) ListApps(ctx context.Context) ([]repository.ListAppsRow, error) {
+	return []repository.ListAppsRow{{App: q.app.App, OauthConfig: q.app.OauthConfig}}, nil
+}
+
+func (q *signingKeyQuerier) ListApiResources(ctx context.Context) ([]repository.ApiResource, error) {
+	return nil, nil
+}
+
+func (q *signingKeyQuerier) ListSigningKeysByAppID(ctx context.Context, appID uuid.UUID) ([]repository.SigningKey, error) {
+	return slices.Clone(q.keys), nil
+}
+
+func (q *signingKeyQuerier) ListDueSigningKeys(ctx context.Context) ([]repository.SigningKey, error) {
+	var due []repository.SigningKey
+	for _, key := range q.keys {
+		if key.State == KeyStateNext && key.ActivatesAt != nil && !key.ActivatesAt.After(time.Now()) {
+			due = append(due, key)
+		}
+	}
+	return due, nil
+}
+
+func (q *signingKeyQuerier) CreateSigningKey(ctx context.Context, arg repository.CreateSigningKeyParams) (repository.SigningKey, error) {
+	row := signingKeyRow(arg)
+	q.keys = append(q.keys, row)
+	return row, nil
+}
+
+func (q *signingKeyQuerier) FindSigningKeyByKid(ctx context.Context, kid string) (repository.FindSigningKeyByKidRow, error) {
+	for _, key := range q.keys {
+		if key.Kid == kid {
+			return repository.FindSigningKeyByKidRow{SigningKey: key, App: q.app.App}, nil
+		}
+	}
+	return repository.FindSigningKeyByKidRow{}, pgx.ErrNoRows
+}
+
+func (q *signingKeyQuerier) UpdateSigningKeyState(ctx context.Context, arg repository.UpdateSigningKeyStateParams) error {
+	if arg.State == q.failState {
+		return errors.New("connection reset")
+	}
+	for i, key := range q.keys {
+		if key.ID == arg.ID {
+			q.keys[i].State = arg.State
+			q.keys[i].ActivatedAt = arg.ActivatedAt
+			q.keys[i].ExpiresAt = arg.ExpiresAt
+		}
+	}
+	return nil
+}
+
+func (q *signingKeyQuerier) RevokeExpiredSigningKeys(ctx context.Context) error {
+	for i, key := range q.keys {
+		if key.State == KeyStateRetiring && !key.ExpiresAt.After(time.Now()) {
+			q.keys[i].State = KeyStateRevoked
+		}
+	}
+	return nil
+}
+
+func (q *signingKeyQuerier) state(kid string) string {
+	for _, key := range q.keys {
+		if key.Kid == kid {
+			return key.State
+		}
+	}
+	return ""
+}
+
+func newSigningKeyService(t *testing.T) (*Service, *signingKeyQuerier, string) {
+	owner := uuid.New()
+	querier := &signingKeyQuerier{app: repository.FindAppByClientIDRow{
+		App: repository.App{
+			ID:        uuid.New(),
+			ClientID:  "app.example.com",
+			CreatedBy: owner,
+		},
+		OauthConfig: repository.OauthConfig{
+			JwtAlgo:     "HS256",
+			JwtLifetime: "15m",
+		},
+	}}
+	s := New(&config.Config{
+		Issuer: "https://id.example.com",
+		Keys: config.Keys{
+			MasterKeyResolver: "literal://signing-key-test-master-key",
+		},
+	}, querier, nil, nil)
+	querier.keys = append(querier.keys, testSigningKey(t, s, querier.app.App.ID, KeyStateActive))
+	return s, querier, owner.String()
+}
+
+func TestSigningKeyRotation(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s, querier, owner := newSigningKeyService(t)
+	first := querier.keys[0].Kid
+
+	next, err := s.CreateSigningKey(ctx, owner, "app.example.com", CreateSigningKeyPayload{Algo: "HS256"})
+	assert.NoError(t, err)
+	assert.Equal(t, KeyStateNext, next.State)
+
+	rotated, err := s.RotateSigningKey(ctx, owner, "app.example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, next.Kid, rotated.Kid)
+	assert.Equal(t, KeyStateActive, querier.state(next.Kid))
+	assert.Equal(t, KeyStateRetiring, querier.state(first))
+	// the retiring key verifies until the tokens it signed have expired
+	assert.WithinDuration(t, time.Now().Add(15*time.Minute), *querier.keys[0].ExpiresAt, time.Minute)
+
+	err = s.RevokeSigningKey(ctx, owner, "app.example.com", next.Kid)
+	assert.ErrorIs(t, err, ErrActiveSigningKey)
+	err = s.RevokeSigningKey(ctx, owner, "app.example.com", first)
+	assert.NoError(t, err)
+	assert.Equal(t, KeyStateRevoked, querier.state(first))
+}
+
+func TestScheduledSigningKeyRotation(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s, querier, owner := newSigningKeyService(t)
+	first := querier.keys[0].Kid
+	activatesAt := time.Now().Add(-time.Second)
+	next, err := s.CreateSigningKey(ctx, owner, "app.example.com", CreateSigningKeyPayload{Algo: "HS256", ActivatesAt: &activatesAt})
+	assert.NoError(t, err)
+
+	assert.NoError(t, s.RotateDueSigningKeys(ctx))
+	assert.Equal(t, KeyStateActive, querier.state(next.Kid))
+	assert.Equal(t, KeyStateRetiring, querier.state(first))
+
+	// revoked once its tokens have expired
+	expired := time.Now().Add(-time.Second)
+	querier.keys[0].ExpiresAt = &expired
+	assert.NoError(t, s.RotateDueSigningKeys(ctx))
+	assert.Equal(t, KeyStateRevoked, querier.state(first))
+	assert.Equal(t, KeyStateActive, querier.state(next.Kid))
+}
+
+func TestSigningKeyRotationGeneratesNextKey(t *testing.T) {
+	t.Parallel()
+
+	s, querier, owner := newSigningKeyService(t)
+	rotated, err := s.RotateSigningKey(context.Background(), owner, "app.example.com")
+	assert.NoError(t, err)
+	assert.True(t, rotated.Generated)
+	assert.Len(t, querier.keys, 2)
+	assert.Equal(t, KeyStateRetiring, querier.keys[0].State)
+	assert.Equal(t, KeyStateActive, querier.keys[1].State)
+}
+
+func TestSigningKeyRotationRollback(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s, querier, owner := newSigningKeyService(t)
+	first := querier.keys[0].Kid
+	next, err := s.CreateSigningKey(ctx, owner, "app.example.com", CreateSigningKeyPayload{Algo: "HS256"})
+	assert.NoError(t, err)
+
+	// the next key was activated before retiring the active one failed
+	querier.failState = KeyStateRetiring
+	_, err = s.RotateSigningKey(ctx, owner, "app.example.com")
+	assert.Error(t, err)
+	assert.Equal(t, KeyStateActive, querier.state(first))
+	assert.Equal(t, KeyStateNext, querier.state(next.Kid))
+}
+
+func TestSigningKeyOwnership(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s, _, _ := newSigningKeyService(t)
+	_, err := s.ListSigningKeys(ctx, uuid.NewString(), "app.example.com")
+	assert.ErrorIs(t, err, ErrAppForbidden)
+	_, err = s.RotateSigningKey(ctx, uuid.NewString(), "app.example.com")
+	assert.ErrorIs(t, err, ErrAppForbidden)
+	_, err = s.ListSigningKeys(ctx, uuid.Nil.String(), "unknown.example.com")
+	assert.ErrorIs(t, err, ErrAppNotFound)
+
+	keys, err := s.ListSigningKeys(ctx, uuid.Nil.String(), "app.example.com")
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+}
+
+func TestCheckMasterKey(t *testing.T) {
+	t.Parallel()
+	check := func(resolver string) error {
+		s := New(&config.Config{Keys: config.Keys{MasterKeyResolver: resolver}}, nil, nil, nil)
+		return s.CheckMasterKey()
+	}
+
+	assert.ErrorIs(t, check(""), ErrNoMasterKey)
+	assert.ErrorIs(t, check("literal://"), ErrNoMasterKey)
+	assert.Error(t, check("env://PORICHOY_TEST_MISSING_MASTER_KEY"))
+	// db:// secrets are sealed with the master key themselves
+	assert.Error(t, check("db://master-key"))
+	assert.NoError(t, check("literal://check-test-master-key"))
+}
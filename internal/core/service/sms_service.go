@@ -0,0 +1,354 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aritradeveops/porichoy/internal/core/cryptoutil"
+	"github.com/aritradeveops/porichoy/internal/core/validation"
+	"github.com/aritradeveops/porichoy/internal/persistence/repository"
+	"github.com/aritradeveops/porichoy/internal/pkg/logger"
+	"github.com/aritradeveops/porichoy/internal/pkg/sms"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	smsLoginPurpose = "porichoy sms login"
+	SmsCodeLifetime = 5 * time.Minute
+	smsCodeDigits   = 6
+)
+
+// what a code sent by text message is good for, a code is never accepted
+// for another purpose
+const (
+	smsPurposeVerify = "verify"
+	smsPurposeLogin  = "login"
+	smsPurposeMfa    = "mfa"
+)
+
+var (
+	ErrInvalidSmsCode         = errors.New("sms_service: invalid or expired code")
+	ErrPhoneNumberNotAllowed  = errors.New("sms_service: phone numbers of this country are not allowed")
+	ErrPhoneNumberTaken       = errors.New("sms_service: phone number belongs to another account")
+	ErrPhoneNumberNotVerified = errors.New("sms_service: no verified phone number")
+	ErrSmsMfaNotAllowed       = errors.New("sms_service: a text message can not be the second factor of a login started with one")
+)
+
+type SetPhoneNumberPayload struct {
+	PhoneNumber string `json:"phone_number" validate:"required,e164"`
+	UserIP      string `json:"user_ip" validate:"required"`
+}
+
+type VerifyPhoneNumberPayload struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type CompleteSmsLoginPayload struct {
+	LoginToken string `json:"login_token" validate:"required"`
+	Code       string `json:"code" validate:"required"`
+	ClientID   string `json:"client_id"`
+	UserAgent  string `json:"user_agent" validate:"required"`
+	UserIP     string `json:"user_ip" validate:"required"`
+}
+
+type SendSmsMfaPayload struct {
+	MfaToken string `json:"mfa_token" validate:"required"`
+	UserIP   string `json:"user_ip" validate:"required"`
+}
+
+type VerifySmsMfaPayload struct {
+	MfaToken  string `json:"mfa_token" validate:"required"`
+	Code      string `json:"code" validate:"required"`
+	UserAgent string `json:"user_agent" validate:"required"`
+	UserIP    string `json:"user_ip" validate:"required"`
+}
+
+type smsLoginClaims struct {
+	Subject string `json:"sub"`
+}
+
+// phoneNumberAllowed checks the calling code of a number against the
+// countries the server sends text messages to.
+func (s *Service) phoneNumberAllowed(number string) bool {
+	codes := s.config.SMS.AllowedCountryCodes
+	if len(codes) == 0 {
+		return true
+	}
+	for _, code := range codes {
+		if strings.HasPrefix(number, code) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowSms limits the text messages sent to a number and on behalf of an
+// ip, every message costs money and can be used to harass the owner.
+func (s *Service) allowSms(number string, ip string) bool {
+	return s.smsLimits.ip.Allow(ip) && s.smsLimits.number.Allow(number)
+}
+
+// sendSmsCode texts a new code to number, only the latest code of each
+// purpose works.
+func (s *Service) sendSmsCode(ctx context.Context, user repository.User, number string, purpose string, ip string) error {
+	err := s.repository.RevokeSmsCodes(ctx, repository.RevokeSmsCodesParams{UserID: user.ID, Purpose: purpose})
+	if err != nil {
+		return err
+	}
+	code, err := cryptoutil.GenerateCode(smsCodeDigits)
+	if err != nil {
+		return err
+	}
+	hashedCode, err := cryptoutil.HashSecret(code)
+	if err != nil {
+		return err
+	}
+	err = s.repository.CreateSmsCode(ctx, repository.CreateSmsCodeParams{
+		UserID:      user.ID,
+		PhoneNumber: number,
+		Purpose:     purpose,
+		HashedCode:  hashedCode,
+		RequestedIp: ip,
+		ExpiresAt:   time.Now().Add(SmsCodeLifetime),
+		CreatedBy:   user.ID,
+	})
+	if err != nil {
+		return err
+	}
+	return s.sms.Send(ctx, sms.Message{
+		To:   number,
+		Text: fmt.Sprintf("%s is your %s code, it expires in %s. Do not share it with anyone.", code, s.totpIssuer(), SmsCodeLifetime),
+	})
+}
+
+// checkSmsCode uses up the latest code of a purpose if it matches. Codes
+// are short so each user only gets a few guesses per purpose.
+func (s *Service) checkSmsCode(ctx context.Context, userID uuid.UUID, purpose string, code string) (repository.SmsCode, error) {
+	if !s.smsLimits.code.Allow(userID.String() + ":" + purpose) {
+		return repository.SmsCode{}, ErrTooManyRequests
+	}
+	row, err := s.repository.FindSmsCode(ctx, repository.FindSmsCodeParams{UserID: userID, Purpose: purpose})
+	if err != nil || !cryptoutil.VerifySecret(row.HashedCode, code) {
+		return repository.SmsCode{}, ErrInvalidSmsCode
+	}
+	// consuming is what makes the code single-use
+	row, err = s.repository.ConsumeSmsCode(ctx, row.ID)
+	if err != nil {
+		return repository.SmsCode{}, ErrInvalidSmsCode
+	}
+	return row, nil
+}
+
+// SetPhoneNumber replaces the number of the logged in user and texts it a
+// code, the number is only used once confirmed with VerifyPhoneNumber.
+func (s *Service) SetPhoneNumber(ctx context.Context, initiator string, payload SetPhoneNumberPayload) error {
+	errs := validation.Validate(payload)
+	if errs != nil {
+		return errs
+	}
+	if !s.phoneNumberAllowed(payload.PhoneNumber) {
+		return ErrPhoneNumberNotAllowed
+	}
+	user, err := s.repository.FindUserByID(ctx, uuid.MustParse(initiator))
+	if err != nil {
+		return err
+	}
+	if owner, err := s.repository.FindUserByPhoneNumber(ctx, pgtype.Text{String: payload.PhoneNumber, Valid: true}); err == nil && owner.ID != user.ID {
+		return ErrPhoneNumberTaken
+	}
+	if !s.allowSms(payload.PhoneNumber, payload.UserIP) {
+		return ErrTooManyRequests
+	}
+	err = s.repository.SetUserPhoneNumber(ctx, repository.SetUserPhoneNumberParams{
+		ID:          user.ID,
+		PhoneNumber: pgtype.Text{String: payload.PhoneNumber, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	return s.sendSmsCode(ctx, user, payload.PhoneNumber, smsPurposeVerify, payload.UserIP)
+}
+
+// VerifyPhoneNumber confirms the number of the logged in user with the code
+// it was sent, from then on it can be used to sign in and as a second factor.
+func (s *Service) VerifyPhoneNumber(ctx context.Context, initiator string, payload VerifyPhoneNumberPayload) error {
+	errs := validation.Validate(payload)
+	if errs != nil {
+		return errs
+	}
+	user, err := s.repository.FindUserByID(ctx, uuid.MustParse(initiator))
+	if err != nil {
+		return err
+	}
+	code, err := s.checkSmsCode(ctx, user.ID, smsPurposeVerify, payload.Code)
+	if err != nil {
+		return err
+	}
+	// the number was changed after the code was sent
+	if !user.PhoneNumber.Valid || user.PhoneNumber.String != code.PhoneNumber {
+		return ErrInvalidSmsCode
+	}
+	if owner, err := s.repository.FindUserByPhoneNumber(ctx, user.PhoneNumber); err == nil && owner.ID != user.ID {
+		return ErrPhoneNumberTaken
+	}
+	return s.repository.MarkPhoneVerified(ctx, repository.MarkPhoneVerifiedParams{
+		ID:          user.ID,
+		PhoneNumber: user.PhoneNumber,
+	})
+}
+
+// RemovePhoneNumber stops the number of the logged in user from being used
+// to sign in or as a second factor.
+func (s *Service) RemovePhoneNumber(ctx context.Context, initiator string) error {
+	return s.repository.SetUserPhoneNumber(ctx, repository.SetUserPhoneNumberParams{
+		ID: uuid.MustParse(initiator),
+	})
+}
+
+// startSmsLogin texts a code to the verified number of a user. Like
+// startEmailLogin it answers the same for numbers without an account.
+func (s *Service) startSmsLogin(ctx context.Context, payload LoginUserPayload) (LoginUserResponse, error) {
+	var response LoginUserResponse
+	if !s.phoneNumberAllowed(payload.PhoneNumber) {
+		return response, ErrPhoneNumberNotAllowed
+	}
+	if !s.allowSms(payload.PhoneNumber, payload.UserIP) {
+		return response, ErrTooManyRequests
+	}
+	key, err := s.masterKey(smsLoginPurpose)
+	if err != nil {
+		return response, err
+	}
+
+	// unknown numbers get a token for nobody, it never matches a code
+	subject := uuid.New()
+	user, err := s.repository.FindUserByPhoneNumber(ctx, pgtype.Text{String: payload.PhoneNumber, Valid: true})
+	known := err == nil && user.DeactivatedAt == nil
+	if known {
+		subject = user.ID
+	}
+	response.LoginToken, err = cryptoutil.SignToken(key, smsLoginClaims{Subject: subject.String()}, SmsCodeLifetime)
+	if err != nil || !known {
+		return response, err
+	}
+	err = s.sendSmsCode(ctx, user, payload.PhoneNumber, smsPurposeLogin, payload.UserIP)
+	if err != nil {
+		logger.Error().Err(err).Str("user_id", user.ID.String()).Msg("failed to send the login code")
+	}
+	return response, nil
+}
+
+// CompleteSmsLogin signs in the user an sms login was started for.
+func (s *Service) CompleteSmsLogin(ctx context.Context, payload CompleteSmsLoginPayload) (LoginUserResponse, error) {
+	var response LoginUserResponse
+	errs := validation.Validate(payload)
+	if errs != nil {
+		return response, errs
+	}
+	if !s.loginMethodEnabled(LoginMethodSms) {
+		return response, ErrInvalidLoginMethod
+	}
+	key, err := s.masterKey(smsLoginPurpose)
+	if err != nil {
+		return response, err
+	}
+	var claims smsLoginClaims
+	if err := cryptoutil.VerifyToken(key, payload.LoginToken, &claims); err != nil {
+		return response, ErrInvalidSmsCode
+	}
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return response, ErrInvalidSmsCode
+	}
+	code, err := s.checkSmsCode(ctx, userID, smsPurposeLogin, payload.Code)
+	if err != nil {
+		return response, err
+	}
+	user, err := s.repository.FindUserByID(ctx, userID)
+	if err != nil {
+		return response, ErrInvalidSmsCode
+	}
+	if user.DeactivatedAt != nil {
+		return response, ErrDeactivatedUser
+	}
+	if user.PhoneVerifiedAt == nil || user.PhoneNumber.String != code.PhoneNumber {
+		return response, ErrInvalidSmsCode
+	}
+	rootApp, err := s.repository.FindRootApp(ctx)
+	if err != nil {
+		return response, err
+	}
+	if err := requireVerifiedEmail(rootApp.OauthConfig, user); err != nil {
+		return response, err
+	}
+	return s.completeLogin(ctx, rootApp, user, payload.ClientID, []string{AmrSms}, payload.UserIP, payload.UserAgent)
+}
+
+// smsMfaAllowed checks that a text message can be the second factor of a
+// login, it can not be when the login started with one as both codes
+// prove the same phone.
+func smsMfaAllowed(user repository.User, claims mfaChallengeClaims) error {
+	if claims.Enroll || user.PhoneVerifiedAt == nil {
+		return ErrPhoneNumberNotVerified
+	}
+	if slices.Contains(claims.Amr, AmrSms) {
+		return ErrSmsMfaNotAllowed
+	}
+	return nil
+}
+
+// SendSmsMfa texts a code to the verified number of a user in the second
+// step of a login.
+func (s *Service) SendSmsMfa(ctx context.Context, payload SendSmsMfaPayload) error {
+	errs := validation.Validate(payload)
+	if errs != nil {
+		return errs
+	}
+	user, claims, err := s.verifyMfaChallenge(ctx, payload.MfaToken)
+	if err != nil {
+		return err
+	}
+	if err := smsMfaAllowed(user, claims); err != nil {
+		return err
+	}
+	if !s.allowSms(user.PhoneNumber.String, payload.UserIP) {
+		return ErrTooManyRequests
+	}
+	return s.sendSmsCode(ctx, user, user.PhoneNumber.String, smsPurposeMfa, payload.UserIP)
+}
+
+// VerifySmsMfa completes a login with the code SendSmsMfa texted.
+func (s *Service) VerifySmsMfa(ctx context.Context, payload VerifySmsMfaPayload) (LoginUserResponse, error) {
+	var response LoginUserResponse
+	errs := validation.Validate(payload)
+	if errs != nil {
+		return response, errs
+	}
+	user, claims, err := s.verifyMfaChallenge(ctx, payload.MfaToken)
+	if err != nil {
+		return response, err
+	}
+	if err := smsMfaAllowed(user, claims); err != nil {
+		return response, err
+	}
+	if !s.mfaLimits.Allow(user.ID.String()) {
+		return response, ErrTooManyRequests
+	}
+	code, err := s.checkSmsCode(ctx, user.ID, smsPurposeMfa, payload.Code)
+	if err != nil {
+		return response, err
+	}
+	if user.PhoneNumber.String != code.PhoneNumber {
+		return response, ErrInvalidSmsCode
+	}
+	rootApp, err := s.repository.FindRootApp(ctx)
+	if err != nil {
+		return response, err
+	}
+	return s.createLoginSession(ctx, rootApp, user, claims.amr(AmrSms), payload.UserIP, payload.UserAgent)
+}
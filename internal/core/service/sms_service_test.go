@@ -0,0 +1,342 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/aritradeveops/porichoy/internal/config"
+	"github.com/aritradeveops/porichoy/internal/persistence/repository"
+	"github.com/aritradeveops/porichoy/internal/pkg/sms"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+// smsQuerier keeps the users and codes the text message flows touch,
+// anything else panics on the nil Querier.
+type smsQuerier struct {
+	repository.Querier
+	users    map[uuid.UUID]repository.User
+	codes    []repository.SmsCode
+	rootApp  repository.FindRootAppRow
+	key      repository.SigningKey
+	totp     bool
+	sessions []repository.CreateSessionParams
+}
+
+func (q *smsQuerier) FindRootApp(ctx context.Context) (repository.FindRootAppRow, error) {
+	return q.rootApp, nil
+}
+
+func (q *smsQuerier) FindActiveSigningKey(ctx context.Context, appID uuid.UUID) (repository.SigningKey, error) {
+	return q.key, nil
+}
+
+func (q *smsQuerier) FindTotpFactor(ctx context.Context, userID uuid.UUID) (repository.TotpFactor, error) {
+	if !q.totp {
+		return repository.TotpFactor{}, pgx.ErrNoRows
+	}
+	now := time.Now()
+	return repository.TotpFactor{UserID: userID, ConfirmedAt: &now}, nil
+}
+
+func (q *smsQuerier) ListWebauthnCredentials(ctx context.Context, userID uuid.UUID) ([]repository.WebauthnCredential, error) {
+	return nil, nil
+}
+
+func (q *smsQuerier) CreateSession(ctx context.Context, arg repository.CreateSessionParams) error {
+	q.sessions = append(q.sessions, arg)
+	return nil
+}
+
+func (q *smsQuerier) FindUserByID(ctx context.Context, id uuid.UUID) (repository.User, error) {
+	user, ok := q.users[id]
+	if !ok {
+		return user, errors.New("no rows in result set")
+	}
+	return user, nil
+}
+
+func (q *smsQuerier) FindUserByPhoneNumber(ctx context.Context, phoneNumber pgtype.Text) (repository.User, error) {
+	for _, user := range q.users {
+		if user.PhoneVerifiedAt != nil && user.PhoneNumber == phoneNumber {
+			return user, nil
+		}
+	}
+	return repository.User{}, errors.New("no rows in result set")
+}
+
+func (q *smsQuerier) SetUserPhoneNumber(ctx context.Context, arg repository.SetUserPhoneNumberParams) error {
+	user := q.users[arg.ID]
+	user.PhoneNumber = arg.PhoneNumber
+	user.PhoneVerifiedAt = nil
+	q.users[arg.ID] = user
+	return nil
+}
+
+func (q *smsQuerier) MarkPhoneVerified(ctx context.Context, arg repository.MarkPhoneVerifiedParams) error {
+	user := q.users[arg.ID]
+	if user.PhoneNumber == arg.PhoneNumber {
+		now := time.Now()
+		user.PhoneVerifiedAt = &now
+		q.users[arg.ID] = user
+	}
+	return nil
+}
+
+func (q *smsQuerier) RevokeSmsCodes(ctx context.Context, arg repository.RevokeSmsCodesParams) error {
+	for i, code := range q.codes {
+		if code.UserID == arg.UserID && code.Purpose == arg.Purpose {
+			q.codes[i].ExpiresAt = time.Now()
+		}
+	}
+	return nil
+}
+
+func (q *smsQuerier) CreateSmsCode(ctx context.Context, arg repository.CreateSmsCodeParams) error {
+	q.codes = append(q.codes, repository.SmsCode{
+		ID:          uuid.New(),
+		UserID:      arg.UserID,
+		PhoneNumber: arg.PhoneNumber,
+		Purpose:     arg.Purpose,
+		HashedCode:  arg.HashedCode,
+		RequestedIp: arg.RequestedIp,
+		ExpiresAt:   arg.ExpiresAt,
+		CreatedAt:   time.Now(),
+		CreatedBy:   arg.CreatedBy,
+	})
+	return nil
+}
+
+func (q *smsQuerier) FindSmsCode(ctx context.Context, arg repository.FindSmsCodeParams) (repository.SmsCode, error) {
+	for i := len(q.codes) - 1; i >= 0; i-- {
+		code := q.codes[i]
+		if code.UserID == arg.UserID && code.Purpose == arg.Purpose && code.UsedAt == nil && code.ExpiresAt.After(time.Now()) {
+			return code, nil
+		}
+	}
+	return repository.SmsCode{}, errors.New("no rows in result set")
+}
+
+func (q *smsQuerier) ConsumeSmsCode(ctx context.Context, id uuid.UUID) (repository.SmsCode, error) {
+	for i, code := range q.codes {
+		if code.ID == id && code.UsedAt == nil {
+			now := time.Now()
+			q.codes[i].UsedAt = &now
+			return q.codes[i], nil
+		}
+	}
+	return repository.SmsCode{}, errors.New("no rows in result set")
+}
+
+type recordingSms struct {
+	messages []sms.Message
+}
+
+func (p *recordingSms) Send(ctx context.Context, message sms.Message) error {
+	p.messages = append(p.messages, message)
+	return nil
+}
+
+// lastCode reads the code out of the latest text message.
+func (p *recordingSms) lastCode() string {
+	return regexp.MustCompile(`^[0-9]{6}`).FindString(p.messages[len(p.messages)-1].Text)
+}
+
+func newSmsService(t *testing.T, countryCodes ...string) (*Service, *smsQuerier, *recordingSms, repository.User) {
+	user := repository.User{
+		ID:    uuid.New(),
+		Email: "jane@example.com",
+		Name:  "Jane",
+	}
+	querier := &smsQuerier{users: map[uuid.UUID]repository.User{user.ID: user}}
+	provider := &recordingSms{}
+	s := New(&config.Config{
+		Issuer: "https://id.example.com",
+		Keys: config.Keys{
+			MasterKeyResolver: "literal://sms-test-master-key",
+		},
+		SMS: config.SMS{AllowedCountryCodes: countryCodes},
+	}, querier, nil, provider)
+	querier.rootApp, querier.key = testRootApp(t, s)
+	return s, querier, provider, user
+}
+
+// withVerifiedPhone gives the user of newSmsService a verified number.
+func withVerifiedPhone(querier *smsQuerier, user repository.User) repository.User {
+	now := time.Now()
+	user.PhoneNumber = pgtype.Text{String: "+8801712345678", Valid: true}
+	user.PhoneVerifiedAt = &now
+	querier.users[user.ID] = user
+	return user
+}
+
+func startSmsLogin(t *testing.T, s *Service, number string) string {
+	response, err := s.LoginUser(context.Background(), LoginUserPayload{
+		Method:      LoginMethodSms,
+		PhoneNumber: number,
+		UserAgent:   "test",
+		UserIP:      "192.0.2.1",
+		Host:        "id.example.com",
+	})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, response.LoginToken)
+	assert.Empty(t, response.AccessToken)
+	return response.LoginToken
+}
+
+func TestVerifyPhoneNumber(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s, querier, provider, user := newSmsService(t)
+	err := s.SetPhoneNumber(ctx, user.ID.String(), SetPhoneNumberPayload{PhoneNumber: "+8801712345678", UserIP: "192.0.2.1"})
+	assert.NoError(t, err)
+	assert.Len(t, provider.messages, 1)
+	assert.Equal(t, "+8801712345678", provider.messages[0].To)
+
+	err = s.VerifyPhoneNumber(ctx, user.ID.String(), VerifyPhoneNumberPayload{Code: "not-it"})
+	assert.ErrorIs(t, err, ErrInvalidSmsCode)
+	assert.Nil(t, querier.users[user.ID].PhoneVerifiedAt)
+
+	err = s.VerifyPhoneNumber(ctx, user.ID.String(), VerifyPhoneNumberPayload{Code: provider.lastCode()})
+	assert.NoError(t, err)
+	assert.NotNil(t, querier.users[user.ID].PhoneVerifiedAt)
+
+	// codes are single-use
+	err = s.VerifyPhoneNumber(ctx, user.ID.String(), VerifyPhoneNumberPayload{Code: provider.lastCode()})
+	assert.ErrorIs(t, err, ErrInvalidSmsCode)
+}
+
+func TestPhoneNumberCountryAllowList(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s, _, provider, user := newSmsService(t, "+880")
+	err := s.SetPhoneNumber(ctx, user.ID.String(), SetPhoneNumberPayload{PhoneNumber: "+14155550100", UserIP: "192.0.2.1"})
+	assert.ErrorIs(t, err, ErrPhoneNumberNotAllowed)
+	assert.Empty(t, provider.messages)
+
+	err = s.SetPhoneNumber(ctx, user.ID.String(), SetPhoneNumberPayload{PhoneNumber: "+8801712345678", UserIP: "192.0.2.1"})
+	assert.NoError(t, err)
+}
+
+func TestSmsLogin(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s, querier, provider, user := newSmsService(t)
+	user = withVerifiedPhone(querier, user)
+	loginToken := startSmsLogin(t, s, "+8801712345678")
+	assert.Len(t, provider.messages, 1)
+	code := provider.lastCode()
+
+	payload := CompleteSmsLoginPayload{LoginToken: loginToken, Code: "000000", UserAgent: "test", UserIP: "192.0.2.1"}
+	_, err := s.CompleteSmsLogin(ctx, payload)
+	assert.ErrorIs(t, err, ErrInvalidSmsCode)
+
+	payload.Code = code
+	response, err := s.CompleteSmsLogin(ctx, payload)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, response.AccessToken)
+	assert.Len(t, querier.sessions, 1)
+	assert.Equal(t, user.ID, querier.sessions[0].UserID)
+
+	// the code was used up with the login
+	_, err = s.CompleteSmsLogin(ctx, payload)
+	assert.ErrorIs(t, err, ErrInvalidSmsCode)
+}
+
+func TestSmsLoginUnknownNumber(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s, querier, provider, user := newSmsService(t)
+	withVerifiedPhone(querier, user)
+	// answers like for an account so numbers can not be probed
+	loginToken := startSmsLogin(t, s, "+8801812345678")
+	assert.Empty(t, provider.messages)
+
+	// a code texted to another account does not complete this login
+	startSmsLogin(t, s, "+8801712345678")
+	_, err := s.CompleteSmsLogin(ctx, CompleteSmsLoginPayload{
+		LoginToken: loginToken,
+		Code:       provider.lastCode(),
+		UserAgent:  "test",
+		UserIP:     "192.0.2.1",
+	})
+	assert.ErrorIs(t, err, ErrInvalidSmsCode)
+	assert.Empty(t, querier.sessions)
+}
+
+func TestSmsMfa(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s, querier, provider, user := newSmsService(t)
+	user = withVerifiedPhone(querier, user)
+	challenge, err := s.mfaChallenge(user, MfaStepVerify, []string{MfaMethodSms}, []string{AmrPassword})
+	assert.NoError(t, err)
+
+	err = s.SendSmsMfa(ctx, SendSmsMfaPayload{MfaToken: challenge.MfaToken, UserIP: "192.0.2.1"})
+	assert.NoError(t, err)
+	response, err := s.VerifySmsMfa(ctx, VerifySmsMfaPayload{
+		MfaToken:  challenge.MfaToken,
+		Code:      provider.lastCode(),
+		UserAgent: "test",
+		UserIP:    "192.0.2.1",
+	})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, response.AccessToken)
+	assert.Len(t, querier.sessions, 1)
+}
+
+func TestSmsMfaAfterSmsLogin(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s, querier, provider, user := newSmsService(t)
+	withVerifiedPhone(querier, user)
+	querier.totp = true
+	loginToken := startSmsLogin(t, s, "+8801712345678")
+	response, err := s.CompleteSmsLogin(ctx, CompleteSmsLoginPayload{
+		LoginToken: loginToken,
+		Code:       provider.lastCode(),
+		UserAgent:  "test",
+		UserIP:     "192.0.2.1",
+	})
+	assert.NoError(t, err)
+	assert.True(t, response.MfaRequired)
+	assert.Equal(t, []string{MfaMethodTotp}, response.MfaMethods)
+
+	// the phone that passed the first step can not pass the second one
+	err = s.SendSmsMfa(ctx, SendSmsMfaPayload{MfaToken: response.MfaToken, UserIP: "192.0.2.1"})
+	assert.ErrorIs(t, err, ErrSmsMfaNotAllowed)
+	assert.Len(t, provider.messages, 1)
+	_, err = s.VerifySmsMfa(ctx, VerifySmsMfaPayload{
+		MfaToken:  response.MfaToken,
+		Code:      "000000",
+		UserAgent: "test",
+		UserIP:    "192.0.2.1",
+	})
+	assert.ErrorIs(t, err, ErrSmsMfaNotAllowed)
+	assert.Empty(t, querier.sessions)
+}
+
+func TestSmsRateLimit(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s, _, provider, user := newSmsService(t)
+	payload := SetPhoneNumberPayload{PhoneNumber: "+8801712345678", UserIP: "192.0.2.1"}
+	var err error
+	for range 4 {
+		err = s.SetPhoneNumber(ctx, user.ID.String(), payload)
+	}
+	assert.ErrorIs(t, err, ErrTooManyRequests)
+	assert.Len(t, provider.messages, 3)
+}
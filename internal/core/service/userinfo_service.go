@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/aritradeveops/porichoy/internal/core/cryptoutil"
+	"github.com/aritradeveops/porichoy/internal/core/jwtutil"
+	"github.com/aritradeveops/porichoy/internal/persistence/repository"
+	"github.com/google/uuid"
+)
+
+// OpenID Connect scopes, the profile and email scopes select the claims
+// about the end user that are released to the client
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+)
+
+var (
+	ErrInvalidAccessToken = errors.New("userinfo_service: invalid access token")
+)
+
+type UserinfoResponse struct {
+	Info jwtutil.UserInfo
+	// the signed and encrypted response, set when the client registered an
+	// encryption key
+	JWT string
+}
+
+// Userinfo returns the claims about the owner of the access token. Tokens
+// bound to a certificate are only accepted along with that certificate.
+func (s *Service) Userinfo(ctx context.Context, accessToken string, certs []*x509.Certificate) (UserinfoResponse, error) {
+	var resp UserinfoResponse
+	payload, err := jwtutil.Verify(ctx, accessToken, s.KeyStore())
+	if err != nil {
+		return resp, ErrInvalidAccessToken
+	}
+	if payload.Cnf != nil && payload.Cnf.X5tS256 != "" {
+		if len(certs) == 0 || cryptoutil.CertificateThumbprint(certs[0]) != payload.Cnf.X5tS256 {
+			return resp, ErrInvalidAccessToken
+		}
+	}
+	userID, err := uuid.Parse(payload.UserID)
+	if err != nil {
+		return resp, ErrInvalidAccessToken
+	}
+	user, err := s.repository.FindUserByID(ctx, userID)
+	if err != nil || user.DeactivatedAt != nil {
+		return resp, ErrInvalidAccessToken
+	}
+	resp.Info = jwtutil.UserInfo{
+		Subject: user.ID.String(),
+		Profile: userProfile(user, strings.Fields(payload.Scope)),
+	}
+
+	app, err := s.repository.FindAppByClientID(ctx, payload.ClientID)
+	if err != nil || !app.OauthConfig.EncryptionJwk.Valid {
+		return resp, nil
+	}
+	key, err := s.activeSigningKey(ctx, app.App.ID)
+	if err != nil {
+		return resp, err
+	}
+	signed, err := jwtutil.SignUserInfo(key, resp.Info, app.App.ClientID, s.config.IssuerURL())
+	if err != nil {
+		return resp, err
+	}
+	resp.JWT, err = encryptForClient(app.OauthConfig, signed)
+	return resp, err
+}
+
+// userProfile releases the claims the scopes ask for, tokens issued without
+// the openid scope predate it and get all of them.
+func userProfile(user repository.User, scopes []string) jwtutil.Profile {
+	all := !slices.Contains(scopes, ScopeOpenID)
+	var profile jwtutil.Profile
+	if all || slices.Contains(scopes, ScopeProfile) {
+		profile.Name = user.Name
+		profile.Picture = user.Dp.String
+	}
+	if all || slices.Contains(scopes, ScopeEmail) {
+		profile.Email = user.Email
+		profile.EmailVerified = emailVerified(user)
+	}
+	return profile
+}
+
+// encryptForClient encrypts the signed token with the key the client
+// registered, it is returned as is when there is none.
+func encryptForClient(config repository.OauthConfig, signed string) (string, error) {
+	if !config.EncryptionJwk.Valid {
+		return signed, nil
+	}
+	enc := config.EncryptionEnc.String
+	if enc == "" {
+		enc = jwtutil.EncryptionEncA256GCM
+	}
+	return jwtutil.Encrypt(signed, config.EncryptionJwk.String, config.EncryptionAlg.String, enc)
+}
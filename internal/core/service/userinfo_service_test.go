@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aritradeveops/porichoy/internal/config"
+	"github.com/aritradeveops/porichoy/internal/core/jwtutil"
+	"github.com/aritradeveops/porichoy/internal/persistence/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// userinfoQuerier keeps the root app, one other app and their keys along
+// with the users the tokens are about.
+type userinfoQuerier struct {
+	repository.Querier
+	root  repository.FindRootAppRow
+	app   repository.App
+	keys  []repository.SigningKey
+	users []repository.User
+}
+
+func (q *userinfoQuerier) FindRootApp(ctx context.Context) (repository.FindRootAppRow, error) {
+	return q.root, nil
+}
+
+func (q *userinfoQuerier) FindSigningKeyByKid(ctx context.Context, kid string) (repository.FindSigningKeyByKidRow, error) {
+	for _, key := range q.keys {
+		if key.Kid != kid {
+			continue
+		}
+		app := q.app
+		if key.AppID == q.root.App.ID {
+			app = q.root.App
+		}
+		return repository.FindSigningKeyByKidRow{SigningKey: key, App: app}, nil
+	}
+	return repository.FindSigningKeyByKidRow{}, pgx.ErrNoRows
+}
+
+func (q *userinfoQuerier) ListApiResources(ctx context.Context) ([]repository.ApiResource, error) {
+	return nil, nil
+}
+
+func (q *userinfoQuerier) FindUserByID(ctx context.Context, id uuid.UUID) (repository.User, error) {
+	for _, user := range q.users {
+		if user.ID == id {
+			return user, nil
+		}
+	}
+	return repository.User{}, pgx.ErrNoRows
+}
+
+func (q *userinfoQuerier) FindAppByClientID(ctx context.Context, clientID string) (repository.FindAppByClientIDRow, error) {
+	return repository.FindAppByClientIDRow{}, pgx.ErrNoRows
+}
+
+func newUserinfoService(t *testing.T) (*Service, *userinfoQuerier) {
+	querier := &userinfoQuerier{
+		app: repository.App{
+			ID:       uuid.New(),
+			Domain:   "app.example.com",
+			ClientID: "app.example.com",
+		},
+		users: []repository.User{{
+			ID:    uuid.New(),
+			Email: "jane@example.com",
+			Name:  "Jane",
+		}},
+	}
+	s := New(&config.Config{
+		Issuer: "https://id.example.com",
+		Keys: config.Keys{
+			MasterKeyResolver: "literal://userinfo-test-master-key",
+		},
+	}, querier, nil, nil)
+	var rootKey repository.SigningKey
+	querier.root, rootKey = testRootApp(t, s)
+	querier.keys = append(querier.keys, rootKey)
+	return s, querier
+}
+
+// appKey stores a key of the other app, generated when resolver is empty.
+func appKey(t *testing.T, s *Service, querier *userinfoQuerier, alg string, resolver string) repository.SigningKey {
+	params, err := s.signingKeyParams(querier.app.ID, alg, resolver, KeyStateActive, uuid.Nil)
+	assert.NoError(t, err)
+	key := signingKeyRow(params)
+	querier.keys = append(querier.keys, key)
+	return key
+}
+
+func signUserinfoToken(t *testing.T, s *Service, key repository.SigningKey, aud string, userID uuid.UUID) string {
+	secret, err := s.keyMaterial(key)
+	assert.NoError(t, err)
+	token, err := jwtutil.Sign(jwtutil.SigningKey{
+		ID:       key.Kid,
+		Alg:      key.Algo,
+		Resolver: key.SecretResolver.String,
+		Secret:   secret,
+	}, jwtutil.JwtPayload{
+		UserID:   userID.String(),
+		ClientID: aud,
+		Scope:    "openid email",
+	}, aud, s.config.IssuerURL(), time.Minute)
+	assert.NoError(t, err)
+	return token
+}
+
+func TestUserinfo(t *testing.T) {
+	t.Parallel()
+	s, querier := newUserinfoService(t)
+	user := querier.users[0]
+
+	for _, key := range []repository.SigningKey{
+		querier.keys[0],
+		appKey(t, s, querier, "ES256", ""),
+	} {
+		aud := querier.app.Domain
+		if key.AppID == querier.root.App.ID {
+			aud = querier.root.App.Domain
+		}
+		resp, err := s.Userinfo(context.Background(), signUserinfoToken(t, s, key, aud, user.ID), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, user.ID.String(), resp.Info.Subject)
+		assert.Equal(t, "jane@example.com", resp.Info.Email)
+	}
+}
+
+// an app owner knows the secrets of its HMAC keys and the keys it resolves
+// itself, it must not be able to sign tokens for someone else's profile
+func TestUserinfoRefusesKeysKnownToAppOwners(t *testing.T) {
+	t.Parallel()
+	s, querier := newUserinfoService(t)
+	victim := querier.users[0]
+
+	for name, key := range map[string]repository.SigningKey{
+		"generated HS256": appKey(t, s, querier, "HS256", ""),
+		"resolved HS256":  appKey(t, s, querier, "HS256", "literal://owner-chosen-secret"),
+	} {
+		_, err := s.Userinfo(context.Background(), signUserinfoToken(t, s, key, querier.app.Domain, victim.ID), nil)
+		assert.ErrorIs(t, err, ErrInvalidAccessToken, name)
+	}
+}
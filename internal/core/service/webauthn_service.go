@@ -0,0 +1,541 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/aritradeveops/porichoy/internal/core/cryptoutil"
+	"github.com/aritradeveops/porichoy/internal/core/validation"
+	"github.com/aritradeveops/porichoy/internal/persistence/repository"
+	"github.com/aritradeveops/porichoy/internal/pkg/logger"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+)
+
+const (
+	webauthnCeremonyPurpose  = "porichoy webauthn ceremony"
+	WebauthnCeremonyLifetime = 5 * time.Minute
+)
+
+// the ceremonies a signed session can be used for, a session of one is
+// never accepted by another
+const (
+	ceremonyRegistration = "registration"
+	ceremonyLogin        = "login"
+	ceremonyMfa          = "mfa"
+)
+
+var (
+	ErrInvalidPasskey  = errors.New("webauthn_service: passkey could not be verified")
+	ErrPasskeyCloned   = errors.New("webauthn_service: passkey signature counter went backwards, it may have been cloned")
+	ErrPasskeyNotFound = errors.New("webauthn_service: passkey not found")
+)
+
+// PasskeyCeremony is passed to navigator.credentials, Session has to be sent
+// back with the response of the authenticator.
+type PasskeyCeremony struct {
+	Options any    `json:"options"`
+	Session string `json:"session"`
+}
+
+type FinishPasskeyRegistrationPayload struct {
+	Session    string          `json:"session" validate:"required"`
+	Name       string          `json:"name" validate:"required,max=255"`
+	Credential json.RawMessage `json:"credential" validate:"required"`
+}
+
+type FinishPasskeyLoginPayload struct {
+	Session    string          `json:"session" validate:"required"`
+	Credential json.RawMessage `json:"credential" validate:"required"`
+	UserAgent  string          `json:"user_agent" validate:"required"`
+	UserIP     string          `json:"user_ip" validate:"required"`
+}
+
+type BeginPasskeyMfaPayload struct {
+	MfaToken string `json:"mfa_token" validate:"required"`
+}
+
+type FinishPasskeyMfaPayload struct {
+	MfaToken   string          `json:"mfa_token" validate:"required"`
+	Session    string          `json:"session" validate:"required"`
+	Credential json.RawMessage `json:"credential" validate:"required"`
+	UserAgent  string          `json:"user_agent" validate:"required"`
+	UserIP     string          `json:"user_ip" validate:"required"`
+}
+
+// Passkey is what the user gets to see of a registered credential.
+type Passkey struct {
+	ID           uuid.UUID  `json:"id"`
+	Name         string     `json:"name"`
+	Synced       bool       `json:"synced"`
+	CloneWarning bool       `json:"clone_warning"`
+	CreatedAt    time.Time  `json:"created_at"`
+	LastUsedAt   *time.Time `json:"last_used_at"`
+}
+
+type ceremonyClaims struct {
+	Kind    string               `json:"kind"`
+	Session webauthn.SessionData `json:"session"`
+}
+
+// webauthnUser adapts a user and their credentials to webauthn.User, the
+// user handle is the user id.
+type webauthnUser struct {
+	user        repository.User
+	credentials []repository.WebauthnCredential
+}
+
+func (u webauthnUser) WebAuthnID() []byte {
+	return u.user.ID[:]
+}
+
+func (u webauthnUser) WebAuthnName() string {
+	return u.user.Email
+}
+
+func (u webauthnUser) WebAuthnDisplayName() string {
+	return u.user.Name
+}
+
+func (u webauthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(u.credentials))
+	for _, row := range u.credentials {
+		id, err := base64.RawURLEncoding.DecodeString(row.CredentialID)
+		if err != nil {
+			continue
+		}
+		transports := make([]protocol.AuthenticatorTransport, len(row.Transports))
+		for i, transport := range row.Transports {
+			transports[i] = protocol.AuthenticatorTransport(transport)
+		}
+		credentials = append(credentials, webauthn.Credential{
+			ID:              id,
+			PublicKey:       row.PublicKey,
+			AttestationType: row.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				BackupEligible: row.BackupEligible,
+				BackupState:    row.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:       row.Aaguid,
+				SignCount:    uint32(row.SignCount),
+				CloneWarning: row.CloneWarning,
+			},
+		})
+	}
+	return credentials
+}
+
+// credential returns the row of a credential verified by the library.
+func (u webauthnUser) credential(id []byte) (repository.WebauthnCredential, bool) {
+	encoded := base64.RawURLEncoding.EncodeToString(id)
+	for _, row := range u.credentials {
+		if row.CredentialID == encoded {
+			return row, true
+		}
+	}
+	return repository.WebauthnCredential{}, false
+}
+
+// relyingParty is created on first use, the configured relying party or
+// one for the host of the issuer.
+func (s *Service) relyingParty() (*webauthn.WebAuthn, error) {
+	s.webauthn.once.Do(func() {
+		config := s.config.WebAuthn
+		issuer, err := url.Parse(s.config.IssuerURL())
+		if err != nil {
+			s.webauthn.err = err
+			return
+		}
+		if config.RPID == "" {
+			config.RPID = issuer.Hostname()
+		}
+		if config.RPDisplayName == "" {
+			config.RPDisplayName = "Porichoy"
+		}
+		if len(config.Origins) == 0 {
+			config.Origins = []string{issuer.Scheme + "://" + issuer.Host}
+		}
+		s.webauthn.rp, s.webauthn.err = webauthn.New(&webauthn.Config{
+			RPID:          config.RPID,
+			RPDisplayName: config.RPDisplayName,
+			RPOrigins:     config.Origins,
+		})
+	})
+	return s.webauthn.rp, s.webauthn.err
+}
+
+// signCeremony keeps the state of a ceremony with the client instead of
+// the server, signed so it can not be altered.
+func (s *Service) signCeremony(kind string, session *webauthn.SessionData) (string, error) {
+	key, err := s.masterKey(webauthnCeremonyPurpose)
+	if err != nil {
+		return "", err
+	}
+	return cryptoutil.SignToken(key, ceremonyClaims{Kind: kind, Session: *session}, WebauthnCeremonyLifetime)
+}
+
+// verifyCeremony accepts a session only once, its challenge is recorded as
+// used until the session would have expired anyway.
+func (s *Service) verifyCeremony(ctx context.Context, kind string, token string) (webauthn.SessionData, error) {
+	key, err := s.masterKey(webauthnCeremonyPurpose)
+	if err != nil {
+		return webauthn.SessionData{}, err
+	}
+	var claims ceremonyClaims
+	if err := cryptoutil.VerifyToken(key, token, &claims); err != nil || claims.Kind != kind {
+		return webauthn.SessionData{}, ErrInvalidPasskey
+	}
+	used, err := s.repository.UseWebauthnChallenge(ctx, repository.UseWebauthnChallengeParams{
+		Challenge: claims.Session.Challenge,
+		ExpiresAt: time.Now().Add(WebauthnCeremonyLifetime),
+	})
+	if err != nil {
+		return webauthn.SessionData{}, err
+	}
+	if used == 0 {
+		return webauthn.SessionData{}, ErrInvalidPasskey
+	}
+	return claims.Session, nil
+}
+
+func (s *Service) webauthnUser(ctx context.Context, user repository.User) (webauthnUser, error) {
+	credentials, err := s.repository.ListWebauthnCredentials(ctx, user.ID)
+	if err != nil {
+		return webauthnUser{}, err
+	}
+	return webauthnUser{user: user, credentials: credentials}, nil
+}
+
+// BeginPasskeyRegistration starts registering a passkey for a logged in
+// user. Passkeys are discoverable and verify the user so they can replace
+// the password.
+func (s *Service) BeginPasskeyRegistration(ctx context.Context, initiator string) (PasskeyCeremony, error) {
+	var ceremony PasskeyCeremony
+	rp, err := s.relyingParty()
+	if err != nil {
+		return ceremony, err
+	}
+	user, err := s.repository.FindUserByID(ctx, uuid.MustParse(initiator))
+	if err != nil {
+		return ceremony, err
+	}
+	wu, err := s.webauthnUser(ctx, user)
+	if err != nil {
+		return ceremony, err
+	}
+	creation, session, err := rp.BeginRegistration(wu,
+		webauthn.WithExclusions(webauthn.Credentials(wu.WebAuthnCredentials()).CredentialDescriptors()),
+		webauthn.WithAuthenticatorSelection(protocol.AuthenticatorSelection{
+			ResidentKey:        protocol.ResidentKeyRequirementRequired,
+			RequireResidentKey: protocol.ResidentKeyRequired(),
+			UserVerification:   protocol.VerificationRequired,
+		}),
+	)
+	if err != nil {
+		return ceremony, err
+	}
+	ceremony.Options = creation
+	ceremony.Session, err = s.signCeremony(ceremonyRegistration, session)
+	return ceremony, err
+}
+
+// FinishPasskeyRegistration verifies the new credential and stores it.
+func (s *Service) FinishPasskeyRegistration(ctx context.Context, initiator string, payload FinishPasskeyRegistrationPayload) (Passkey, error) {
+	errs := validation.Validate(payload)
+	if errs != nil {
+		return Passkey{}, errs
+	}
+	rp, err := s.relyingParty()
+	if err != nil {
+		return Passkey{}, err
+	}
+	session, err := s.verifyCeremony(ctx, ceremonyRegistration, payload.Session)
+	if err != nil {
+		return Passkey{}, err
+	}
+	user, err := s.repository.FindUserByID(ctx, uuid.MustParse(initiator))
+	if err != nil {
+		return Passkey{}, err
+	}
+	if !bytes.Equal(session.UserID, user.ID[:]) {
+		return Passkey{}, ErrInvalidPasskey
+	}
+	wu, err := s.webauthnUser(ctx, user)
+	if err != nil {
+		return Passkey{}, err
+	}
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(payload.Credential)
+	if err != nil {
+		return Passkey{}, ErrInvalidPasskey
+	}
+	credential, err := rp.CreateCredential(wu, session, parsed)
+	if err != nil {
+		logger.Error().Err(err).Str("user_id", user.ID.String()).Msg("passkey registration failed")
+		return Passkey{}, ErrInvalidPasskey
+	}
+
+	transports := make([]string, len(credential.Transport))
+	for i, transport := range credential.Transport {
+		transports[i] = string(transport)
+	}
+	row, err := s.repository.CreateWebauthnCredential(ctx, repository.CreateWebauthnCredentialParams{
+		UserID:          user.ID,
+		CredentialID:    base64.RawURLEncoding.EncodeToString(credential.ID),
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Aaguid:          credential.Authenticator.AAGUID,
+		Transports:      transports,
+		SignCount:       int64(credential.Authenticator.SignCount),
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+		Name:            payload.Name,
+		CreatedBy:       user.ID,
+	})
+	if err != nil {
+		return Passkey{}, err
+	}
+	return newPasskey(row), nil
+}
+
+func (s *Service) ListPasskeys(ctx context.Context, initiator string) ([]Passkey, error) {
+	rows, err := s.repository.ListWebauthnCredentials(ctx, uuid.MustParse(initiator))
+	if err != nil {
+		return nil, err
+	}
+	passkeys := make([]Passkey, len(rows))
+	for i, row := range rows {
+		passkeys[i] = newPasskey(row)
+	}
+	return passkeys, nil
+}
+
+func (s *Service) DeletePasskey(ctx context.Context, initiator string, id string) error {
+	passkeyID, err := uuid.Parse(id)
+	if err != nil {
+		return ErrPasskeyNotFound
+	}
+	deleted, err := s.repository.DeleteWebauthnCredential(ctx, repository.DeleteWebauthnCredentialParams{
+		ID:     passkeyID,
+		UserID: uuid.MustParse(initiator),
+	})
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrPasskeyNotFound
+	}
+	return nil
+}
+
+// BeginPasskeyLogin starts a passwordless login, the authenticator picks
+// the passkey and with it the user.
+func (s *Service) BeginPasskeyLogin(ctx context.Context) (PasskeyCeremony, error) {
+	var ceremony PasskeyCeremony
+	rp, err := s.relyingParty()
+	if err != nil {
+		return ceremony, err
+	}
+	assertion, session, err := rp.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		return ceremony, err
+	}
+	ceremony.Options = assertion
+	ceremony.Session, err = s.signCeremony(ceremonyLogin, session)
+	return ceremony, err
+}
+
+// FinishPasskeyLogin signs the user in without a password. The passkey is
+// something the user has and the verification something they are or know,
+// so the login counts as multi-factor and no mfa challenge follows.
+func (s *Service) FinishPasskeyLogin(ctx context.Context, payload FinishPasskeyLoginPayload) (LoginUserResponse, error) {
+	var response LoginUserResponse
+	errs := validation.Validate(payload)
+	if errs != nil {
+		return response, errs
+	}
+	rootApp, err := s.repository.FindRootApp(ctx)
+	if err != nil {
+		return response, err
+	}
+	user, err := s.verifyPasskeyLogin(ctx, payload.Session, payload.Credential)
+	if err != nil {
+		return response, err
+	}
+	if user.DeactivatedAt != nil {
+		return response, ErrDeactivatedUser
+	}
+	if err := requireVerifiedEmail(rootApp.OauthConfig, user); err != nil {
+		return response, err
+	}
+	return s.createLoginSession(ctx, rootApp, user, []string{AmrHardwareKey, AmrUser}, payload.UserIP, payload.UserAgent)
+}
+
+func (s *Service) verifyPasskeyLogin(ctx context.Context, token string, credential json.RawMessage) (repository.User, error) {
+	rp, err := s.relyingParty()
+	if err != nil {
+		return repository.User{}, err
+	}
+	session, err := s.verifyCeremony(ctx, ceremonyLogin, token)
+	if err != nil {
+		return repository.User{}, err
+	}
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(credential)
+	if err != nil {
+		return repository.User{}, ErrInvalidPasskey
+	}
+	var wu webauthnUser
+	handler := func(rawID, userHandle []byte) (webauthn.User, error) {
+		row, err := s.repository.FindWebauthnCredential(ctx, base64.RawURLEncoding.EncodeToString(rawID))
+		if err != nil {
+			return nil, ErrPasskeyNotFound
+		}
+		if !bytes.Equal(userHandle, row.UserID[:]) {
+			return nil, ErrInvalidPasskey
+		}
+		user, err := s.repository.FindUserByID(ctx, row.UserID)
+		if err != nil {
+			return nil, err
+		}
+		wu, err = s.webauthnUser(ctx, user)
+		return wu, err
+	}
+	_, verified, err := rp.ValidatePasskeyLogin(handler, session, parsed)
+	if err != nil {
+		return repository.User{}, ErrInvalidPasskey
+	}
+	if err := s.recordPasskeyUse(ctx, wu, verified); err != nil {
+		return repository.User{}, err
+	}
+	return wu.user, nil
+}
+
+// BeginPasskeyMfa starts verifying a passkey as the second factor of a
+// login, only the passkeys of the user are allowed.
+func (s *Service) BeginPasskeyMfa(ctx context.Context, payload BeginPasskeyMfaPayload) (PasskeyCeremony, error) {
+	var ceremony PasskeyCeremony
+	errs := validation.Validate(payload)
+	if errs != nil {
+		return ceremony, errs
+	}
+	rp, err := s.relyingParty()
+	if err != nil {
+		return ceremony, err
+	}
+	user, claims, err := s.verifyMfaChallenge(ctx, payload.MfaToken)
+	if err != nil {
+		return ceremony, err
+	}
+	if claims.Enroll {
+		return ceremony, ErrInvalidMfaToken
+	}
+	wu, err := s.webauthnUser(ctx, user)
+	if err != nil {
+		return ceremony, err
+	}
+	if len(wu.credentials) == 0 {
+		return ceremony, ErrPasskeyNotFound
+	}
+	assertion, session, err := rp.BeginLogin(wu)
+	if err != nil {
+		return ceremony, err
+	}
+	ceremony.Options = assertion
+	ceremony.Session, err = s.signCeremony(ceremonyMfa, session)
+	return ceremony, err
+}
+
+// FinishPasskeyMfa completes a login with a passkey as the second factor.
+func (s *Service) FinishPasskeyMfa(ctx context.Context, payload FinishPasskeyMfaPayload) (LoginUserResponse, error) {
+	var response LoginUserResponse
+	errs := validation.Validate(payload)
+	if errs != nil {
+		return response, errs
+	}
+	rp, err := s.relyingParty()
+	if err != nil {
+		return response, err
+	}
+	user, claims, err := s.verifyMfaChallenge(ctx, payload.MfaToken)
+	if err != nil {
+		return response, err
+	}
+	if claims.Enroll {
+		return response, ErrInvalidMfaToken
+	}
+	session, err := s.verifyCeremony(ctx, ceremonyMfa, payload.Session)
+	if err != nil {
+		return response, err
+	}
+	if !bytes.Equal(session.UserID, user.ID[:]) {
+		return response, ErrInvalidPasskey
+	}
+	if !s.mfaLimits.Allow(user.ID.String()) {
+		return response, ErrTooManyRequests
+	}
+	wu, err := s.webauthnUser(ctx, user)
+	if err != nil {
+		return response, err
+	}
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(payload.Credential)
+	if err != nil {
+		return response, ErrInvalidPasskey
+	}
+	verified, err := rp.ValidateLogin(wu, session, parsed)
+	if err != nil {
+		return response, ErrInvalidPasskey
+	}
+	if err := s.recordPasskeyUse(ctx, wu, verified); err != nil {
+		return response, err
+	}
+	rootApp, err := s.repository.FindRootApp(ctx)
+	if err != nil {
+		return response, err
+	}
+	return s.createLoginSession(ctx, rootApp, user, claims.amr(AmrHardwareKey), payload.UserIP, payload.UserAgent)
+}
+
+// recordPasskeyUse stores the signature counter of a verified assertion. A
+// counter that did not go up means two authenticators share the key, the
+// passkey is flagged and refused until the user registers a new one.
+func (s *Service) recordPasskeyUse(ctx context.Context, wu webauthnUser, credential *webauthn.Credential) error {
+	row, ok := wu.credential(credential.ID)
+	if !ok {
+		return ErrPasskeyNotFound
+	}
+	if row.CloneWarning {
+		return ErrPasskeyCloned
+	}
+	err := s.repository.UpdateWebauthnCredentialUse(ctx, repository.UpdateWebauthnCredentialUseParams{
+		ID:           row.ID,
+		SignCount:    int64(credential.Authenticator.SignCount),
+		BackupState:  credential.Flags.BackupState,
+		CloneWarning: credential.Authenticator.CloneWarning,
+	})
+	if err != nil {
+		return err
+	}
+	if credential.Authenticator.CloneWarning {
+		logger.Warn().Str("user_id", row.UserID.String()).Str("passkey_id", row.ID.String()).Msg("passkey may have been cloned")
+		return ErrPasskeyCloned
+	}
+	return nil
+}
+
+func newPasskey(row repository.WebauthnCredential) Passkey {
+	return Passkey{
+		ID:           row.ID,
+		Name:         row.Name,
+		Synced:       row.BackupState,
+		CloneWarning: row.CloneWarning,
+		CreatedAt:    row.CreatedAt,
+		LastUsedAt:   row.LastUsedAt,
+	}
+}
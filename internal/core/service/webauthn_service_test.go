@@ -0,0 +1,332 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aritradeveops/porichoy/internal/config"
+	"github.com/aritradeveops/porichoy/internal/persistence/repository"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testRPID   = "id.example.com"
+	testOrigin = "https://id.example.com"
+)
+
+// passkeyQuerier keeps just the users and credentials the passkey
+// ceremonies touch, anything else panics on the nil Querier.
+type passkeyQuerier struct {
+	repository.Querier
+	users       map[uuid.UUID]repository.User
+	credentials []repository.WebauthnCredential
+	challenges  map[string]time.Time
+}
+
+func (q *passkeyQuerier) FindUserByID(ctx context.Context, id uuid.UUID) (repository.User, error) {
+	user, ok := q.users[id]
+	if !ok {
+		return user, errors.New("no rows in result set")
+	}
+	return user, nil
+}
+
+func (q *passkeyQuerier) CreateWebauthnCredential(ctx context.Context, arg repository.CreateWebauthnCredentialParams) (repository.WebauthnCredential, error) {
+	row := repository.WebauthnCredential{
+		ID:              uuid.New(),
+		UserID:          arg.UserID,
+		CredentialID:    arg.CredentialID,
+		PublicKey:       arg.PublicKey,
+		AttestationType: arg.AttestationType,
+		Aaguid:          arg.Aaguid,
+		Transports:      arg.Transports,
+		SignCount:       arg.SignCount,
+		BackupEligible:  arg.BackupEligible,
+		BackupState:     arg.BackupState,
+		Name:            arg.Name,
+		CreatedAt:       time.Now(),
+		CreatedBy:       arg.CreatedBy,
+	}
+	q.credentials = append(q.credentials, row)
+	return row, nil
+}
+
+func (q *passkeyQuerier) FindWebauthnCredential(ctx context.Context, credentialID string) (repository.WebauthnCredential, error) {
+	for _, row := range q.credentials {
+		if row.CredentialID == credentialID {
+			return row, nil
+		}
+	}
+	return repository.WebauthnCredential{}, errors.New("no rows in result set")
+}
+
+func (q *passkeyQuerier) ListWebauthnCredentials(ctx context.Context, userID uuid.UUID) ([]repository.WebauthnCredential, error) {
+	var rows []repository.WebauthnCredential
+	for _, row := range q.credentials {
+		if row.UserID == userID {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+func (q *passkeyQuerier) UpdateWebauthnCredentialUse(ctx context.Context, arg repository.UpdateWebauthnCredentialUseParams) error {
+	for i, row := range q.credentials {
+		if row.ID == arg.ID {
+			now := time.Now()
+			q.credentials[i].SignCount = arg.SignCount
+			q.credentials[i].BackupState = arg.BackupState
+			q.credentials[i].CloneWarning = arg.CloneWarning
+			q.credentials[i].LastUsedAt = &now
+		}
+	}
+	return nil
+}
+
+func (q *passkeyQuerier) UseWebauthnChallenge(ctx context.Context, arg repository.UseWebauthnChallengeParams) (int64, error) {
+	if _, ok := q.challenges[arg.Challenge]; ok {
+		return 0, nil
+	}
+	q.challenges[arg.Challenge] = arg.ExpiresAt
+	return 1, nil
+}
+
+// softAuthenticator is a passkey held in memory, it answers the ceremonies
+// the way a platform authenticator with user verification would.
+type softAuthenticator struct {
+	id         []byte
+	key        *ecdsa.PrivateKey
+	userHandle []byte
+	signCount  uint32
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	id := make([]byte, 16)
+	_, err = rand.Read(id)
+	assert.NoError(t, err)
+	return &softAuthenticator{id: id, key: key}
+}
+
+func (a *softAuthenticator) authenticatorData(flags byte, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	return append(data, attested...)
+}
+
+func clientData(t *testing.T, kind string, challenge string) []byte {
+	data, err := json.Marshal(map[string]string{
+		"type":      kind,
+		"challenge": challenge,
+		"origin":    testOrigin,
+	})
+	assert.NoError(t, err)
+	return data
+}
+
+func (a *softAuthenticator) create(t *testing.T, options any) json.RawMessage {
+	creation := options.(*protocol.CredentialCreation)
+	a.userHandle = creation.Response.User.ID.(protocol.URLEncodedBase64)
+
+	// COSE_Key of an EC2 P-256 key for ES256
+	publicKey, err := cbor.Marshal(map[int]any{
+		1:  2,
+		3:  -7,
+		-1: 1,
+		-2: a.key.PublicKey.X.FillBytes(make([]byte, 32)),
+		-3: a.key.PublicKey.Y.FillBytes(make([]byte, 32)),
+	})
+	assert.NoError(t, err)
+	attested := make([]byte, 16) // zero aaguid
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.id)))
+	attested = append(attested, a.id...)
+	attested = append(attested, publicKey...)
+
+	// user present, user verified, attested credential data
+	attestation, err := cbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": a.authenticatorData(0x45, attested),
+	})
+	assert.NoError(t, err)
+
+	return a.encode(t, map[string]any{
+		"clientDataJSON":    clientData(t, "webauthn.create", creation.Response.Challenge.String()),
+		"attestationObject": attestation,
+		"transports":        []string{"internal"},
+	})
+}
+
+func (a *softAuthenticator) get(t *testing.T, options any) json.RawMessage {
+	assertion := options.(*protocol.CredentialAssertion)
+	client := clientData(t, "webauthn.get", assertion.Response.Challenge.String())
+	a.signCount++
+	data := a.authenticatorData(0x05, nil)
+	hash := sha256.Sum256(client)
+	digest := sha256.Sum256(append(append([]byte{}, data...), hash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	assert.NoError(t, err)
+
+	return a.encode(t, map[string]any{
+		"clientDataJSON":    client,
+		"authenticatorData": data,
+		"signature":         signature,
+		"userHandle":        a.userHandle,
+	})
+}
+
+func (a *softAuthenticator) encode(t *testing.T, response map[string]any) json.RawMessage {
+	for name, value := range response {
+		if raw, ok := value.([]byte); ok {
+			response[name] = base64.RawURLEncoding.EncodeToString(raw)
+		}
+	}
+	credential, err := json.Marshal(map[string]any{
+		"id":       base64.RawURLEncoding.EncodeToString(a.id),
+		"rawId":    base64.RawURLEncoding.EncodeToString(a.id),
+		"type":     "public-key",
+		"response": response,
+	})
+	assert.NoError(t, err)
+	return credential
+}
+
+func newPasskeyService(t *testing.T) (*Service, *passkeyQuerier, repository.User) {
+	user := repository.User{
+		ID:        uuid.New(),
+		Email:     "jane@example.com",
+		Name:      "Jane",
+		CreatedAt: time.Now(),
+	}
+	querier := &passkeyQuerier{
+		users:      map[uuid.UUID]repository.User{user.ID: user},
+		challenges: map[string]time.Time{},
+	}
+	s := New(&config.Config{
+		Issuer: testOrigin,
+		Keys: config.Keys{
+			MasterKeyResolver: "literal://webauthn-test-master-key",
+		},
+	}, querier, nil, nil)
+	return s, querier, user
+}
+
+func TestPasskeyLogin(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s, querier, user := newPasskeyService(t)
+	authenticator := newSoftAuthenticator(t)
+
+	registration, err := s.BeginPasskeyRegistration(ctx, user.ID.String())
+	assert.NoError(t, err)
+	passkey, err := s.FinishPasskeyRegistration(ctx, user.ID.String(), FinishPasskeyRegistrationPayload{
+		Session:    registration.Session,
+		Name:       "Laptop",
+		Credential: authenticator.create(t, registration.Options),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Laptop", passkey.Name)
+	assert.Len(t, querier.credentials, 1)
+
+	login, err := s.BeginPasskeyLogin(ctx)
+	assert.NoError(t, err)
+	got, err := s.verifyPasskeyLogin(ctx, login.Session, authenticator.get(t, login.Options))
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, got.ID)
+	assert.EqualValues(t, 1, querier.credentials[0].SignCount)
+	assert.NotNil(t, querier.credentials[0].LastUsedAt)
+
+	// a session is only good for the ceremony it was issued for
+	_, err = s.verifyPasskeyLogin(ctx, registration.Session, authenticator.get(t, login.Options))
+	assert.ErrorIs(t, err, ErrInvalidPasskey)
+}
+
+func TestPasskeyCeremonyReplay(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s, _, user := newPasskeyService(t)
+	authenticator := newSoftAuthenticator(t)
+
+	registration, err := s.BeginPasskeyRegistration(ctx, user.ID.String())
+	assert.NoError(t, err)
+	credential := authenticator.create(t, registration.Options)
+	_, err = s.FinishPasskeyRegistration(ctx, user.ID.String(), FinishPasskeyRegistrationPayload{
+		Session:    registration.Session,
+		Name:       "Laptop",
+		Credential: credential,
+	})
+	assert.NoError(t, err)
+	_, err = s.FinishPasskeyRegistration(ctx, user.ID.String(), FinishPasskeyRegistrationPayload{
+		Session:    registration.Session,
+		Name:       "Laptop",
+		Credential: credential,
+	})
+	assert.ErrorIs(t, err, ErrInvalidPasskey)
+
+	login, err := s.BeginPasskeyLogin(ctx)
+	assert.NoError(t, err)
+	assertion := authenticator.get(t, login.Options)
+	_, err = s.verifyPasskeyLogin(ctx, login.Session, assertion)
+	assert.NoError(t, err)
+
+	// a captured assertion can not be sent again within the session lifetime
+	_, err = s.verifyPasskeyLogin(ctx, login.Session, assertion)
+	assert.ErrorIs(t, err, ErrInvalidPasskey)
+	authenticator.signCount++
+	_, err = s.verifyPasskeyLogin(ctx, login.Session, authenticator.get(t, login.Options))
+	assert.ErrorIs(t, err, ErrInvalidPasskey)
+}
+
+func TestPasskeyCloneDetection(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s, querier, user := newPasskeyService(t)
+	authenticator := newSoftAuthenticator(t)
+
+	registration, err := s.BeginPasskeyRegistration(ctx, user.ID.String())
+	assert.NoError(t, err)
+	_, err = s.FinishPasskeyRegistration(ctx, user.ID.String(), FinishPasskeyRegistrationPayload{
+		Session:    registration.Session,
+		Name:       "Phone",
+		Credential: authenticator.create(t, registration.Options),
+	})
+	assert.NoError(t, err)
+
+	authenticator.signCount = 10
+	login, err := s.BeginPasskeyLogin(ctx)
+	assert.NoError(t, err)
+	_, err = s.verifyPasskeyLogin(ctx, login.Session, authenticator.get(t, login.Options))
+	assert.NoError(t, err)
+
+	// a copy of the key still counting from where it was cloned
+	clone := *authenticator
+	clone.signCount = 5
+	login, err = s.BeginPasskeyLogin(ctx)
+	assert.NoError(t, err)
+	_, err = s.verifyPasskeyLogin(ctx, login.Session, clone.get(t, login.Options))
+	assert.ErrorIs(t, err, ErrPasskeyCloned)
+	assert.True(t, querier.credentials[0].CloneWarning)
+
+	// once flagged the passkey is refused, even with a counter that went up
+	login, err = s.BeginPasskeyLogin(ctx)
+	assert.NoError(t, err)
+	_, err = s.verifyPasskeyLogin(ctx, login.Session, authenticator.get(t, login.Options))
+	assert.ErrorIs(t, err, ErrPasskeyCloned)
+}
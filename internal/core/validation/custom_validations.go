@@ -4,6 +4,8 @@ import (
 	"slices"
 	"strings"
 
+	"github.com/aritradeveops/porichoy/internal/core/jwtutil"
+	"github.com/aritradeveops/porichoy/pkg/resolver"
 	"github.com/aritradeveops/porichoy/pkg/timex"
 	"github.com/go-playground/validator/v10"
 )
@@ -59,29 +61,32 @@ func ValidatePassword(password string) ValidationErrors {
 	return errs
 }
 
-// resolvers can be of the following type
-// env:<environment_variable_name>
-// db:<table_id>
-// literal:<literal_value>
-// s3:<s3key>
+// resolvers can be of any scheme registered with the resolver package, the
+// built in ones are
+// env://<environment_variable_name>
+// db://<secret_name>
+// literal://<literal_value>
+// file://<path>[#key]
+// vault://<mount>/<path>[#field]
+// s3://<bucket>/<key>[#key]
 func ValidateResolvers(fl validator.FieldLevel) bool {
-	// will come from resolvers package
-	availableResolvers := []string{"env", "db", "literal", "s3"}
-	val := fl.Field().String()
-	parts := strings.Split(val, ":")
-	if len(parts) != 2 {
-		return false
-	}
-
-	source := parts[0]
-
-	return slices.Contains(availableResolvers, source)
+	scheme, _, found := strings.Cut(fl.Field().String(), "://")
+	return found && slices.Contains(resolver.Providers(), scheme)
 }
 
 func ValidateJWTAlgo(fl validator.FieldLevel) bool {
-	availableAlgorithms := []string{"HS256", "HS512", "JWK"}
-	val := fl.Field().String()
-	return slices.Contains(availableAlgorithms, val)
+	return jwtutil.IsSupportedAlgorithm(fl.Field().String())
+}
+
+// prompt is a space delimited list of none, login, consent and select_account
+func ValidatePrompt(fl validator.FieldLevel) bool {
+	availablePrompts := []string{"none", "login", "consent", "select_account"}
+	for prompt := range strings.FieldsSeq(fl.Field().String()) {
+		if !slices.Contains(availablePrompts, prompt) {
+			return false
+		}
+	}
+	return true
 }
 
 func ValidateDuration(fl validator.FieldLevel) bool {
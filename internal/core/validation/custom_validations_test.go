@@ -0,0 +1,32 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateResolvers(t *testing.T) {
+	t.Parallel()
+
+	type config struct {
+		Secret string `json:"secret" validate:"resolver"`
+	}
+
+	cases := []struct {
+		value string
+		valid bool
+	}{
+		{"env://PORICHOY_MASTER_KEY", true},
+		{"literal://postgres://user:password@db:5432/porichoy", true},
+		{"vault://secret/porichoy#master_key", true},
+		{"env:PORICHOY_MASTER_KEY", false},
+		{"kms://alias/porichoy", false},
+		{"PORICHOY_MASTER_KEY", false},
+		{"", false},
+	}
+	for _, c := range cases {
+		errs := Validate(config{Secret: c.value})
+		assert.Equal(t, c.valid, errs == nil, c.value)
+	}
+}
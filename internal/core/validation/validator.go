@@ -23,6 +23,7 @@ func init() {
 	validate.RegisterValidation("jwt_algo", ValidateJWTAlgo)
 	validate.RegisterValidation("duration", ValidateDuration)
 	validate.RegisterValidation("resolver", ValidateResolvers)
+	validate.RegisterValidation("prompt", ValidatePrompt)
 }
 
 type ValidationError struct {
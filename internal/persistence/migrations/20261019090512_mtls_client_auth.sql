@@ -0,0 +1,2 @@
+-- Modify "oauth_configs" table
+ALTER TABLE "public"."oauth_configs" ADD COLUMN "token_endpoint_auth_method" character varying(50) NOT NULL DEFAULT 'client_secret_post', ADD COLUMN "tls_client_auth_subject_dn" text NULL, ADD COLUMN "tls_client_certificate" text NULL;
@@ -0,0 +1,19 @@
+-- Create "consents" table
+CREATE TABLE "public"."consents" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "user_id" uuid NOT NULL,
+  "app_id" uuid NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "created_by" uuid NOT NULL,
+  "updated_at" timestamptz NULL,
+  "updated_by" uuid NULL,
+  "deleted_at" timestamptz NULL,
+  "deleted_by" uuid NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "consents_user_id_app_id_key" UNIQUE ("user_id", "app_id"),
+  CONSTRAINT "consents_app_id_fkey" FOREIGN KEY ("app_id") REFERENCES "public"."apps" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "consents_created_by_fkey" FOREIGN KEY ("created_by") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "consents_deleted_by_fkey" FOREIGN KEY ("deleted_by") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "consents_updated_by_fkey" FOREIGN KEY ("updated_by") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "consents_user_id_fkey" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION
+);
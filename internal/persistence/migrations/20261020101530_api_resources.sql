@@ -0,0 +1,21 @@
+-- Modify "oauth_calls" table
+ALTER TABLE "public"."oauth_calls" ADD COLUMN "resource" text NULL, ADD COLUMN "scope" text NULL;
+-- Create "api_resources" table
+CREATE TABLE "public"."api_resources" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "name" character varying(255) NOT NULL,
+  "identifier" character varying(255) NOT NULL,
+  "scopes" text[] NOT NULL DEFAULT '{}',
+  "token_lifetime" character varying(10) NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "created_by" uuid NOT NULL,
+  "updated_at" timestamptz NULL,
+  "updated_by" uuid NULL,
+  "deleted_at" timestamptz NULL,
+  "deleted_by" uuid NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "api_resources_identifier_key" UNIQUE ("identifier"),
+  CONSTRAINT "api_resources_created_by_fkey" FOREIGN KEY ("created_by") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "api_resources_deleted_by_fkey" FOREIGN KEY ("deleted_by") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "api_resources_updated_by_fkey" FOREIGN KEY ("updated_by") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION
+);
@@ -0,0 +1,4 @@
+-- Modify "oauth_calls" table
+ALTER TABLE "public"."oauth_calls" ADD COLUMN "auth_time" timestamptz NULL, ADD COLUMN "amr" text[] NULL;
+-- Modify "oauth_configs" table
+ALTER TABLE "public"."oauth_configs" ADD COLUMN "access_token_profile" character varying(20) NOT NULL DEFAULT 'legacy';
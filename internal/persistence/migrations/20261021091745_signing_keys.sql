@@ -0,0 +1,29 @@
+-- Create "signing_keys" table
+CREATE TABLE "public"."signing_keys" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "kid" character varying(255) NOT NULL,
+  "algo" character varying(10) NOT NULL,
+  "secret_resolver" text NOT NULL,
+  "state" character varying(10) NOT NULL DEFAULT 'next',
+  "activates_at" timestamptz NULL,
+  "activated_at" timestamptz NULL,
+  "expires_at" timestamptz NULL,
+  "app_id" uuid NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "created_by" uuid NOT NULL,
+  "updated_at" timestamptz NULL,
+  "updated_by" uuid NULL,
+  "deleted_at" timestamptz NULL,
+  "deleted_by" uuid NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "signing_keys_kid_key" UNIQUE ("kid"),
+  CONSTRAINT "signing_keys_app_id_fkey" FOREIGN KEY ("app_id") REFERENCES "public"."apps" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "signing_keys_created_by_fkey" FOREIGN KEY ("created_by") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "signing_keys_deleted_by_fkey" FOREIGN KEY ("deleted_by") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "signing_keys_updated_by_fkey" FOREIGN KEY ("updated_by") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION
+);
+-- Seed the active key of the existing apps, the client id was their kid
+INSERT INTO "public"."signing_keys" ("kid", "algo", "secret_resolver", "state", "activated_at", "app_id", "created_by")
+SELECT "apps"."client_id", "oauth_configs"."jwt_algo", "oauth_configs"."jwt_secret_resolver", 'active', CURRENT_TIMESTAMP, "apps"."id", "oauth_configs"."created_by"
+FROM "public"."apps" JOIN "public"."oauth_configs" ON "oauth_configs"."app_id" = "apps"."id"
+WHERE "oauth_configs"."jwt_secret_resolver" IS NOT NULL;
@@ -0,0 +1,2 @@
+-- Modify "signing_keys" table
+ALTER TABLE "public"."signing_keys" ALTER COLUMN "secret_resolver" DROP NOT NULL, ADD COLUMN "encrypted_private_key" text NULL;
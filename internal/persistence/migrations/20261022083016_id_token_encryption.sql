@@ -0,0 +1,4 @@
+-- Modify "oauth_calls" table
+ALTER TABLE "public"."oauth_calls" ADD COLUMN "nonce" text NULL;
+-- Modify "oauth_configs" table
+ALTER TABLE "public"."oauth_configs" ADD COLUMN "encryption_jwk" text NULL, ADD COLUMN "encryption_alg" character varying(20) NULL, ADD COLUMN "encryption_enc" character varying(20) NULL;
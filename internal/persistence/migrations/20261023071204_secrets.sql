@@ -0,0 +1,16 @@
+-- Create "secrets" table
+CREATE TABLE "public"."secrets" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "name" character varying(255) NOT NULL,
+  "encrypted_value" text NOT NULL,
+  "encrypted_data_key" text NOT NULL,
+  "version" integer NOT NULL DEFAULT 1,
+  "created_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "created_by" uuid NOT NULL,
+  "updated_at" timestamptz NULL,
+  "updated_by" uuid NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "secrets_name_key" UNIQUE ("name"),
+  CONSTRAINT "secrets_created_by_fkey" FOREIGN KEY ("created_by") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "secrets_updated_by_fkey" FOREIGN KEY ("updated_by") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION
+);
@@ -0,0 +1,24 @@
+-- Create "client_secrets" table
+CREATE TABLE "public"."client_secrets" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "hashed_secret" text NOT NULL,
+  "expires_at" timestamptz NULL,
+  "app_id" uuid NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "created_by" uuid NOT NULL,
+  "updated_at" timestamptz NULL,
+  "updated_by" uuid NULL,
+  "deleted_at" timestamptz NULL,
+  "deleted_by" uuid NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "client_secrets_app_id_fkey" FOREIGN KEY ("app_id") REFERENCES "public"."apps" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "client_secrets_created_by_fkey" FOREIGN KEY ("created_by") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "client_secrets_deleted_by_fkey" FOREIGN KEY ("deleted_by") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "client_secrets_updated_by_fkey" FOREIGN KEY ("updated_by") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION
+);
+-- Move the plaintext secrets over as salted hashes, sha256$<salt>$<hex(sha256(salt || secret))>
+INSERT INTO "public"."client_secrets" ("hashed_secret", "app_id", "created_by")
+SELECT 'sha256$' || "salted"."salt" || '$' || encode(sha256(convert_to("salted"."salt" || "salted"."client_secret", 'UTF8')), 'hex'), "salted"."app_id", "salted"."created_by"
+FROM (SELECT md5(random()::text || clock_timestamp()::text) AS "salt", "client_secret", "app_id", "created_by" FROM "public"."oauth_configs") AS "salted";
+-- Modify "oauth_configs" table
+ALTER TABLE "public"."oauth_configs" DROP COLUMN "client_secret";
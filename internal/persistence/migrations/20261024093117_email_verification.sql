@@ -0,0 +1,4 @@
+-- Modify "users" table
+ALTER TABLE "public"."users" ADD COLUMN "email_verified_at" timestamptz NULL;
+-- Modify "oauth_configs" table
+ALTER TABLE "public"."oauth_configs" ADD COLUMN "require_verified_email" boolean NOT NULL DEFAULT false;
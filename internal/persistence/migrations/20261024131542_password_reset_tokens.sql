@@ -0,0 +1,21 @@
+-- Create "password_reset_tokens" table
+CREATE TABLE "public"."password_reset_tokens" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "hashed_token" text NOT NULL,
+  "user_id" uuid NOT NULL,
+  "requested_ip" character varying(45) NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "used_at" timestamptz NULL,
+  "created_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "created_by" uuid NOT NULL,
+  "updated_at" timestamptz NULL,
+  "updated_by" uuid NULL,
+  "deleted_at" timestamptz NULL,
+  "deleted_by" uuid NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "password_reset_tokens_hashed_token_key" UNIQUE ("hashed_token"),
+  CONSTRAINT "password_reset_tokens_created_by_fkey" FOREIGN KEY ("created_by") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "password_reset_tokens_deleted_by_fkey" FOREIGN KEY ("deleted_by") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "password_reset_tokens_updated_by_fkey" FOREIGN KEY ("updated_by") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "password_reset_tokens_user_id_fkey" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION
+);
@@ -0,0 +1,28 @@
+-- Create "webauthn_credentials" table
+CREATE TABLE "public"."webauthn_credentials" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "user_id" uuid NOT NULL,
+  "credential_id" text NOT NULL,
+  "public_key" bytea NOT NULL,
+  "attestation_type" character varying(32) NOT NULL,
+  "aaguid" bytea NOT NULL,
+  "transports" text[] NOT NULL DEFAULT '{}',
+  "sign_count" bigint NOT NULL DEFAULT 0,
+  "backup_eligible" boolean NOT NULL DEFAULT false,
+  "backup_state" boolean NOT NULL DEFAULT false,
+  "clone_warning" boolean NOT NULL DEFAULT false,
+  "name" character varying(255) NOT NULL,
+  "last_used_at" timestamptz NULL,
+  "created_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "created_by" uuid NOT NULL,
+  "updated_at" timestamptz NULL,
+  "updated_by" uuid NULL,
+  "deleted_at" timestamptz NULL,
+  "deleted_by" uuid NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "webauthn_credentials_credential_id_key" UNIQUE ("credential_id"),
+  CONSTRAINT "webauthn_credentials_created_by_fkey" FOREIGN KEY ("created_by") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "webauthn_credentials_deleted_by_fkey" FOREIGN KEY ("deleted_by") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "webauthn_credentials_updated_by_fkey" FOREIGN KEY ("updated_by") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "webauthn_credentials_user_id_fkey" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION
+);
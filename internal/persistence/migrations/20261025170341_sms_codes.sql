@@ -0,0 +1,26 @@
+-- Modify "users" table
+ALTER TABLE "public"."users" ADD COLUMN "phone_number" character varying(16) NULL, ADD COLUMN "phone_verified_at" timestamptz NULL;
+-- Create index "users_verified_phone_number_key" to table: "users"
+CREATE UNIQUE INDEX "users_verified_phone_number_key" ON "public"."users" ("phone_number") WHERE ((phone_verified_at IS NOT NULL) AND (deleted_at IS NULL));
+-- Create "sms_codes" table
+CREATE TABLE "public"."sms_codes" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "user_id" uuid NOT NULL,
+  "phone_number" character varying(16) NOT NULL,
+  "purpose" character varying(16) NOT NULL,
+  "hashed_code" text NOT NULL,
+  "requested_ip" character varying(45) NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "used_at" timestamptz NULL,
+  "created_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "created_by" uuid NOT NULL,
+  "updated_at" timestamptz NULL,
+  "updated_by" uuid NULL,
+  "deleted_at" timestamptz NULL,
+  "deleted_by" uuid NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "sms_codes_created_by_fkey" FOREIGN KEY ("created_by") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "sms_codes_deleted_by_fkey" FOREIGN KEY ("deleted_by") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "sms_codes_updated_by_fkey" FOREIGN KEY ("updated_by") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "sms_codes_user_id_fkey" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION
+);
@@ -0,0 +1,2 @@
+-- Modify "oauth_configs" table
+ALTER TABLE "public"."oauth_configs" ADD COLUMN "api_resources" text[] NULL;
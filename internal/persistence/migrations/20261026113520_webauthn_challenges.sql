@@ -0,0 +1,7 @@
+-- Create "webauthn_challenges" table
+CREATE TABLE "public"."webauthn_challenges" (
+  "challenge" text NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "used_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("challenge")
+);
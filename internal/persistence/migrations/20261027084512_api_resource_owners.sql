@@ -0,0 +1,2 @@
+-- Modify "api_resources" table
+ALTER TABLE "public"."api_resources" ADD COLUMN "owner_id" uuid NULL, ADD CONSTRAINT "api_resources_owner_id_fkey" FOREIGN KEY ("owner_id") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION;
@@ -0,0 +1,4 @@
+-- codes live for minutes, the outstanding ones can not be redeemed without their redirect uri
+DELETE FROM "public"."oauth_calls";
+-- Modify "oauth_calls" table
+ALTER TABLE "public"."oauth_calls" ADD COLUMN "redirect_uri" text NOT NULL;
@@ -0,0 +1,12 @@
+-- name: CreateApiResource :one
+INSERT INTO "api_resources" (
+  name, identifier, scopes, token_lifetime, created_by, owner_id
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+) RETURNING *;
+
+-- name: FindApiResourceByIdentifier :one
+SELECT * FROM "api_resources" WHERE identifier = $1 AND deleted_at IS NULL;
+
+-- name: ListApiResources :many
+SELECT * FROM "api_resources" WHERE deleted_at IS NULL ORDER BY created_at;
\ No newline at end of file
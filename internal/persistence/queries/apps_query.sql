@@ -15,3 +15,8 @@ WHERE app.client_id = $1 AND app.deleted_by IS NULL;
 SELECT sqlc.embed(app), sqlc.embed(oauth_config) FROM "apps" AS app
 LEFT JOIN "oauth_configs" AS oauth_config ON app.id = oauth_config.app_id
 WHERE app.created_by = '00000000-0000-0000-0000-000000000000' AND app.deleted_by IS NULL;
+
+-- name: ListApps :many
+SELECT sqlc.embed(app), sqlc.embed(oauth_config) FROM "apps" AS app
+LEFT JOIN "oauth_configs" AS oauth_config ON app.id = oauth_config.app_id
+WHERE app.deleted_by IS NULL ORDER BY app.created_at;
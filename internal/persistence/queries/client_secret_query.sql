@@ -0,0 +1,15 @@
+-- name: CreateClientSecret :one
+INSERT INTO "client_secrets" (
+  hashed_secret, expires_at, app_id, created_by
+) VALUES (
+  $1, $2, $3, $4
+) RETURNING *;
+
+-- name: ListValidClientSecrets :many
+SELECT * FROM "client_secrets"
+WHERE app_id = $1 AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP) AND deleted_at IS NULL
+ORDER BY created_at DESC;
+
+-- name: ExpireClientSecret :exec
+UPDATE "client_secrets" SET expires_at = $2, updated_at = CURRENT_TIMESTAMP, updated_by = $3
+WHERE id = $1;
\ No newline at end of file
@@ -0,0 +1,10 @@
+-- name: UpsertConsent :exec
+INSERT INTO "consents" (
+  "user_id", "app_id", "created_by"
+) VALUES (
+  $1, $2, $3
+) ON CONFLICT ("user_id", "app_id") DO UPDATE
+SET "updated_at" = CURRENT_TIMESTAMP, "updated_by" = EXCLUDED."created_by", "deleted_at" = NULL, "deleted_by" = NULL;
+
+-- name: FindConsent :one
+SELECT * FROM "consents" WHERE "user_id" = $1 AND "app_id" = $2 AND "deleted_at" IS NULL;
\ No newline at end of file
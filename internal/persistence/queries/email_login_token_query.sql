@@ -0,0 +1,23 @@
+-- name: CreateEmailLoginToken :exec
+INSERT INTO "email_login_tokens" (
+  id, hashed_token, hashed_code, user_id, requested_ip, expires_at, created_by
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+);
+
+-- name: FindEmailLoginToken :one
+SELECT * FROM "email_login_tokens"
+WHERE id = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP AND deleted_at IS NULL;
+
+-- name: FindEmailLoginTokenByHash :one
+SELECT * FROM "email_login_tokens"
+WHERE hashed_token = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP AND deleted_at IS NULL;
+
+-- name: ConsumeEmailLoginToken :one
+UPDATE "email_login_tokens" SET used_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP, updated_by = user_id
+WHERE id = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP AND deleted_at IS NULL
+RETURNING *;
+
+-- name: RevokeEmailLoginTokens :exec
+UPDATE "email_login_tokens" SET expires_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP, updated_by = $1
+WHERE user_id = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP;
\ No newline at end of file
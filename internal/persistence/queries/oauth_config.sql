@@ -1,8 +1,20 @@
+-- name: AddAppApiResource :exec
+UPDATE "oauth_configs"
+SET "api_resources" = array_append(COALESCE("api_resources", '{}'), sqlc.arg(identifier)::TEXT), "updated_at" = CURRENT_TIMESTAMP, "updated_by" = sqlc.arg(updated_by)
+WHERE "app_id" = sqlc.arg(app_id) AND NOT (sqlc.arg(identifier)::TEXT = ANY(COALESCE("api_resources", '{}')));
+
 -- name: CreateOauthInfo :exec
 INSERT INTO "oauth_configs" (
-  client_secret, redirect_uris, success_callback_url, error_callback_url, 
+  redirect_uris, success_callback_url, error_callback_url, 
   jwt_algo, jwt_secret_resolver, jwt_lifetime, refresh_token_lifetime, app_id,
-  created_by
+  created_by, token_endpoint_auth_method, tls_client_auth_subject_dn, tls_client_certificate,
+  access_token_profile, encryption_jwk, encryption_alg, encryption_enc,
+  require_verified_email, require_mfa
 ) VALUES (
-  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
-);
\ No newline at end of file
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18
+);
+
+-- name: RemoveAppApiResource :exec
+UPDATE "oauth_configs"
+SET "api_resources" = array_remove("api_resources", sqlc.arg(identifier)::TEXT), "updated_at" = CURRENT_TIMESTAMP, "updated_by" = sqlc.arg(updated_by)
+WHERE "app_id" = sqlc.arg(app_id);
\ No newline at end of file
@@ -1,8 +1,8 @@
 -- name: CreateOauthCall :exec
-INSERT INTO "oauth_calls" (app_id, code, user_id, expires_at) VALUES ($1, $2, $3, $4);
+INSERT INTO "oauth_calls" (app_id, code, user_id, resource, scope, auth_time, amr, nonce, expires_at, redirect_uri) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);
 
--- name: FindOauthCallByCode :one
-SELECT * FROM "oauth_calls" WHERE code = $1 AND expires_at > NOW();
+-- name: ConsumeOauthCall :one
+DELETE FROM "oauth_calls" WHERE code = $1 AND expires_at > NOW() RETURNING *;
 
 
 
@@ -0,0 +1,19 @@
+-- name: CreatePasswordResetToken :exec
+INSERT INTO "password_reset_tokens" (
+  hashed_token, user_id, requested_ip, expires_at, created_by
+) VALUES (
+  $1, $2, $3, $4, $5
+);
+
+-- name: FindPasswordResetToken :one
+SELECT * FROM "password_reset_tokens"
+WHERE hashed_token = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP AND deleted_at IS NULL;
+
+-- name: ConsumePasswordResetToken :one
+UPDATE "password_reset_tokens" SET used_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP, updated_by = user_id
+WHERE hashed_token = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP AND deleted_at IS NULL
+RETURNING *;
+
+-- name: RevokePasswordResetTokens :exec
+UPDATE "password_reset_tokens" SET expires_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP, updated_by = $1
+WHERE user_id = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP;
\ No newline at end of file
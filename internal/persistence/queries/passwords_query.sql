@@ -6,4 +6,23 @@ INSERT INTO "passwords" (
 );
 
 -- name: FindUserPassword :one
-SELECT * FROM "passwords" WHERE created_by = $1 AND deleted_at IS NULL;
\ No newline at end of file
+SELECT * FROM "passwords" WHERE created_by = $1 AND deleted_at IS NULL;
+
+-- name: ListPasswordHistory :many
+SELECT * FROM "passwords" WHERE created_by = $1
+ORDER BY created_at DESC LIMIT $2;
+
+-- name: ReplaceUserPassword :exec
+WITH replaced AS (
+  UPDATE "passwords" SET deleted_at = CURRENT_TIMESTAMP, deleted_by = $2
+  WHERE created_by = $2 AND deleted_at IS NULL
+)
+INSERT INTO "passwords" (
+  hashed_password, created_by
+) VALUES (
+  $1, $2
+);
+
+-- name: UpdatePasswordHash :exec
+UPDATE "passwords" SET hashed_password = $2, updated_at = CURRENT_TIMESTAMP, updated_by = created_by
+WHERE id = $1 AND deleted_at IS NULL;
\ No newline at end of file
@@ -0,0 +1,17 @@
+-- name: CreateRecoveryCode :exec
+INSERT INTO "recovery_codes" (
+  user_id, hashed_code, created_by
+) VALUES (
+  $1, $2, $3
+);
+
+-- name: ListUnusedRecoveryCodes :many
+SELECT * FROM "recovery_codes" WHERE user_id = $1 AND used_at IS NULL AND deleted_at IS NULL;
+
+-- name: UseRecoveryCode :execrows
+UPDATE "recovery_codes" SET used_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND used_at IS NULL AND deleted_at IS NULL;
+
+-- name: DeleteRecoveryCodes :exec
+UPDATE "recovery_codes" SET deleted_at = CURRENT_TIMESTAMP, deleted_by = $2
+WHERE user_id = $1 AND deleted_at IS NULL;
\ No newline at end of file
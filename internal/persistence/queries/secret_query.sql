@@ -0,0 +1,20 @@
+-- name: CreateSecret :one
+INSERT INTO "secrets" (
+  name, encrypted_value, encrypted_data_key, created_by
+) VALUES (
+  $1, $2, $3, $4
+) RETURNING *;
+
+-- name: FindSecretByName :one
+SELECT * FROM "secrets" WHERE name = $1;
+
+-- name: ListSecrets :many
+SELECT * FROM "secrets" ORDER BY name;
+
+-- name: RotateSecret :one
+UPDATE "secrets" SET encrypted_value = $2, encrypted_data_key = $3, version = version + 1, updated_at = CURRENT_TIMESTAMP, updated_by = $4
+WHERE name = $1
+RETURNING *;
+
+-- name: DeleteSecretByName :execrows
+DELETE FROM "secrets" WHERE name = $1;
\ No newline at end of file
@@ -9,3 +9,6 @@ SELECT * FROM "sessions" WHERE "refresh_token" = $1 AND "app_id" = $2 AND expire
 
 -- name: DeleteSession :exec
 DELETE FROM "sessions" WHERE "user_id" = $1 AND "deleted_at" IS NULL;
+
+-- name: DeleteOtherSessions :exec
+DELETE FROM "sessions" WHERE "user_id" = $1 AND "refresh_token" <> $2 AND "deleted_at" IS NULL;
@@ -0,0 +1,33 @@
+-- name: CreateSigningKey :one
+INSERT INTO "signing_keys" (
+  kid, algo, secret_resolver, encrypted_private_key, state, activates_at, activated_at, app_id, created_by
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9
+) RETURNING *;
+
+-- name: FindSigningKeyByKid :one
+SELECT sqlc.embed(signing_key), sqlc.embed(app) FROM "signing_keys" AS signing_key
+JOIN "apps" AS app ON app.id = signing_key.app_id
+WHERE signing_key.kid = $1 AND signing_key.deleted_at IS NULL AND app.deleted_by IS NULL;
+
+-- name: FindActiveSigningKey :one
+SELECT * FROM "signing_keys" WHERE app_id = $1 AND state = 'active' AND deleted_at IS NULL
+ORDER BY activated_at DESC LIMIT 1;
+
+-- name: ListSigningKeysByAppID :many
+SELECT * FROM "signing_keys" WHERE app_id = $1 AND deleted_at IS NULL ORDER BY created_at;
+
+-- name: ListPublishedSigningKeys :many
+SELECT * FROM "signing_keys" WHERE state IN ('next', 'active', 'retiring') AND deleted_at IS NULL ORDER BY created_at;
+
+-- name: ListDueSigningKeys :many
+SELECT * FROM "signing_keys" WHERE state = 'next' AND activates_at <= CURRENT_TIMESTAMP AND deleted_at IS NULL
+ORDER BY activates_at;
+
+-- name: UpdateSigningKeyState :exec
+UPDATE "signing_keys" SET state = $2, activated_at = $3, expires_at = $4, updated_at = CURRENT_TIMESTAMP, updated_by = $5
+WHERE id = $1;
+
+-- name: RevokeExpiredSigningKeys :exec
+UPDATE "signing_keys" SET state = 'revoked', updated_at = CURRENT_TIMESTAMP
+WHERE state = 'retiring' AND expires_at <= CURRENT_TIMESTAMP;
\ No newline at end of file
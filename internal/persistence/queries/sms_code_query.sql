@@ -0,0 +1,21 @@
+-- name: CreateSmsCode :exec
+INSERT INTO "sms_codes" (
+  user_id, phone_number, purpose, hashed_code, requested_ip, expires_at, created_by
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+);
+
+-- name: FindSmsCode :one
+SELECT * FROM "sms_codes"
+WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP AND deleted_at IS NULL
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: ConsumeSmsCode :one
+UPDATE "sms_codes" SET used_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP, updated_by = user_id
+WHERE id = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP AND deleted_at IS NULL
+RETURNING *;
+
+-- name: RevokeSmsCodes :exec
+UPDATE "sms_codes" SET expires_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP, updated_by = $1
+WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP;
\ No newline at end of file
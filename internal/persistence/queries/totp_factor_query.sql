@@ -0,0 +1,22 @@
+-- name: CreateTotpFactor :one
+INSERT INTO "totp_factors" (
+  user_id, encrypted_secret, created_by
+) VALUES (
+  $1, $2, $3
+) RETURNING *;
+
+-- name: FindTotpFactor :one
+SELECT * FROM "totp_factors" WHERE user_id = $1 AND deleted_at IS NULL
+ORDER BY created_at DESC LIMIT 1;
+
+-- name: ConfirmTotpFactor :exec
+UPDATE "totp_factors" SET confirmed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP, updated_by = user_id
+WHERE id = $1;
+
+-- name: UseTotpStep :execrows
+UPDATE "totp_factors" SET last_used_step = $2
+WHERE id = $1 AND last_used_step < $2 AND deleted_at IS NULL;
+
+-- name: DeleteTotpFactors :exec
+UPDATE "totp_factors" SET deleted_at = CURRENT_TIMESTAMP, deleted_by = $2
+WHERE user_id = $1 AND deleted_at IS NULL;
\ No newline at end of file
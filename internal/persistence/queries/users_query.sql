@@ -10,3 +10,22 @@ SELECT * FROM "users" WHERE email = $1 AND deleted_at IS NULL;
 
 -- name: FindUserByID :one
 SELECT * FROM "users" WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: MarkEmailVerified :exec
+UPDATE "users" SET email_verified_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP, updated_by = $1
+WHERE id = $1 AND email = $2 AND email_verified_at IS NULL AND deleted_at IS NULL;
+
+-- name: SetUserMfaRequired :exec
+UPDATE "users" SET mfa_required = $2, updated_at = CURRENT_TIMESTAMP, updated_by = $3
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: FindUserByPhoneNumber :one
+SELECT * FROM "users" WHERE phone_number = $1 AND phone_verified_at IS NOT NULL AND deleted_at IS NULL;
+
+-- name: SetUserPhoneNumber :exec
+UPDATE "users" SET phone_number = $2, phone_verified_at = NULL, updated_at = CURRENT_TIMESTAMP, updated_by = $1
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: MarkPhoneVerified :exec
+UPDATE "users" SET phone_verified_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP, updated_by = $1
+WHERE id = $1 AND phone_number = $2 AND phone_verified_at IS NULL AND deleted_at IS NULL;
\ No newline at end of file
@@ -0,0 +1,6 @@
+-- name: UseWebauthnChallenge :execrows
+WITH expired AS (
+  DELETE FROM "webauthn_challenges" WHERE expires_at < CURRENT_TIMESTAMP
+)
+INSERT INTO "webauthn_challenges" (challenge, expires_at) VALUES ($1, $2)
+ON CONFLICT (challenge) DO NOTHING;
\ No newline at end of file
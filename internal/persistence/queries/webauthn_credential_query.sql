@@ -0,0 +1,22 @@
+-- name: CreateWebauthnCredential :one
+INSERT INTO "webauthn_credentials" (
+  user_id, credential_id, public_key, attestation_type, aaguid, transports,
+  sign_count, backup_eligible, backup_state, name, created_by
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+) RETURNING *;
+
+-- name: FindWebauthnCredential :one
+SELECT * FROM "webauthn_credentials" WHERE credential_id = $1 AND deleted_at IS NULL;
+
+-- name: ListWebauthnCredentials :many
+SELECT * FROM "webauthn_credentials" WHERE user_id = $1 AND deleted_at IS NULL ORDER BY created_at;
+
+-- name: UpdateWebauthnCredentialUse :exec
+UPDATE "webauthn_credentials" SET sign_count = $2, backup_state = $3, clone_warning = $4,
+  last_used_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP, updated_by = user_id
+WHERE id = $1;
+
+-- name: DeleteWebauthnCredential :execrows
+UPDATE "webauthn_credentials" SET deleted_at = CURRENT_TIMESTAMP, deleted_by = user_id
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
\ No newline at end of file
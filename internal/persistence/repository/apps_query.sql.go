@@ -59,7 +59,7 @@ func (q *Queries) CreateApp(ctx context.Context, arg CreateAppParams) (App, erro
 }
 
 const findAppByClientID = `-- name: FindAppByClientID :one
-SELECT app.id, app.name, app.domain, app.landing_url, app.logo, app.client_id, app.created_at, app.created_by, app.updated_at, app.updated_by, app.deactivated_at, app.deactivated_by, app.deleted_at, app.deleted_by, oauth_config.id, oauth_config.client_secret, oauth_config.redirect_uris, oauth_config.success_callback_url, oauth_config.error_callback_url, oauth_config.jwt_algo, oauth_config.jwt_secret_resolver, oauth_config.jwt_lifetime, oauth_config.refresh_token_lifetime, oauth_config.app_id, oauth_config.created_at, oauth_config.created_by, oauth_config.updated_at, oauth_config.updated_by, oauth_config.deleted_at, oauth_config.deleted_by FROM "apps" AS app
+SELECT app.id, app.name, app.domain, app.landing_url, app.logo, app.client_id, app.created_at, app.created_by, app.updated_at, app.updated_by, app.deactivated_at, app.deactivated_by, app.deleted_at, app.deleted_by, oauth_config.id, oauth_config.redirect_uris, oauth_config.success_callback_url, oauth_config.error_callback_url, oauth_config.jwt_algo, oauth_config.jwt_secret_resolver, oauth_config.jwt_lifetime, oauth_config.refresh_token_lifetime, oauth_config.token_endpoint_auth_method, oauth_config.tls_client_auth_subject_dn, oauth_config.tls_client_certificate, oauth_config.access_token_profile, oauth_config.encryption_jwk, oauth_config.encryption_alg, oauth_config.encryption_enc, oauth_config.require_verified_email, oauth_config.require_mfa, oauth_config.api_resources, oauth_config.app_id, oauth_config.created_at, oauth_config.created_by, oauth_config.updated_at, oauth_config.updated_by, oauth_config.deleted_at, oauth_config.deleted_by FROM "apps" AS app
 LEFT JOIN "oauth_configs" AS oauth_config ON app.id = oauth_config.app_id
 WHERE app.client_id = $1 AND app.deleted_by IS NULL
 `
@@ -88,7 +88,6 @@ func (q *Queries) FindAppByClientID(ctx context.Context, clientID string) (FindA
 		&i.App.DeletedAt,
 		&i.App.DeletedBy,
 		&i.OauthConfig.ID,
-		&i.OauthConfig.ClientSecret,
 		&i.OauthConfig.RedirectUris,
 		&i.OauthConfig.SuccessCallbackUrl,
 		&i.OauthConfig.ErrorCallbackUrl,
@@ -96,6 +95,16 @@ func (q *Queries) FindAppByClientID(ctx context.Context, clientID string) (FindA
 		&i.OauthConfig.JwtSecretResolver,
 		&i.OauthConfig.JwtLifetime,
 		&i.OauthConfig.RefreshTokenLifetime,
+		&i.OauthConfig.TokenEndpointAuthMethod,
+		&i.OauthConfig.TlsClientAuthSubjectDn,
+		&i.OauthConfig.TlsClientCertificate,
+		&i.OauthConfig.AccessTokenProfile,
+		&i.OauthConfig.EncryptionJwk,
+		&i.OauthConfig.EncryptionAlg,
+		&i.OauthConfig.EncryptionEnc,
+		&i.OauthConfig.RequireVerifiedEmail,
+		&i.OauthConfig.RequireMfa,
+		&i.OauthConfig.ApiResources,
 		&i.OauthConfig.AppID,
 		&i.OauthConfig.CreatedAt,
 		&i.OauthConfig.CreatedBy,
@@ -108,7 +117,7 @@ func (q *Queries) FindAppByClientID(ctx context.Context, clientID string) (FindA
 }
 
 const findRootApp = `-- name: FindRootApp :one
-SELECT app.id, app.name, app.domain, app.landing_url, app.logo, app.client_id, app.created_at, app.created_by, app.updated_at, app.updated_by, app.deactivated_at, app.deactivated_by, app.deleted_at, app.deleted_by, oauth_config.id, oauth_config.client_secret, oauth_config.redirect_uris, oauth_config.success_callback_url, oauth_config.error_callback_url, oauth_config.jwt_algo, oauth_config.jwt_secret_resolver, oauth_config.jwt_lifetime, oauth_config.refresh_token_lifetime, oauth_config.app_id, oauth_config.created_at, oauth_config.created_by, oauth_config.updated_at, oauth_config.updated_by, oauth_config.deleted_at, oauth_config.deleted_by FROM "apps" AS app
+SELECT app.id, app.name, app.domain, app.landing_url, app.logo, app.client_id, app.created_at, app.created_by, app.updated_at, app.updated_by, app.deactivated_at, app.deactivated_by, app.deleted_at, app.deleted_by, oauth_config.id, oauth_config.redirect_uris, oauth_config.success_callback_url, oauth_config.error_callback_url, oauth_config.jwt_algo, oauth_config.jwt_secret_resolver, oauth_config.jwt_lifetime, oauth_config.refresh_token_lifetime, oauth_config.token_endpoint_auth_method, oauth_config.tls_client_auth_subject_dn, oauth_config.tls_client_certificate, oauth_config.access_token_profile, oauth_config.encryption_jwk, oauth_config.encryption_alg, oauth_config.encryption_enc, oauth_config.require_verified_email, oauth_config.require_mfa, oauth_config.api_resources, oauth_config.app_id, oauth_config.created_at, oauth_config.created_by, oauth_config.updated_at, oauth_config.updated_by, oauth_config.deleted_at, oauth_config.deleted_by FROM "apps" AS app
 LEFT JOIN "oauth_configs" AS oauth_config ON app.id = oauth_config.app_id
 WHERE app.created_by = '00000000-0000-0000-0000-000000000000' AND app.deleted_by IS NULL
 `
@@ -138,7 +147,6 @@ func (q *Queries) FindRootApp(ctx context.Context) (FindRootAppRow, error) {
 		&i.App.DeletedAt,
 		&i.App.DeletedBy,
 		&i.OauthConfig.ID,
-		&i.OauthConfig.ClientSecret,
 		&i.OauthConfig.RedirectUris,
 		&i.OauthConfig.SuccessCallbackUrl,
 		&i.OauthConfig.ErrorCallbackUrl,
@@ -146,6 +154,16 @@ func (q *Queries) FindRootApp(ctx context.Context) (FindRootAppRow, error) {
 		&i.OauthConfig.JwtSecretResolver,
 		&i.OauthConfig.JwtLifetime,
 		&i.OauthConfig.RefreshTokenLifetime,
+		&i.OauthConfig.TokenEndpointAuthMethod,
+		&i.OauthConfig.TlsClientAuthSubjectDn,
+		&i.OauthConfig.TlsClientCertificate,
+		&i.OauthConfig.AccessTokenProfile,
+		&i.OauthConfig.EncryptionJwk,
+		&i.OauthConfig.EncryptionAlg,
+		&i.OauthConfig.EncryptionEnc,
+		&i.OauthConfig.RequireVerifiedEmail,
+		&i.OauthConfig.RequireMfa,
+		&i.OauthConfig.ApiResources,
 		&i.OauthConfig.AppID,
 		&i.OauthConfig.CreatedAt,
 		&i.OauthConfig.CreatedBy,
@@ -156,3 +174,74 @@ func (q *Queries) FindRootApp(ctx context.Context) (FindRootAppRow, error) {
 	)
 	return i, err
 }
+
+const listApps = `-- name: ListApps :many
+SELECT app.id, app.name, app.domain, app.landing_url, app.logo, app.client_id, app.created_at, app.created_by, app.updated_at, app.updated_by, app.deactivated_at, app.deactivated_by, app.deleted_at, app.deleted_by, oauth_config.id, oauth_config.redirect_uris, oauth_config.success_callback_url, oauth_config.error_callback_url, oauth_config.jwt_algo, oauth_config.jwt_secret_resolver, oauth_config.jwt_lifetime, oauth_config.refresh_token_lifetime, oauth_config.token_endpoint_auth_method, oauth_config.tls_client_auth_subject_dn, oauth_config.tls_client_certificate, oauth_config.access_token_profile, oauth_config.encryption_jwk, oauth_config.encryption_alg, oauth_config.encryption_enc, oauth_config.require_verified_email, oauth_config.require_mfa, oauth_config.api_resources, oauth_config.app_id, oauth_config.created_at, oauth_config.created_by, oauth_config.updated_at, oauth_config.updated_by, oauth_config.deleted_at, oauth_config.deleted_by FROM "apps" AS app
+LEFT JOIN "oauth_configs" AS oauth_config ON app.id = oauth_config.app_id
+WHERE app.deleted_by IS NULL ORDER BY app.created_at
+`
+
+type ListAppsRow struct {
+	App         App         `json:"app"`
+	OauthConfig OauthConfig `json:"oauth_config"`
+}
+
+func (q *Queries) ListApps(ctx context.Context) ([]ListAppsRow, error) {
+	rows, err := q.db.Query(ctx, listApps)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListAppsRow
+	for rows.Next() {
+		var i ListAppsRow
+		if err := rows.Scan(
+			&i.App.ID,
+			&i.App.Name,
+			&i.App.Domain,
+			&i.App.LandingUrl,
+			&i.App.Logo,
+			&i.App.ClientID,
+			&i.App.CreatedAt,
+			&i.App.CreatedBy,
+			&i.App.UpdatedAt,
+			&i.App.UpdatedBy,
+			&i.App.DeactivatedAt,
+			&i.App.DeactivatedBy,
+			&i.App.DeletedAt,
+			&i.App.DeletedBy,
+			&i.OauthConfig.ID,
+			&i.OauthConfig.RedirectUris,
+			&i.OauthConfig.SuccessCallbackUrl,
+			&i.OauthConfig.ErrorCallbackUrl,
+			&i.OauthConfig.JwtAlgo,
+			&i.OauthConfig.JwtSecretResolver,
+			&i.OauthConfig.JwtLifetime,
+			&i.OauthConfig.RefreshTokenLifetime,
+			&i.OauthConfig.TokenEndpointAuthMethod,
+			&i.OauthConfig.TlsClientAuthSubjectDn,
+			&i.OauthConfig.TlsClientCertificate,
+			&i.OauthConfig.AccessTokenProfile,
+			&i.OauthConfig.EncryptionJwk,
+			&i.OauthConfig.EncryptionAlg,
+			&i.OauthConfig.EncryptionEnc,
+			&i.OauthConfig.RequireVerifiedEmail,
+			&i.OauthConfig.RequireMfa,
+			&i.OauthConfig.ApiResources,
+			&i.OauthConfig.AppID,
+			&i.OauthConfig.CreatedAt,
+			&i.OauthConfig.CreatedBy,
+			&i.OauthConfig.UpdatedAt,
+			&i.OauthConfig.UpdatedBy,
+			&i.OauthConfig.DeletedAt,
+			&i.OauthConfig.DeletedBy,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
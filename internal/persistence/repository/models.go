@@ -11,6 +11,21 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type ApiResource struct {
+	ID            uuid.UUID  `json:"id"`
+	Name          string     `json:"name"`
+	Identifier    string     `json:"identifier"`
+	Scopes        []string   `json:"scopes"`
+	TokenLifetime string     `json:"token_lifetime"`
+	CreatedAt     time.Time  `json:"created_at"`
+	CreatedBy     uuid.UUID  `json:"created_by"`
+	UpdatedAt     *time.Time `json:"updated_at"`
+	UpdatedBy     *uuid.UUID `json:"updated_by"`
+	DeletedAt     *time.Time `json:"deleted_at"`
+	DeletedBy     *uuid.UUID `json:"deleted_by"`
+	OwnerID       *uuid.UUID `json:"owner_id"`
+}
+
 type App struct {
 	ID            uuid.UUID   `json:"id"`
 	Name          string      `json:"name"`
@@ -28,31 +43,87 @@ type App struct {
 	DeletedBy     *uuid.UUID  `json:"deleted_by"`
 }
 
+type ClientSecret struct {
+	ID           uuid.UUID  `json:"id"`
+	HashedSecret string     `json:"hashed_secret"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	AppID        uuid.UUID  `json:"app_id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	CreatedBy    uuid.UUID  `json:"created_by"`
+	UpdatedAt    *time.Time `json:"updated_at"`
+	UpdatedBy    *uuid.UUID `json:"updated_by"`
+	DeletedAt    *time.Time `json:"deleted_at"`
+	DeletedBy    *uuid.UUID `json:"deleted_by"`
+}
+
+type Consent struct {
+	ID        uuid.UUID  `json:"id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	AppID     uuid.UUID  `json:"app_id"`
+	CreatedAt time.Time  `json:"created_at"`
+	CreatedBy uuid.UUID  `json:"created_by"`
+	UpdatedAt *time.Time `json:"updated_at"`
+	UpdatedBy *uuid.UUID `json:"updated_by"`
+	DeletedAt *time.Time `json:"deleted_at"`
+	DeletedBy *uuid.UUID `json:"deleted_by"`
+}
+
+type EmailLoginToken struct {
+	ID          uuid.UUID  `json:"id"`
+	HashedToken string     `json:"hashed_token"`
+	HashedCode  string     `json:"hashed_code"`
+	UserID      uuid.UUID  `json:"user_id"`
+	RequestedIp string     `json:"requested_ip"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	UsedAt      *time.Time `json:"used_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CreatedBy   uuid.UUID  `json:"created_by"`
+	UpdatedAt   *time.Time `json:"updated_at"`
+	UpdatedBy   *uuid.UUID `json:"updated_by"`
+	DeletedAt   *time.Time `json:"deleted_at"`
+	DeletedBy   *uuid.UUID `json:"deleted_by"`
+}
+
 type OauthCall struct {
-	ID        uuid.UUID `json:"id"`
-	AppID     uuid.UUID `json:"app_id"`
-	Code      string    `json:"code"`
-	UserID    uuid.UUID `json:"user_id"`
-	ExpiresAt time.Time `json:"expires_at"`
+	ID          uuid.UUID   `json:"id"`
+	AppID       uuid.UUID   `json:"app_id"`
+	Code        string      `json:"code"`
+	UserID      uuid.UUID   `json:"user_id"`
+	Resource    pgtype.Text `json:"resource"`
+	Scope       pgtype.Text `json:"scope"`
+	AuthTime    *time.Time  `json:"auth_time"`
+	Amr         []string    `json:"amr"`
+	Nonce       pgtype.Text `json:"nonce"`
+	ExpiresAt   time.Time   `json:"expires_at"`
+	RedirectUri string      `json:"redirect_uri"`
 }
 
 type OauthConfig struct {
-	ID                   uuid.UUID   `json:"id"`
-	ClientSecret         string      `json:"client_secret"`
-	RedirectUris         []string    `json:"redirect_uris"`
-	SuccessCallbackUrl   string      `json:"success_callback_url"`
-	ErrorCallbackUrl     string      `json:"error_callback_url"`
-	JwtAlgo              string      `json:"jwt_algo"`
-	JwtSecretResolver    pgtype.Text `json:"jwt_secret_resolver"`
-	JwtLifetime          string      `json:"jwt_lifetime"`
-	RefreshTokenLifetime string      `json:"refresh_token_lifetime"`
-	AppID                uuid.UUID   `json:"app_id"`
-	CreatedAt            time.Time   `json:"created_at"`
-	CreatedBy            uuid.UUID   `json:"created_by"`
-	UpdatedAt            *time.Time  `json:"updated_at"`
-	UpdatedBy            *uuid.UUID  `json:"updated_by"`
-	DeletedAt            *time.Time  `json:"deleted_at"`
-	DeletedBy            *uuid.UUID  `json:"deleted_by"`
+	ID                      uuid.UUID   `json:"id"`
+	RedirectUris            []string    `json:"redirect_uris"`
+	SuccessCallbackUrl      string      `json:"success_callback_url"`
+	ErrorCallbackUrl        string      `json:"error_callback_url"`
+	JwtAlgo                 string      `json:"jwt_algo"`
+	JwtSecretResolver       pgtype.Text `json:"jwt_secret_resolver"`
+	JwtLifetime             string      `json:"jwt_lifetime"`
+	RefreshTokenLifetime    string      `json:"refresh_token_lifetime"`
+	TokenEndpointAuthMethod string      `json:"token_endpoint_auth_method"`
+	TlsClientAuthSubjectDn  pgtype.Text `json:"tls_client_auth_subject_dn"`
+	TlsClientCertificate    pgtype.Text `json:"tls_client_certificate"`
+	AccessTokenProfile      string      `json:"access_token_profile"`
+	EncryptionJwk           pgtype.Text `json:"encryption_jwk"`
+	EncryptionAlg           pgtype.Text `json:"encryption_alg"`
+	EncryptionEnc           pgtype.Text `json:"encryption_enc"`
+	RequireVerifiedEmail    bool        `json:"require_verified_email"`
+	RequireMfa              bool        `json:"require_mfa"`
+	ApiResources            []string    `json:"api_resources"`
+	AppID                   uuid.UUID   `json:"app_id"`
+	CreatedAt               time.Time   `json:"created_at"`
+	CreatedBy               uuid.UUID   `json:"created_by"`
+	UpdatedAt               *time.Time  `json:"updated_at"`
+	UpdatedBy               *uuid.UUID  `json:"updated_by"`
+	DeletedAt               *time.Time  `json:"deleted_at"`
+	DeletedBy               *uuid.UUID  `json:"deleted_by"`
 }
 
 type Password struct {
@@ -66,6 +137,46 @@ type Password struct {
 	DeletedBy      *uuid.UUID `json:"deleted_by"`
 }
 
+type PasswordResetToken struct {
+	ID          uuid.UUID  `json:"id"`
+	HashedToken string     `json:"hashed_token"`
+	UserID      uuid.UUID  `json:"user_id"`
+	RequestedIp string     `json:"requested_ip"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	UsedAt      *time.Time `json:"used_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CreatedBy   uuid.UUID  `json:"created_by"`
+	UpdatedAt   *time.Time `json:"updated_at"`
+	UpdatedBy   *uuid.UUID `json:"updated_by"`
+	DeletedAt   *time.Time `json:"deleted_at"`
+	DeletedBy   *uuid.UUID `json:"deleted_by"`
+}
+
+type RecoveryCode struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	HashedCode string     `json:"hashed_code"`
+	UsedAt     *time.Time `json:"used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	CreatedBy  uuid.UUID  `json:"created_by"`
+	UpdatedAt  *time.Time `json:"updated_at"`
+	UpdatedBy  *uuid.UUID `json:"updated_by"`
+	DeletedAt  *time.Time `json:"deleted_at"`
+	DeletedBy  *uuid.UUID `json:"deleted_by"`
+}
+
+type Secret struct {
+	ID               uuid.UUID  `json:"id"`
+	Name             string     `json:"name"`
+	EncryptedValue   string     `json:"encrypted_value"`
+	EncryptedDataKey string     `json:"encrypted_data_key"`
+	Version          int32      `json:"version"`
+	CreatedAt        time.Time  `json:"created_at"`
+	CreatedBy        uuid.UUID  `json:"created_by"`
+	UpdatedAt        *time.Time `json:"updated_at"`
+	UpdatedBy        *uuid.UUID `json:"updated_by"`
+}
+
 type Session struct {
 	ID           uuid.UUID  `json:"id"`
 	UserID       uuid.UUID  `json:"user_id"`
@@ -82,17 +193,99 @@ type Session struct {
 	DeletedBy    *uuid.UUID `json:"deleted_by"`
 }
 
+type SmsCode struct {
+	ID          uuid.UUID  `json:"id"`
+	UserID      uuid.UUID  `json:"user_id"`
+	PhoneNumber string     `json:"phone_number"`
+	Purpose     string     `json:"purpose"`
+	HashedCode  string     `json:"hashed_code"`
+	RequestedIp string     `json:"requested_ip"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	UsedAt      *time.Time `json:"used_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CreatedBy   uuid.UUID  `json:"created_by"`
+	UpdatedAt   *time.Time `json:"updated_at"`
+	UpdatedBy   *uuid.UUID `json:"updated_by"`
+	DeletedAt   *time.Time `json:"deleted_at"`
+	DeletedBy   *uuid.UUID `json:"deleted_by"`
+}
+
+type SigningKey struct {
+	ID                  uuid.UUID   `json:"id"`
+	Kid                 string      `json:"kid"`
+	Algo                string      `json:"algo"`
+	SecretResolver      pgtype.Text `json:"secret_resolver"`
+	EncryptedPrivateKey pgtype.Text `json:"encrypted_private_key"`
+	State               string      `json:"state"`
+	ActivatesAt         *time.Time  `json:"activates_at"`
+	ActivatedAt         *time.Time  `json:"activated_at"`
+	ExpiresAt           *time.Time  `json:"expires_at"`
+	AppID               uuid.UUID   `json:"app_id"`
+	CreatedAt           time.Time   `json:"created_at"`
+	CreatedBy           uuid.UUID   `json:"created_by"`
+	UpdatedAt           *time.Time  `json:"updated_at"`
+	UpdatedBy           *uuid.UUID  `json:"updated_by"`
+	DeletedAt           *time.Time  `json:"deleted_at"`
+	DeletedBy           *uuid.UUID  `json:"deleted_by"`
+}
+
+type TotpFactor struct {
+	ID              uuid.UUID  `json:"id"`
+	UserID          uuid.UUID  `json:"user_id"`
+	EncryptedSecret string     `json:"encrypted_secret"`
+	ConfirmedAt     *time.Time `json:"confirmed_at"`
+	LastUsedStep    int64      `json:"last_used_step"`
+	CreatedAt       time.Time  `json:"created_at"`
+	CreatedBy       uuid.UUID  `json:"created_by"`
+	UpdatedAt       *time.Time `json:"updated_at"`
+	UpdatedBy       *uuid.UUID `json:"updated_by"`
+	DeletedAt       *time.Time `json:"deleted_at"`
+	DeletedBy       *uuid.UUID `json:"deleted_by"`
+}
+
 type User struct {
-	ID            uuid.UUID   `json:"id"`
-	Email         string      `json:"email"`
-	Name          string      `json:"name"`
-	Dp            pgtype.Text `json:"dp"`
-	CreatedAt     time.Time   `json:"created_at"`
-	CreatedBy     uuid.UUID   `json:"created_by"`
-	UpdatedAt     *time.Time  `json:"updated_at"`
-	UpdatedBy     *uuid.UUID  `json:"updated_by"`
-	DeactivatedAt *time.Time  `json:"deactivated_at"`
-	DeactivatedBy *uuid.UUID  `json:"deactivated_by"`
-	DeletedAt     *time.Time  `json:"deleted_at"`
-	DeletedBy     *uuid.UUID  `json:"deleted_by"`
+	ID              uuid.UUID   `json:"id"`
+	Email           string      `json:"email"`
+	Name            string      `json:"name"`
+	Dp              pgtype.Text `json:"dp"`
+	EmailVerifiedAt *time.Time  `json:"email_verified_at"`
+	MfaRequired     bool        `json:"mfa_required"`
+	PhoneNumber     pgtype.Text `json:"phone_number"`
+	PhoneVerifiedAt *time.Time  `json:"phone_verified_at"`
+	CreatedAt       time.Time   `json:"created_at"`
+	CreatedBy       uuid.UUID   `json:"created_by"`
+	UpdatedAt       *time.Time  `json:"updated_at"`
+	UpdatedBy       *uuid.UUID  `json:"updated_by"`
+	DeactivatedAt   *time.Time  `json:"deactivated_at"`
+	DeactivatedBy   *uuid.UUID  `json:"deactivated_by"`
+	DeletedAt       *time.Time  `json:"deleted_at"`
+	DeletedBy       *uuid.UUID  `json:"deleted_by"`
+}
+
+type WebauthnChallenge struct {
+	Challenge string    `json:"challenge"`
+	ExpiresAt time.Time `json:"expires_at"`
+	UsedAt    time.Time `json:"used_at"`
+}
+
+type WebauthnCredential struct {
+	ID              uuid.UUID  `json:"id"`
+	UserID          uuid.UUID  `json:"user_id"`
+	CredentialID    string     `json:"credential_id"`
+	PublicKey       []byte     `json:"public_key"`
+	AttestationType string     `json:"attestation_type"`
+	Aaguid          []byte     `json:"aaguid"`
+	Transports      []string   `json:"transports"`
+	SignCount       int64      `json:"sign_count"`
+	BackupEligible  bool       `json:"backup_eligible"`
+	BackupState     bool       `json:"backup_state"`
+	CloneWarning    bool       `json:"clone_warning"`
+	Name            string     `json:"name"`
+	LastUsedAt      *time.Time `json:"last_used_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	CreatedBy       uuid.UUID  `json:"created_by"`
+	UpdatedAt       *time.Time `json:"updated_at"`
+	UpdatedBy       *uuid.UUID `json:"updated_by"`
+	DeletedAt       *time.Time `json:"deleted_at"`
+	DeletedBy       *uuid.UUID `json:"deleted_by"`
 }
@@ -12,32 +12,58 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const addAppApiResource = `-- name: AddAppApiResource :exec
+UPDATE "oauth_configs"
+SET "api_resources" = array_append(COALESCE("api_resources", '{}'), $1::TEXT), "updated_at" = CURRENT_TIMESTAMP, "updated_by" = $2
+WHERE "app_id" = $3 AND NOT ($1::TEXT = ANY(COALESCE("api_resources", '{}')))
+`
+
+type AddAppApiResourceParams struct {
+	Identifier string     `json:"identifier"`
+	UpdatedBy  *uuid.UUID `json:"updated_by"`
+	AppID      uuid.UUID  `json:"app_id"`
+}
+
+func (q *Queries) AddAppApiResource(ctx context.Context, arg AddAppApiResourceParams) error {
+	_, err := q.db.Exec(ctx, addAppApiResource, arg.Identifier, arg.UpdatedBy, arg.AppID)
+	return err
+}
+
 const createOauthInfo = `-- name: CreateOauthInfo :exec
 INSERT INTO "oauth_configs" (
-  client_secret, redirect_uris, success_callback_url, error_callback_url, 
+  redirect_uris, success_callback_url, error_callback_url, 
   jwt_algo, jwt_secret_resolver, jwt_lifetime, refresh_token_lifetime, app_id,
-  created_by
+  created_by, token_endpoint_auth_method, tls_client_auth_subject_dn, tls_client_certificate,
+  access_token_profile, encryption_jwk, encryption_alg, encryption_enc,
+  require_verified_email, require_mfa
 ) VALUES (
-  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18
 )
 `
 
 type CreateOauthInfoParams struct {
-	ClientSecret         string      `json:"client_secret"`
-	RedirectUris         []string    `json:"redirect_uris"`
-	SuccessCallbackUrl   string      `json:"success_callback_url"`
-	ErrorCallbackUrl     string      `json:"error_callback_url"`
-	JwtAlgo              string      `json:"jwt_algo"`
-	JwtSecretResolver    pgtype.Text `json:"jwt_secret_resolver"`
-	JwtLifetime          string      `json:"jwt_lifetime"`
-	RefreshTokenLifetime string      `json:"refresh_token_lifetime"`
-	AppID                uuid.UUID   `json:"app_id"`
-	CreatedBy            uuid.UUID   `json:"created_by"`
+	RedirectUris            []string    `json:"redirect_uris"`
+	SuccessCallbackUrl      string      `json:"success_callback_url"`
+	ErrorCallbackUrl        string      `json:"error_callback_url"`
+	JwtAlgo                 string      `json:"jwt_algo"`
+	JwtSecretResolver       pgtype.Text `json:"jwt_secret_resolver"`
+	JwtLifetime             string      `json:"jwt_lifetime"`
+	RefreshTokenLifetime    string      `json:"refresh_token_lifetime"`
+	AppID                   uuid.UUID   `json:"app_id"`
+	CreatedBy               uuid.UUID   `json:"created_by"`
+	TokenEndpointAuthMethod string      `json:"token_endpoint_auth_method"`
+	TlsClientAuthSubjectDn  pgtype.Text `json:"tls_client_auth_subject_dn"`
+	TlsClientCertificate    pgtype.Text `json:"tls_client_certificate"`
+	AccessTokenProfile      string      `json:"access_token_profile"`
+	EncryptionJwk           pgtype.Text `json:"encryption_jwk"`
+	EncryptionAlg           pgtype.Text `json:"encryption_alg"`
+	EncryptionEnc           pgtype.Text `json:"encryption_enc"`
+	RequireVerifiedEmail    bool        `json:"require_verified_email"`
+	RequireMfa              bool        `json:"require_mfa"`
 }
 
 func (q *Queries) CreateOauthInfo(ctx context.Context, arg CreateOauthInfoParams) error {
 	_, err := q.db.Exec(ctx, createOauthInfo,
-		arg.ClientSecret,
 		arg.RedirectUris,
 		arg.SuccessCallbackUrl,
 		arg.ErrorCallbackUrl,
@@ -47,6 +73,32 @@ func (q *Queries) CreateOauthInfo(ctx context.Context, arg CreateOauthInfoParams
 		arg.RefreshTokenLifetime,
 		arg.AppID,
 		arg.CreatedBy,
+		arg.TokenEndpointAuthMethod,
+		arg.TlsClientAuthSubjectDn,
+		arg.TlsClientCertificate,
+		arg.AccessTokenProfile,
+		arg.EncryptionJwk,
+		arg.EncryptionAlg,
+		arg.EncryptionEnc,
+		arg.RequireVerifiedEmail,
+		arg.RequireMfa,
 	)
 	return err
 }
+
+const removeAppApiResource = `-- name: RemoveAppApiResource :exec
+UPDATE "oauth_configs"
+SET "api_resources" = array_remove("api_resources", $1::TEXT), "updated_at" = CURRENT_TIMESTAMP, "updated_by" = $2
+WHERE "app_id" = $3
+`
+
+type RemoveAppApiResourceParams struct {
+	Identifier string     `json:"identifier"`
+	UpdatedBy  *uuid.UUID `json:"updated_by"`
+	AppID      uuid.UUID  `json:"app_id"`
+}
+
+func (q *Queries) RemoveAppApiResource(ctx context.Context, arg RemoveAppApiResourceParams) error {
+	_, err := q.db.Exec(ctx, removeAppApiResource, arg.Identifier, arg.UpdatedBy, arg.AppID)
+	return err
+}
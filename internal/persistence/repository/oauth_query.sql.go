@@ -10,17 +10,24 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const createOauthCall = `-- name: CreateOauthCall :exec
-INSERT INTO "oauth_calls" (app_id, code, user_id, expires_at) VALUES ($1, $2, $3, $4)
+INSERT INTO "oauth_calls" (app_id, code, user_id, resource, scope, auth_time, amr, nonce, expires_at, redirect_uri) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 `
 
 type CreateOauthCallParams struct {
-	AppID     uuid.UUID `json:"app_id"`
-	Code      string    `json:"code"`
-	UserID    uuid.UUID `json:"user_id"`
-	ExpiresAt time.Time `json:"expires_at"`
+	AppID       uuid.UUID   `json:"app_id"`
+	Code        string      `json:"code"`
+	UserID      uuid.UUID   `json:"user_id"`
+	Resource    pgtype.Text `json:"resource"`
+	Scope       pgtype.Text `json:"scope"`
+	AuthTime    *time.Time  `json:"auth_time"`
+	Amr         []string    `json:"amr"`
+	Nonce       pgtype.Text `json:"nonce"`
+	ExpiresAt   time.Time   `json:"expires_at"`
+	RedirectUri string      `json:"redirect_uri"`
 }
 
 func (q *Queries) CreateOauthCall(ctx context.Context, arg CreateOauthCallParams) error {
@@ -28,24 +35,36 @@ func (q *Queries) CreateOauthCall(ctx context.Context, arg CreateOauthCallParams
 		arg.AppID,
 		arg.Code,
 		arg.UserID,
+		arg.Resource,
+		arg.Scope,
+		arg.AuthTime,
+		arg.Amr,
+		arg.Nonce,
 		arg.ExpiresAt,
+		arg.RedirectUri,
 	)
 	return err
 }
 
-const findOauthCallByCode = `-- name: FindOauthCallByCode :one
-SELECT id, app_id, code, user_id, expires_at FROM "oauth_calls" WHERE code = $1 AND expires_at > NOW()
+const consumeOauthCall = `-- name: ConsumeOauthCall :one
+DELETE FROM "oauth_calls" WHERE code = $1 AND expires_at > NOW() RETURNING id, app_id, code, user_id, resource, scope, auth_time, amr, nonce, expires_at, redirect_uri
 `
 
-func (q *Queries) FindOauthCallByCode(ctx context.Context, code string) (OauthCall, error) {
-	row := q.db.QueryRow(ctx, findOauthCallByCode, code)
+func (q *Queries) ConsumeOauthCall(ctx context.Context, code string) (OauthCall, error) {
+	row := q.db.QueryRow(ctx, consumeOauthCall, code)
 	var i OauthCall
 	err := row.Scan(
 		&i.ID,
 		&i.AppID,
 		&i.Code,
 		&i.UserID,
+		&i.Resource,
+		&i.Scope,
+		&i.AuthTime,
+		&i.Amr,
+		&i.Nonce,
 		&i.ExpiresAt,
+		&i.RedirectUri,
 	)
 	return i, err
 }
@@ -48,3 +48,79 @@ func (q *Queries) FindUserPassword(ctx context.Context, createdBy uuid.UUID) (Pa
 	)
 	return i, err
 }
+
+const listPasswordHistory = `-- name: ListPasswordHistory :many
+SELECT id, hashed_password, created_at, created_by, updated_at, updated_by, deleted_at, deleted_by FROM "passwords" WHERE created_by = $1
+ORDER BY created_at DESC LIMIT $2
+`
+
+type ListPasswordHistoryParams struct {
+	CreatedBy uuid.UUID `json:"created_by"`
+	Limit     int32     `json:"limit"`
+}
+
+func (q *Queries) ListPasswordHistory(ctx context.Context, arg ListPasswordHistoryParams) ([]Password, error) {
+	rows, err := q.db.Query(ctx, listPasswordHistory, arg.CreatedBy, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Password
+	for rows.Next() {
+		var i Password
+		if err := rows.Scan(
+			&i.ID,
+			&i.HashedPassword,
+			&i.CreatedAt,
+			&i.CreatedBy,
+			&i.UpdatedAt,
+			&i.UpdatedBy,
+			&i.DeletedAt,
+			&i.DeletedBy,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const replaceUserPassword = `-- name: ReplaceUserPassword :exec
+WITH replaced AS (
+  UPDATE "passwords" SET deleted_at = CURRENT_TIMESTAMP, deleted_by = $2
+  WHERE created_by = $2 AND deleted_at IS NULL
+)
+INSERT INTO "passwords" (
+  hashed_password, created_by
+) VALUES (
+  $1, $2
+)
+`
+
+type ReplaceUserPasswordParams struct {
+	HashedPassword string    `json:"hashed_password"`
+	CreatedBy      uuid.UUID `json:"created_by"`
+}
+
+func (q *Queries) ReplaceUserPassword(ctx context.Context, arg ReplaceUserPasswordParams) error {
+	_, err := q.db.Exec(ctx, replaceUserPassword, arg.HashedPassword, arg.CreatedBy)
+	return err
+}
+
+const updatePasswordHash = `-- name: UpdatePasswordHash :exec
+UPDATE "passwords" SET hashed_password = $2, updated_at = CURRENT_TIMESTAMP, updated_by = created_by
+WHERE id = $1 AND deleted_at IS NULL
+`
+
+type UpdatePasswordHashParams struct {
+	ID             uuid.UUID `json:"id"`
+	HashedPassword string    `json:"hashed_password"`
+}
+
+func (q *Queries) UpdatePasswordHash(ctx context.Context, arg UpdatePasswordHashParams) error {
+	_, err := q.db.Exec(ctx, updatePasswordHash, arg.ID, arg.HashedPassword)
+	return err
+}
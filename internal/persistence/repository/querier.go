@@ -8,24 +8,86 @@ import (
 	"context"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Querier interface {
+	AddAppApiResource(ctx context.Context, arg AddAppApiResourceParams) error
+	ConfirmTotpFactor(ctx context.Context, id uuid.UUID) error
+	ConsumeEmailLoginToken(ctx context.Context, id uuid.UUID) (EmailLoginToken, error)
+	ConsumeOauthCall(ctx context.Context, code string) (OauthCall, error)
+	ConsumePasswordResetToken(ctx context.Context, hashedToken string) (PasswordResetToken, error)
+	ConsumeSmsCode(ctx context.Context, id uuid.UUID) (SmsCode, error)
+	CreateApiResource(ctx context.Context, arg CreateApiResourceParams) (ApiResource, error)
 	CreateApp(ctx context.Context, arg CreateAppParams) (App, error)
+	CreateClientSecret(ctx context.Context, arg CreateClientSecretParams) (ClientSecret, error)
+	CreateEmailLoginToken(ctx context.Context, arg CreateEmailLoginTokenParams) error
 	CreateOauthCall(ctx context.Context, arg CreateOauthCallParams) error
 	CreateOauthInfo(ctx context.Context, arg CreateOauthInfoParams) error
 	CreatePasswordForUser(ctx context.Context, arg CreatePasswordForUserParams) error
+	CreatePasswordResetToken(ctx context.Context, arg CreatePasswordResetTokenParams) error
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) error
+	CreateSecret(ctx context.Context, arg CreateSecretParams) (Secret, error)
 	CreateSession(ctx context.Context, arg CreateSessionParams) error
+	CreateSigningKey(ctx context.Context, arg CreateSigningKeyParams) (SigningKey, error)
+	CreateSmsCode(ctx context.Context, arg CreateSmsCodeParams) error
+	CreateTotpFactor(ctx context.Context, arg CreateTotpFactorParams) (TotpFactor, error)
+	CreateWebauthnCredential(ctx context.Context, arg CreateWebauthnCredentialParams) (WebauthnCredential, error)
+	DeleteOtherSessions(ctx context.Context, arg DeleteOtherSessionsParams) error
+	DeleteRecoveryCodes(ctx context.Context, arg DeleteRecoveryCodesParams) error
+	DeleteSecretByName(ctx context.Context, name string) (int64, error)
 	DeleteSession(ctx context.Context, userID uuid.UUID) error
+	DeleteTotpFactors(ctx context.Context, arg DeleteTotpFactorsParams) error
+	DeleteWebauthnCredential(ctx context.Context, arg DeleteWebauthnCredentialParams) (int64, error)
+	ExpireClientSecret(ctx context.Context, arg ExpireClientSecretParams) error
+	FindActiveSigningKey(ctx context.Context, appID uuid.UUID) (SigningKey, error)
+	FindApiResourceByIdentifier(ctx context.Context, identifier string) (ApiResource, error)
 	FindAppByClientID(ctx context.Context, clientID string) (FindAppByClientIDRow, error)
-	FindOauthCallByCode(ctx context.Context, code string) (OauthCall, error)
+	FindConsent(ctx context.Context, arg FindConsentParams) (Consent, error)
+	FindEmailLoginToken(ctx context.Context, id uuid.UUID) (EmailLoginToken, error)
+	FindEmailLoginTokenByHash(ctx context.Context, hashedToken string) (EmailLoginToken, error)
+	FindPasswordResetToken(ctx context.Context, hashedToken string) (PasswordResetToken, error)
 	// TODO: find some other way of finding the root app
 	FindRootApp(ctx context.Context) (FindRootAppRow, error)
+	FindSecretByName(ctx context.Context, name string) (Secret, error)
 	FindSessionByRefreshTokenAndAppID(ctx context.Context, arg FindSessionByRefreshTokenAndAppIDParams) (Session, error)
+	FindSigningKeyByKid(ctx context.Context, kid string) (FindSigningKeyByKidRow, error)
+	FindSmsCode(ctx context.Context, arg FindSmsCodeParams) (SmsCode, error)
+	FindTotpFactor(ctx context.Context, userID uuid.UUID) (TotpFactor, error)
 	FindUserByEmail(ctx context.Context, email string) (User, error)
 	FindUserByID(ctx context.Context, id uuid.UUID) (User, error)
+	FindUserByPhoneNumber(ctx context.Context, phoneNumber pgtype.Text) (User, error)
 	FindUserPassword(ctx context.Context, createdBy uuid.UUID) (Password, error)
+	FindWebauthnCredential(ctx context.Context, credentialID string) (WebauthnCredential, error)
+	ListApiResources(ctx context.Context) ([]ApiResource, error)
+	ListApps(ctx context.Context) ([]ListAppsRow, error)
+	ListDueSigningKeys(ctx context.Context) ([]SigningKey, error)
+	ListPasswordHistory(ctx context.Context, arg ListPasswordHistoryParams) ([]Password, error)
+	ListPublishedSigningKeys(ctx context.Context) ([]SigningKey, error)
+	ListSecrets(ctx context.Context) ([]Secret, error)
+	ListSigningKeysByAppID(ctx context.Context, appID uuid.UUID) ([]SigningKey, error)
+	ListUnusedRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]RecoveryCode, error)
+	ListValidClientSecrets(ctx context.Context, appID uuid.UUID) ([]ClientSecret, error)
+	ListWebauthnCredentials(ctx context.Context, userID uuid.UUID) ([]WebauthnCredential, error)
+	MarkEmailVerified(ctx context.Context, arg MarkEmailVerifiedParams) error
+	MarkPhoneVerified(ctx context.Context, arg MarkPhoneVerifiedParams) error
 	RegisterUser(ctx context.Context, arg RegisterUserParams) (User, error)
+	ReplaceUserPassword(ctx context.Context, arg ReplaceUserPasswordParams) error
+	RevokeEmailLoginTokens(ctx context.Context, userID uuid.UUID) error
+	RevokeExpiredSigningKeys(ctx context.Context) error
+	RevokePasswordResetTokens(ctx context.Context, userID uuid.UUID) error
+	RemoveAppApiResource(ctx context.Context, arg RemoveAppApiResourceParams) error
+	RevokeSmsCodes(ctx context.Context, arg RevokeSmsCodesParams) error
+	RotateSecret(ctx context.Context, arg RotateSecretParams) (Secret, error)
+	SetUserMfaRequired(ctx context.Context, arg SetUserMfaRequiredParams) error
+	SetUserPhoneNumber(ctx context.Context, arg SetUserPhoneNumberParams) error
+	UpdatePasswordHash(ctx context.Context, arg UpdatePasswordHashParams) error
+	UpdateSigningKeyState(ctx context.Context, arg UpdateSigningKeyStateParams) error
+	UpdateWebauthnCredentialUse(ctx context.Context, arg UpdateWebauthnCredentialUseParams) error
+	UpsertConsent(ctx context.Context, arg UpsertConsentParams) error
+	UseRecoveryCode(ctx context.Context, id uuid.UUID) (int64, error)
+	UseTotpStep(ctx context.Context, arg UseTotpStepParams) (int64, error)
+	UseWebauthnChallenge(ctx context.Context, arg UseWebauthnChallengeParams) (int64, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -43,6 +43,20 @@ func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) er
 	return err
 }
 
+const deleteOtherSessions = `-- name: DeleteOtherSessions :exec
+DELETE FROM "sessions" WHERE "user_id" = $1 AND "refresh_token" <> $2 AND "deleted_at" IS NULL
+`
+
+type DeleteOtherSessionsParams struct {
+	UserID       uuid.UUID `json:"user_id"`
+	RefreshToken string    `json:"refresh_token"`
+}
+
+func (q *Queries) DeleteOtherSessions(ctx context.Context, arg DeleteOtherSessionsParams) error {
+	_, err := q.db.Exec(ctx, deleteOtherSessions, arg.UserID, arg.RefreshToken)
+	return err
+}
+
 const deleteSession = `-- name: DeleteSession :exec
 DELETE FROM "sessions" WHERE "user_id" = $1 AND "deleted_at" IS NULL
 `
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TxBeginner is a DBTX that can start a transaction, like pgxpool.Pool or a
+// pgx.Tx which starts a savepoint.
+type TxBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// InTx runs fn with queries bound to a transaction, it is committed when fn
+// succeeds and rolled back otherwise. On a DBTX that can not begin one fn
+// runs with q.
+func (q *Queries) InTx(ctx context.Context, fn func(Querier) error) error {
+	beginner, ok := q.db.(TxBeginner)
+	if !ok {
+		return fn(q)
+	}
+	tx, err := beginner.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	// a no-op once committed
+	defer tx.Rollback(ctx)
+	if err := fn(q.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
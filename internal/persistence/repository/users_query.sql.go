@@ -9,10 +9,11 @@ import (
 	"context"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const findUserByEmail = `-- name: FindUserByEmail :one
-SELECT id, email, name, dp, created_at, created_by, updated_at, updated_by, deactivated_at, deactivated_by, deleted_at, deleted_by FROM "users" WHERE email = $1 AND deleted_at IS NULL
+SELECT id, email, name, dp, email_verified_at, mfa_required, phone_number, phone_verified_at, created_at, created_by, updated_at, updated_by, deactivated_at, deactivated_by, deleted_at, deleted_by FROM "users" WHERE email = $1 AND deleted_at IS NULL
 `
 
 func (q *Queries) FindUserByEmail(ctx context.Context, email string) (User, error) {
@@ -23,6 +24,10 @@ func (q *Queries) FindUserByEmail(ctx context.Context, email string) (User, erro
 		&i.Email,
 		&i.Name,
 		&i.Dp,
+		&i.EmailVerifiedAt,
+		&i.MfaRequired,
+		&i.PhoneNumber,
+		&i.PhoneVerifiedAt,
 		&i.CreatedAt,
 		&i.CreatedBy,
 		&i.UpdatedAt,
@@ -36,7 +41,7 @@ func (q *Queries) FindUserByEmail(ctx context.Context, email string) (User, erro
 }
 
 const findUserByID = `-- name: FindUserByID :one
-SELECT id, email, name, dp, created_at, created_by, updated_at, updated_by, deactivated_at, deactivated_by, deleted_at, deleted_by FROM "users" WHERE id = $1 AND deleted_at IS NULL
+SELECT id, email, name, dp, email_verified_at, mfa_required, phone_number, phone_verified_at, created_at, created_by, updated_at, updated_by, deactivated_at, deactivated_by, deleted_at, deleted_by FROM "users" WHERE id = $1 AND deleted_at IS NULL
 `
 
 func (q *Queries) FindUserByID(ctx context.Context, id uuid.UUID) (User, error) {
@@ -47,6 +52,10 @@ func (q *Queries) FindUserByID(ctx context.Context, id uuid.UUID) (User, error)
 		&i.Email,
 		&i.Name,
 		&i.Dp,
+		&i.EmailVerifiedAt,
+		&i.MfaRequired,
+		&i.PhoneNumber,
+		&i.PhoneVerifiedAt,
 		&i.CreatedAt,
 		&i.CreatedBy,
 		&i.UpdatedAt,
@@ -59,12 +68,70 @@ func (q *Queries) FindUserByID(ctx context.Context, id uuid.UUID) (User, error)
 	return i, err
 }
 
+const findUserByPhoneNumber = `-- name: FindUserByPhoneNumber :one
+SELECT id, email, name, dp, email_verified_at, mfa_required, phone_number, phone_verified_at, created_at, created_by, updated_at, updated_by, deactivated_at, deactivated_by, deleted_at, deleted_by FROM "users" WHERE phone_number = $1 AND phone_verified_at IS NOT NULL AND deleted_at IS NULL
+`
+
+func (q *Queries) FindUserByPhoneNumber(ctx context.Context, phoneNumber pgtype.Text) (User, error) {
+	row := q.db.QueryRow(ctx, findUserByPhoneNumber, phoneNumber)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Name,
+		&i.Dp,
+		&i.EmailVerifiedAt,
+		&i.MfaRequired,
+		&i.PhoneNumber,
+		&i.PhoneVerifiedAt,
+		&i.CreatedAt,
+		&i.CreatedBy,
+		&i.UpdatedAt,
+		&i.UpdatedBy,
+		&i.DeactivatedAt,
+		&i.DeactivatedBy,
+		&i.DeletedAt,
+		&i.DeletedBy,
+	)
+	return i, err
+}
+
+const markEmailVerified = `-- name: MarkEmailVerified :exec
+UPDATE "users" SET email_verified_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP, updated_by = $1
+WHERE id = $1 AND email = $2 AND email_verified_at IS NULL AND deleted_at IS NULL
+`
+
+type MarkEmailVerifiedParams struct {
+	ID    uuid.UUID `json:"id"`
+	Email string    `json:"email"`
+}
+
+func (q *Queries) MarkEmailVerified(ctx context.Context, arg MarkEmailVerifiedParams) error {
+	_, err := q.db.Exec(ctx, markEmailVerified, arg.ID, arg.Email)
+	return err
+}
+
+const markPhoneVerified = `-- name: MarkPhoneVerified :exec
+UPDATE "users" SET phone_verified_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP, updated_by = $1
+WHERE id = $1 AND phone_number = $2 AND phone_verified_at IS NULL AND deleted_at IS NULL
+`
+
+type MarkPhoneVerifiedParams struct {
+	ID          uuid.UUID   `json:"id"`
+	PhoneNumber pgtype.Text `json:"phone_number"`
+}
+
+func (q *Queries) MarkPhoneVerified(ctx context.Context, arg MarkPhoneVerifiedParams) error {
+	_, err := q.db.Exec(ctx, markPhoneVerified, arg.ID, arg.PhoneNumber)
+	return err
+}
+
 const registerUser = `-- name: RegisterUser :one
 INSERT INTO "users" (
   id, email, name, created_by
 ) VALUES (
   $1, $2, $3, $4
-) RETURNING id, email, name, dp, created_at, created_by, updated_at, updated_by, deactivated_at, deactivated_by, deleted_at, deleted_by
+) RETURNING id, email, name, dp, email_verified_at, mfa_required, phone_number, phone_verified_at, created_at, created_by, updated_at, updated_by, deactivated_at, deactivated_by, deleted_at, deleted_by
 `
 
 type RegisterUserParams struct {
@@ -87,6 +154,10 @@ func (q *Queries) RegisterUser(ctx context.Context, arg RegisterUserParams) (Use
 		&i.Email,
 		&i.Name,
 		&i.Dp,
+		&i.EmailVerifiedAt,
+		&i.MfaRequired,
+		&i.PhoneNumber,
+		&i.PhoneVerifiedAt,
 		&i.CreatedAt,
 		&i.CreatedBy,
 		&i.UpdatedAt,
@@ -98,3 +169,34 @@ func (q *Queries) RegisterUser(ctx context.Context, arg RegisterUserParams) (Use
 	)
 	return i, err
 }
+
+const setUserMfaRequired = `-- name: SetUserMfaRequired :exec
+UPDATE "users" SET mfa_required = $2, updated_at = CURRENT_TIMESTAMP, updated_by = $3
+WHERE id = $1 AND deleted_at IS NULL
+`
+
+type SetUserMfaRequiredParams struct {
+	ID          uuid.UUID  `json:"id"`
+	MfaRequired bool       `json:"mfa_required"`
+	UpdatedBy   *uuid.UUID `json:"updated_by"`
+}
+
+func (q *Queries) SetUserMfaRequired(ctx context.Context, arg SetUserMfaRequiredParams) error {
+	_, err := q.db.Exec(ctx, setUserMfaRequired, arg.ID, arg.MfaRequired, arg.UpdatedBy)
+	return err
+}
+
+const setUserPhoneNumber = `-- name: SetUserPhoneNumber :exec
+UPDATE "users" SET phone_number = $2, phone_verified_at = NULL, updated_at = CURRENT_TIMESTAMP, updated_by = $1
+WHERE id = $1 AND deleted_at IS NULL
+`
+
+type SetUserPhoneNumberParams struct {
+	ID          uuid.UUID   `json:"id"`
+	PhoneNumber pgtype.Text `json:"phone_number"`
+}
+
+func (q *Queries) SetUserPhoneNumber(ctx context.Context, arg SetUserPhoneNumberParams) error {
+	_, err := q.db.Exec(ctx, setUserPhoneNumber, arg.ID, arg.PhoneNumber)
+	return err
+}
@@ -3,6 +3,10 @@ CREATE TABLE "users" (
   email varchar(255) UNIQUE NOT NULL,
   name varchar(255) NOT NULL,
   dp TEXT, 
+  email_verified_at timestamptz,
+  mfa_required BOOLEAN NOT NULL DEFAULT false,
+  phone_number varchar(16),
+  phone_verified_at timestamptz,
   created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
   created_by uuid NOT NULL,
   updated_at timestamptz,
@@ -15,4 +19,8 @@ CREATE TABLE "users" (
   FOREIGN KEY("created_by") REFERENCES "users"("id"),
   FOREIGN KEY("updated_by") REFERENCES "users"("id"), 
   FOREIGN KEY("deleted_by") REFERENCES "users"("id")
-);
\ No newline at end of file
+);
+
+-- a number signs in to one account, once it is verified
+CREATE UNIQUE INDEX "users_verified_phone_number_key" ON "users" (phone_number)
+WHERE phone_verified_at IS NOT NULL AND deleted_at IS NULL;
\ No newline at end of file
@@ -1,6 +1,5 @@
 CREATE TABLE "oauth_configs" (
   id uuid NOT NULL DEFAULT gen_random_uuid(),
-  client_secret TEXT NOT NULL,
   redirect_uris TEXT[],
   success_callback_url TEXT NOT NULL,
   error_callback_url TEXT NOT NULL, 
@@ -8,6 +7,16 @@ CREATE TABLE "oauth_configs" (
   jwt_secret_resolver TEXT,
   jwt_lifetime varchar(10) NOT NULL,
   refresh_token_lifetime varchar(10) NOT NULL,
+  token_endpoint_auth_method varchar(50) NOT NULL DEFAULT 'client_secret_post',
+  tls_client_auth_subject_dn TEXT,
+  tls_client_certificate TEXT,
+  access_token_profile varchar(20) NOT NULL DEFAULT 'legacy',
+  encryption_jwk TEXT,
+  encryption_alg varchar(20),
+  encryption_enc varchar(20),
+  require_verified_email BOOLEAN NOT NULL DEFAULT false,
+  require_mfa BOOLEAN NOT NULL DEFAULT false,
+  api_resources TEXT[],
   app_id uuid NOT NUll,
   created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
   created_by uuid NOT NULL,
@@ -3,7 +3,13 @@ CREATE TABLE "oauth_calls" (
   app_id uuid NOT NULL,
   code varchar(255) NOT NULL,
   user_id uuid NOT NULL,
+  resource TEXT,
+  scope TEXT,
+  auth_time timestamptz,
+  amr TEXT[],
+  nonce TEXT,
   expires_at timestamptz NOT NULL,
+  redirect_uri TEXT NOT NULL,
   PRIMARY KEY("id"),
   FOREIGN KEY("app_id") REFERENCES "apps"("id"),
   FOREIGN KEY("user_id") REFERENCES "users"("id")
@@ -0,0 +1,19 @@
+CREATE TABLE "api_resources" (
+  id uuid NOT NULL DEFAULT gen_random_uuid(),
+  name varchar(255) NOT NULL,
+  identifier varchar(255) NOT NULL UNIQUE,
+  scopes TEXT[] NOT NULL DEFAULT '{}',
+  token_lifetime varchar(10) NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  created_by uuid NOT NULL,
+  updated_at timestamptz,
+  updated_by uuid,
+  deleted_at timestamptz,
+  deleted_by uuid,
+  owner_id uuid,
+  PRIMARY KEY("id"),
+  FOREIGN KEY("created_by") REFERENCES "users"("id"),
+  FOREIGN KEY("updated_by") REFERENCES "users"("id"), 
+  FOREIGN KEY("deleted_by") REFERENCES "users"("id"),
+  FOREIGN KEY("owner_id") REFERENCES "users"("id")
+)
\ No newline at end of file
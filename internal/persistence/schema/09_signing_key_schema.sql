@@ -0,0 +1,23 @@
+CREATE TABLE "signing_keys" (
+  id uuid NOT NULL DEFAULT gen_random_uuid(),
+  kid varchar(255) NOT NULL UNIQUE,
+  algo varchar(10) NOT NULL,
+  secret_resolver TEXT,
+  encrypted_private_key TEXT,
+  state varchar(10) NOT NULL DEFAULT 'next',
+  activates_at timestamptz,
+  activated_at timestamptz,
+  expires_at timestamptz,
+  app_id uuid NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  created_by uuid NOT NULL,
+  updated_at timestamptz,
+  updated_by uuid,
+  deleted_at timestamptz,
+  deleted_by uuid,
+  PRIMARY KEY("id"),
+  FOREIGN KEY("created_by") REFERENCES "users"("id"),
+  FOREIGN KEY("updated_by") REFERENCES "users"("id"), 
+  FOREIGN KEY("deleted_by") REFERENCES "users"("id"),
+  FOREIGN KEY("app_id") REFERENCES "apps"("id")
+)
\ No newline at end of file
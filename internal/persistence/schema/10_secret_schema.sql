@@ -0,0 +1,14 @@
+CREATE TABLE "secrets" (
+  id uuid NOT NULL DEFAULT gen_random_uuid(),
+  name varchar(255) NOT NULL UNIQUE,
+  encrypted_value TEXT NOT NULL,
+  encrypted_data_key TEXT NOT NULL,
+  version integer NOT NULL DEFAULT 1,
+  created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  created_by uuid NOT NULL,
+  updated_at timestamptz,
+  updated_by uuid,
+  PRIMARY KEY("id"),
+  FOREIGN KEY("created_by") REFERENCES "users"("id"),
+  FOREIGN KEY("updated_by") REFERENCES "users"("id")
+)
\ No newline at end of file
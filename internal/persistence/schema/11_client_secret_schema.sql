@@ -0,0 +1,17 @@
+CREATE TABLE "client_secrets" (
+  id uuid NOT NULL DEFAULT gen_random_uuid(),
+  hashed_secret TEXT NOT NULL,
+  expires_at timestamptz,
+  app_id uuid NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  created_by uuid NOT NULL,
+  updated_at timestamptz,
+  updated_by uuid,
+  deleted_at timestamptz,
+  deleted_by uuid,
+  PRIMARY KEY("id"),
+  FOREIGN KEY("created_by") REFERENCES "users"("id"),
+  FOREIGN KEY("updated_by") REFERENCES "users"("id"), 
+  FOREIGN KEY("deleted_by") REFERENCES "users"("id"),
+  FOREIGN KEY("app_id") REFERENCES "apps"("id")
+)
\ No newline at end of file
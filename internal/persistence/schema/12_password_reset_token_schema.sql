@@ -0,0 +1,19 @@
+CREATE TABLE "password_reset_tokens" (
+  id uuid NOT NULL DEFAULT gen_random_uuid(),
+  hashed_token TEXT NOT NULL UNIQUE,
+  user_id uuid NOT NULL,
+  requested_ip varchar(45) NOT NULL,
+  expires_at timestamptz NOT NULL,
+  used_at timestamptz,
+  created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  created_by uuid NOT NULL,
+  updated_at timestamptz,
+  updated_by uuid,
+  deleted_at timestamptz,
+  deleted_by uuid,
+  PRIMARY KEY("id"),
+  FOREIGN KEY("created_by") REFERENCES "users"("id"),
+  FOREIGN KEY("updated_by") REFERENCES "users"("id"), 
+  FOREIGN KEY("deleted_by") REFERENCES "users"("id"),
+  FOREIGN KEY("user_id") REFERENCES "users"("id")
+)
\ No newline at end of file
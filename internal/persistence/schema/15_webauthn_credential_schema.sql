@@ -0,0 +1,26 @@
+CREATE TABLE "webauthn_credentials" (
+  id uuid NOT NULL DEFAULT gen_random_uuid(),
+  user_id uuid NOT NULL,
+  credential_id TEXT UNIQUE NOT NULL,
+  public_key BYTEA NOT NULL,
+  attestation_type varchar(32) NOT NULL,
+  aaguid BYTEA NOT NULL,
+  transports TEXT[] NOT NULL DEFAULT '{}',
+  sign_count BIGINT NOT NULL DEFAULT 0,
+  backup_eligible BOOLEAN NOT NULL DEFAULT false,
+  backup_state BOOLEAN NOT NULL DEFAULT false,
+  clone_warning BOOLEAN NOT NULL DEFAULT false,
+  name varchar(255) NOT NULL,
+  last_used_at timestamptz,
+  created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  created_by uuid NOT NULL,
+  updated_at timestamptz,
+  updated_by uuid,
+  deleted_at timestamptz,
+  deleted_by uuid,
+  PRIMARY KEY("id"),
+  FOREIGN KEY("created_by") REFERENCES "users"("id"),
+  FOREIGN KEY("updated_by") REFERENCES "users"("id"), 
+  FOREIGN KEY("deleted_by") REFERENCES "users"("id"),
+  FOREIGN KEY("user_id") REFERENCES "users"("id")
+)
\ No newline at end of file
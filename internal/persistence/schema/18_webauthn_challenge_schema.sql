@@ -0,0 +1,6 @@
+CREATE TABLE "webauthn_challenges" (
+  challenge TEXT NOT NULL,
+  expires_at timestamptz NOT NULL,
+  used_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY("challenge")
+)
\ No newline at end of file
@@ -0,0 +1,60 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aritradeveops/porichoy/internal/pkg/logger"
+)
+
+// FileMailer writes every message to an .eml file in a directory instead of
+// sending it, handy for local testing and tests.
+type FileMailer struct {
+	from string
+	dir  string
+}
+
+func NewFileMailer(from string, dir string) *FileMailer {
+	return &FileMailer{from: from, dir: dir}
+}
+
+func (m *FileMailer) Send(ctx context.Context, message Message) error {
+	if err := validAddress(message.To); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.dir, 0o700); err != nil {
+		return fmt.Errorf("mailer: %v", err)
+	}
+	recipient := strings.NewReplacer("/", "_", "\\", "_", "@", "_at_").Replace(message.To)
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), recipient)
+	if err := os.WriteFile(filepath.Join(m.dir, name), compose(m.from, message), 0o600); err != nil {
+		return fmt.Errorf("mailer: %v", err)
+	}
+	return nil
+}
+
+// LogMailer logs messages instead of sending them, links in them can be
+// followed straight from the console during development.
+type LogMailer struct {
+	from string
+}
+
+func NewLogMailer(from string) *LogMailer {
+	return &LogMailer{from: from}
+}
+
+func (m *LogMailer) Send(ctx context.Context, message Message) error {
+	if err := validAddress(message.To); err != nil {
+		return err
+	}
+	logger.Info().
+		Str("from", m.from).
+		Str("to", message.To).
+		Str("subject", message.Subject).
+		Msg(message.Text)
+	return nil
+}
@@ -0,0 +1,75 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aritradeveops/porichoy/internal/config"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer sends transactional mail like verification links.
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// New returns the mailer the config asks for, log when nothing is configured.
+func New(config config.Mail) (Mailer, error) {
+	switch config.Driver {
+	case "smtp":
+		if config.SMTP == nil {
+			return nil, fmt.Errorf("mailer: the smtp driver needs mail.smtp")
+		}
+		return NewSMTPMailer(config.From, *config.SMTP), nil
+	case "file":
+		return NewFileMailer(config.From, config.Dir), nil
+	case "log", "":
+		return NewLogMailer(config.From), nil
+	default:
+		return nil, fmt.Errorf("mailer: unknown driver %s", config.Driver)
+	}
+}
+
+// compose renders a message as RFC 5322 text, multipart when it has html.
+func compose(from string, message Message) []byte {
+	var b strings.Builder
+	header := func(name, value string) {
+		b.WriteString(name + ": " + value + "\r\n")
+	}
+	header("From", from)
+	header("To", message.To)
+	header("Subject", message.Subject)
+	header("MIME-Version", "1.0")
+	if message.HTML == "" {
+		header("Content-Type", `text/plain; charset="utf-8"`)
+		b.WriteString("\r\n" + crlf(message.Text))
+		return []byte(b.String())
+	}
+	const boundary = "porichoy-alternative"
+	header("Content-Type", `multipart/alternative; boundary="`+boundary+`"`)
+	b.WriteString("\r\n--" + boundary + "\r\n")
+	b.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n\r\n" + crlf(message.Text) + "\r\n")
+	b.WriteString("--" + boundary + "\r\n")
+	b.WriteString("Content-Type: text/html; charset=\"utf-8\"\r\n\r\n" + crlf(message.HTML) + "\r\n")
+	b.WriteString("--" + boundary + "--\r\n")
+	return []byte(b.String())
+}
+
+func crlf(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "\r\n", "\n"), "\n", "\r\n")
+}
+
+// validAddress keeps header injection out of the recipient.
+func validAddress(address string) error {
+	if address == "" || strings.ContainsAny(address, "\r\n") {
+		return fmt.Errorf("mailer: invalid recipient %q", address)
+	}
+	return nil
+}
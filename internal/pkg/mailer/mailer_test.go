@@ -0,0 +1,45 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aritradeveops/porichoy/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileMailer(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "mail")
+	m, err := New(config.Mail{Driver: "file", From: "Porichoy <no-reply@localhost>", Dir: dir})
+	assert.NoError(t, err)
+
+	err = m.Send(context.Background(), Message{
+		To:      "jane@example.com",
+		Subject: "Verify your email address",
+		Text:    "open\nhttp://localhost:8080/verify",
+		HTML:    "<a href=\"http://localhost:8080/verify\">open</a>",
+	})
+	assert.NoError(t, err)
+
+	files, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.True(t, strings.HasSuffix(files[0].Name(), "-jane_at_example.com.eml"))
+	content, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "To: jane@example.com\r\n")
+	assert.Contains(t, string(content), "open\r\nhttp://localhost:8080/verify")
+	assert.Contains(t, string(content), "multipart/alternative")
+
+	// recipients can not smuggle in headers
+	err = m.Send(context.Background(), Message{To: "jane@example.com\r\nBcc: eve@example.com"})
+	assert.Error(t, err)
+
+	_, err = New(config.Mail{Driver: "pigeon"})
+	assert.Error(t, err)
+}
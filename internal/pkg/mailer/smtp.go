@@ -0,0 +1,75 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+
+	"github.com/aritradeveops/porichoy/internal/config"
+)
+
+// SMTPMailer sends mail through an smtp relay, upgrading the connection with
+// STARTTLS whenever the server offers it.
+type SMTPMailer struct {
+	from   string
+	config config.SMTP
+}
+
+func NewSMTPMailer(from string, config config.SMTP) *SMTPMailer {
+	return &SMTPMailer{from: from, config: config}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, message Message) error {
+	if err := validAddress(message.To); err != nil {
+		return err
+	}
+	address := net.JoinHostPort(m.config.Host, strconv.Itoa(m.config.Port))
+	dialer := net.Dialer{Timeout: 10 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return fmt.Errorf("mailer: %v", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	client, err := smtp.NewClient(conn, m.config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("mailer: %v", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.config.Host}); err != nil {
+			return fmt.Errorf("mailer: %v", err)
+		}
+	}
+	if m.config.Username != "" {
+		// net/smtp refuses plain auth over an unencrypted connection
+		auth := smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("mailer: %v", err)
+		}
+	}
+	if err := client.Mail(m.from); err != nil {
+		return fmt.Errorf("mailer: %v", err)
+	}
+	if err := client.Rcpt(message.To); err != nil {
+		return fmt.Errorf("mailer: %v", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("mailer: %v", err)
+	}
+	if _, err := w.Write(compose(m.from, message)); err != nil {
+		return fmt.Errorf("mailer: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("mailer: %v", err)
+	}
+	return client.Quit()
+}
@@ -0,0 +1,26 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	l := New(2, time.Minute)
+	l.now = func() time.Time { return now }
+
+	assert.True(t, l.Allow("jane@example.com"))
+	assert.True(t, l.Allow("jane@example.com"))
+	assert.False(t, l.Allow("jane@example.com"))
+	// keys are limited on their own
+	assert.True(t, l.Allow("10.0.0.1"))
+
+	now = now.Add(time.Minute)
+	assert.True(t, l.Allow("jane@example.com"))
+	assert.Len(t, l.windows, 1)
+}
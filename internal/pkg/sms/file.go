@@ -0,0 +1,53 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aritradeveops/porichoy/internal/pkg/logger"
+)
+
+// FileProvider writes every message to a .txt file in a directory instead
+// of sending it, handy for local testing and tests.
+type FileProvider struct {
+	dir string
+}
+
+func NewFileProvider(dir string) *FileProvider {
+	return &FileProvider{dir: dir}
+}
+
+func (p *FileProvider) Send(ctx context.Context, message Message) error {
+	if err := validNumber(message.To); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(p.dir, 0o700); err != nil {
+		return fmt.Errorf("sms: %v", err)
+	}
+	name := fmt.Sprintf("%d-%s.txt", time.Now().UnixNano(), message.To)
+	if err := os.WriteFile(filepath.Join(p.dir, name), []byte(message.Text), 0o600); err != nil {
+		return fmt.Errorf("sms: %v", err)
+	}
+	return nil
+}
+
+// LogProvider logs messages instead of sending them, codes in them can be
+// read straight from the console during development.
+type LogProvider struct{}
+
+func NewLogProvider() *LogProvider {
+	return &LogProvider{}
+}
+
+func (p *LogProvider) Send(ctx context.Context, message Message) error {
+	if err := validNumber(message.To); err != nil {
+		return err
+	}
+	logger.Info().
+		Str("to", message.To).
+		Msg(message.Text)
+	return nil
+}
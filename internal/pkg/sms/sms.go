@@ -0,0 +1,47 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/aritradeveops/porichoy/internal/config"
+)
+
+type Message struct {
+	// To is the number in E.164 format, like +8801712345678
+	To   string `json:"to"`
+	Text string `json:"text"`
+}
+
+// SMSProvider sends text messages like one-time passcodes.
+type SMSProvider interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// New returns the provider the config asks for, log when nothing is
+// configured.
+func New(config config.SMS) (SMSProvider, error) {
+	switch config.Driver {
+	case "webhook":
+		if config.Webhook == nil {
+			return nil, fmt.Errorf("sms: the webhook driver needs sms.webhook")
+		}
+		return NewWebhookProvider(*config.Webhook), nil
+	case "file":
+		return NewFileProvider(config.Dir), nil
+	case "log", "":
+		return NewLogProvider(), nil
+	default:
+		return nil, fmt.Errorf("sms: unknown driver %s", config.Driver)
+	}
+}
+
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+func validNumber(number string) error {
+	if !e164.MatchString(number) {
+		return fmt.Errorf("sms: invalid recipient %q", number)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package sms
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aritradeveops/porichoy/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileProvider(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "sms")
+	p, err := New(config.SMS{Driver: "file", Dir: dir})
+	assert.NoError(t, err)
+
+	err = p.Send(context.Background(), Message{To: "+8801712345678", Text: "Your code is 123456"})
+	assert.NoError(t, err)
+	files, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	content, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	assert.NoError(t, err)
+	assert.Equal(t, "Your code is 123456", string(content))
+
+	// only E.164 numbers, nothing that could become a path
+	err = p.Send(context.Background(), Message{To: "../../etc/passwd"})
+	assert.Error(t, err)
+
+	_, err = New(config.SMS{Driver: "pigeon"})
+	assert.Error(t, err)
+}
+
+func TestWebhookProvider(t *testing.T) {
+	t.Parallel()
+
+	var received Message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte("webhook-secret"))
+		mac.Write(body)
+		if r.Header.Get(SignatureHeader) != "sha256="+hex.EncodeToString(mac.Sum(nil)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	p, err := New(config.SMS{Driver: "webhook", Webhook: &config.SMSWebhook{URL: server.URL, Secret: "webhook-secret"}})
+	assert.NoError(t, err)
+	err = p.Send(context.Background(), Message{To: "+14155550123", Text: "Your code is 654321"})
+	assert.NoError(t, err)
+	assert.Equal(t, Message{To: "+14155550123", Text: "Your code is 654321"}, received)
+
+	// a receiver that rejects the message fails the send
+	p = NewWebhookProvider(config.SMSWebhook{URL: server.URL, Secret: "wrong"})
+	err = p.Send(context.Background(), Message{To: "+14155550123", Text: "Your code is 654321"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,62 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aritradeveops/porichoy/internal/config"
+)
+
+// SignatureHeader carries the hex HMAC-SHA256 of the body, keyed with the
+// webhook secret, so the receiver can tell the message came from porichoy.
+const SignatureHeader = "X-Porichoy-Signature"
+
+// WebhookProvider posts every message as json to a url, whatever listens
+// there hands it to the carrier or gateway of choice.
+type WebhookProvider struct {
+	config config.SMSWebhook
+	client *http.Client
+}
+
+func NewWebhookProvider(config config.SMSWebhook) *WebhookProvider {
+	return &WebhookProvider{
+		config: config,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *WebhookProvider) Send(ctx context.Context, message Message) error {
+	if err := validNumber(message.To); err != nil {
+		return err
+	}
+	body, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("sms: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("sms: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.config.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(p.config.Secret))
+		mac.Write(body)
+		req.Header.Set(SignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	res, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sms: %v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("sms: webhook answered %s", res.Status)
+	}
+	return nil
+}
@@ -11,7 +11,16 @@ import (
 
 const authUserKey = "auth_user"
 
-func Middleware(redirect ...bool) fiber.Handler {
+// Authn authenticates requests with the session tokens verified against keys.
+type Authn struct {
+	keys jwtutil.KeyStore
+}
+
+func New(keys jwtutil.KeyStore) *Authn {
+	return &Authn{keys: keys}
+}
+
+func (a *Authn) Middleware(redirect ...bool) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		bearer := c.Get("Authorization")
 		if bearer == "" {
@@ -25,7 +34,7 @@ func Middleware(redirect ...bool) fiber.Handler {
 			return fiber.ErrUnauthorized
 		}
 		accessToken := strings.TrimPrefix(bearer, "Bearer ")
-		payload, err := jwtutil.Verify(accessToken)
+		payload, err := jwtutil.Verify(c.Context(), accessToken, a.keys)
 		if err != nil {
 			if len(redirect) > 0 && redirect[0] {
 				return c.Redirect("/login?next=" + url.QueryEscape(c.OriginalURL()))
@@ -37,6 +46,25 @@ func Middleware(redirect ...bool) fiber.Handler {
 	}
 }
 
+// Optional authenticates the request when it carries a valid access token but
+// lets anonymous requests through, handlers must check the user themselves.
+func (a *Authn) Optional() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		bearer := c.Get("Authorization")
+		if bearer == "" {
+			bearer = c.Cookies("access_token")
+		}
+		if bearer == "" {
+			return c.Next()
+		}
+		payload, err := jwtutil.Verify(c.Context(), strings.TrimPrefix(bearer, "Bearer "), a.keys)
+		if err == nil {
+			c.Locals(authUserKey, payload)
+		}
+		return c.Next()
+	}
+}
+
 func GetUserFromContext(c *fiber.Ctx) (*jwtutil.JwtPayload, error) {
 	userIn := c.Locals(authUserKey)
 	if userIn == nil {
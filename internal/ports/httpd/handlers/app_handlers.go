@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"errors"
+	"time"
+
 	"github.com/aritradeveops/porichoy/internal/core/service"
 	"github.com/aritradeveops/porichoy/internal/pkg/logger"
 	"github.com/aritradeveops/porichoy/internal/pkg/translation"
@@ -9,17 +12,26 @@ import (
 )
 
 type CreateAppPayload struct {
-	Name                 string   `json:"name"`
-	Domain               string   `json:"domain"`
-	LandingUrl           string   `json:"landing_url"`
-	Logo                 string   `json:"logo"`
-	RedirectUris         []string `json:"redirect_uris"`
-	SuccessCallbackUrl   string   `json:"success_callback_url"`
-	ErrorCallbackUrl     string   `json:"error_callback_url"`
-	JwtAlgo              string   `json:"jwt_algo"`
-	JwtSecretResolver    string   `json:"jwt_secret_resolver"`
-	JwtLifetime          string   `json:"jwt_lifetime" validate:"required,duration"`
-	RefreshTokenLifetime string   `json:"refresh_token_lifetime" validate:"required,duration"`
+	Name                    string   `json:"name"`
+	Domain                  string   `json:"domain"`
+	LandingUrl              string   `json:"landing_url"`
+	Logo                    string   `json:"logo"`
+	RedirectUris            []string `json:"redirect_uris"`
+	SuccessCallbackUrl      string   `json:"success_callback_url"`
+	ErrorCallbackUrl        string   `json:"error_callback_url"`
+	JwtAlgo                 string   `json:"jwt_algo"`
+	JwtSecretResolver       string   `json:"jwt_secret_resolver"`
+	JwtLifetime             string   `json:"jwt_lifetime" validate:"required,duration"`
+	RefreshTokenLifetime    string   `json:"refresh_token_lifetime" validate:"required,duration"`
+	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method"`
+	TlsClientAuthSubjectDn  string   `json:"tls_client_auth_subject_dn"`
+	TlsClientCertificate    string   `json:"tls_client_certificate"`
+	AccessTokenProfile      string   `json:"access_token_profile"`
+	EncryptionJwk           string   `json:"encryption_jwk"`
+	EncryptionAlg           string   `json:"encryption_alg"`
+	EncryptionEnc           string   `json:"encryption_enc"`
+	RequireVerifiedEmail    bool     `json:"require_verified_email"`
+	RequireMfa              bool     `json:"require_mfa"`
 }
 
 func (h *Handlers) CreateApp(c *fiber.Ctx) error {
@@ -37,10 +49,50 @@ func (h *Handlers) CreateApp(c *fiber.Ctx) error {
 	app, err := h.service.CreateApp(c.Context(), user.UserID, service.CreateAppPayload(payload))
 	if err != nil {
 		logger.Error().Err(err)
-		return err
+		return sendAppError(c, err)
 	}
 
 	return c.JSON(NewSuccessResponse(translation.Localize(c, "controller.create", map[string]string{
 		"Entity": "App",
 	}), app))
 }
+
+type RotateClientSecretPayload struct {
+	GracePeriod string     `json:"grace_period"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+func (h *Handlers) RotateClientSecret(c *fiber.Ctx) error {
+	var payload RotateClientSecretPayload
+	err := c.BodyParser(&payload)
+	if err != nil {
+		return err
+	}
+
+	user, err := authn.GetUserFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	secret, err := h.service.RotateClientSecret(c.Context(), user.UserID, c.Params("client_id"), service.RotateClientSecretPayload(payload))
+	if err != nil {
+		return sendAppError(c, err)
+	}
+
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "app.rotate_secret"), secret))
+}
+
+// sendAppError answers the errors of looking up an app to manage and of
+// pointing its keys at secrets.
+func sendAppError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, service.ErrAppNotFound) {
+		c.Status(fiber.StatusNotFound)
+		return c.JSON(NewErrorResponse(translation.Localize(c, "errors.404", map[string]string{
+			"Resource": "App",
+		}), err))
+	} else if errors.Is(err, service.ErrAppForbidden) || errors.Is(err, service.ErrKeySourceForbidden) {
+		c.Status(fiber.StatusForbidden)
+		return c.JSON(NewErrorResponse(translation.Localize(c, "errors.403"), err))
+	}
+	return err
+}
@@ -1,12 +1,15 @@
 package handlers
 
 import (
+	"crypto/x509"
 	"errors"
+	"strings"
 
 	"github.com/aritradeveops/porichoy/internal/core/service"
 	"github.com/aritradeveops/porichoy/internal/pkg/logger"
 	"github.com/aritradeveops/porichoy/internal/pkg/translation"
 	"github.com/aritradeveops/porichoy/internal/ports/httpd/authn"
+	"github.com/aritradeveops/porichoy/internal/ports/httpd/oauth2"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -17,19 +20,28 @@ type RegisterUserPayload struct {
 }
 
 type LoginUserPayload struct {
-	Email    string `json:"email,omitempty"`
-	Password string `json:"password,omitempty"`
+	Method      string `json:"method,omitempty"`
+	Email       string `json:"email,omitempty"`
+	Password    string `json:"password,omitempty"`
+	PhoneNumber string `json:"phone_number,omitempty"`
+	ClientID    string `json:"client_id,omitempty"`
+}
+
+type CompleteEmailLoginPayload struct {
+	Token      string `json:"token"`
+	LoginToken string `json:"login_token"`
+	Code       string `json:"code"`
+	ClientID   string `json:"client_id"`
 }
 
-type Oauth2Payload struct {
-	ClientID            string `query:"client_id"`
-	ResponseType        string `query:"response_type"`
-	RedirectURI         string `query:"redirect_uri"`
-	CodeChallenge       string `query:"code_challenge"`
-	CodeChallengeMethod string `query:"code_challenge_method"`
-	State               string `query:"state"`
-	LoginHint           string `query:"login_hint"`
-	Nonce               string `query:"nonce"`
+type EnrollMfaPayload struct {
+	MfaToken string `json:"mfa_token"`
+}
+
+type VerifyMfaPayload struct {
+	MfaToken     string `json:"mfa_token"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
 }
 
 type Oauth2TokenPayload struct {
@@ -38,6 +50,7 @@ type Oauth2TokenPayload struct {
 	GrantType    string `query:"grant_type"`
 	Code         string `query:"code"`
 	RedirectURI  string `query:"redirect_uri"`
+	Resource     string `query:"resource"`
 }
 
 func (h *Handlers) RegisterUser(c *fiber.Ctx) error {
@@ -56,6 +69,101 @@ func (h *Handlers) RegisterUser(c *fiber.Ctx) error {
 	return c.JSON(NewSuccessResponse(translation.Localize(c, "user.register"), user))
 }
 
+type ResendVerificationEmailPayload struct {
+	Email string `json:"email"`
+}
+
+func (h *Handlers) VerifyEmail(c *fiber.Ctx) error {
+	err := h.service.VerifyEmail(c.Context(), c.Query("token"))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidVerificationLink) {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(NewErrorResponse(translation.Localize(c, "user.invalid_verification_link"), err))
+		}
+		return err
+	}
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "user.verify_email"), nil))
+}
+
+func (h *Handlers) ResendVerificationEmail(c *fiber.Ctx) error {
+	var payload ResendVerificationEmailPayload
+	err := c.BodyParser(&payload)
+	if err != nil {
+		return err
+	}
+	err = h.service.ResendVerificationEmail(c.Context(), service.ResendVerificationEmailPayload{
+		Email:  payload.Email,
+		UserIP: c.IP(),
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrTooManyRequests) {
+			c.Status(fiber.StatusTooManyRequests)
+			return c.JSON(NewErrorResponse(translation.Localize(c, "errors.429"), err))
+		}
+		return err
+	}
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "user.resend_verification"), nil))
+}
+
+type ForgotPasswordPayload struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordPayload struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func (h *Handlers) ForgotPassword(c *fiber.Ctx) error {
+	var payload ForgotPasswordPayload
+	err := c.BodyParser(&payload)
+	if err != nil {
+		return err
+	}
+	err = h.service.ForgotPassword(c.Context(), service.ForgotPasswordPayload{
+		Email:  payload.Email,
+		UserIP: c.IP(),
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrTooManyRequests) {
+			c.Status(fiber.StatusTooManyRequests)
+			return c.JSON(NewErrorResponse(translation.Localize(c, "errors.429"), err))
+		}
+		return err
+	}
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "user.forgot_password"), nil))
+}
+
+func (h *Handlers) ResetPassword(c *fiber.Ctx) error {
+	var payload ResetPasswordPayload
+	err := c.BodyParser(&payload)
+	if err != nil {
+		return err
+	}
+	err = h.service.ResetPassword(c.Context(), service.ResetPasswordPayload{
+		Token:    payload.Token,
+		Password: payload.Password,
+		UserIP:   c.IP(),
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrTooManyRequests) {
+			c.Status(fiber.StatusTooManyRequests)
+			return c.JSON(NewErrorResponse(translation.Localize(c, "errors.429"), err))
+		} else if errors.Is(err, service.ErrInvalidResetToken) {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(NewErrorResponse(translation.Localize(c, "user.invalid_reset_token"), err))
+		} else if errors.Is(err, service.ErrPasswordReused) {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(NewErrorResponse(translation.Localize(c, "user.password_reused"), err))
+		} else if errors.Is(err, service.ErrDeactivatedUser) {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(NewErrorResponse(translation.Localize(c, "user.deactivated"), err))
+		}
+		return err
+	}
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "user.reset_password"), nil))
+}
+
 func (h *Handlers) LoginUser(c *fiber.Ctx) error {
 	var payload LoginUserPayload
 	err := c.BodyParser(&payload)
@@ -63,11 +171,14 @@ func (h *Handlers) LoginUser(c *fiber.Ctx) error {
 		return err
 	}
 	tokens, err := h.service.LoginUser(c.Context(), service.LoginUserPayload{
-		Email:     payload.Email,
-		Password:  payload.Password,
-		UserAgent: c.Get("User-Agent"),
-		UserIP:    c.IP(),
-		Host:      c.Hostname(),
+		Method:      payload.Method,
+		Email:       payload.Email,
+		Password:    payload.Password,
+		PhoneNumber: payload.PhoneNumber,
+		UserAgent:   c.Get("User-Agent"),
+		UserIP:      c.IP(),
+		Host:        c.Hostname(),
+		ClientID:    payload.ClientID,
 	})
 	if err != nil {
 		if errors.Is(err, service.ErrInvalidLoginCredentials) {
@@ -79,10 +190,109 @@ func (h *Handlers) LoginUser(c *fiber.Ctx) error {
 		} else if errors.Is(err, service.ErrInvalidLoginMethod) {
 			c.Status(fiber.StatusBadRequest)
 			return c.JSON(NewErrorResponse(translation.Localize(c, "user.invalid_login_method"), err))
+		} else if errors.Is(err, service.ErrEmailNotVerified) {
+			c.Status(fiber.StatusForbidden)
+			return c.JSON(NewErrorResponse(translation.Localize(c, "user.email_not_verified"), err))
+		} else if errors.Is(err, service.ErrTooManyRequests) {
+			c.Status(fiber.StatusTooManyRequests)
+			return c.JSON(NewErrorResponse(translation.Localize(c, "errors.429"), err))
+		} else if errors.Is(err, service.ErrPhoneNumberNotAllowed) {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(NewErrorResponse(translation.Localize(c, "sms.not_allowed"), err))
 		}
 		return err
 	}
 
+	// no session yet, the client has to continue with CompleteEmailLogin
+	// or CompleteSmsLogin
+	if tokens.LoginToken != "" {
+		if payload.Method == service.LoginMethodSms {
+			return c.JSON(NewSuccessResponse(translation.Localize(c, "sms.login_sent"), tokens))
+		}
+		return c.JSON(NewSuccessResponse(translation.Localize(c, "user.email_login_sent"), tokens))
+	}
+	return sendLoginTokens(c, tokens)
+}
+
+func (h *Handlers) CompleteEmailLogin(c *fiber.Ctx) error {
+	var payload CompleteEmailLoginPayload
+	err := c.BodyParser(&payload)
+	if err != nil {
+		return err
+	}
+	tokens, err := h.service.CompleteEmailLogin(c.Context(), service.CompleteEmailLoginPayload{
+		Token:      payload.Token,
+		LoginToken: payload.LoginToken,
+		Code:       payload.Code,
+		ClientID:   payload.ClientID,
+		UserAgent:  c.Get("User-Agent"),
+		UserIP:     c.IP(),
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidEmailLogin) {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(NewErrorResponse(translation.Localize(c, "user.invalid_email_login"), err))
+		} else if errors.Is(err, service.ErrInvalidLoginMethod) {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(NewErrorResponse(translation.Localize(c, "user.invalid_method"), err))
+		} else if errors.Is(err, service.ErrDeactivatedUser) {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(NewErrorResponse(translation.Localize(c, "user.deactivated"), err))
+		} else if errors.Is(err, service.ErrTooManyRequests) {
+			c.Status(fiber.StatusTooManyRequests)
+			return c.JSON(NewErrorResponse(translation.Localize(c, "errors.429"), err))
+		}
+		return err
+	}
+	return sendLoginTokens(c, tokens)
+}
+
+// sendLoginTokens answers a login past its first factor, with the session
+// or with the mfa challenge that still stands between the user and it.
+func sendLoginTokens(c *fiber.Ctx, tokens service.LoginUserResponse) error {
+	// no session yet, the client has to continue with VerifyMfa
+	if tokens.MfaRequired {
+		return c.JSON(NewSuccessResponse(translation.Localize(c, "user.mfa_required"), tokens))
+	}
+
+	setSessionCookies(c, tokens)
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "user.login"), tokens))
+}
+
+func (h *Handlers) EnrollMfa(c *fiber.Ctx) error {
+	var payload EnrollMfaPayload
+	err := c.BodyParser(&payload)
+	if err != nil {
+		return err
+	}
+	enrollment, err := h.service.EnrollMfa(c.Context(), service.EnrollMfaPayload(payload))
+	if err != nil {
+		return sendMfaError(c, err)
+	}
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "user.enroll_totp"), enrollment))
+}
+
+func (h *Handlers) VerifyMfa(c *fiber.Ctx) error {
+	var payload VerifyMfaPayload
+	err := c.BodyParser(&payload)
+	if err != nil {
+		return err
+	}
+	tokens, err := h.service.VerifyMfa(c.Context(), service.VerifyMfaPayload{
+		MfaToken:     payload.MfaToken,
+		Code:         payload.Code,
+		RecoveryCode: payload.RecoveryCode,
+		UserAgent:    c.Get("User-Agent"),
+		UserIP:       c.IP(),
+	})
+	if err != nil {
+		return sendMfaError(c, err)
+	}
+	setSessionCookies(c, tokens)
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "user.login"), tokens))
+}
+
+func setSessionCookies(c *fiber.Ctx, tokens service.LoginUserResponse) {
 	c.Cookie(&fiber.Cookie{
 		Name:     "access_token",
 		Value:    tokens.AccessToken,
@@ -96,12 +306,35 @@ func (h *Handlers) LoginUser(c *fiber.Ctx) error {
 		HTTPOnly: true,
 		Expires:  tokens.RefreshTokenExpiry,
 	})
+}
 
-	return c.JSON(NewSuccessResponse(translation.Localize(c, "user.login"), tokens))
+// sendMfaError answers the errors shared by every endpoint taking a second
+// factor.
+func sendMfaError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, service.ErrInvalidMfaToken) {
+		c.Status(fiber.StatusUnauthorized)
+		return c.JSON(NewErrorResponse(translation.Localize(c, "user.invalid_mfa_token"), err))
+	} else if errors.Is(err, service.ErrInvalidMfaCode) {
+		c.Status(fiber.StatusBadRequest)
+		return c.JSON(NewErrorResponse(translation.Localize(c, "user.invalid_mfa_code"), err))
+	} else if errors.Is(err, service.ErrTotpAlreadyEnrolled) {
+		c.Status(fiber.StatusConflict)
+		return c.JSON(NewErrorResponse(translation.Localize(c, "user.totp_enrolled"), err))
+	} else if errors.Is(err, service.ErrTotpNotEnrolled) {
+		c.Status(fiber.StatusBadRequest)
+		return c.JSON(NewErrorResponse(translation.Localize(c, "user.totp_not_enrolled"), err))
+	} else if errors.Is(err, service.ErrTooManyRequests) {
+		c.Status(fiber.StatusTooManyRequests)
+		return c.JSON(NewErrorResponse(translation.Localize(c, "errors.429"), err))
+	} else if errors.Is(err, service.ErrDeactivatedUser) {
+		c.Status(fiber.StatusBadRequest)
+		return c.JSON(NewErrorResponse(translation.Localize(c, "user.deactivated"), err))
+	}
+	return err
 }
 
 func (h *Handlers) Oauth2(c *fiber.Ctx) error {
-	var payload Oauth2Payload
+	var payload oauth2.AuthorizationPayload
 	err := c.QueryParser(&payload)
 	if err != nil {
 		return err
@@ -110,7 +343,8 @@ func (h *Handlers) Oauth2(c *fiber.Ctx) error {
 	if err != nil {
 		return err
 	}
-	response, err := h.service.Oauth2(c.Context(), user.UserID, service.Oauth2Payload(payload))
+	// consent is only ever given on the consent screen
+	response, err := h.service.Oauth2(c.Context(), user, service.Oauth2Payload(payload), "")
 	if err != nil {
 		logger.Error().Err(err).Msg("oauth2 error")
 		if response.OauthConfig.ErrorCallbackUrl != "" {
@@ -120,8 +354,8 @@ func (h *Handlers) Oauth2(c *fiber.Ctx) error {
 		return err
 	}
 
-	if response.Oauth2CodeResponse != nil {
-		return c.Redirect(response.Oauth2CodeResponse.RedirectURI)
+	if response.Authorization != nil {
+		return oauth2.SendAuthorizationResponse(c, *response.Authorization)
 	}
 
 	return c.JSON(NewSuccessResponse(translation.Localize(c, "user.oauth2"), response))
@@ -134,15 +368,27 @@ func (h *Handlers) Token(c *fiber.Ctx) error {
 		return err
 	}
 	tokens, err := h.service.Token(c.Context(), service.Oauth2TokenPayload{
-		ClientID:     payload.ClientID,
-		ClientSecret: payload.ClientSecret,
-		GrantType:    payload.GrantType,
-		Code:         payload.Code,
-		RedirectURI:  payload.RedirectURI,
-		UserAgent:    c.Get("User-Agent"),
-		UserIP:       c.IP(),
+		ClientID:           payload.ClientID,
+		ClientSecret:       payload.ClientSecret,
+		GrantType:          payload.GrantType,
+		Code:               payload.Code,
+		RedirectURI:        payload.RedirectURI,
+		Resource:           payload.Resource,
+		UserAgent:          c.Get("User-Agent"),
+		UserIP:             c.IP(),
+		ClientCertificates: peerCertificates(c),
 	})
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidClient) {
+			c.Status(fiber.StatusUnauthorized)
+			return c.JSON(NewErrorResponse(translation.Localize(c, "user.invalid_client"), err))
+		} else if errors.Is(err, service.ErrInvalidTarget) {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(NewErrorResponse(translation.Localize(c, "user.invalid_target"), err))
+		} else if errors.Is(err, service.ErrInvalidGrant) {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(NewErrorResponse(translation.Localize(c, "user.invalid_grant"), err))
+		}
 		return err
 	}
 	return c.JSON(NewSuccessResponse(translation.Localize(c, "user.token"), tokens))
@@ -161,3 +407,36 @@ func (h *Handlers) LogoutUser(c *fiber.Ctx) error {
 	c.ClearCookie("refresh_token")
 	return c.JSON(NewSuccessResponse(translation.Localize(c, "user.logout"), nil))
 }
+
+// Userinfo returns the claims about the owner of the bearer token, as plain
+// json or as a nested jwt for clients that registered an encryption key.
+func (h *Handlers) Userinfo(c *fiber.Ctx) error {
+	accessToken := strings.TrimPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+	if accessToken == "" {
+		accessToken = c.FormValue("access_token")
+	}
+	resp, err := h.service.Userinfo(c.Context(), accessToken, peerCertificates(c))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidAccessToken) {
+			c.Set(fiber.HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+			c.Status(fiber.StatusUnauthorized)
+			return c.JSON(NewErrorResponse(translation.Localize(c, "user.invalid_token"), err))
+		}
+		return err
+	}
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	if resp.JWT != "" {
+		c.Set(fiber.HeaderContentType, "application/jwt")
+		return c.SendString(resp.JWT)
+	}
+	return c.JSON(resp.Info)
+}
+
+// peerCertificates returns the client certificates of a mutual tls connection.
+func peerCertificates(c *fiber.Ctx) []*x509.Certificate {
+	state := c.Context().TLSConnectionState()
+	if state == nil {
+		return nil
+	}
+	return state.PeerCertificates
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/aritradeveops/porichoy/internal/core/service"
+	"github.com/aritradeveops/porichoy/internal/pkg/translation"
+	"github.com/aritradeveops/porichoy/internal/ports/httpd/authn"
+	"github.com/gofiber/fiber/v2"
+)
+
+type ChangePasswordPayload struct {
+	CurrentPassword  string `json:"current_password"`
+	NewPassword      string `json:"new_password"`
+	EndOtherSessions bool   `json:"end_other_sessions"`
+}
+
+func (h *Handlers) ChangePassword(c *fiber.Ctx) error {
+	var payload ChangePasswordPayload
+	err := c.BodyParser(&payload)
+	if err != nil {
+		return err
+	}
+
+	user, err := authn.GetUserFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	err = h.service.ChangePassword(c.Context(), user.UserID, service.ChangePasswordPayload{
+		CurrentPassword:  payload.CurrentPassword,
+		NewPassword:      payload.NewPassword,
+		EndOtherSessions: payload.EndOtherSessions,
+		RefreshToken:     c.Cookies("refresh_token"),
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrIncorrectPassword) {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(NewErrorResponse(translation.Localize(c, "user.incorrect_password"), err))
+		} else if errors.Is(err, service.ErrPasswordReused) {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(NewErrorResponse(translation.Localize(c, "user.password_reused"), err))
+		} else if errors.Is(err, service.ErrInvalidLoginMethod) {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(NewErrorResponse(translation.Localize(c, "user.invalid_method"), err))
+		} else if errors.Is(err, service.ErrTooManyRequests) {
+			c.Status(fiber.StatusTooManyRequests)
+			return c.JSON(NewErrorResponse(translation.Localize(c, "errors.429"), err))
+		}
+		return err
+	}
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "user.change_password"), nil))
+}
+
+type ConfirmTotpPayload struct {
+	Code string `json:"code"`
+}
+
+type MfaCodePayload struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+func (h *Handlers) EnrollTotp(c *fiber.Ctx) error {
+	user, err := authn.GetUserFromContext(c)
+	if err != nil {
+		return err
+	}
+	enrollment, err := h.service.EnrollTotp(c.Context(), user.UserID)
+	if err != nil {
+		return sendMfaError(c, err)
+	}
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "user.enroll_totp"), enrollment))
+}
+
+func (h *Handlers) ConfirmTotp(c *fiber.Ctx) error {
+	var payload ConfirmTotpPayload
+	err := c.BodyParser(&payload)
+	if err != nil {
+		return err
+	}
+	user, err := authn.GetUserFromContext(c)
+	if err != nil {
+		return err
+	}
+	codes, err := h.service.ConfirmTotp(c.Context(), user.UserID, service.ConfirmTotpPayload(payload))
+	if err != nil {
+		return sendMfaError(c, err)
+	}
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "user.confirm_totp"), RecoveryCodesResponse{RecoveryCodes: codes}))
+}
+
+func (h *Handlers) DisableTotp(c *fiber.Ctx) error {
+	var payload MfaCodePayload
+	err := c.BodyParser(&payload)
+	if err != nil {
+		return err
+	}
+	user, err := authn.GetUserFromContext(c)
+	if err != nil {
+		return err
+	}
+	err = h.service.DisableTotp(c.Context(), user.UserID, service.MfaCodePayload(payload))
+	if err != nil {
+		return sendMfaError(c, err)
+	}
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "user.disable_totp"), nil))
+}
+
+func (h *Handlers) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	var payload MfaCodePayload
+	err := c.BodyParser(&payload)
+	if err != nil {
+		return err
+	}
+	user, err := authn.GetUserFromContext(c)
+	if err != nil {
+		return err
+	}
+	codes, err := h.service.RegenerateRecoveryCodes(c.Context(), user.UserID, service.MfaCodePayload(payload))
+	if err != nil {
+		return sendMfaError(c, err)
+	}
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "user.regenerate_recovery_codes"), RecoveryCodesResponse{RecoveryCodes: codes}))
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/aritradeveops/porichoy/internal/core/service"
+	"github.com/aritradeveops/porichoy/internal/pkg/translation"
+	"github.com/aritradeveops/porichoy/internal/ports/httpd/authn"
+	"github.com/gofiber/fiber/v2"
+)
+
+type FinishPasskeyRegistrationPayload struct {
+	Session    string          `json:"session"`
+	Name       string          `json:"name"`
+	Credential json.RawMessage `json:"credential"`
+}
+
+type FinishPasskeyLoginPayload struct {
+	Session    string          `json:"session"`
+	Credential json.RawMessage `json:"credential"`
+}
+
+type BeginPasskeyMfaPayload struct {
+	MfaToken string `json:"mfa_token"`
+}
+
+type FinishPasskeyMfaPayload struct {
+	MfaToken   string          `json:"mfa_token"`
+	Session    string          `json:"session"`
+	Credential json.RawMessage `json:"credential"`
+}
+
+func (h *Handlers) BeginPasskeyRegistration(c *fiber.Ctx) error {
+	user, err := authn.GetUserFromContext(c)
+	if err != nil {
+		return err
+	}
+	ceremony, err := h.service.BeginPasskeyRegistration(c.Context(), user.UserID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "passkey.begin"), ceremony))
+}
+
+func (h *Handlers) FinishPasskeyRegistration(c *fiber.Ctx) error {
+	var payload FinishPasskeyRegistrationPayload
+	err := c.BodyParser(&payload)
+	if err != nil {
+		return err
+	}
+	user, err := authn.GetUserFromContext(c)
+	if err != nil {
+		return err
+	}
+	passkey, err := h.service.FinishPasskeyRegistration(c.Context(), user.UserID, service.FinishPasskeyRegistrationPayload(payload))
+	if err != nil {
+		return sendPasskeyError(c, err)
+	}
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "passkey.register"), passkey))
+}
+
+func (h *Handlers) ListPasskeys(c *fiber.Ctx) error {
+	user, err := authn.GetUserFromContext(c)
+	if err != nil {
+		return err
+	}
+	passkeys, err := h.service.ListPasskeys(c.Context(), user.UserID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "controller.list", map[string]string{
+		"Entity": "Passkey",
+	}), passkeys))
+}
+
+func (h *Handlers) DeletePasskey(c *fiber.Ctx) error {
+	user, err := authn.GetUserFromContext(c)
+	if err != nil {
+		return err
+	}
+	err = h.service.DeletePasskey(c.Context(), user.UserID, c.Params("id"))
+	if err != nil {
+		return sendPasskeyError(c, err)
+	}
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "controller.delete", map[string]string{
+		"Entity": "Passkey",
+	}), nil))
+}
+
+func (h *Handlers) BeginPasskeyLogin(c *fiber.Ctx) error {
+	ceremony, err := h.service.BeginPasskeyLogin(c.Context())
+	if err != nil {
+		return err
+	}
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "passkey.begin"), ceremony))
+}
+
+func (h *Handlers) FinishPasskeyLogin(c *fiber.Ctx) error {
+	var payload FinishPasskeyLoginPayload
+	err := c.BodyParser(&payload)
+	if err != nil {
+		return err
+	}
+	tokens, err := h.service.FinishPasskeyLogin(c.Context(), service.FinishPasskeyLoginPayload{
+		Session:    payload.Session,
+		Credential: payload.Credential,
+		UserAgent:  c.Get("User-Agent"),
+		UserIP:     c.IP(),
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrEmailNotVerified) {
+			c.Status(fiber.StatusForbidden)
+			return c.JSON(NewErrorResponse(translation.Localize(c, "user.email_not_verified"), err))
+		}
+		return sendPasskeyError(c, err)
+	}
+	setSessionCookies(c, tokens)
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "user.login"), tokens))
+}
+
+func (h *Handlers) BeginPasskeyMfa(c *fiber.Ctx) error {
+	var payload BeginPasskeyMfaPayload
+	err := c.BodyParser(&payload)
+	if err != nil {
+		return err
+	}
+	ceremony, err := h.service.BeginPasskeyMfa(c.Context(), service.BeginPasskeyMfaPayload(payload))
+	if err != nil {
+		return sendPasskeyError(c, err)
+	}
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "passkey.begin"), ceremony))
+}
+
+func (h *Handlers) FinishPasskeyMfa(c *fiber.Ctx) error {
+	var payload FinishPasskeyMfaPayload
+	err := c.BodyParser(&payload)
+	if err != nil {
+		return err
+	}
+	tokens, err := h.service.FinishPasskeyMfa(c.Context(), service.FinishPasskeyMfaPayload{
+		MfaToken:   payload.MfaToken,
+		Session:    payload.Session,
+		Credential: payload.Credential,
+		UserAgent:  c.Get("User-Agent"),
+		UserIP:     c.IP(),
+	})
+	if err != nil {
+		return sendPasskeyError(c, err)
+	}
+	setSessionCookies(c, tokens)
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "user.login"), tokens))
+}
+
+// sendPasskeyError answers the errors of the passkey ceremonies, the ones
+// shared with the other second factors included.
+func sendPasskeyError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, service.ErrInvalidPasskey) {
+		c.Status(fiber.StatusBadRequest)
+		return c.JSON(NewErrorResponse(translation.Localize(c, "passkey.invalid"), err))
+	} else if errors.Is(err, service.ErrPasskeyCloned) {
+		c.Status(fiber.StatusForbidden)
+		return c.JSON(NewErrorResponse(translation.Localize(c, "passkey.cloned"), err))
+	} else if errors.Is(err, service.ErrPasskeyNotFound) {
+		c.Status(fiber.StatusNotFound)
+		return c.JSON(NewErrorResponse(translation.Localize(c, "errors.404", map[string]string{
+			"Resource": "Passkey",
+		}), err))
+	}
+	return sendMfaError(c, err)
+}
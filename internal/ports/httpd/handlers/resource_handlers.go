@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/aritradeveops/porichoy/internal/core/service"
+	"github.com/aritradeveops/porichoy/internal/pkg/translation"
+	"github.com/aritradeveops/porichoy/internal/ports/httpd/authn"
+	"github.com/gofiber/fiber/v2"
+)
+
+type CreateApiResourcePayload struct {
+	Name          string   `json:"name"`
+	Identifier    string   `json:"identifier"`
+	Scopes        []string `json:"scopes"`
+	TokenLifetime string   `json:"token_lifetime"`
+	Owner         string   `json:"owner"`
+}
+
+type ApiResourceGrantPayload struct {
+	Identifier string `json:"identifier"`
+	ClientID   string `json:"client_id"`
+}
+
+func (h *Handlers) CreateApiResource(c *fiber.Ctx) error {
+	var payload CreateApiResourcePayload
+	err := c.BodyParser(&payload)
+	if err != nil {
+		return err
+	}
+
+	user, err := authn.GetUserFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	resource, err := h.service.CreateApiResource(c.Context(), user.UserID, service.CreateApiResourcePayload(payload))
+	if err != nil {
+		return sendResourceError(c, err)
+	}
+
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "controller.create", map[string]string{
+		"Entity": "Resource",
+	}), resource))
+}
+
+func (h *Handlers) ListApiResources(c *fiber.Ctx) error {
+	resources, err := h.service.ListApiResources(c.Context())
+	if err != nil {
+		return err
+	}
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "controller.list", map[string]string{
+		"Entity": "Resource",
+	}), resources))
+}
+
+func (h *Handlers) GrantApiResource(c *fiber.Ctx) error {
+	var payload ApiResourceGrantPayload
+	err := c.BodyParser(&payload)
+	if err != nil {
+		return err
+	}
+
+	user, err := authn.GetUserFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	err = h.service.GrantApiResource(c.Context(), user.UserID, service.ApiResourceGrantPayload(payload))
+	if err != nil {
+		return sendResourceError(c, err)
+	}
+
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "resource.grant"), nil))
+}
+
+func (h *Handlers) RevokeApiResource(c *fiber.Ctx) error {
+	var payload ApiResourceGrantPayload
+	err := c.BodyParser(&payload)
+	if err != nil {
+		return err
+	}
+
+	user, err := authn.GetUserFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	err = h.service.RevokeApiResource(c.Context(), user.UserID, service.ApiResourceGrantPayload(payload))
+	if err != nil {
+		return sendResourceError(c, err)
+	}
+
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "resource.revoke"), nil))
+}
+
+// sendResourceError answers the errors shared by the api resource endpoints.
+func sendResourceError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, service.ErrApiResourceNotFound) {
+		c.Status(fiber.StatusNotFound)
+		return c.JSON(NewErrorResponse(translation.Localize(c, "errors.404", map[string]string{
+			"Resource": "Resource",
+		}), err))
+	} else if errors.Is(err, service.ErrApiResourceForbidden) {
+		c.Status(fiber.StatusForbidden)
+		return c.JSON(NewErrorResponse(translation.Localize(c, "errors.403"), err))
+	}
+	return sendAppError(c, err)
+}
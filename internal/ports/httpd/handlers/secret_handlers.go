@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/aritradeveops/porichoy/internal/core/service"
+	"github.com/aritradeveops/porichoy/internal/pkg/translation"
+	"github.com/aritradeveops/porichoy/internal/ports/httpd/authn"
+	"github.com/gofiber/fiber/v2"
+)
+
+type CreateSecretPayload struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type RotateSecretPayload struct {
+	Value string `json:"value"`
+}
+
+func (h *Handlers) ListSecrets(c *fiber.Ctx) error {
+	user, err := authn.GetUserFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	secrets, err := h.service.ListSecrets(c.Context(), user.UserID)
+	if err != nil {
+		return sendSecretError(c, err)
+	}
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "controller.list", map[string]string{
+		"Entity": "Secret",
+	}), secrets))
+}
+
+func (h *Handlers) CreateSecret(c *fiber.Ctx) error {
+	var payload CreateSecretPayload
+	err := c.BodyParser(&payload)
+	if err != nil {
+		return err
+	}
+
+	user, err := authn.GetUserFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	secret, err := h.service.CreateSecret(c.Context(), user.UserID, service.CreateSecretPayload(payload))
+	if err != nil {
+		return sendSecretError(c, err)
+	}
+
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "controller.create", map[string]string{
+		"Entity": "Secret",
+	}), secret))
+}
+
+func (h *Handlers) RotateSecret(c *fiber.Ctx) error {
+	var payload RotateSecretPayload
+	err := c.BodyParser(&payload)
+	if err != nil {
+		return err
+	}
+
+	user, err := authn.GetUserFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	secret, err := h.service.RotateSecret(c.Context(), user.UserID, c.Params("name"), service.RotateSecretPayload(payload))
+	if err != nil {
+		return sendSecretError(c, err)
+	}
+
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "secret.rotate"), secret))
+}
+
+func (h *Handlers) DeleteSecret(c *fiber.Ctx) error {
+	user, err := authn.GetUserFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	err = h.service.DeleteSecret(c.Context(), user.UserID, c.Params("name"))
+	if err != nil {
+		return sendSecretError(c, err)
+	}
+
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "controller.delete", map[string]string{
+		"Entity": "Secret",
+	}), nil))
+}
+
+// sendSecretError answers the errors shared by the secret endpoints.
+func sendSecretError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, service.ErrSecretNotFound) {
+		return fiber.ErrNotFound
+	} else if errors.Is(err, service.ErrSecretForbidden) {
+		c.Status(fiber.StatusForbidden)
+		return c.JSON(NewErrorResponse(translation.Localize(c, "errors.403"), err))
+	}
+	return err
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/aritradeveops/porichoy/internal/core/service"
+	"github.com/aritradeveops/porichoy/internal/pkg/translation"
+	"github.com/aritradeveops/porichoy/internal/ports/httpd/authn"
+	"github.com/gofiber/fiber/v2"
+)
+
+type CreateSigningKeyPayload struct {
+	Algo           string     `json:"algo"`
+	SecretResolver string     `json:"secret_resolver"`
+	ActivatesAt    *time.Time `json:"activates_at"`
+}
+
+func (h *Handlers) ListSigningKeys(c *fiber.Ctx) error {
+	user, err := authn.GetUserFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	keys, err := h.service.ListSigningKeys(c.Context(), user.UserID, c.Params("client_id"))
+	if err != nil {
+		return sendAppError(c, err)
+	}
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "controller.list", map[string]string{
+		"Entity": "Signing key",
+	}), keys))
+}
+
+func (h *Handlers) CreateSigningKey(c *fiber.Ctx) error {
+	var payload CreateSigningKeyPayload
+	err := c.BodyParser(&payload)
+	if err != nil {
+		return err
+	}
+
+	user, err := authn.GetUserFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	key, err := h.service.CreateSigningKey(c.Context(), user.UserID, c.Params("client_id"), service.CreateSigningKeyPayload(payload))
+	if err != nil {
+		return sendAppError(c, err)
+	}
+
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "controller.create", map[string]string{
+		"Entity": "Signing key",
+	}), key))
+}
+
+func (h *Handlers) RotateSigningKey(c *fiber.Ctx) error {
+	user, err := authn.GetUserFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	key, err := h.service.RotateSigningKey(c.Context(), user.UserID, c.Params("client_id"))
+	if err != nil {
+		if errors.Is(err, service.ErrNoNextSigningKey) {
+			c.Status(fiber.StatusConflict)
+			return c.JSON(NewErrorResponse(translation.Localize(c, "signing_key.no_next_key"), err))
+		}
+		return sendAppError(c, err)
+	}
+
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "signing_key.rotate"), key))
+}
+
+func (h *Handlers) RevokeSigningKey(c *fiber.Ctx) error {
+	user, err := authn.GetUserFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	err = h.service.RevokeSigningKey(c.Context(), user.UserID, c.Params("client_id"), c.Params("kid"))
+	if err != nil {
+		if errors.Is(err, service.ErrActiveSigningKey) {
+			c.Status(fiber.StatusConflict)
+			return c.JSON(NewErrorResponse(translation.Localize(c, "signing_key.active_key"), err))
+		} else if errors.Is(err, service.ErrSigningKeyNotFound) {
+			return fiber.ErrNotFound
+		}
+		return sendAppError(c, err)
+	}
+
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "signing_key.revoke"), nil))
+}
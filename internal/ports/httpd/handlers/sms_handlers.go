@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/aritradeveops/porichoy/internal/core/service"
+	"github.com/aritradeveops/porichoy/internal/pkg/translation"
+	"github.com/aritradeveops/porichoy/internal/ports/httpd/authn"
+	"github.com/gofiber/fiber/v2"
+)
+
+type SetPhoneNumberPayload struct {
+	PhoneNumber string `json:"phone_number"`
+}
+
+type VerifyPhoneNumberPayload struct {
+	Code string `json:"code"`
+}
+
+type CompleteSmsLoginPayload struct {
+	LoginToken string `json:"login_token"`
+	Code       string `json:"code"`
+	ClientID   string `json:"client_id"`
+}
+
+type SendSmsMfaPayload struct {
+	MfaToken string `json:"mfa_token"`
+}
+
+type VerifySmsMfaPayload struct {
+	MfaToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+func (h *Handlers) SetPhoneNumber(c *fiber.Ctx) error {
+	var payload SetPhoneNumberPayload
+	err := c.BodyParser(&payload)
+	if err != nil {
+		return err
+	}
+	user, err := authn.GetUserFromContext(c)
+	if err != nil {
+		return err
+	}
+	err = h.service.SetPhoneNumber(c.Context(), user.UserID, service.SetPhoneNumberPayload{
+		PhoneNumber: payload.PhoneNumber,
+		UserIP:      c.IP(),
+	})
+	if err != nil {
+		return sendSmsError(c, err)
+	}
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "sms.code_sent"), nil))
+}
+
+func (h *Handlers) VerifyPhoneNumber(c *fiber.Ctx) error {
+	var payload VerifyPhoneNumberPayload
+	err := c.BodyParser(&payload)
+	if err != nil {
+		return err
+	}
+	user, err := authn.GetUserFromContext(c)
+	if err != nil {
+		return err
+	}
+	err = h.service.VerifyPhoneNumber(c.Context(), user.UserID, service.VerifyPhoneNumberPayload(payload))
+	if err != nil {
+		return sendSmsError(c, err)
+	}
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "sms.verified"), nil))
+}
+
+func (h *Handlers) RemovePhoneNumber(c *fiber.Ctx) error {
+	user, err := authn.GetUserFromContext(c)
+	if err != nil {
+		return err
+	}
+	err = h.service.RemovePhoneNumber(c.Context(), user.UserID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "sms.removed"), nil))
+}
+
+func (h *Handlers) CompleteSmsLogin(c *fiber.Ctx) error {
+	var payload CompleteSmsLoginPayload
+	err := c.BodyParser(&payload)
+	if err != nil {
+		return err
+	}
+	tokens, err := h.service.CompleteSmsLogin(c.Context(), service.CompleteSmsLoginPayload{
+		LoginToken: payload.LoginToken,
+		Code:       payload.Code,
+		ClientID:   payload.ClientID,
+		UserAgent:  c.Get("User-Agent"),
+		UserIP:     c.IP(),
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidLoginMethod) {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(NewErrorResponse(translation.Localize(c, "user.invalid_method"), err))
+		} else if errors.Is(err, service.ErrDeactivatedUser) {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(NewErrorResponse(translation.Localize(c, "user.deactivated"), err))
+		} else if errors.Is(err, service.ErrEmailNotVerified) {
+			c.Status(fiber.StatusForbidden)
+			return c.JSON(NewErrorResponse(translation.Localize(c, "user.email_not_verified"), err))
+		}
+		return sendSmsError(c, err)
+	}
+	return sendLoginTokens(c, tokens)
+}
+
+func (h *Handlers) SendSmsMfa(c *fiber.Ctx) error {
+	var payload SendSmsMfaPayload
+	err := c.BodyParser(&payload)
+	if err != nil {
+		return err
+	}
+	err = h.service.SendSmsMfa(c.Context(), service.SendSmsMfaPayload{
+		MfaToken: payload.MfaToken,
+		UserIP:   c.IP(),
+	})
+	if err != nil {
+		return sendSmsError(c, err)
+	}
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "sms.code_sent"), nil))
+}
+
+func (h *Handlers) VerifySmsMfa(c *fiber.Ctx) error {
+	var payload VerifySmsMfaPayload
+	err := c.BodyParser(&payload)
+	if err != nil {
+		return err
+	}
+	tokens, err := h.service.VerifySmsMfa(c.Context(), service.VerifySmsMfaPayload{
+		MfaToken:  payload.MfaToken,
+		Code:      payload.Code,
+		UserAgent: c.Get("User-Agent"),
+		UserIP:    c.IP(),
+	})
+	if err != nil {
+		return sendSmsError(c, err)
+	}
+	setSessionCookies(c, tokens)
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "user.login"), tokens))
+}
+
+// sendSmsError answers the errors of the text message flows, the ones
+// shared with the other second factors included.
+func sendSmsError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, service.ErrInvalidSmsCode) {
+		c.Status(fiber.StatusBadRequest)
+		return c.JSON(NewErrorResponse(translation.Localize(c, "sms.invalid_code"), err))
+	} else if errors.Is(err, service.ErrPhoneNumberNotAllowed) {
+		c.Status(fiber.StatusBadRequest)
+		return c.JSON(NewErrorResponse(translation.Localize(c, "sms.not_allowed"), err))
+	} else if errors.Is(err, service.ErrPhoneNumberTaken) {
+		c.Status(fiber.StatusConflict)
+		return c.JSON(NewErrorResponse(translation.Localize(c, "sms.number_taken"), err))
+	} else if errors.Is(err, service.ErrPhoneNumberNotVerified) {
+		c.Status(fiber.StatusBadRequest)
+		return c.JSON(NewErrorResponse(translation.Localize(c, "sms.not_verified"), err))
+	} else if errors.Is(err, service.ErrSmsMfaNotAllowed) {
+		c.Status(fiber.StatusBadRequest)
+		return c.JSON(NewErrorResponse(translation.Localize(c, "sms.mfa_not_allowed"), err))
+	}
+	return sendMfaError(c, err)
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/aritradeveops/porichoy/internal/core/service"
+	"github.com/aritradeveops/porichoy/internal/pkg/translation"
+	"github.com/aritradeveops/porichoy/internal/ports/httpd/authn"
+	"github.com/gofiber/fiber/v2"
+)
+
+type SetMfaPolicyPayload struct {
+	Required bool `json:"required"`
+}
+
+func (h *Handlers) SetMfaPolicy(c *fiber.Ctx) error {
+	var payload SetMfaPolicyPayload
+	err := c.BodyParser(&payload)
+	if err != nil {
+		return err
+	}
+	user, err := authn.GetUserFromContext(c)
+	if err != nil {
+		return err
+	}
+	err = h.service.SetMfaPolicy(c.Context(), user.UserID, c.Params("id"), service.SetMfaPolicyPayload(payload))
+	if err != nil {
+		if errors.Is(err, service.ErrMfaPolicyForbidden) {
+			c.Status(fiber.StatusForbidden)
+			return c.JSON(NewErrorResponse(translation.Localize(c, "errors.403"), err))
+		} else if errors.Is(err, service.ErrUserNotFound) {
+			c.Status(fiber.StatusNotFound)
+			return c.JSON(NewErrorResponse(translation.Localize(c, "errors.404", map[string]string{
+				"Resource": "User",
+			}), err))
+		}
+		return err
+	}
+	return c.JSON(NewSuccessResponse(translation.Localize(c, "user.mfa_policy"), nil))
+}
@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+// JWKS publishes the public signing keys, the set is served as is so that
+// standard jwt libraries can consume it.
+func (h *Handlers) JWKS(c *fiber.Ctx) error {
+	set, err := h.service.JWKS(c.Context())
+	if err != nil {
+		return err
+	}
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(set)
+}
+
+// OpenIDConfiguration serves the provider metadata for discovery.
+func (h *Handlers) OpenIDConfiguration(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(h.service.OpenIDConfiguration())
+}
@@ -1,10 +1,12 @@
 package httpd
 
 import (
+	"crypto/tls"
 	"fmt"
 
 	"github.com/aritradeveops/porichoy/internal/config"
 	"github.com/aritradeveops/porichoy/internal/pkg/translation"
+	"github.com/aritradeveops/porichoy/internal/ports/httpd/authn"
 	"github.com/aritradeveops/porichoy/internal/ports/httpd/handlers"
 	"github.com/aritradeveops/porichoy/internal/ports/httpd/middlewares"
 	"github.com/aritradeveops/porichoy/internal/ports/httpd/ui"
@@ -19,9 +21,10 @@ type Server struct {
 	config   *config.Config
 	handlers *handlers.Handlers
 	ui       *ui.UI
+	authn    *authn.Authn
 }
 
-func NewServer(config *config.Config, handlers *handlers.Handlers, ui *ui.UI) *Server {
+func NewServer(config *config.Config, handlers *handlers.Handlers, ui *ui.UI, authn *authn.Authn) *Server {
 	engine := html.New("./template/vanilla", ".html")
 	logMiddleware := logger.New()
 	app := fiber.New(fiber.Config{
@@ -38,13 +41,32 @@ func NewServer(config *config.Config, handlers *handlers.Handlers, ui *ui.UI) *S
 		app:      app,
 		handlers: handlers,
 		ui:       ui,
+		authn:    authn,
 	}
 	server.setupRoutes()
 	return server
 }
 
 func (s *Server) Start() error {
-	return s.app.Listen(fmt.Sprintf("%s:%d", s.config.Http.Host, s.config.Http.Port))
+	addr := fmt.Sprintf("%s:%d", s.config.Http.Host, s.config.Http.Port)
+	if s.config.Http.TLS == nil {
+		return s.app.Listen(addr)
+	}
+	cert, err := tls.LoadX509KeyPair(s.config.Http.TLS.CertFile, s.config.Http.TLS.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load tls certificate: %v", err)
+	}
+	ln, err := tls.Listen("tcp", addr, &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+		// client certificates are only requested here, they are verified by the
+		// token endpoint as self signed certificates are valid for some apps
+		ClientAuth: tls.RequestClientCert,
+	})
+	if err != nil {
+		return err
+	}
+	return s.app.Listener(ln)
 }
 func (s *Server) Shutdown() error {
 	return s.app.Shutdown()
@@ -0,0 +1,75 @@
+// Package oauth2 holds what the api handlers and the ui share of the
+// authorization endpoint.
+package oauth2
+
+import (
+	"crypto/subtle"
+	"time"
+
+	"github.com/aritradeveops/porichoy/internal/core/cryptoutil"
+	"github.com/aritradeveops/porichoy/internal/core/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+// AuthorizationPayload is the query of an authorization request, or the form
+// of the consent screen carrying it.
+type AuthorizationPayload struct {
+	ClientID            string `query:"client_id" form:"client_id"`
+	ResponseType        string `query:"response_type" form:"response_type"`
+	RedirectURI         string `query:"redirect_uri" form:"redirect_uri"`
+	CodeChallenge       string `query:"code_challenge" form:"code_challenge"`
+	CodeChallengeMethod string `query:"code_challenge_method" form:"code_challenge_method"`
+	State               string `query:"state" form:"state"`
+	LoginHint           string `query:"login_hint" form:"login_hint"`
+	Nonce               string `query:"nonce" form:"nonce"`
+	Prompt              string `query:"prompt" form:"prompt"`
+	MaxAge              string `query:"max_age" form:"max_age"`
+	ResponseMode        string `query:"response_mode" form:"response_mode"`
+	Scope               string `query:"scope" form:"scope"`
+	Resource            string `query:"resource" form:"resource"`
+}
+
+// SendAuthorizationResponse returns the authorization response to the client
+// with the requested response mode.
+func SendAuthorizationResponse(c *fiber.Ctx, response service.Oauth2AuthorizationResponse) error {
+	if response.ResponseMode == service.ResponseModeFormPost {
+		c.Set(fiber.HeaderCacheControl, "no-store")
+		return c.Render("form_post", response)
+	}
+	return c.Redirect(response.URL())
+}
+
+// the double submit cookie guarding the consent form
+const consentCookie = "oauth2_consent"
+
+// ConsentToken sets a fresh token in a cookie for the consent form, which
+// has to send it back in its token field.
+func ConsentToken(c *fiber.Ctx) (string, error) {
+	token, err := cryptoutil.GenerateHash(32)
+	if err != nil {
+		return "", err
+	}
+	setConsentCookie(c, token, time.Time{})
+	return token, nil
+}
+
+// CheckConsentToken reports whether the submitted consent form carries the
+// token of its cookie, so another site can not approve on the end user's
+// behalf. The cookie is single use.
+func CheckConsentToken(c *fiber.Ctx) bool {
+	cookie := c.Cookies(consentCookie)
+	setConsentCookie(c, "", time.Now().Add(-time.Hour))
+	return cookie != "" && subtle.ConstantTimeCompare([]byte(cookie), []byte(c.FormValue("token"))) == 1
+}
+
+func setConsentCookie(c *fiber.Ctx, value string, expires time.Time) {
+	c.Cookie(&fiber.Cookie{
+		Name:     consentCookie,
+		Value:    value,
+		Path:     "/oauth2",
+		Expires:  expires,
+		HTTPOnly: true,
+		Secure:   c.Protocol() == "https",
+		SameSite: fiber.CookieSameSiteStrictMode,
+	})
+}
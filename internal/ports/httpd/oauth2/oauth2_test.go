@@ -0,0 +1,162 @@
+package oauth2
+
+import (
+	"io"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/aritradeveops/porichoy/internal/core/service"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/template/html/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// newApp answers every request with response, rendered with the templates
+// the server uses.
+func newApp(response service.Oauth2AuthorizationResponse) *fiber.App {
+	app := fiber.New(fiber.Config{
+		Views: html.New("../../../../template/vanilla", ".html"),
+	})
+	app.Get("/", func(c *fiber.Ctx) error {
+		return SendAuthorizationResponse(c, response)
+	})
+	return app
+}
+
+func TestSendAuthorizationResponse(t *testing.T) {
+	t.Parallel()
+	params := url.Values{"code": {"abc"}, "state": {"xyz"}}
+
+	tests := []struct {
+		mode     string
+		location string
+	}{
+		{service.ResponseModeQuery, "https://app.example.com/callback?code=abc&state=xyz"},
+		{service.ResponseModeFragment, "https://app.example.com/callback#code=abc&state=xyz"},
+	}
+	for _, test := range tests {
+		t.Run(test.mode, func(t *testing.T) {
+			t.Parallel()
+			app := newApp(service.Oauth2AuthorizationResponse{
+				RedirectURI:  "https://app.example.com/callback",
+				ResponseMode: test.mode,
+				Params:       params,
+			})
+			resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+			assert.NoError(t, err)
+			assert.Equal(t, fiber.StatusFound, resp.StatusCode)
+			assert.Equal(t, test.location, resp.Header.Get(fiber.HeaderLocation))
+		})
+	}
+}
+
+func TestSendAuthorizationResponseFormPost(t *testing.T) {
+	t.Parallel()
+	app := newApp(service.Oauth2AuthorizationResponse{
+		RedirectURI:  "https://app.example.com/callback",
+		ResponseMode: service.ResponseModeFormPost,
+		Params:       url.Values{"code": {"abc"}, "state": {"xyz"}},
+	})
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	// the code must not be cached by the browser
+	assert.Equal(t, "no-store", resp.Header.Get(fiber.HeaderCacheControl))
+	assert.Empty(t, resp.Header.Get(fiber.HeaderLocation))
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `action="https://app.example.com/callback"`)
+	assert.Contains(t, string(body), `name="code" value="abc"`)
+	assert.Contains(t, string(body), `name="state" value="xyz"`)
+}
+
+func TestAuthorizationPayload(t *testing.T) {
+	t.Parallel()
+	app := fiber.New()
+	var got service.Oauth2Payload
+	app.Get("/", func(c *fiber.Ctx) error {
+		var payload AuthorizationPayload
+		if err := c.QueryParser(&payload); err != nil {
+			return err
+		}
+		got = service.Oauth2Payload(payload)
+		return nil
+	})
+	query := url.Values{
+		"client_id":     {"app.example.com"},
+		"response_type": {"code"},
+		"redirect_uri":  {"https://app.example.com/callback"},
+		"prompt":        {"login consent"},
+		"max_age":       {"300"},
+		"response_mode": {"form_post"},
+		"resource":      {"https://billing.example.com"},
+	}
+	_, err := app.Test(httptest.NewRequest("GET", "/?"+query.Encode(), nil))
+	assert.NoError(t, err)
+	assert.Equal(t, service.Oauth2Payload{
+		ClientID:     "app.example.com",
+		ResponseType: "code",
+		RedirectURI:  "https://app.example.com/callback",
+		Prompt:       "login consent",
+		MaxAge:       "300",
+		ResponseMode: "form_post",
+		Resource:     "https://billing.example.com",
+	}, got)
+}
+
+func TestConsentToken(t *testing.T) {
+	t.Parallel()
+	app := fiber.New()
+	app.Get("/oauth2", func(c *fiber.Ctx) error {
+		token, err := ConsentToken(c)
+		if err != nil {
+			return err
+		}
+		return c.SendString(token)
+	})
+	app.Post("/oauth2", func(c *fiber.Ctx) error {
+		if !CheckConsentToken(c) {
+			return fiber.ErrForbidden
+		}
+		return nil
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/oauth2", nil))
+	assert.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	token := string(body)
+	assert.NotEmpty(t, token)
+	cookie := resp.Header.Get(fiber.HeaderSetCookie)
+	assert.Contains(t, cookie, consentCookie+"="+token)
+	assert.Contains(t, cookie, "HttpOnly")
+	assert.Contains(t, cookie, "SameSite=Strict")
+
+	tests := []struct {
+		name   string
+		cookie string
+		form   string
+		status int
+	}{
+		{"matching", token, token, fiber.StatusOK},
+		{"no cookie", "", token, fiber.StatusForbidden},
+		{"no token", token, "", fiber.StatusForbidden},
+		{"other token", token, "forged", fiber.StatusForbidden},
+		{"both empty", "", "", fiber.StatusForbidden},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("POST", "/oauth2", strings.NewReader(url.Values{
+			"token":    {test.form},
+			"decision": {"approve"},
+		}.Encode()))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationForm)
+		if test.cookie != "" {
+			req.Header.Set(fiber.HeaderCookie, consentCookie+"="+test.cookie)
+		}
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, test.status, resp.StatusCode, test.name)
+	}
+}
@@ -1,20 +1,25 @@
 package httpd
 
-import (
-	"github.com/aritradeveops/porichoy/internal/ports/httpd/authn"
-)
-
 func (s *Server) setupRoutes() {
 	router := s.app
 	// router.Use("/", filesystem.New(filesystem.Config{
 	// 	Root: http.Dir("./template/vanilla"),
 	// }))
 
+	router.Static("/assets", "./template/vanilla/assets")
 	router.Get("/", s.ui.Index)
 	router.Get("/login", s.ui.Login)
+	router.Get("/login/email", s.ui.EmailLogin)
+	router.Get("/login/sms", s.ui.SmsLogin)
 	router.Get("/register", s.ui.Register)
-	router.Get("/oauth2", authn.Middleware(true), s.ui.OAuth2)
-	router.Get("/profile", authn.Middleware(true), s.ui.Profile)
+	router.Get("/forgot-password", s.ui.ForgotPassword)
+	router.Get("/reset-password", s.ui.ResetPassword)
+	router.Get("/mfa", s.ui.Mfa)
+	router.Get("/oauth2", s.authn.Optional(), s.ui.OAuth2)
+	router.Post("/oauth2", s.authn.Middleware(true), s.ui.OAuth2Consent)
+	router.Get("/profile", s.authn.Middleware(true), s.ui.Profile)
+	router.Get("/.well-known/jwks.json", s.handlers.JWKS)
+	router.Get("/.well-known/openid-configuration", s.handlers.OpenIDConfiguration)
 
 	apiRouter := router.Group("/api/v1")
 	apiRouter.Get("/", s.handlers.Hello)
@@ -22,11 +27,57 @@ func (s *Server) setupRoutes() {
 	authRouter := apiRouter.Group("/auth")
 	authRouter.Post("/register", s.handlers.RegisterUser)
 	authRouter.Post("/login", s.handlers.LoginUser)
-	authRouter.Get("/oauth2", authn.Middleware(true), s.handlers.Oauth2)
+	authRouter.Post("/login/mfa", s.handlers.VerifyMfa)
+	authRouter.Post("/login/email", s.handlers.CompleteEmailLogin)
+	authRouter.Post("/login/sms", s.handlers.CompleteSmsLogin)
+	authRouter.Post("/mfa/totp/enroll", s.handlers.EnrollMfa)
+	authRouter.Post("/mfa/passkey/begin", s.handlers.BeginPasskeyMfa)
+	authRouter.Post("/mfa/passkey/finish", s.handlers.FinishPasskeyMfa)
+	authRouter.Post("/mfa/sms/send", s.handlers.SendSmsMfa)
+	authRouter.Post("/mfa/sms/verify", s.handlers.VerifySmsMfa)
+	authRouter.Post("/passkey/begin", s.handlers.BeginPasskeyLogin)
+	authRouter.Post("/passkey/finish", s.handlers.FinishPasskeyLogin)
+	authRouter.Get("/verify-email", s.handlers.VerifyEmail)
+	authRouter.Post("/verify-email/resend", s.handlers.ResendVerificationEmail)
+	authRouter.Post("/password/forgot", s.handlers.ForgotPassword)
+	authRouter.Post("/password/reset", s.handlers.ResetPassword)
+	authRouter.Get("/oauth2", s.authn.Middleware(true), s.handlers.Oauth2)
 	authRouter.Post("/token", s.handlers.Token)
-	authRouter.Post("/logout", authn.Middleware(), s.handlers.LogoutUser)
-	appRouter := apiRouter.Group("/apps", authn.Middleware())
+	authRouter.Get("/userinfo", s.handlers.Userinfo)
+	authRouter.Post("/userinfo", s.handlers.Userinfo)
+	authRouter.Post("/logout", s.authn.Middleware(), s.handlers.LogoutUser)
+	meRouter := apiRouter.Group("/me", s.authn.Middleware())
+	meRouter.Post("/password", s.handlers.ChangePassword)
+	meRouter.Post("/mfa/totp", s.handlers.EnrollTotp)
+	meRouter.Post("/mfa/totp/confirm", s.handlers.ConfirmTotp)
+	meRouter.Post("/mfa/totp/delete", s.handlers.DisableTotp)
+	meRouter.Post("/mfa/recovery-codes", s.handlers.RegenerateRecoveryCodes)
+	meRouter.Get("/passkeys", s.handlers.ListPasskeys)
+	meRouter.Post("/passkeys/register/begin", s.handlers.BeginPasskeyRegistration)
+	meRouter.Post("/passkeys/register/finish", s.handlers.FinishPasskeyRegistration)
+	meRouter.Post("/passkeys/:id/delete", s.handlers.DeletePasskey)
+	meRouter.Post("/phone", s.handlers.SetPhoneNumber)
+	meRouter.Post("/phone/verify", s.handlers.VerifyPhoneNumber)
+	meRouter.Post("/phone/delete", s.handlers.RemovePhoneNumber)
+	userRouter := apiRouter.Group("/users", s.authn.Middleware())
+	userRouter.Post("/:id/mfa-policy", s.handlers.SetMfaPolicy)
+	appRouter := apiRouter.Group("/apps", s.authn.Middleware())
 	appRouter.Post("/create", s.handlers.CreateApp)
+	appRouter.Post("/:client_id/rotate-secret", s.handlers.RotateClientSecret)
+	appRouter.Get("/:client_id/keys", s.handlers.ListSigningKeys)
+	appRouter.Post("/:client_id/keys/create", s.handlers.CreateSigningKey)
+	appRouter.Post("/:client_id/keys/rotate", s.handlers.RotateSigningKey)
+	appRouter.Post("/:client_id/keys/:kid/revoke", s.handlers.RevokeSigningKey)
+	resourceRouter := apiRouter.Group("/resources", s.authn.Middleware())
+	resourceRouter.Get("/", s.handlers.ListApiResources)
+	resourceRouter.Post("/create", s.handlers.CreateApiResource)
+	resourceRouter.Post("/grant", s.handlers.GrantApiResource)
+	resourceRouter.Post("/revoke", s.handlers.RevokeApiResource)
+	secretRouter := apiRouter.Group("/secrets", s.authn.Middleware())
+	secretRouter.Get("/", s.handlers.ListSecrets)
+	secretRouter.Post("/create", s.handlers.CreateSecret)
+	secretRouter.Post("/:name/rotate", s.handlers.RotateSecret)
+	secretRouter.Post("/:name/delete", s.handlers.DeleteSecret)
 	configRouter := apiRouter.Group("/config")
 	configRouter.Post("/configure", s.handlers.Configure)
 }
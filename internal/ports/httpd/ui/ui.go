@@ -2,10 +2,15 @@ package ui
 
 import (
 	"fmt"
+	"net/url"
+	"slices"
+	"strings"
 
+	"github.com/aritradeveops/porichoy/internal/core/jwtutil"
 	"github.com/aritradeveops/porichoy/internal/core/service"
 	"github.com/aritradeveops/porichoy/internal/pkg/logger"
 	"github.com/aritradeveops/porichoy/internal/ports/httpd/authn"
+	"github.com/aritradeveops/porichoy/internal/ports/httpd/oauth2"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -13,8 +18,36 @@ type UI struct {
 	template string
 	service  *service.Service
 }
-type OauthConsentPayload struct {
-	ClientID string `query:"client_id" validate:"required"`
+
+type LoginPage struct {
+	LoginHint string
+	// the app the login is for, it may require mfa
+	ClientID string
+}
+
+// EmailLoginPage is opened from the link in the email, with its Token, or
+// after the email was sent to type in the code.
+type EmailLoginPage struct {
+	Token    string
+	ClientID string
+}
+
+// SmsLoginPage takes the code texted after a login by phone number was
+// started on the login page.
+type SmsLoginPage struct {
+	ClientID string
+}
+
+// ConsentPage asks the end user to let AppName access their account, the
+// form posts the authorization request in Params back with the Token.
+type ConsentPage struct {
+	AppName string
+	Token   string
+	Params  url.Values
+}
+
+type ResetPasswordPage struct {
+	Token string
 }
 
 func New(template string, service *service.Service) *UI {
@@ -29,13 +62,43 @@ func (u *UI) Index(c *fiber.Ctx) error {
 }
 
 func (u *UI) Login(c *fiber.Ctx) error {
-	return c.Render("login", nil)
+	return c.Render("login", LoginPage{
+		LoginHint: c.Query("login_hint"),
+		ClientID:  c.Query("client_id"),
+	})
+}
+
+func (u *UI) EmailLogin(c *fiber.Ctx) error {
+	return c.Render("email_login", EmailLoginPage{
+		Token:    c.Query("token"),
+		ClientID: c.Query("client_id"),
+	})
+}
+
+func (u *UI) SmsLogin(c *fiber.Ctx) error {
+	return c.Render("sms_login", SmsLoginPage{
+		ClientID: c.Query("client_id"),
+	})
+}
+
+func (u *UI) Mfa(c *fiber.Ctx) error {
+	return c.Render("mfa", nil)
 }
 
 func (u *UI) Register(c *fiber.Ctx) error {
 	return c.Render("register", nil)
 }
 
+func (u *UI) ForgotPassword(c *fiber.Ctx) error {
+	return c.Render("forgot_password", nil)
+}
+
+func (u *UI) ResetPassword(c *fiber.Ctx) error {
+	return c.Render("reset_password", ResetPasswordPage{
+		Token: c.Query("token"),
+	})
+}
+
 func (u *UI) Profile(c *fiber.Ctx) error {
 	user, err := authn.GetUserFromContext(c)
 	if err != nil {
@@ -46,17 +109,81 @@ func (u *UI) Profile(c *fiber.Ctx) error {
 }
 
 func (u *UI) OAuth2(c *fiber.Ctx) error {
-	var payload OauthConsentPayload
-	logger.Info().Any("queries", c.Queries()).Msg("oauth2")
+	var payload oauth2.AuthorizationPayload
 	if err := c.QueryParser(&payload); err != nil {
 		return err
 	}
 	logger.Info().Any("payload", payload).Msg("oauth2")
-	resp, err := u.service.Oauth2ConsentResponse(c.Context(), service.Oauth2ConsentPayload{
-		ClientID: payload.ClientID,
+	user, _ := authn.GetUserFromContext(c)
+	request := service.Oauth2Payload(payload)
+	resp, err := u.service.Oauth2Prompt(c.Context(), user, request)
+	if err != nil {
+		return err
+	}
+
+	switch resp.Step {
+	case service.Oauth2StepError:
+		return oauth2.SendAuthorizationResponse(c, *resp.Authorization)
+	case service.Oauth2StepLogin:
+		// drop prompt=login so the request is not looping back to the login page
+		query := c.Request().URI().QueryArgs()
+		prompts := slices.DeleteFunc(request.Prompts(), func(p string) bool {
+			return p == service.PromptLogin
+		})
+		if len(prompts) > 0 {
+			query.Set("prompt", strings.Join(prompts, " "))
+		} else {
+			query.Del("prompt")
+		}
+		next := "/oauth2?" + query.String()
+		return c.Redirect("/login?next=" + url.QueryEscape(next) + "&login_hint=" + url.QueryEscape(payload.LoginHint) +
+			"&client_id=" + url.QueryEscape(payload.ClientID))
+	case service.Oauth2StepApprove:
+		return u.authorize(c, user, request, "")
+	}
+	token, err := oauth2.ConsentToken(c)
+	if err != nil {
+		return err
+	}
+	params, err := url.ParseQuery(string(c.Request().URI().QueryString()))
+	if err != nil {
+		return err
+	}
+	params.Del("token")
+	return c.Render("oauth2", ConsentPage{
+		AppName: resp.AppName,
+		Token:   token,
+		Params:  params,
 	})
+}
+
+// OAuth2Consent takes the decision of the end user from the consent screen.
+func (u *UI) OAuth2Consent(c *fiber.Ctx) error {
+	if !oauth2.CheckConsentToken(c) {
+		return fiber.ErrForbidden
+	}
+	var payload oauth2.AuthorizationPayload
+	if err := c.BodyParser(&payload); err != nil {
+		return err
+	}
+	user, err := authn.GetUserFromContext(c)
 	if err != nil {
 		return err
 	}
-	return c.Render("oauth2", resp)
+	decision := service.ConsentDeny
+	if c.FormValue("decision") == service.ConsentApprove {
+		decision = service.ConsentApprove
+	}
+	return u.authorize(c, user, service.Oauth2Payload(payload), decision)
+}
+
+func (u *UI) authorize(c *fiber.Ctx, user *jwtutil.JwtPayload, request service.Oauth2Payload, decision string) error {
+	resp, err := u.service.Oauth2(c.Context(), user, request, decision)
+	if err != nil {
+		return err
+	}
+	if resp.Authorization == nil {
+		return fiber.ErrBadRequest
+	}
+	return oauth2.SendAuthorizationResponse(c, *resp.Authorization)
 }
@@ -0,0 +1,39 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// dbTimeout bounds a lookup in the secret store, resolving has no context of
+// its own to be cancelled with.
+const dbTimeout = 10 * time.Second
+
+// SecretStore looks up a named secret, porichoy implements it on top of its
+// secrets table.
+type SecretStore interface {
+	Secret(ctx context.Context, name string) (string, error)
+}
+
+// resolves from the secrets stored in porichoy, e.g. db://github-app-key
+type DBResolver struct {
+	Store SecretStore
+}
+
+func (r *DBResolver) Resolve(key string) (any, error) {
+	name := strings.TrimPrefix(key, "db://")
+	if r.Store == nil {
+		return nil, fmt.Errorf("db_resolver: no secret store configured to resolve %s", name)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+	return r.Store.Secret(ctx, name)
+}
+
+// UseSecretStore makes db:// secrets resolvable through the process wide
+// factory.
+func UseSecretStore(store SecretStore) {
+	Register("db", &DBResolver{Store: store})
+}
@@ -0,0 +1,147 @@
+package resolver
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// resolves from a file on disk, e.g. file:///run/secrets/jwt.pem or
+// file:///run/secrets/app.json#jwt.secret to pick a key out of a JSON or
+// YAML document. Files readable by everyone are refused unless they are
+// under one of the AllowWorldReadable directories, e.g. a Kubernetes secret
+// volume mounted with the default 0644 mode. Those are taken from
+// PORICHOY_FILE_WORLD_READABLE_DIRS, a list like $PATH, when not set.
+//
+// The contents are cached per path and re-read whenever the file's size or
+// modification time changes, so secrets mounted by Kubernetes (which are
+// swapped through a symlink) rotate without a restart.
+type FileResolver struct {
+	AllowWorldReadable []string
+
+	configure sync.Once
+	mu        sync.Mutex
+	cache     map[string]fileEntry
+}
+
+type fileEntry struct {
+	modTime time.Time
+	size    int64
+	content []byte
+}
+
+func (r *FileResolver) Resolve(key string) (any, error) {
+	r.configure.Do(r.fromEnv)
+	path, selector, _ := strings.Cut(strings.TrimPrefix(key, "file://"), "#")
+	if path == "" {
+		return nil, fmt.Errorf("file_resolver: path is empty")
+	}
+	content, err := r.read(path)
+	if err != nil {
+		return nil, err
+	}
+	if selector == "" {
+		return strings.TrimRight(string(content), "\r\n"), nil
+	}
+	value, err := selectKey(path, content, selector)
+	if err != nil {
+		return nil, fmt.Errorf("file_resolver: %v", err)
+	}
+	return value, nil
+}
+
+func (r *FileResolver) read(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("file_resolver: %v", err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("file_resolver: %s is not a regular file", path)
+	}
+	if info.Mode().Perm()&0o004 != 0 && !r.worldReadable(path) {
+		return nil, fmt.Errorf("file_resolver: %s is world readable (%s), restrict it to 0600 or 0640", path, info.Mode().Perm())
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if entry, ok := r.cache[path]; ok && entry.size == info.Size() && entry.modTime.Equal(info.ModTime()) {
+		return entry.content, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("file_resolver: %v", err)
+	}
+	if r.cache == nil {
+		r.cache = map[string]fileEntry{}
+	}
+	r.cache[path] = fileEntry{modTime: info.ModTime(), size: info.Size(), content: content}
+	return content, nil
+}
+
+// fromEnv fills AllowWorldReadable when it was not configured.
+func (r *FileResolver) fromEnv() {
+	if r.AllowWorldReadable == nil {
+		r.AllowWorldReadable = filepath.SplitList(os.Getenv("PORICHOY_FILE_WORLD_READABLE_DIRS"))
+	}
+}
+
+// worldReadable tells whether path is inside one of the directories
+// world readable files are accepted from.
+func (r *FileResolver) worldReadable(path string) bool {
+	path = filepath.Clean(path)
+	for _, dir := range r.AllowWorldReadable {
+		if dir == "" {
+			continue
+		}
+		dir = filepath.Clean(dir)
+		if path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectKey walks a dot separated path through a JSON or YAML document.
+func selectKey(path string, content []byte, selector string) (any, error) {
+	var doc any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err := json.Unmarshal(content, &doc)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not valid json: %v", path, err)
+		}
+	case ".yaml", ".yml":
+		err := yaml.Unmarshal(content, &doc)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not valid yaml: %v", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("key selector is only supported for json and yaml files")
+	}
+
+	current := doc
+	for part := range strings.SplitSeq(selector, ".") {
+		object, ok := current.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s not found in %s", selector, path)
+		}
+		current, ok = object[part]
+		if !ok {
+			return nil, fmt.Errorf("%s not found in %s", selector, path)
+		}
+	}
+	switch v := current.(type) {
+	case string:
+		return v, nil
+	case map[string]any, []any:
+		return nil, fmt.Errorf("%s in %s is not a scalar", selector, path)
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
@@ -2,9 +2,12 @@ package resolver
 
 import (
 	"fmt"
+	"maps"
 	"os"
 	"slices"
 	"strings"
+	"sync"
+	"time"
 )
 
 type Resolver interface {
@@ -33,38 +36,167 @@ func (r *LiteralResolver) Resolve(key string) (any, error) {
 	return val, nil
 }
 
-// TODO: db and s3
 type ResolverFactory struct {
+	mu        sync.RWMutex
 	providers map[string]Resolver
+	ttls      map[string]time.Duration
+	cache     map[string]cachedValue
+	// bumped whenever cached values are dropped, a value read before that
+	// is not cached
+	generation uint64
+	now        func() time.Time
 }
 
+type cachedValue struct {
+	value     any
+	expiresAt time.Time
+}
+
+// the process wide factory, secrets resolved through it are shared by every
+// caller and cached for the ttl of their scheme
+var defaultFactory = NewResolverFactory()
+
+// NewResolverFactory returns a factory with the built in resolvers, resolvers
+// that keep their own cache (file, vault) or are cheap are not cached. A
+// changed db:// or s3:// secret can be served from the cache for up to its
+// ttl unless it is forgotten.
 func NewResolverFactory() *ResolverFactory {
-	providers := map[string]Resolver{}
+	r := &ResolverFactory{
+		providers: map[string]Resolver{},
+		ttls:      map[string]time.Duration{},
+		cache:     map[string]cachedValue{},
+		now:       time.Now,
+	}
+	r.Register("env", &EnvResolver{})
+	r.Register("literal", &LiteralResolver{})
+	r.Register("file", &FileResolver{})
+	r.Register("db", &DBResolver{})
+	r.Register("vault", &VaultResolver{})
+	r.Register("s3", &S3Resolver{})
+	r.SetTTL("db", 30*time.Second)
+	r.SetTTL("s3", time.Minute)
+	return r
+}
+
+// Register makes a resolver available for keys of the given scheme, it
+// replaces the resolver registered for the scheme before.
+func (r *ResolverFactory) Register(scheme string, resolver Resolver) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[scheme] = resolver
+	r.forgetScheme(scheme)
+}
+
+// SetTTL sets how long the values of a scheme are cached, they are resolved
+// every time when it is zero.
+func (r *ResolverFactory) SetTTL(scheme string, ttl time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ttls[scheme] = ttl
+	r.forgetScheme(scheme)
+}
 
-	providers["env"] = &EnvResolver{}
-	providers["literal"] = &LiteralResolver{}
-	return &ResolverFactory{
-		providers: providers,
+// Forget drops the cached value of a key and of the selectors into it, the
+// next resolve reads it again. Stores call it when a secret changes.
+func (r *ResolverFactory) Forget(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.generation++
+	for cached := range r.cache {
+		if cached == key || strings.HasPrefix(cached, key+"#") {
+			delete(r.cache, cached)
+		}
 	}
 }
 
-func (r *ResolverFactory) Providers() []string {
-	pros := []string{}
-	for k := range r.providers {
-		pros = append(pros, k)
+func (r *ResolverFactory) forgetScheme(scheme string) {
+	r.generation++
+	for key := range r.cache {
+		if strings.HasPrefix(key, scheme+"://") {
+			delete(r.cache, key)
+		}
 	}
-	return pros
+}
+
+func (r *ResolverFactory) Providers() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Sorted(maps.Keys(r.providers))
 }
 
 func (r *ResolverFactory) Auto(key string) (Resolver, error) {
-	parts := strings.Split(key, "://")
-	if len(parts) != 2 {
+	id, _, found := strings.Cut(key, "://")
+	if !found {
 		return nil, fmt.Errorf("resolver_factory: invalid key")
 	}
-	id := parts[0]
 
-	if !slices.Contains(r.Providers(), id) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	provider, ok := r.providers[id]
+	if !ok {
 		return nil, fmt.Errorf("resolver_factory: provider not implemented for id: %s", id)
 	}
-	return r.providers[id], nil
+	return provider, nil
+}
+
+// Resolve resolves the key with the resolver of its scheme, values are served
+// from the cache until the ttl of the scheme has passed.
+func (r *ResolverFactory) Resolve(key string) (any, error) {
+	provider, err := r.Auto(key)
+	if err != nil {
+		return nil, err
+	}
+	scheme, _, _ := strings.Cut(key, "://")
+
+	r.mu.RLock()
+	ttl := r.ttls[scheme]
+	cached, ok := r.cache[key]
+	generation := r.generation
+	r.mu.RUnlock()
+	now := r.now()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.value, nil
+	}
+
+	value, err := provider.Resolve(key)
+	if err != nil {
+		return nil, err
+	}
+	// a value read while it was forgotten may be the old one, the next
+	// resolve reads it again
+	if ttl > 0 {
+		r.mu.Lock()
+		if r.generation == generation {
+			r.cache[key] = cachedValue{value: value, expiresAt: now.Add(ttl)}
+		}
+		r.mu.Unlock()
+	}
+	return value, nil
+}
+
+func Default() *ResolverFactory {
+	return defaultFactory
+}
+
+// Register adds a resolver to the process wide factory.
+func Register(scheme string, resolver Resolver) {
+	defaultFactory.Register(scheme, resolver)
+}
+
+func SetTTL(scheme string, ttl time.Duration) {
+	defaultFactory.SetTTL(scheme, ttl)
+}
+
+// Forget drops a key from the cache of the process wide factory.
+func Forget(key string) {
+	defaultFactory.Forget(key)
+}
+
+func Providers() []string {
+	return defaultFactory.Providers()
+}
+
+// Resolve resolves a key through the process wide factory.
+func Resolve(key string) (any, error) {
+	return defaultFactory.Resolve(key)
 }
@@ -0,0 +1,134 @@
+package resolver
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingResolver struct {
+	calls int
+}
+
+func (r *countingResolver) Resolve(key string) (any, error) {
+	r.calls++
+	return fmt.Sprintf("%s#%d", key, r.calls), nil
+}
+
+func TestResolverFactory_Register(t *testing.T) {
+	t.Parallel()
+
+	factory := NewResolverFactory()
+	assert.Equal(t, []string{"db", "env", "file", "literal", "s3", "vault"}, factory.Providers())
+
+	_, err := factory.Resolve("kms://alias/porichoy")
+	assert.Error(t, err)
+
+	kms := &countingResolver{}
+	factory.Register("kms", kms)
+	assert.Contains(t, factory.Providers(), "kms")
+	got, err := factory.Resolve("kms://alias/porichoy")
+	assert.NoError(t, err)
+	assert.Equal(t, "kms://alias/porichoy#1", got)
+
+	// schemes without a ttl are resolved every time
+	got, err = factory.Resolve("kms://alias/porichoy")
+	assert.NoError(t, err)
+	assert.Equal(t, "kms://alias/porichoy#2", got)
+
+	got, err = factory.Resolve("literal://postgres://user@db/porichoy")
+	assert.NoError(t, err)
+	assert.Equal(t, "postgres://user@db/porichoy", got)
+}
+
+func TestResolverFactory_TTL(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	factory := NewResolverFactory()
+	factory.now = func() time.Time { return now }
+	kms := &countingResolver{}
+	factory.Register("kms", kms)
+	factory.SetTTL("kms", time.Minute)
+
+	for range 3 {
+		got, err := factory.Resolve("kms://alias/porichoy")
+		assert.NoError(t, err)
+		assert.Equal(t, "kms://alias/porichoy#1", got)
+	}
+
+	now = now.Add(time.Minute)
+	got, err := factory.Resolve("kms://alias/porichoy")
+	assert.NoError(t, err)
+	assert.Equal(t, "kms://alias/porichoy#2", got)
+
+	// registering again drops what was cached for the scheme
+	factory.Register("kms", &countingResolver{calls: 10})
+	got, err = factory.Resolve("kms://alias/porichoy")
+	assert.NoError(t, err)
+	assert.Equal(t, "kms://alias/porichoy#11", got)
+}
+
+func TestResolverFactory_Forget(t *testing.T) {
+	t.Parallel()
+
+	factory := NewResolverFactory()
+	kms := &countingResolver{}
+	factory.Register("kms", kms)
+	factory.SetTTL("kms", time.Hour)
+
+	for _, key := range []string{"kms://alias/porichoy", "kms://alias/porichoy#jwt", "kms://alias/other"} {
+		_, err := factory.Resolve(key)
+		assert.NoError(t, err)
+	}
+	factory.Forget("kms://alias/porichoy")
+
+	// the key and its selectors are read again, other keys stay cached
+	got, err := factory.Resolve("kms://alias/porichoy")
+	assert.NoError(t, err)
+	assert.Equal(t, "kms://alias/porichoy#4", got)
+	got, err = factory.Resolve("kms://alias/porichoy#jwt")
+	assert.NoError(t, err)
+	assert.Equal(t, "kms://alias/porichoy#jwt#5", got)
+	got, err = factory.Resolve("kms://alias/other")
+	assert.NoError(t, err)
+	assert.Equal(t, "kms://alias/other#3", got)
+}
+
+// forgetfulResolver forgets the key while it is being read, like a rotation
+// landing in the middle of a fetch
+type forgetfulResolver struct {
+	countingResolver
+	factory *ResolverFactory
+}
+
+func (r *forgetfulResolver) Resolve(key string) (any, error) {
+	value, err := r.countingResolver.Resolve(key)
+	if r.calls == 1 {
+		r.factory.Forget(key)
+	}
+	return value, err
+}
+
+func TestResolverFactory_ForgetDuringResolve(t *testing.T) {
+	t.Parallel()
+
+	factory := NewResolverFactory()
+	kms := &forgetfulResolver{factory: factory}
+	factory.Register("kms", kms)
+	factory.SetTTL("kms", time.Hour)
+
+	got, err := factory.Resolve("kms://alias/porichoy")
+	assert.NoError(t, err)
+	assert.Equal(t, "kms://alias/porichoy#1", got)
+
+	// the value read before the forget is not cached
+	got, err = factory.Resolve("kms://alias/porichoy")
+	assert.NoError(t, err)
+	assert.Equal(t, "kms://alias/porichoy#2", got)
+	got, err = factory.Resolve("kms://alias/porichoy")
+	assert.NoError(t, err)
+	assert.Equal(t, "kms://alias/porichoy#2", got)
+}
@@ -0,0 +1,267 @@
+package resolver
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sha256 of an empty payload, every request the resolver sends is a GET
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// resolves from an object in an S3 compatible store, e.g.
+// s3://porichoy-secrets/jwt.pem or s3://porichoy-secrets/db.json#uri to pick
+// a key out of a JSON or YAML object like the file resolver does.
+//
+// It is configured with the usual AWS variables (AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN, AWS_REGION and AWS_ENDPOINT_URL_S3
+// for MinIO and friends). Objects encrypted with a customer key (SSE-C) are
+// read with PORICHOY_S3_SSE_CUSTOMER_KEY, a base64 encoded 256 bit key, the
+// other kinds of SSE need nothing on the reader's side.
+//
+// Objects are cached with their ETag and every resolve revalidates them with
+// a conditional GET. Through the process wide factory values are cached for
+// a minute on top, so a replaced object is picked up within a minute.
+type S3Resolver struct {
+	Endpoint        string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// base64 encoded key for SSE-C encrypted objects
+	SSECustomerKey string
+	Client         *http.Client
+
+	configure sync.Once
+	now       func() time.Time
+	mu        sync.Mutex
+	cache     map[string]s3Object
+}
+
+type s3Object struct {
+	etag    string
+	content []byte
+}
+
+func (r *S3Resolver) Resolve(key string) (any, error) {
+	r.configure.Do(r.fromEnv)
+	location, selector, _ := strings.Cut(strings.TrimPrefix(key, "s3://"), "#")
+	bucket, object, _ := strings.Cut(location, "/")
+	if bucket == "" || object == "" {
+		return nil, fmt.Errorf("s3_resolver: expected s3://bucket/key")
+	}
+	if r.AccessKeyID == "" || r.SecretAccessKey == "" {
+		return nil, fmt.Errorf("s3_resolver: no credentials configured, set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	content, err := r.get(ctx, bucket, object)
+	if err != nil {
+		return nil, err
+	}
+	if selector == "" {
+		return strings.TrimRight(string(content), "\r\n"), nil
+	}
+	value, err := selectKey(object, content, selector)
+	if err != nil {
+		return nil, fmt.Errorf("s3_resolver: %v", err)
+	}
+	return value, nil
+}
+
+// fromEnv fills what was not configured from the variables the aws cli uses.
+func (r *S3Resolver) fromEnv() {
+	fill := func(field *string, envs ...string) {
+		for _, env := range envs {
+			if *field == "" {
+				*field = os.Getenv(env)
+			}
+		}
+	}
+	fill(&r.Endpoint, "AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL")
+	fill(&r.Region, "AWS_REGION", "AWS_DEFAULT_REGION")
+	fill(&r.AccessKeyID, "AWS_ACCESS_KEY_ID")
+	fill(&r.SecretAccessKey, "AWS_SECRET_ACCESS_KEY")
+	fill(&r.SessionToken, "AWS_SESSION_TOKEN")
+	fill(&r.SSECustomerKey, "PORICHOY_S3_SSE_CUSTOMER_KEY")
+	if r.Region == "" {
+		r.Region = "us-east-1"
+	}
+	if r.Client == nil {
+		r.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if r.now == nil {
+		r.now = time.Now
+	}
+	r.Endpoint = strings.TrimRight(r.Endpoint, "/")
+}
+
+// objectURL addresses buckets by path on custom endpoints, which is what
+// MinIO and most other implementations expect, and by host on AWS.
+func (r *S3Resolver) objectURL(bucket string, object string) string {
+	if r.Endpoint != "" {
+		return r.Endpoint + "/" + bucket + "/" + escapePath(object)
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, r.Region, escapePath(object))
+}
+
+func (r *S3Resolver) get(ctx context.Context, bucket string, object string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.objectURL(bucket, object), nil)
+	if err != nil {
+		return nil, err
+	}
+	if r.SSECustomerKey != "" {
+		key, err := base64.StdEncoding.DecodeString(r.SSECustomerKey)
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("s3_resolver: the sse customer key must be a base64 encoded 256 bit key")
+		}
+		sum := md5.Sum(key)
+		req.Header.Set("X-Amz-Server-Side-Encryption-Customer-Algorithm", "AES256")
+		req.Header.Set("X-Amz-Server-Side-Encryption-Customer-Key", r.SSECustomerKey)
+		req.Header.Set("X-Amz-Server-Side-Encryption-Customer-Key-Md5", base64.StdEncoding.EncodeToString(sum[:]))
+	}
+
+	cacheKey := bucket + "/" + object
+	r.mu.Lock()
+	cached, ok := r.cache[cacheKey]
+	r.mu.Unlock()
+	if ok {
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+
+	signV4(req, r.AccessKeyID, r.SecretAccessKey, r.SessionToken, r.Region, r.now())
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("s3_resolver: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified && ok {
+		return cached.content, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("s3_resolver: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("s3_resolver: s3://%s returned %d: %s", cacheKey, resp.StatusCode, s3ErrorCode(body))
+	}
+
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		r.mu.Lock()
+		if r.cache == nil {
+			r.cache = map[string]s3Object{}
+		}
+		r.cache[cacheKey] = s3Object{etag: etag, content: body}
+		r.mu.Unlock()
+	}
+	return body, nil
+}
+
+// signV4 signs a request with AWS Signature Version 4, the host and every
+// header already set on the request are signed.
+func signV4(req *http.Request, accessKeyID string, secretAccessKey string, sessionToken string, region string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+	if sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		emptyPayloadHash,
+	}, "\n")
+	scope := date + "/" + region + "/s3/aws4_request"
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hashed[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", accessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	parts := []string{}
+	for _, k := range keys {
+		values := query[k]
+		slices.Sort(values)
+		for _, v := range values {
+			parts = append(parts, url.QueryEscape(k)+"="+strings.ReplaceAll(url.QueryEscape(v), "+", "%20"))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// escapePath escapes an object key the way S3 canonicalizes it, everything
+// but unreserved characters and the separators.
+func escapePath(key string) string {
+	var escaped strings.Builder
+	for _, b := range []byte(key) {
+		switch {
+		case 'a' <= b && b <= 'z', 'A' <= b && b <= 'Z', '0' <= b && b <= '9', strings.IndexByte("-_.~/", b) >= 0:
+			escaped.WriteByte(b)
+		default:
+			fmt.Fprintf(&escaped, "%%%02X", b)
+		}
+	}
+	return escaped.String()
+}
+
+// s3ErrorCode pulls the code out of an S3 xml error without parsing it all.
+func s3ErrorCode(body []byte) string {
+	_, rest, found := strings.Cut(string(body), "<Code>")
+	if !found {
+		return "unknown error"
+	}
+	code, _, _ := strings.Cut(rest, "</Code>")
+	return code
+}
@@ -0,0 +1,335 @@
+package resolver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// resolves from HashiCorp Vault (or anything speaking its http api), e.g.
+// vault://secret/porichoy/db#uri reads the uri field of porichoy/db from the
+// kv mount at secret/. KV v1 and v2 mounts are told apart by asking vault.
+//
+// It authenticates with VAULT_TOKEN, or with AppRole when VAULT_ROLE_ID and
+// VAULT_SECRET_ID are set. Secrets are cached for their lease and renewed
+// (or read again) once two thirds of it have passed, the same goes for the
+// AppRole token. The cache is shared but no lock is held while talking to
+// vault, concurrent misses may each read the secret.
+type VaultResolver struct {
+	Address   string
+	Namespace string
+	Token     string
+	RoleID    string
+	SecretID  string
+	// mount of the approle auth method, approle by default
+	AppRoleMount string
+	// how long secrets without a lease, like kv v2 ones, are cached
+	TTL    time.Duration
+	Client *http.Client
+
+	configure sync.Once
+	now       func() time.Time
+	// guards token, mounts and cache
+	mu     sync.Mutex
+	token  vaultLease
+	mounts map[string]vaultMount
+	cache  map[string]vaultSecret
+}
+
+type vaultLease struct {
+	id        string
+	renewable bool
+	renewAt   time.Time
+	expiresAt time.Time
+}
+
+type vaultMount struct {
+	path    string
+	version string
+}
+
+type vaultSecret struct {
+	data  map[string]any
+	lease vaultLease
+}
+
+type vaultResponse struct {
+	LeaseID       string         `json:"lease_id"`
+	LeaseDuration int            `json:"lease_duration"`
+	Renewable     bool           `json:"renewable"`
+	Data          map[string]any `json:"data"`
+	Auth          *struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int    `json:"lease_duration"`
+		Renewable     bool   `json:"renewable"`
+	} `json:"auth"`
+	Errors []string `json:"errors"`
+}
+
+func (r *VaultResolver) Resolve(key string) (any, error) {
+	r.configure.Do(r.fromEnv)
+	path, field, _ := strings.Cut(strings.TrimPrefix(key, "vault://"), "#")
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil, fmt.Errorf("vault_resolver: path is empty")
+	}
+	if r.Address == "" {
+		return nil, fmt.Errorf("vault_resolver: no vault address configured, set VAULT_ADDR")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	data, err := r.read(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if field == "" {
+		if len(data) != 1 {
+			return nil, fmt.Errorf("vault_resolver: %s has %d fields, select one with #field", path, len(data))
+		}
+		for k := range data {
+			field = k
+		}
+	}
+	value, ok := data[field]
+	if !ok {
+		return nil, fmt.Errorf("vault_resolver: %s has no field %s", path, field)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(value), nil
+}
+
+// fromEnv fills what was not configured from the variables the vault cli uses.
+func (r *VaultResolver) fromEnv() {
+	fill := func(field *string, env string) {
+		if *field == "" {
+			*field = os.Getenv(env)
+		}
+	}
+	fill(&r.Address, "VAULT_ADDR")
+	fill(&r.Namespace, "VAULT_NAMESPACE")
+	fill(&r.Token, "VAULT_TOKEN")
+	fill(&r.RoleID, "VAULT_ROLE_ID")
+	fill(&r.SecretID, "VAULT_SECRET_ID")
+	if r.AppRoleMount == "" {
+		r.AppRoleMount = "approle"
+	}
+	if r.TTL == 0 {
+		r.TTL = time.Minute
+	}
+	if r.Client == nil {
+		r.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if r.now == nil {
+		r.now = time.Now
+	}
+	r.Address = strings.TrimRight(r.Address, "/")
+}
+
+// read returns the data of a secret from the cache while its lease is fresh,
+// renews the lease when it can and reads the secret again otherwise.
+func (r *VaultResolver) read(ctx context.Context, path string) (map[string]any, error) {
+	now := r.now()
+	r.mu.Lock()
+	cached, ok := r.cache[path]
+	r.mu.Unlock()
+	if ok && now.Before(cached.lease.renewAt) {
+		return cached.data, nil
+	}
+	if ok && cached.lease.id != "" && cached.lease.renewable && now.Before(cached.lease.expiresAt) {
+		var resp vaultResponse
+		err := r.do(ctx, http.MethodPut, "sys/leases/renew", map[string]string{"lease_id": cached.lease.id}, &resp)
+		if err == nil {
+			cached.lease = r.lease(resp.LeaseID, resp.LeaseDuration, resp.Renewable)
+			r.store(path, cached)
+			return cached.data, nil
+		}
+	}
+
+	mount, err := r.mount(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	relative := strings.TrimPrefix(strings.TrimPrefix(path, mount.path), "/")
+	apiPath := path
+	if mount.version == "2" {
+		apiPath = mount.path + "/data/" + relative
+	}
+
+	var resp vaultResponse
+	if err := r.do(ctx, http.MethodGet, apiPath, nil, &resp); err != nil {
+		return nil, err
+	}
+	data := resp.Data
+	if mount.version == "2" {
+		nested, ok := resp.Data["data"].(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("vault_resolver: %s has no data, it may have been deleted", path)
+		}
+		data = nested
+	}
+	r.store(path, vaultSecret{data: data, lease: r.lease(resp.LeaseID, resp.LeaseDuration, resp.Renewable)})
+	return data, nil
+}
+
+func (r *VaultResolver) store(path string, secret vaultSecret) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cache == nil {
+		r.cache = map[string]vaultSecret{}
+	}
+	r.cache[path] = secret
+}
+
+// mount finds the kv mount a path lives in and its version. A failed lookup
+// is returned rather than guessed, reading a v2 mount as v1 would only fail
+// later with a confusing error.
+func (r *VaultResolver) mount(ctx context.Context, path string) (vaultMount, error) {
+	r.mu.Lock()
+	for prefix, mount := range r.mounts {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			r.mu.Unlock()
+			return mount, nil
+		}
+	}
+	r.mu.Unlock()
+
+	var resp vaultResponse
+	if err := r.do(ctx, http.MethodGet, "sys/internal/ui/mounts/"+path, nil, &resp); err != nil {
+		return vaultMount{}, fmt.Errorf("vault_resolver: looking up the mount of %s: %w", path, err)
+	}
+	mount := vaultMount{version: "1"}
+	mount.path, _, _ = strings.Cut(path, "/")
+	if p, ok := resp.Data["path"].(string); ok && p != "" {
+		mount.path = strings.Trim(p, "/")
+	}
+	if options, ok := resp.Data["options"].(map[string]any); ok {
+		if version, ok := options["version"].(string); ok && version != "" {
+			mount.version = version
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.mounts == nil {
+		r.mounts = map[string]vaultMount{}
+	}
+	r.mounts[mount.path] = mount
+	return mount, nil
+}
+
+// clientToken returns the token to talk to vault with, logging in with
+// AppRole or renewing the AppRole token as needed.
+func (r *VaultResolver) clientToken(ctx context.Context) (string, error) {
+	if r.RoleID == "" {
+		if r.Token == "" {
+			return "", fmt.Errorf("vault_resolver: no credentials configured, set VAULT_TOKEN or VAULT_ROLE_ID and VAULT_SECRET_ID")
+		}
+		return r.Token, nil
+	}
+
+	now := r.now()
+	r.mu.Lock()
+	token := r.token
+	r.mu.Unlock()
+	if token.id != "" && now.Before(token.renewAt) {
+		return token.id, nil
+	}
+	if token.id != "" && token.renewable && now.Before(token.expiresAt) {
+		var resp vaultResponse
+		err := r.send(ctx, http.MethodPost, "auth/token/renew-self", token.id, nil, &resp)
+		if err == nil && resp.Auth != nil {
+			return r.storeToken(r.lease(resp.Auth.ClientToken, resp.Auth.LeaseDuration, resp.Auth.Renewable)), nil
+		}
+	}
+
+	var resp vaultResponse
+	err := r.send(ctx, http.MethodPost, "auth/"+r.AppRoleMount+"/login", "", map[string]string{
+		"role_id":   r.RoleID,
+		"secret_id": r.SecretID,
+	}, &resp)
+	if err != nil {
+		return "", err
+	}
+	if resp.Auth == nil || resp.Auth.ClientToken == "" {
+		return "", fmt.Errorf("vault_resolver: approle login returned no token")
+	}
+	return r.storeToken(r.lease(resp.Auth.ClientToken, resp.Auth.LeaseDuration, resp.Auth.Renewable)), nil
+}
+
+func (r *VaultResolver) storeToken(token vaultLease) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.token = token
+	return token.id
+}
+
+// lease is renewed after two thirds of its duration, leases without a
+// duration are kept for the configured ttl.
+func (r *VaultResolver) lease(id string, seconds int, renewable bool) vaultLease {
+	now := r.now()
+	duration := time.Duration(seconds) * time.Second
+	if duration <= 0 {
+		return vaultLease{id: id, renewAt: now.Add(r.TTL), expiresAt: now.Add(r.TTL)}
+	}
+	return vaultLease{
+		id:        id,
+		renewable: renewable,
+		renewAt:   now.Add(duration * 2 / 3),
+		expiresAt: now.Add(duration),
+	}
+}
+
+func (r *VaultResolver) do(ctx context.Context, method string, path string, body any, out *vaultResponse) error {
+	token, err := r.clientToken(ctx)
+	if err != nil {
+		return err
+	}
+	return r.send(ctx, method, path, token, body, out)
+}
+
+func (r *VaultResolver) send(ctx context.Context, method string, path string, token string, body any, out *vaultResponse) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, r.Address+"/v1/"+path, reader)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if r.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", r.Namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("vault_resolver: %v", err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil && err != io.EOF {
+		return fmt.Errorf("vault_resolver: invalid response from %s: %v", path, err)
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("vault_resolver: %s returned %d: %s", path, resp.StatusCode, strings.Join(out.Errors, ", "))
+	}
+	return nil
+}
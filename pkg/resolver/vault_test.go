@@ -0,0 +1,213 @@
+package resolver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeVault answers the parts of the vault api the resolver uses, secret/ is
+// a kv v2 mount and legacy/ a kv v1 mount with leased secrets. Reading
+// legacy/slow waits for hold to be closed, telling arrived first.
+type fakeVault struct {
+	mu       sync.Mutex
+	token    string
+	requests map[string]int
+	dbURI    string
+	arrived  chan struct{}
+	hold     chan struct{}
+}
+
+func (v *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+	if path == "legacy/slow" && v.hold != nil {
+		v.arrived <- struct{}{}
+		<-v.hold
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.requests[r.Method+" "+path]++
+
+	reply := func(status int, body any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(body)
+	}
+
+	if path == "auth/approle/login" {
+		var creds map[string]string
+		json.NewDecoder(r.Body).Decode(&creds)
+		if creds["role_id"] != "role" || creds["secret_id"] != "secret" {
+			reply(http.StatusBadRequest, map[string]any{"errors": []string{"invalid role or secret ID"}})
+			return
+		}
+		v.token = "approle-token"
+		reply(http.StatusOK, map[string]any{"auth": map[string]any{"client_token": v.token, "lease_duration": 60, "renewable": true}})
+		return
+	}
+	if r.Header.Get("X-Vault-Token") != v.token {
+		reply(http.StatusForbidden, map[string]any{"errors": []string{"permission denied"}})
+		return
+	}
+
+	switch {
+	case path == "auth/token/renew-self":
+		reply(http.StatusOK, map[string]any{"auth": map[string]any{"client_token": v.token, "lease_duration": 60, "renewable": true}})
+	case path == "sys/leases/renew":
+		reply(http.StatusOK, map[string]any{"lease_id": "legacy/db/lease", "lease_duration": 30, "renewable": true})
+	case strings.HasPrefix(path, "sys/internal/ui/mounts/secret"):
+		reply(http.StatusOK, map[string]any{"data": map[string]any{"path": "secret/", "type": "kv", "options": map[string]any{"version": "2"}}})
+	case strings.HasPrefix(path, "sys/internal/ui/mounts/broken"):
+		reply(http.StatusInternalServerError, map[string]any{"errors": []string{"internal error"}})
+	case strings.HasPrefix(path, "sys/internal/ui/mounts/legacy"):
+		reply(http.StatusOK, map[string]any{"data": map[string]any{"path": "legacy/", "type": "kv", "options": nil}})
+	case path == "secret/data/porichoy/db":
+		reply(http.StatusOK, map[string]any{"data": map[string]any{
+			"data":     map[string]any{"uri": v.dbURI, "port": 5432},
+			"metadata": map[string]any{"version": 1},
+		}})
+	case path == "legacy/jwt", path == "legacy/slow":
+		reply(http.StatusOK, map[string]any{"lease_id": "legacy/db/lease", "lease_duration": 30, "renewable": true, "data": map[string]any{"secret": "legacy-secret"}})
+	default:
+		reply(http.StatusNotFound, map[string]any{"errors": []string{}})
+	}
+}
+
+func newFakeVault(t *testing.T) (*fakeVault, *httptest.Server) {
+	t.Helper()
+	vault := &fakeVault{token: "root-token", requests: map[string]int{}, dbURI: "postgresql://vault@db/porichoy"}
+	server := httptest.NewServer(vault)
+	t.Cleanup(server.Close)
+	return vault, server
+}
+
+func TestVaultResolver(t *testing.T) {
+	t.Parallel()
+	_, server := newFakeVault(t)
+
+	r := &VaultResolver{Address: server.URL, Token: "root-token"}
+	tests := []struct {
+		name    string
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{name: "kv v2 field", key: "vault://secret/porichoy/db#uri", want: "postgresql://vault@db/porichoy"},
+		{name: "kv v2 number", key: "vault://secret/porichoy/db#port", want: "5432"},
+		{name: "kv v1 only field", key: "vault://legacy/jwt", want: "legacy-secret"},
+		{name: "ambiguous field", key: "vault://secret/porichoy/db", wantErr: true},
+		{name: "missing field", key: "vault://secret/porichoy/db#password", wantErr: true},
+		{name: "missing secret", key: "vault://secret/porichoy/missing#uri", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.Resolve(tt.key)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	denied := &VaultResolver{Address: server.URL, Token: "wrong"}
+	_, err := denied.Resolve("vault://secret/porichoy/db#uri")
+	assert.Error(t, err)
+}
+
+func TestVaultResolver_CacheAndRenew(t *testing.T) {
+	t.Parallel()
+	vault, server := newFakeVault(t)
+	vault.token = ""
+
+	now := time.Now()
+	r := &VaultResolver{Address: server.URL, RoleID: "role", SecretID: "secret", TTL: time.Minute}
+	r.now = func() time.Time { return now }
+
+	got, err := r.Resolve("vault://secret/porichoy/db#uri")
+	assert.NoError(t, err)
+	assert.Equal(t, "postgresql://vault@db/porichoy", got)
+	_, err = r.Resolve("vault://legacy/jwt#secret")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, vault.requests["POST auth/approle/login"])
+
+	// fresh secrets are served from the cache
+	vault.mu.Lock()
+	vault.dbURI = "postgresql://rotated@db/porichoy"
+	vault.mu.Unlock()
+	got, err = r.Resolve("vault://secret/porichoy/db#uri")
+	assert.NoError(t, err)
+	assert.Equal(t, "postgresql://vault@db/porichoy", got)
+	assert.Equal(t, 1, vault.requests["GET secret/data/porichoy/db"])
+
+	// past two thirds of the lease the lease is renewed and the token as well
+	now = now.Add(25 * time.Second)
+	_, err = r.Resolve("vault://legacy/jwt#secret")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, vault.requests["PUT sys/leases/renew"])
+	assert.Equal(t, 1, vault.requests["GET legacy/jwt"])
+
+	now = now.Add(20 * time.Second)
+	_, err = r.Resolve("vault://legacy/jwt#secret")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, vault.requests["POST auth/token/renew-self"])
+
+	// secrets without a lease are read again after the ttl
+	now = now.Add(time.Minute)
+	got, err = r.Resolve("vault://secret/porichoy/db#uri")
+	assert.NoError(t, err)
+	assert.Equal(t, "postgresql://rotated@db/porichoy", got)
+	assert.Equal(t, 1, vault.requests["GET sys/internal/ui/mounts/secret/porichoy/db"])
+}
+
+func TestVaultResolver_MountLookupError(t *testing.T) {
+	t.Parallel()
+	vault, server := newFakeVault(t)
+
+	r := &VaultResolver{Address: server.URL, Token: "root-token"}
+	_, err := r.Resolve("vault://broken/porichoy/db#uri")
+	assert.ErrorContains(t, err, "internal error")
+	// the secret is not read as if the mount was kv v1
+	assert.Zero(t, vault.requests["GET broken/porichoy/db"])
+}
+
+func TestVaultResolver_ConcurrentRequests(t *testing.T) {
+	t.Parallel()
+	vault, server := newFakeVault(t)
+	vault.arrived = make(chan struct{})
+	vault.hold = make(chan struct{})
+
+	r := &VaultResolver{Address: server.URL, Token: "root-token"}
+	_, err := r.Resolve("vault://secret/porichoy/db#uri")
+	assert.NoError(t, err)
+
+	slow := make(chan error, 1)
+	go func() {
+		_, err := r.Resolve("vault://legacy/slow#secret")
+		slow <- err
+	}()
+	<-vault.arrived
+
+	// cached secrets are served while another request waits on vault
+	cached := make(chan error, 1)
+	go func() {
+		_, err := r.Resolve("vault://secret/porichoy/db#uri")
+		cached <- err
+	}()
+	select {
+	case err := <-cached:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Error("resolving a cached secret waited for another request")
+	}
+
+	close(vault.hold)
+	assert.NoError(t, <-slow)
+}
@@ -9,6 +9,7 @@ import (
 	"net/http"
 
 	"github.com/AlecAivazis/survey/v2"
+	"github.com/aritradeveops/porichoy/internal/core/jwtutil"
 	"github.com/spf13/cobra"
 	"github.com/zalando/go-keyring"
 )
@@ -24,10 +25,12 @@ type CreateAppPayload struct {
 	SuccessCallbackUrl   string   `json:"success_callback_url" validate:"required,url"`
 	ErrorCallbackUrl     string   `json:"error_callback_url" validate:"required,url"`
 	JwtAlgo              string   `json:"jwt_algo" validate:"required,jwt_algo"`
-	JwtSecretResolveFrom string   `json:"jwt_secret_resolve_from" validate:"oneof=env db literal"`
-	JwtSecretResolver    string   `json:"jwt_secret_resolver" validate:"required,resolver"`
+	JwtSecretResolveFrom string   `json:"jwt_secret_resolve_from" validate:"oneof=generate env file db vault s3 literal"`
+	JwtSecretResolver    string   `json:"jwt_secret_resolver,omitempty" validate:"omitempty,resolver"`
 	JwtLifetime          string   `json:"jwt_lifetime" validate:"required,duration"`
 	RefreshTokenLifetime string   `json:"refresh_token_lifetime" validate:"required,duration"`
+	RequireVerifiedEmail bool     `json:"require_verified_email"`
+	RequireMfa           bool     `json:"require_mfa"`
 }
 
 var appAddCmd = &cobra.Command{
@@ -73,7 +76,7 @@ func addApp(ctx context.Context) error {
 		{
 			Name: "JwtAlgo",
 			Prompt: &survey.Select{
-				Options: []string{"HS256", "RS256", "JWKS"},
+				Options: jwtutil.Algorithms,
 				Message: "Please select an option:",
 			},
 			Validate: survey.Required,
@@ -81,17 +84,10 @@ func addApp(ctx context.Context) error {
 		{
 			Name: "JwtSecretResolveFrom",
 			Prompt: &survey.Select{
-				Options: []string{"env", "file", "literal"},
+				Options: []string{"generate", "env", "file", "db", "vault", "s3", "literal"},
 				Message: "Please select an option:",
 			},
 		},
-		{
-			Name: "JwtSecretResolver",
-			Prompt: &survey.Input{
-				Message: "Jwt resolver:",
-			},
-			Validate: survey.Required,
-		},
 		{
 			Name: "JwtLifetime",
 			Prompt: &survey.Input{
@@ -127,6 +123,18 @@ func addApp(ctx context.Context) error {
 			},
 			Validate: survey.Required,
 		},
+		{
+			Name: "RequireVerifiedEmail",
+			Prompt: &survey.Confirm{
+				Message: "Require a verified email to sign in?",
+			},
+		},
+		{
+			Name: "RequireMfa",
+			Prompt: &survey.Confirm{
+				Message: "Require multi-factor authentication to sign in?",
+			},
+		},
 	}
 
 	var payload CreateAppPayload
@@ -134,8 +142,16 @@ func addApp(ctx context.Context) error {
 		return err
 	}
 
+	// generated keys are created and stored encrypted by porichoy
+	if payload.JwtSecretResolveFrom != "generate" {
+		err := survey.AskOne(&survey.Input{Message: "Jwt resolver:"}, &payload.JwtSecretResolver, survey.WithValidator(survey.Required))
+		if err != nil {
+			return err
+		}
+		payload.JwtSecretResolver = payload.JwtSecretResolveFrom + "://" + payload.JwtSecretResolver
+	}
+
 	payload.RedirectUris = []string{payload.RedirectUri}
-	payload.JwtSecretResolver = payload.JwtSecretResolveFrom + "://" + payload.JwtSecretResolver
 	body, _ := json.Marshal(payload)
 	req, err := http.NewRequest("POST", "http://localhost:8080/api/v1/apps/create", bytes.NewReader(body))
 	if err != nil {
@@ -155,5 +171,6 @@ func addApp(ctx context.Context) error {
 
 	fmt.Println("App created successfully")
 	fmt.Println("App:", string(body))
+	fmt.Println("Store the client_secret now, it is not shown again.")
 	return nil
 }
@@ -0,0 +1,170 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/aritradeveops/porichoy/internal/core/jwtutil"
+	"github.com/spf13/cobra"
+	"github.com/zalando/go-keyring"
+)
+
+type SigningKey struct {
+	Kid         string     `json:"kid"`
+	Algo        string     `json:"algo"`
+	State       string     `json:"state"`
+	ActivatesAt *time.Time `json:"activates_at"`
+	ActivatedAt *time.Time `json:"activated_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+type CreateSigningKeyPayload struct {
+	Algo           string     `json:"algo"`
+	SecretResolver string     `json:"secret_resolver,omitempty"`
+	ActivatesAt    *time.Time `json:"activates_at,omitempty"`
+}
+
+var keysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "Signing key management",
+	Long:  `Inspect, stage, rotate and revoke the signing keys of an app`,
+}
+
+var keysListCmd = &cobra.Command{
+	Use:   "list <client_id>",
+	Short: "List the signing keys of an app",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var keys []SigningKey
+		cobra.CheckErr(apiRequest(http.MethodGet, keysPath(args[0]), nil, &keys))
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "KID\tALG\tSTATE\tACTIVATES AT\tACTIVATED AT\tEXPIRES AT")
+		for _, key := range keys {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", key.Kid, key.Algo, key.State,
+				formatTime(key.ActivatesAt), formatTime(key.ActivatedAt), formatTime(key.ExpiresAt))
+		}
+		w.Flush()
+	},
+}
+
+var keysAddCmd = &cobra.Command{
+	Use:   "add <client_id>",
+	Short: "Stage the next signing key of an app",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		activatesAt, _ := cmd.Flags().GetString("activates-at")
+		payload := CreateSigningKeyPayload{}
+		if activatesAt != "" {
+			t, err := time.Parse(time.RFC3339, activatesAt)
+			cobra.CheckErr(err)
+			payload.ActivatesAt = &t
+		}
+		var resolveFrom string
+		questions := []*survey.Question{
+			{
+				Name: "Algo",
+				Prompt: &survey.Select{
+					Options: jwtutil.Algorithms,
+					Message: "Signing algorithm:",
+				},
+				Validate: survey.Required,
+			},
+		}
+		cobra.CheckErr(survey.Ask(questions, &payload))
+		cobra.CheckErr(survey.AskOne(&survey.Select{
+			Options: []string{"generate", "env", "file", "db", "vault", "s3", "literal"},
+			Message: "Resolve the key from:",
+		}, &resolveFrom))
+		if resolveFrom != "generate" {
+			cobra.CheckErr(survey.AskOne(&survey.Input{Message: "Key resolver:"}, &payload.SecretResolver, survey.WithValidator(survey.Required)))
+			payload.SecretResolver = resolveFrom + "://" + payload.SecretResolver
+		}
+
+		var key SigningKey
+		cobra.CheckErr(apiRequest(http.MethodPost, keysPath(args[0])+"/create", payload, &key))
+		fmt.Printf("Signing key %s staged as %s\n", key.Kid, key.State)
+	},
+}
+
+var keysRotateCmd = &cobra.Command{
+	Use:   "rotate <client_id>",
+	Short: "Activate the next signing key and retire the active one",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var key SigningKey
+		cobra.CheckErr(apiRequest(http.MethodPost, keysPath(args[0])+"/rotate", nil, &key))
+		fmt.Printf("Signing key %s is now active\n", key.Kid)
+	},
+}
+
+var keysRevokeCmd = &cobra.Command{
+	Use:   "revoke <client_id> <kid>",
+	Short: "Revoke a signing key, its tokens are rejected immediately",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		cobra.CheckErr(apiRequest(http.MethodPost, keysPath(args[0])+"/"+url.PathEscape(args[1])+"/revoke", nil, nil))
+		fmt.Printf("Signing key %s revoked\n", args[1])
+	},
+}
+
+func newKeysCmd() *cobra.Command {
+	keysAddCmd.Flags().String("activates-at", "", "RFC 3339 time to activate the key at, it is activated by the next rotate otherwise")
+	keysCmd.AddCommand(keysListCmd, keysAddCmd, keysRotateCmd, keysRevokeCmd)
+	return keysCmd
+}
+
+func keysPath(clientID string) string {
+	return "http://localhost:8080/api/v1/apps/" + url.PathEscape(clientID) + "/keys"
+}
+
+// apiRequest calls the porichoy api and decodes the data of the response
+// into out.
+func apiRequest(method, url string, payload any, out any) error {
+	accessToken, err := keyring.Get("porichoy", "access_token")
+	if err != nil {
+		return err
+	}
+	var body io.Reader
+	if payload != nil {
+		b, _ := json.Marshal(payload)
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("request failed: %s", string(respBody))
+	}
+	if out == nil {
+		return nil
+	}
+	response := struct {
+		Data any `json:"data"`
+	}{Data: out}
+	return json.Unmarshal(respBody, &response)
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Local().Format(time.DateTime)
+}
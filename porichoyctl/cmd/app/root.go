@@ -12,5 +12,7 @@ var appCmd = &cobra.Command{
 
 func NewCmd() *cobra.Command {
 	appCmd.AddCommand(appAddCmd)
+	appCmd.AddCommand(newKeysCmd())
+	appCmd.AddCommand(newRotateSecretCmd())
 	return appCmd
 }
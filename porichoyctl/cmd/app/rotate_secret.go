@@ -0,0 +1,54 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+type RotateClientSecretPayload struct {
+	GracePeriod string     `json:"grace_period,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+type RotatedClientSecret struct {
+	ClientSecret      string     `json:"client_secret"`
+	ExpiresAt         *time.Time `json:"expires_at"`
+	PreviousExpiresAt *time.Time `json:"previous_expires_at"`
+}
+
+var rotateSecretCmd = &cobra.Command{
+	Use:   "rotate-secret <client_id>",
+	Short: "Issue a new client secret for an app",
+	Long:  `Issue a new client secret, the current one keeps working for the grace period`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		gracePeriod, _ := cmd.Flags().GetString("grace-period")
+		expiresAt, _ := cmd.Flags().GetString("expires-at")
+		payload := RotateClientSecretPayload{GracePeriod: gracePeriod}
+		if expiresAt != "" {
+			t, err := time.Parse(time.RFC3339, expiresAt)
+			cobra.CheckErr(err)
+			payload.ExpiresAt = &t
+		}
+
+		var rotated RotatedClientSecret
+		path := "http://localhost:8080/api/v1/apps/" + url.PathEscape(args[0]) + "/rotate-secret"
+		cobra.CheckErr(apiRequest(http.MethodPost, path, payload, &rotated))
+		fmt.Println("Client secret:", rotated.ClientSecret)
+		fmt.Println("Store it now, it is not shown again.")
+		fmt.Println("Expires at:", formatTime(rotated.ExpiresAt))
+		if rotated.PreviousExpiresAt != nil {
+			fmt.Println("The previous secret works until", formatTime(rotated.PreviousExpiresAt))
+		}
+	},
+}
+
+func newRotateSecretCmd() *cobra.Command {
+	rotateSecretCmd.Flags().String("grace-period", "24h", "how long the current secret keeps working")
+	rotateSecretCmd.Flags().String("expires-at", "", "RFC 3339 time the new secret stops working at, it does not expire otherwise")
+	return rotateSecretCmd
+}
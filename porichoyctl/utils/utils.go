@@ -17,5 +17,5 @@ func Logo() string {
 }
 
 func Welcome() {
-	fmt.Println(logo)
+	fmt.Print(logo)
 }
@@ -0,0 +1,110 @@
+// Helpers for the passkey ceremonies. The server sends the options with
+// binary fields base64url encoded and expects the response the same way.
+
+function base64urlToBuffer(value) {
+  const base64 = value.replace(/-/g, "+").replace(/_/g, "/")
+  const padded = base64 + "=".repeat((4 - base64.length % 4) % 4)
+  return Uint8Array.from(atob(padded), c => c.charCodeAt(0)).buffer
+}
+
+function bufferToBase64url(buffer) {
+  const bytes = String.fromCharCode(...new Uint8Array(buffer))
+  return btoa(bytes).replace(/\+/g, "-").replace(/\//g, "_").replace(/=+$/, "")
+}
+
+function postJSON(url, payload) {
+  return fetch(url, {
+    method: "POST",
+    body: JSON.stringify(payload || {}),
+    headers: {
+      "Content-Type": "application/json"
+    }
+  }).then(res => res.json().then(body => {
+    if (!res.ok) {
+      throw new Error(body.message)
+    }
+    return body
+  }))
+}
+
+function creationOptions(options) {
+  const publicKey = { ...options.publicKey }
+  publicKey.challenge = base64urlToBuffer(publicKey.challenge)
+  publicKey.user = { ...publicKey.user, id: base64urlToBuffer(publicKey.user.id) }
+  publicKey.excludeCredentials = (publicKey.excludeCredentials || []).map(credential => ({
+    ...credential,
+    id: base64urlToBuffer(credential.id)
+  }))
+  return { publicKey }
+}
+
+function requestOptions(options) {
+  const publicKey = { ...options.publicKey }
+  publicKey.challenge = base64urlToBuffer(publicKey.challenge)
+  publicKey.allowCredentials = (publicKey.allowCredentials || []).map(credential => ({
+    ...credential,
+    id: base64urlToBuffer(credential.id)
+  }))
+  return { publicKey }
+}
+
+function encodeAttestation(credential) {
+  return {
+    id: credential.id,
+    rawId: bufferToBase64url(credential.rawId),
+    type: credential.type,
+    response: {
+      clientDataJSON: bufferToBase64url(credential.response.clientDataJSON),
+      attestationObject: bufferToBase64url(credential.response.attestationObject),
+      transports: credential.response.getTransports ? credential.response.getTransports() : []
+    }
+  }
+}
+
+function encodeAssertion(credential) {
+  return {
+    id: credential.id,
+    rawId: bufferToBase64url(credential.rawId),
+    type: credential.type,
+    response: {
+      clientDataJSON: bufferToBase64url(credential.response.clientDataJSON),
+      authenticatorData: bufferToBase64url(credential.response.authenticatorData),
+      signature: bufferToBase64url(credential.response.signature),
+      userHandle: credential.response.userHandle ? bufferToBase64url(credential.response.userHandle) : null
+    }
+  }
+}
+
+// registerPasskey adds a passkey to the account of the logged in user.
+async function registerPasskey(name) {
+  const begin = await postJSON("/api/v1/me/passkeys/register/begin")
+  const credential = await navigator.credentials.create(creationOptions(begin.data.options))
+  return postJSON("/api/v1/me/passkeys/register/finish", {
+    session: begin.data.session,
+    name,
+    credential: encodeAttestation(credential)
+  })
+}
+
+// loginWithPasskey signs in without a password, the authenticator picks
+// the account.
+async function loginWithPasskey() {
+  const begin = await postJSON("/api/v1/auth/passkey/begin")
+  const credential = await navigator.credentials.get(requestOptions(begin.data.options))
+  return postJSON("/api/v1/auth/passkey/finish", {
+    session: begin.data.session,
+    credential: encodeAssertion(credential)
+  })
+}
+
+// verifyPasskeyMfa completes a password login with a passkey as the second
+// factor.
+async function verifyPasskeyMfa(mfa_token) {
+  const begin = await postJSON("/api/v1/auth/mfa/passkey/begin", { mfa_token })
+  const credential = await navigator.credentials.get(requestOptions(begin.data.options))
+  return postJSON("/api/v1/auth/mfa/passkey/finish", {
+    mfa_token,
+    session: begin.data.session,
+    credential: encodeAssertion(credential)
+  })
+}